@@ -0,0 +1,168 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+// flarereplay replays a chain exported by admin_exportChain on top of its
+// genesis in a fresh database. It reports the first block whose state root,
+// receipts root or minted inflation diverges, and writes a per block summary
+// of the Flare system contract activity.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow"
+	"github.com/ava-labs/coreth/cmd/utils"
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/internal/flags"
+	"github.com/ava-labs/coreth/plugin/evm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	genesisFlag = &cli.StringFlag{
+		Name:  "genesis",
+		Usage: "Path to the genesis json of the exported chain",
+	}
+	chainFlag = &cli.StringFlag{
+		Name:  "chain",
+		Usage: "Path to the RLP file written by admin_exportChain (gzipped if it ends in .gz), or to the chain archive directory written by admin_exportChainArchive",
+	}
+	networkIDFlag = &cli.UintFlag{
+		Name:  "network-id",
+		Usage: "ID of the network whose upgrades apply to the chain (default = genesis config as is)",
+	}
+	assetIDFlag = &cli.StringFlag{
+		Name:  "avax-asset-id",
+		Usage: "ID of the primary network asset, required to replay blocks with atomic transactions",
+	}
+	datadirFlag = &cli.StringFlag{
+		Name:  "datadir",
+		Usage: "Empty directory to replay the chain into (default = in memory)",
+	}
+	summaryFlag = &cli.StringFlag{
+		Name:  "summary",
+		Usage: "Output file for the per block summary of Flare system contract activity (default = stdout)",
+	}
+	referenceFlag = &cli.StringFlag{
+		Name:  "reference",
+		Usage: "Summary written by an earlier replay to compare the minted inflation against",
+	}
+)
+
+var app = flags.NewApp("Flare state transition replay tool")
+
+func init() {
+	app.Name = "flarereplay"
+	app.Flags = []cli.Flag{
+		genesisFlag,
+		chainFlag,
+		networkIDFlag,
+		assetIDFlag,
+		datadirFlag,
+		summaryFlag,
+		referenceFlag,
+	}
+	app.Action = flarereplay
+}
+
+func flarereplay(c *cli.Context) error {
+	if c.String(genesisFlag.Name) == "" {
+		utils.Fatalf("No genesis specified (--genesis)")
+	}
+	if c.String(chainFlag.Name) == "" {
+		utils.Fatalf("No exported chain specified (--chain)")
+	}
+	genesis, err := readGenesis(c.String(genesisFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to read genesis: %v", err)
+	}
+	ctx := &snow.Context{NetworkID: uint32(c.Uint(networkIDFlag.Name))}
+	if id := c.String(assetIDFlag.Name); id != "" {
+		if ctx.AVAXAssetID, err = ids.FromString(id); err != nil {
+			utils.Fatalf("Invalid asset ID %q: %v", id, err)
+		}
+	}
+	if err := evm.ConfigureReplayGenesis(genesis, ctx); err != nil {
+		utils.Fatalf("Failed to configure chain: %v", err)
+	}
+
+	var reference map[uint64]*blockSummary
+	if path := c.String(referenceFlag.Name); path != "" {
+		if reference, err = readSummaries(path); err != nil {
+			utils.Fatalf("Failed to read reference summary: %v", err)
+		}
+	}
+	var out io.Writer = os.Stdout
+	if path := c.String(summaryFlag.Name); path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			utils.Fatalf("Failed to create summary file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	db, err := openDatabase(c.String(datadirFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	r, err := newReplayer(db, genesis, ctx, reference)
+	if err != nil {
+		utils.Fatalf("Failed to create chain: %v", err)
+	}
+	defer r.stop()
+
+	div, err := r.replayFile(c.String(chainFlag.Name), json.NewEncoder(out))
+	if err != nil {
+		return err
+	}
+	if div != nil {
+		div.log()
+		return fmt.Errorf("block %d (%s) diverged", div.Number, div.Hash.Hex())
+	}
+	return nil
+}
+
+// readGenesis reads the genesis json at [path].
+func readGenesis(path string) (*core.Genesis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	genesis := new(core.Genesis)
+	if err := json.Unmarshal(data, genesis); err != nil {
+		return nil, err
+	}
+	if genesis.Config == nil {
+		return nil, fmt.Errorf("genesis %s has no chain config", path)
+	}
+	return genesis, nil
+}
+
+// openDatabase opens a fresh database in [dir], or in memory if [dir] is empty.
+func openDatabase(dir string) (ethdb.Database, error) {
+	if dir == "" {
+		return rawdb.NewMemoryDatabase(), nil
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("directory %s is not empty", dir)
+	}
+	return rawdb.NewLevelDBDatabase(dir, 512, 256, "", false)
+}
+
+func main() {
+	log.SetDefault(log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stderr, log.LevelInfo, true)))
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
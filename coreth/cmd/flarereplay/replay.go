@@ -0,0 +1,292 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow"
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/internal/chainarchive"
+	"github.com/ava-labs/coreth/plugin/evm"
+	"github.com/ava-labs/coreth/trie"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// progressInterval is how often the replay progress is logged.
+const progressInterval = 8 * time.Second
+
+var errAtomicAssetID = errors.New("block has atomic transactions, set the primary network asset ID (--avax-asset-id)")
+
+// mismatch is a value of a replayed block that differs from the expected one.
+type mismatch struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Replayed string `json:"replayed"`
+}
+
+// divergence is the first replayed block that did not reproduce the exported
+// chain or the reference summary.
+type divergence struct {
+	Number     uint64      `json:"number"`
+	Hash       common.Hash `json:"hash"`
+	Mismatches []mismatch  `json:"mismatches"`
+	Error      string      `json:"error,omitempty"` // why the block was rejected
+}
+
+func (d *divergence) add(field string, expected, replayed fmt.Stringer) {
+	if expected.String() != replayed.String() {
+		d.Mismatches = append(d.Mismatches, mismatch{
+			Field:    field,
+			Expected: expected.String(),
+			Replayed: replayed.String(),
+		})
+	}
+}
+
+func (d *divergence) log() {
+	log.Error("Replayed block diverged", "number", d.Number, "hash", d.Hash, "err", d.Error)
+	for _, m := range d.Mismatches {
+		log.Error("Mismatch", "field", m.Field, "expected", m.Expected, "replayed", m.Replayed)
+	}
+}
+
+// replayer replays exported blocks on a fresh chain.
+type replayer struct {
+	db        ethdb.Database
+	chain     *core.BlockChain
+	ctx       *snow.Context
+	reference map[uint64]*blockSummary // summaries of an earlier replay, if any
+}
+
+func newReplayer(db ethdb.Database, genesis *core.Genesis, ctx *snow.Context, reference map[uint64]*blockSummary) (*replayer, error) {
+	engine := dummy.NewFakerWithCallbacks(evm.NewReplayCallbacks(ctx, genesis.Config))
+	chain, err := core.NewBlockChain(db, core.DefaultCacheConfig, genesis, engine, vm.Config{}, common.Hash{}, false)
+	if err != nil {
+		return nil, err
+	}
+	return &replayer{
+		db:        db,
+		chain:     chain,
+		ctx:       ctx,
+		reference: reference,
+	}, nil
+}
+
+func (r *replayer) stop() {
+	r.chain.Stop()
+}
+
+// replayFile replays the blocks exported to [path] in order and encodes the
+// summary of each of them to [enc]. It stops at the first diverging block and
+// returns it.
+func (r *replayer) replayFile(path string, enc *json.Encoder) (*divergence, error) {
+	next, closer, err := openBlocks(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closer()
+
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		count  int
+		last   *types.Block // last parsed block, locating parse failures
+	)
+	for {
+		block, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			if last == nil {
+				return nil, fmt.Errorf("first block: failed to parse: %w", err)
+			}
+			return nil, fmt.Errorf("block %d: failed to parse: %w", last.NumberU64()+1, err)
+		}
+		last = block
+		// The genesis block is only checked against the one of the chain.
+		if block.NumberU64() == 0 {
+			if genesis := r.chain.Genesis(); block.Hash() != genesis.Hash() {
+				return nil, fmt.Errorf("exported genesis %s does not match %s", block.Hash().Hex(), genesis.Hash().Hex())
+			}
+			continue
+		}
+		summary, div, err := r.replayBlock(block)
+		if err != nil || div != nil {
+			return div, err
+		}
+		if err := enc.Encode(summary); err != nil {
+			return nil, err
+		}
+		count++
+		if time.Since(logged) > progressInterval {
+			log.Info("Replaying blocks", "count", count, "number", block.NumberU64(), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	r.chain.DrainAcceptorQueue()
+	log.Info("Replayed blocks", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil, nil
+}
+
+// openBlocks returns an iterator over the blocks exported to [path], either a
+// chain archive directory or a file of RLP encoded blocks, gzipped if it ends
+// in .gz. The iterator returns io.EOF after the last block.
+func openBlocks(path string) (func() (*types.Block, error), func(), error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		archive, err := chainarchive.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		var (
+			chunk   int
+			entries []*chainarchive.Entry
+		)
+		next := func() (*types.Block, error) {
+			for len(entries) == 0 {
+				if chunk == len(archive.Manifest().Chunks) {
+					return nil, io.EOF
+				}
+				var err error
+				if entries, err = archive.ReadChunk(chunk); err != nil {
+					return nil, err
+				}
+				chunk++
+			}
+			block := entries[0].Block
+			entries = entries[1:]
+			return block, nil
+		}
+		return next, func() {}, nil
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var reader io.Reader = in
+	if strings.HasSuffix(path, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			in.Close()
+			return nil, nil, err
+		}
+	}
+	stream := rlp.NewStream(reader, 0)
+	next := func() (*types.Block, error) {
+		block := new(types.Block)
+		if err := stream.Decode(block); err != nil {
+			return nil, err
+		}
+		return block, nil
+	}
+	return next, func() { in.Close() }, nil
+}
+
+// replayBlock inserts and accepts [block] and returns its summary, or the
+// divergence if it could not be reproduced.
+func (r *replayer) replayBlock(block *types.Block) (*blockSummary, *divergence, error) {
+	if len(block.ExtData()) > 0 && r.ctx.AVAXAssetID == ids.Empty {
+		return nil, nil, fmt.Errorf("block %d: %w", block.NumberU64(), errAtomicAssetID)
+	}
+	if err := r.chain.InsertBlock(block); err != nil {
+		div, derr := r.diagnose(block, err)
+		return nil, div, derr
+	}
+	flare := &core.FlareResults{
+		Daemons:     rawdb.ReadDaemonResults(r.db, block.Hash(), block.NumberU64()),
+		Fees:        rawdb.ReadFeeTreatments(r.db, block.Hash(), block.NumberU64()),
+		Divergences: rawdb.ReadAttestationDivergences(r.db, block.Hash(), block.NumberU64()),
+	}
+	// Governance changes are not journaled by the chain, so the blocks that
+	// signal them are replayed once more while the state of their parent is
+	// still available.
+	if r.chain.HasGovernanceCalls(block) {
+		parent := r.chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+		statedb, err := r.chain.StateAt(parent.Root)
+		if err != nil {
+			return nil, nil, err
+		}
+		if flare.Governance, err = r.chain.ReplayGovernanceChanges(block, statedb); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := r.chain.Accept(block); err != nil {
+		return nil, nil, err
+	}
+	summary := newBlockSummary(block, flare)
+	div := &divergence{Number: block.NumberU64(), Hash: block.Hash()}
+	if err := r.checkReference(div, summary); err != nil {
+		return nil, nil, err
+	}
+	if len(div.Mismatches) > 0 {
+		return summary, div, nil
+	}
+	return summary, nil, nil
+}
+
+// diagnose processes [block], which the chain rejected with [insertErr], once
+// more on top of the state of its parent to find which of its roots and
+// totals diverge.
+func (r *replayer) diagnose(block *types.Block, insertErr error) (*divergence, error) {
+	div := &divergence{
+		Number: block.NumberU64(),
+		Hash:   block.Hash(),
+		Error:  insertErr.Error(),
+	}
+	parent := r.chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("block %d: parent %s not found: %w", block.NumberU64(), block.ParentHash().Hex(), insertErr)
+	}
+	statedb, err := r.chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	receipts, _, flare, usedGas, err := r.chain.Processor().Process(block, parent, statedb, *r.chain.GetVMConfig())
+	if err != nil {
+		// The block could not be processed at all, so there are no roots to
+		// compare.
+		div.Error = err.Error()
+		return div, nil
+	}
+	var (
+		root        = statedb.IntermediateRoot(r.chain.Config().IsEIP158(block.Number()))
+		receiptHash = types.DeriveSha(receipts, trie.NewStackTrie(nil))
+	)
+	div.add("stateRoot", block.Root(), root)
+	div.add("receiptsRoot", block.ReceiptHash(), receiptHash)
+	div.add("gasUsed", new(big.Int).SetUint64(block.GasUsed()), new(big.Int).SetUint64(usedGas))
+	if err := r.checkReference(div, newBlockSummary(block, flare)); err != nil {
+		return nil, err
+	}
+	return div, nil
+}
+
+// checkReference compares the minted inflation of [summary] with the one of
+// the reference summary of the same block, if any.
+func (r *replayer) checkReference(div *divergence, summary *blockSummary) error {
+	ref, ok := r.reference[summary.Number]
+	if !ok {
+		return nil
+	}
+	if ref.Hash != summary.Hash {
+		return fmt.Errorf("block %d: reference summary is of block %s, not %s", summary.Number, ref.Hash.Hex(), summary.Hash.Hex())
+	}
+	div.add("minted", ref.Minted, summary.Minted)
+	return nil
+}
@@ -0,0 +1,272 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/snow"
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/internal/chainarchive"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ava-labs/coreth/plugin/evm"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+// replayBlocks is the number of blocks of the chain generated by
+// newReplayChain.
+const replayBlocks = 4
+
+// newReplayChain generates a chain on a Flare genesis configured like the
+// replay tool does, with a transfer in every block and a daemon minting 1000
+// after each of them. It returns the genesis, the replay context and the
+// chain, starting with the genesis block.
+func newReplayChain(t *testing.T) (*core.Genesis, *snow.Context, []*types.Block) {
+	var (
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr      = crypto.PubkeyToAddress(key.PublicKey)
+		config    = *params.TestFlareChainConfig
+		ctx       = &snow.Context{}
+		blockTime = uint64(time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC).Unix())
+	)
+	genesis := &core.Genesis{
+		Config:    &config,
+		Timestamp: blockTime,
+		GasLimit:  params.CortinaGasLimit,
+		Alloc: types.GenesisAlloc{
+			addr: {Balance: new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(100))},
+			// Returns a mint request of 1000: PUSH2 0x03e8 PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+			config.SystemContracts().Daemon.Address: {Balance: common.Big0, Code: []byte{0x61, 0x03, 0xe8, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}},
+		},
+	}
+	require.NoError(t, evm.ConfigureReplayGenesis(genesis, ctx))
+
+	signer := types.LatestSigner(genesis.Config)
+	engine := dummy.NewFakerWithCallbacks(evm.NewReplayCallbacks(ctx, genesis.Config))
+	_, blocks, _, err := core.GenerateChainWithGenesis(genesis, engine, replayBlocks, 10, func(i int, block *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), common.Address{0xaa}, big.NewInt(1), params.TxGas, big.NewInt(225*params.GWei), nil), signer, key)
+		require.NoError(t, err)
+		block.AddTx(tx)
+	})
+	require.NoError(t, err)
+	return genesis, ctx, append([]*types.Block{genesis.ToBlock()}, blocks...)
+}
+
+// writeBlocks exports [blocks] to [path] like admin_exportChain, gzipped if
+// the path ends in .gz.
+func writeBlocks(t *testing.T, path string, blocks []*types.Block) {
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var out io.Writer = file
+	if filepath.Ext(path) == ".gz" {
+		gz := gzip.NewWriter(file)
+		defer gz.Close()
+		out = gz
+	}
+	for _, block := range blocks {
+		require.NoError(t, rlp.Encode(out, block))
+	}
+}
+
+// writeArchive exports [blocks] to a chain archive in [dir] like
+// admin_exportChainArchive.
+func writeArchive(t *testing.T, dir string, genesis *core.Genesis, ctx *snow.Context, blocks []*types.Block) {
+	engine := dummy.NewFakerWithCallbacks(evm.NewReplayCallbacks(ctx, genesis.Config))
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), core.DefaultCacheConfig, genesis, engine, vm.Config{}, common.Hash{}, false)
+	require.NoError(t, err)
+	defer chain.Stop()
+
+	_, err = chain.InsertChain(blocks[1:])
+	require.NoError(t, err)
+	for _, block := range blocks[1:] {
+		require.NoError(t, chain.Accept(block))
+	}
+	chain.DrainAcceptorQueue()
+	require.NoError(t, chainarchive.Export(chain, nil, dir, 0, uint64(len(blocks)-1)))
+}
+
+// replay replays the chain exported to [path] on a fresh chain, comparing it
+// against [reference], and returns the summaries written by the replay.
+func replay(t *testing.T, genesis *core.Genesis, ctx *snow.Context, path string, reference map[uint64]*blockSummary) ([]*blockSummary, *divergence, error) {
+	r, err := newReplayer(rawdb.NewMemoryDatabase(), genesis, ctx, reference)
+	require.NoError(t, err)
+	defer r.stop()
+
+	var out bytes.Buffer
+	div, err := r.replayFile(path, json.NewEncoder(&out))
+
+	var (
+		summaries []*blockSummary
+		dec       = json.NewDecoder(&out)
+	)
+	for {
+		summary := new(blockSummary)
+		if err := dec.Decode(summary); errors.Is(err, io.EOF) {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, div, err
+}
+
+// Tests that the chains exported in every supported format replay without
+// divergence, and that the summaries report the inflation minted by the
+// daemon.
+func TestReplay(t *testing.T) {
+	genesis, ctx, blocks := newReplayChain(t)
+	dir := t.TempDir()
+
+	paths := map[string]string{
+		"rlp":     filepath.Join(dir, "chain.rlp"),
+		"gzipped": filepath.Join(dir, "chain.rlp.gz"),
+		"archive": filepath.Join(dir, "archive"),
+	}
+	writeBlocks(t, paths["rlp"], blocks)
+	writeBlocks(t, paths["gzipped"], blocks)
+	writeArchive(t, paths["archive"], genesis, ctx, blocks)
+
+	for name, path := range paths {
+		t.Run(name, func(t *testing.T) {
+			// The exported blocks are read back in order, genesis included.
+			next, closer, err := openBlocks(path)
+			require.NoError(t, err)
+			for _, want := range blocks {
+				block, err := next()
+				require.NoError(t, err)
+				require.Equal(t, want.Hash(), block.Hash())
+			}
+			_, err = next()
+			require.ErrorIs(t, err, io.EOF)
+			closer()
+
+			summaries, div, err := replay(t, genesis, ctx, path, nil)
+			require.NoError(t, err)
+			require.Nil(t, div)
+			require.Len(t, summaries, replayBlocks)
+			for i, summary := range summaries {
+				require.Equal(t, blocks[i+1].Hash(), summary.Hash)
+				require.Equal(t, 1, summary.Transactions)
+				require.Equal(t, 1, summary.DaemonCalls)
+				require.Zero(t, summary.DaemonErrors)
+				require.Equal(t, big.NewInt(1000), summary.Minted.ToInt())
+			}
+		})
+	}
+
+	// A truncated export is reported at the block following the last one
+	// parsed.
+	truncated := filepath.Join(dir, "truncated.rlp")
+	writeBlocks(t, truncated, blocks[:replayBlocks])
+	file, err := os.OpenFile(truncated, os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = file.Write([]byte{0xf9, 0x01})
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	_, _, err = replay(t, genesis, ctx, truncated, nil)
+	require.ErrorContains(t, err, "block 4: failed to parse")
+}
+
+// Tests that the replay stops at the first block whose state root is not
+// reproduced and reports the diverging root.
+func TestReplayDivergence(t *testing.T) {
+	genesis, ctx, blocks := newReplayChain(t)
+
+	// Inject a divergence in the third block, as a change of the state
+	// transition would.
+	header := blocks[3].Header()
+	header.Root = common.Hash{0x01}
+	diverged := append(append([]*types.Block{}, blocks[:3]...), blocks[3].WithSeal(header))
+	diverged = append(diverged, blocks[4:]...)
+	path := filepath.Join(t.TempDir(), "chain.rlp")
+	writeBlocks(t, path, diverged)
+
+	summaries, div, err := replay(t, genesis, ctx, path, nil)
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	require.NotNil(t, div)
+	require.Equal(t, uint64(3), div.Number)
+	require.Equal(t, diverged[3].Hash(), div.Hash)
+	require.NotEmpty(t, div.Error)
+	require.Equal(t, []mismatch{{
+		Field:    "stateRoot",
+		Expected: header.Root.String(),
+		Replayed: blocks[3].Root().String(),
+	}}, div.Mismatches)
+
+	// A chain exported from another genesis is not replayed at all.
+	other := *genesis
+	other.Timestamp++
+	_, _, err = replay(t, &other, ctx, path, nil)
+	require.ErrorContains(t, err, "does not match")
+}
+
+// Tests that the minted inflation is compared against the summaries of an
+// earlier replay given as reference.
+func TestReplayReference(t *testing.T) {
+	genesis, ctx, blocks := newReplayChain(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chain.rlp")
+	writeBlocks(t, path, blocks)
+
+	summaries, div, err := replay(t, genesis, ctx, path, nil)
+	require.NoError(t, err)
+	require.Nil(t, div)
+
+	// writeReference writes [summaries] to a reference file and reads it back
+	// like --reference does.
+	writeReference := func(summaries []*blockSummary) map[uint64]*blockSummary {
+		ref := filepath.Join(dir, "reference.jsonl")
+		file, err := os.Create(ref)
+		require.NoError(t, err)
+		enc := json.NewEncoder(file)
+		for _, summary := range summaries {
+			require.NoError(t, enc.Encode(summary))
+		}
+		require.NoError(t, file.Close())
+		reference, err := readSummaries(ref)
+		require.NoError(t, err)
+		return reference
+	}
+
+	// The replay matches its own summaries.
+	_, div, err = replay(t, genesis, ctx, path, writeReference(summaries))
+	require.NoError(t, err)
+	require.Nil(t, div)
+
+	// A block minting a different amount than the reference diverges.
+	summaries[1].Minted.ToInt().SetUint64(999)
+	replayed, div, err := replay(t, genesis, ctx, path, writeReference(summaries))
+	require.NoError(t, err)
+	require.Len(t, replayed, 1)
+	require.NotNil(t, div)
+	require.Equal(t, uint64(2), div.Number)
+	require.Empty(t, div.Error)
+	require.Equal(t, []mismatch{{Field: "minted", Expected: "0x3e7", Replayed: "0x3e8"}}, div.Mismatches)
+
+	// A reference summary of another block is rejected.
+	summaries[1].Minted.ToInt().SetUint64(1000)
+	summaries[1].Hash = common.Hash{0x01}
+	_, _, err = replay(t, genesis, ctx, path, writeReference(summaries))
+	require.ErrorContains(t, err, "reference summary is of block")
+}
@@ -0,0 +1,105 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// blockSummary is the Flare system contract activity of a replayed block.
+type blockSummary struct {
+	Number       uint64      `json:"number"`
+	Hash         common.Hash `json:"hash"`
+	Transactions int         `json:"transactions"`
+
+	// daemon calls, the inflation they requested and the inflation minted
+	DaemonCalls  int          `json:"daemonCalls"`
+	DaemonErrors int          `json:"daemonErrors"`
+	MintRequest  *hexutil.Big `json:"mintRequest"`
+	Minted       *hexutil.Big `json:"minted"`
+
+	// fees paid to the burn address, net of the refunds of prioritised calls
+	Prioritised int          `json:"prioritised"`
+	Burned      *hexutil.Big `json:"burned"`
+	Refunded    *hexutil.Big `json:"refunded"`
+
+	// state connector rounds on which the local attestors diverged
+	AttestationDivergences int `json:"attestationDivergences"`
+
+	// kinds of the governance changes signalled by the transactions
+	Governance []string `json:"governance,omitempty"`
+}
+
+// newBlockSummary summarises the Flare results of processing [block].
+func newBlockSummary(block *types.Block, flare *core.FlareResults) *blockSummary {
+	var (
+		mintRequest = new(big.Int)
+		minted      = new(big.Int)
+		burned      = new(big.Int)
+		refunded    = new(big.Int)
+	)
+	summary := &blockSummary{
+		Number:                 block.NumberU64(),
+		Hash:                   block.Hash(),
+		Transactions:           len(block.Transactions()),
+		DaemonCalls:            len(flare.Daemons),
+		AttestationDivergences: len(flare.Divergences),
+	}
+	for _, result := range flare.Daemons {
+		if result.Error != "" {
+			summary.DaemonErrors++
+		}
+		mintRequest.Add(mintRequest, result.MintRequest)
+		minted.Add(minted, result.Minted)
+	}
+	for _, treatment := range flare.Fees {
+		if treatment.Prioritised {
+			summary.Prioritised++
+		}
+		burned.Add(burned, treatment.Fee)
+		burned.Sub(burned, treatment.Refund)
+		refunded.Add(refunded, treatment.Refund)
+	}
+	for _, change := range flare.Governance {
+		summary.Governance = append(summary.Governance, change.Kind)
+	}
+	summary.MintRequest = (*hexutil.Big)(mintRequest)
+	summary.Minted = (*hexutil.Big)(minted)
+	summary.Burned = (*hexutil.Big)(burned)
+	summary.Refunded = (*hexutil.Big)(refunded)
+	return summary
+}
+
+// readSummaries reads the block summaries written by an earlier replay to
+// [path], keyed by block number.
+func readSummaries(path string) (map[uint64]*blockSummary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var (
+		summaries = make(map[uint64]*blockSummary)
+		dec       = json.NewDecoder(file)
+	)
+	for {
+		summary := new(blockSummary)
+		if err := dec.Decode(summary); errors.Is(err, io.EOF) {
+			return summaries, nil
+		} else if err != nil {
+			return nil, err
+		}
+		summaries[summary.Number] = summary
+	}
+}
@@ -0,0 +1,60 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockSummary(t *testing.T) {
+	t.Parallel()
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7)})
+	flare := &core.FlareResults{
+		Daemons: types.DaemonResults{
+			{MintRequest: big.NewInt(100), Minted: big.NewInt(100)},
+			{MintRequest: big.NewInt(50), Minted: big.NewInt(0), Error: "mint request exceeds max"},
+		},
+		Fees: types.FeeTreatments{
+			{Fee: big.NewInt(30), Refund: big.NewInt(0)},
+			{Prioritised: true, Fee: big.NewInt(20), Refund: big.NewInt(15)},
+		},
+		Governance: types.GovernanceChanges{
+			{Kind: types.GovernanceTimelockChange},
+		},
+	}
+	summary := newBlockSummary(block, flare)
+	require.Equal(t, uint64(7), summary.Number)
+	require.Equal(t, 2, summary.DaemonCalls)
+	require.Equal(t, 1, summary.DaemonErrors)
+	require.Equal(t, big.NewInt(150), summary.MintRequest.ToInt())
+	require.Equal(t, big.NewInt(100), summary.Minted.ToInt())
+	require.Equal(t, 1, summary.Prioritised)
+	require.Equal(t, big.NewInt(35), summary.Burned.ToInt())
+	require.Equal(t, big.NewInt(15), summary.Refunded.ToInt())
+	require.Equal(t, []string{types.GovernanceTimelockChange}, summary.Governance)
+
+	// Summaries written by a replay are read back as its reference.
+	path := filepath.Join(t.TempDir(), "summary.jsonl")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	enc := json.NewEncoder(file)
+	require.NoError(t, enc.Encode(summary))
+	require.NoError(t, enc.Encode(newBlockSummary(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(8)}), &core.FlareResults{})))
+	require.NoError(t, file.Close())
+
+	reference, err := readSummaries(path)
+	require.NoError(t, err)
+	require.Len(t, reference, 2)
+	require.Equal(t, summary.Hash, reference[7].Hash)
+	require.Equal(t, big.NewInt(100), reference[7].Minted.ToInt())
+	require.Zero(t, reference[8].Minted.ToInt().Sign())
+}
@@ -0,0 +1,70 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"time"
+
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/metrics"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	attestationDivergenceCounter      = metrics.NewRegisteredCounter("chain/stateconnector/divergences", nil)
+	attestationDivergenceUnfinalised  = metrics.NewRegisteredCounter("chain/stateconnector/divergences/unfinalised", nil)
+	attestationDivergenceRoundGauge   = metrics.NewRegisteredGauge("chain/stateconnector/divergences/round", nil)
+	attestationDivergenceBlockGauge   = metrics.NewRegisteredGauge("chain/stateconnector/divergences/block", nil)
+	attestationDivergenceDetectedTime = metrics.NewRegisteredGauge("chain/stateconnector/divergences/time", nil)
+)
+
+// attestationDivergenceReport is the most recent divergence of the local
+// attestors accepted by the chain, along with the time it was accepted.
+type attestationDivergenceReport struct {
+	divergence *types.AttestationDivergence
+	detected   time.Time
+}
+
+// reportAcceptedAttestationDivergences logs and exports the metrics of the
+// divergences journaled for an accepted block, and remembers the last one for
+// the health check. Divergences are journaled when the block is inserted, but
+// only reported once it is accepted, the journal of a rejected block is
+// deleted along with the block.
+func (bc *BlockChain) reportAcceptedAttestationDivergences(block *types.Block) {
+	divergences := rawdb.ReadAttestationDivergences(bc.db, block.Hash(), block.NumberU64())
+	if len(divergences) == 0 {
+		return
+	}
+	now := time.Now()
+	for _, d := range divergences {
+		log.Warn("Local attestors diverged from default attestors",
+			"round", d.Round, "block", d.BlockNumber, "hash", d.BlockHash, "tx", d.TxHash,
+			"defaultMajority", d.DefaultReachedMajority, "defaultDecision", d.DefaultDecision,
+			"localMajority", d.LocalReachedMajority, "localDecision", d.LocalDecision,
+			"finalised", d.Finalised,
+		)
+		attestationDivergenceCounter.Inc(1)
+		if !d.Finalised {
+			attestationDivergenceUnfinalised.Inc(1)
+		}
+	}
+	last := divergences[len(divergences)-1]
+	attestationDivergenceRoundGauge.Update(last.Round.Int64())
+	attestationDivergenceBlockGauge.Update(int64(last.BlockNumber))
+	attestationDivergenceDetectedTime.Update(now.Unix())
+
+	bc.lastAttestationDivergence.Store(&attestationDivergenceReport{divergence: last, detected: now})
+}
+
+// LastAttestationDivergence returns the most recent divergence between the
+// local and the default attestors accepted since the chain was started and the
+// time it was accepted, or nil if there was none.
+func (bc *BlockChain) LastAttestationDivergence() (*types.AttestationDivergence, time.Time) {
+	report := bc.lastAttestationDivergence.Load()
+	if report == nil {
+		return nil, time.Time{}
+	}
+	return report.divergence, report.detected
+}
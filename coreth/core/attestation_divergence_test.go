@@ -0,0 +1,98 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that the attestation divergences of a block are only reported once the
+// block is accepted, and that a verified block that is rejected leaves no
+// record of its divergences.
+func TestAttestationDivergencesAccepted(t *testing.T) {
+	require := require.New(t)
+	var (
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr      = crypto.PubkeyToAddress(key.PublicKey)
+		blockTime = uint64(time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC).Unix())
+		config    = *params.TestFlareChainConfig
+		contracts = *config.SystemContracts()
+	)
+	// The state connector is only called before Durango. The single local
+	// attestor reaches a majority the default attestors never reach, as every
+	// attestor votes for its own address.
+	config.DurangoBlockTimestamp = nil
+	config.EtnaTimestamp = nil
+	contracts.StateConnector.LocalAttestors = []common.Address{{0x10}}
+	config.FlareSystemContracts = &contracts
+	stateConnector, ok := contracts.StateConnector.Address.At(blockTime)
+	require.True(ok)
+
+	gspec := &Genesis{
+		Config:    &config,
+		Timestamp: blockTime,
+		GasLimit:  params.CortinaGasLimit,
+		Alloc: types.GenesisAlloc{
+			addr: {Balance: new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(100))},
+			// Returns the caller: CALLER PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+			stateConnector: {Balance: common.Big0, Code: []byte{0x33, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}},
+		},
+	}
+	signer := types.LatestSigner(&config)
+	// generate returns a block submitting an attestation, which finalises the
+	// given round.
+	generate := func(round int64) *types.Block {
+		_, blocks, _, err := GenerateChainWithGenesis(gspec, dummy.NewFakerWithCallbacks(TestCallbacks), 1, 10, func(i int, block *BlockGen) {
+			block.SetCoinbase(common.HexToAddress("0x0100000000000000000000000000000000000000"))
+			data := append(contracts.StateConnector.SubmitAttestationSelector[:], common.BigToHash(big.NewInt(round)).Bytes()...)
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), stateConnector, common.Big0, 1_000_000, big.NewInt(225*params.GWei), data), signer, key)
+			require.NoError(err)
+			block.AddTx(tx)
+		})
+		require.NoError(err)
+		return blocks[0]
+	}
+	accepted, rejected := generate(1), generate(2)
+
+	db := rawdb.NewMemoryDatabase()
+	chain, err := NewBlockChain(db, DefaultCacheConfig, gspec, dummy.NewFakerWithCallbacks(TestCallbacks), vm.Config{}, common.Hash{}, false)
+	require.NoError(err)
+	defer chain.Stop()
+
+	_, err = chain.InsertChain(types.Blocks{rejected})
+	require.NoError(err)
+	_, err = chain.InsertChain(types.Blocks{accepted})
+	require.NoError(err)
+	require.Len(rawdb.ReadAttestationDivergences(db, rejected.Hash(), 1), 1)
+	divergence, _ := chain.LastAttestationDivergence()
+	require.Nil(divergence, "divergence of a verified block reported before it was accepted")
+
+	// The sibling is rejected once the other block is accepted.
+	require.NoError(chain.Accept(accepted))
+	require.NoError(chain.Reject(rejected))
+	chain.DrainAcceptorQueue()
+	require.Nil(rawdb.ReadAttestationDivergences(db, rejected.Hash(), 1))
+	divergence, detected := chain.LastAttestationDivergence()
+	require.NotNil(divergence)
+	require.Equal(accepted.Hash(), divergence.BlockHash)
+	require.Equal(big.NewInt(1), divergence.Round)
+	require.False(divergence.DefaultReachedMajority)
+	require.True(divergence.LocalReachedMajority)
+	require.False(detected.IsZero())
+
+	divergences := rawdb.ReadAttestationDivergencesInRange(db, 0, 1)
+	require.Len(divergences, 1)
+	require.Equal(accepted.Hash(), divergences[0].BlockHash)
+}
@@ -43,6 +43,7 @@ import (
 	"github.com/ava-labs/coreth/consensus/misc/eip4844"
 	"github.com/ava-labs/coreth/core/rawdb"
 	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/state/pruner"
 	"github.com/ava-labs/coreth/core/state/snapshot"
 	"github.com/ava-labs/coreth/core/types"
 	"github.com/ava-labs/coreth/core/vm"
@@ -168,7 +169,14 @@ type CacheConfig struct {
 	TransactionHistory              uint64  // Number of recent blocks for which to maintain transaction lookup indices
 	SkipTxIndexing                  bool    // Whether to skip transaction indexing
 	StateHistory                    uint64  // Number of blocks from head whose state histories are reserved.
+	StateHistoryDirectory           string  // Directory of the state histories of the path-based scheme
 	StateScheme                     string  // Scheme used to store ethereum states and merkle tree nodes on top
+	OnlinePruning                   bool    // Whether to delete stale trie nodes in the background
+	OnlinePruningDataDirectory      string  // Directory of the bloom filter of the online pruning session
+	OnlinePruningBloomFilterSize    uint64  // Memory allowance (MB) of the bloom filter of the online pruning session
+	OnlinePruningInterval           uint64  // Blocks accepted between online pruning sessions
+	StateArchive                    bool    // Whether to archive the state diffs of accepted blocks to serve historical states
+	StateArchiveRebuild             bool    // Whether to discard and rebuild a state archive not reaching the last accepted block
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
@@ -186,9 +194,10 @@ func (c *CacheConfig) triedbConfig() *triedb.Config {
 	}
 	if c.StateScheme == rawdb.PathScheme {
 		config.PathDB = &pathdb.Config{
-			StateHistory:   c.StateHistory,
-			CleanCacheSize: c.TrieCleanLimit * 1024 * 1024,
-			DirtyCacheSize: c.TrieDirtyLimit * 1024 * 1024,
+			StateHistory:     c.StateHistory,
+			HistoryDirectory: c.StateHistoryDirectory,
+			CleanCacheSize:   c.TrieCleanLimit * 1024 * 1024,
+			DirtyCacheSize:   c.TrieDirtyLimit * 1024 * 1024,
 		}
 	}
 	return config
@@ -242,12 +251,17 @@ type BlockChain struct {
 	chainConfig *params.ChainConfig // Chain & network configuration
 	cacheConfig *CacheConfig        // Cache configuration for pruning
 
-	db           ethdb.Database   // Low level persistent database to store final content in
-	snaps        *snapshot.Tree   // Snapshot tree for fast trie leaf access
-	triedb       *triedb.Database // The database handler for maintaining trie nodes.
-	stateCache   state.Database   // State database to reuse between imports (contains state cache)
-	txIndexer    *txIndexer       // Transaction indexer, might be nil if not enabled
-	stateManager TrieWriter
+	db            ethdb.Database     // Low level persistent database to store final content in
+	snaps         *snapshot.Tree     // Snapshot tree for fast trie leaf access
+	triedb        *triedb.Database   // The database handler for maintaining trie nodes.
+	stateCache    state.Database     // State database to reuse between imports (contains state cache)
+	txIndexer     *txIndexer         // Transaction indexer, might be nil if not enabled
+	traceIndexer  *traceIndexer      // Trace indexer, might be nil if not enabled
+	logIndexer    *logIndexer        // Log indexer, might be nil if not enabled
+	govIndexer    *governanceIndexer // Governance change indexer, might be nil if not enabled
+	onlinePruner  *onlinePruner      // Online state pruner, might be nil if not enabled
+	stateArchiver *stateArchiver     // State archiver, might be nil if not enabled
+	stateManager  TrieWriter
 
 	hc                *HeaderChain
 	rmLogsFeed        event.Feed
@@ -268,6 +282,8 @@ type BlockChain struct {
 
 	currentBlock atomic.Pointer[types.Header] // Current head of the block chain
 
+	lastAttestationDivergence atomic.Pointer[attestationDivergenceReport] // Most recent divergence of the local attestors
+
 	bodyCache     *lru.Cache[common.Hash, *types.Body]      // Cache for the most recent block bodies
 	receiptsCache *lru.Cache[common.Hash, []*types.Receipt] // Cache for the most recent receipts per block
 	blockCache    *lru.Cache[common.Hash, *types.Block]     // Cache for the most recent entire blocks
@@ -342,6 +358,20 @@ func NewBlockChain(
 	// Open trie database with provided config
 	triedb := triedb.NewDatabase(db, cacheConfig.triedbConfig())
 
+	// Install the write barrier of online pruning before any trie node is
+	// flushed, resuming the interrupted session if any.
+	var statePruner *pruner.OnlinePruner
+	if cacheConfig.OnlinePruning {
+		config := pruner.OnlineConfig{
+			Datadir:   cacheConfig.OnlinePruningDataDirectory,
+			BloomSize: cacheConfig.OnlinePruningBloomFilterSize,
+		}
+		var err error
+		if statePruner, err = pruner.NewOnlinePruner(db, triedb, config); err != nil {
+			return nil, fmt.Errorf("failed to initialize online pruning: %w", err)
+		}
+	}
+
 	// Setup the genesis block, commit the provided genesis specification
 	// to database if the genesis block is not present yet, or load the
 	// stored one from database.
@@ -395,6 +425,12 @@ func NewBlockChain(
 	// Create the state manager
 	bc.stateManager = NewTrieWriter(bc.triedb, cacheConfig)
 
+	// Create the state archiver before reprocessing, so that the state diffs of
+	// the reprocessed accepted blocks are archived.
+	if cacheConfig.StateArchive {
+		bc.stateArchiver = newStateArchiver(bc)
+	}
+
 	// Re-generate current block state if it is missing
 	if err := bc.loadLastState(lastAcceptedHash); err != nil {
 		return nil, err
@@ -412,6 +448,11 @@ func NewBlockChain(
 	if !bc.HasState(head.Root) {
 		return nil, fmt.Errorf("head state missing %d:%s", head.Number, head.Hash())
 	}
+	if bc.stateArchiver != nil {
+		if err := bc.stateArchiver.init(); err != nil {
+			return nil, fmt.Errorf("failed to initialize state archive: %w", err)
+		}
+	}
 
 	if err := bc.protectTrieIndex(); err != nil {
 		return nil, err
@@ -447,6 +488,10 @@ func NewBlockChain(
 	if bc.cacheConfig.TransactionHistory != 0 {
 		bc.txIndexer = newTxIndexer(bc.cacheConfig.TransactionHistory, bc)
 	}
+	// Start online pruning if it's enabled.
+	if statePruner != nil {
+		bc.onlinePruner = newOnlinePruner(statePruner, bc.cacheConfig.OnlinePruningInterval, bc)
+	}
 	return bc, nil
 }
 
@@ -472,6 +517,9 @@ func (bc *BlockChain) batchBlockAcceptedIndices(batch ethdb.Batch, b *types.Bloc
 	if err := rawdb.WriteAcceptorTip(batch, b.Hash()); err != nil {
 		return fmt.Errorf("%w: failed to write acceptor tip key", err)
 	}
+	if bc.stateArchiver != nil {
+		bc.stateArchiver.batchAccepted(batch, b)
+	}
 	return nil
 }
 
@@ -559,6 +607,7 @@ func (bc *BlockChain) startAcceptor() {
 		if err := bc.writeBlockAcceptedIndices(next); err != nil {
 			log.Crit("failed to write accepted block effects", "err", err)
 		}
+		bc.reportAcceptedAttestationDivergences(next)
 
 		// Ensure [hc.acceptedNumberCache] and [acceptedLogsCache] have latest content
 		bc.hc.acceptedNumberCache.Put(next.NumberU64(), next.Header())
@@ -911,6 +960,22 @@ func (bc *BlockChain) stopWithoutSaving() {
 	if bc.txIndexer != nil {
 		bc.txIndexer.close()
 	}
+	// Signal shutdown log indexer.
+	if bc.logIndexer != nil {
+		bc.logIndexer.close()
+	}
+	// Signal shutdown trace indexer.
+	if bc.traceIndexer != nil {
+		bc.traceIndexer.close()
+	}
+	// Signal shutdown governance indexer.
+	if bc.govIndexer != nil {
+		bc.govIndexer.close()
+	}
+	// Signal shutdown online pruning.
+	if bc.onlinePruner != nil {
+		bc.onlinePruner.close()
+	}
 
 	log.Info("Closing quit channel")
 	close(bc.quit)
@@ -1025,6 +1090,38 @@ func (bc *BlockChain) LastAcceptedBlock() *types.Block {
 	return bc.acceptorTip
 }
 
+// StartTraceIndexer starts tracing the accepted blocks with [tracer] in the
+// background, keeping the traces of the last [limit] blocks (0 keeps them
+// all). It must be called at most once, before the chain is stopped.
+func (bc *BlockChain) StartTraceIndexer(limit uint64, tracer BlockTracer) {
+	bc.traceIndexer = newTraceIndexer(limit, tracer, bc)
+}
+
+// StartGovernanceIndexer starts indexing the governance changes of the
+// accepted blocks, replayed with [replayer], in the background. It must be
+// called at most once, before the chain is stopped.
+func (bc *BlockChain) StartGovernanceIndexer(replayer GovernanceReplayer) {
+	bc.govIndexer = newGovernanceIndexer(replayer, bc)
+}
+
+// StartLogIndexer starts indexing the logs of the accepted blocks by address
+// and topic in the background, backfilling the blocks accepted before. If
+// [rebuild] is set, the existing index is dropped first. It must be called at
+// most once, before the chain is stopped.
+func (bc *BlockChain) StartLogIndexer(rebuild bool) {
+	bc.logIndexer = newLogIndexer(rebuild, bc)
+}
+
+// OnlinePruningStatus returns the progress of the online pruning session, nil
+// if online pruning is not enabled.
+func (bc *BlockChain) OnlinePruningStatus() *pruner.OnlineStatus {
+	if bc.onlinePruner == nil {
+		return nil
+	}
+	status := bc.onlinePruner.pruner.Status()
+	return &status
+}
+
 // Accept sets a minimum height at which no reorg can pass. Additionally,
 // this function may trigger a reorg if the block being accepted is not in the
 // canonical chain.
@@ -1081,6 +1178,9 @@ func (bc *BlockChain) Reject(block *types.Block) error {
 	// Remove the block since its data is no longer needed
 	batch := bc.db.NewBatch()
 	rawdb.DeleteBlock(batch, block.Hash(), block.NumberU64())
+	if bc.stateArchiver != nil {
+		rawdb.DeleteStateArchiveDiff(batch, block.Hash(), block.NumberU64())
+	}
 	if err := batch.Write(); err != nil {
 		return fmt.Errorf("failed to write delete block batch: %w", err)
 	}
@@ -1126,8 +1226,8 @@ func (bc *BlockChain) newTip(block *types.Block) bool {
 // canonical chain.
 // writeBlockAndSetHead expects to be the last verification step during InsertBlock
 // since it creates a reference that will only be cleaned up by Accept/Reject.
-func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB) error {
-	if err := bc.writeBlockWithState(block, receipts, state); err != nil {
+func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types.Receipt, logs []*types.Log, flare *FlareResults, state *state.StateDB) error {
+	if err := bc.writeBlockWithState(block, receipts, flare, state); err != nil {
 		return err
 	}
 
@@ -1144,14 +1244,19 @@ func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types
 
 // writeBlockWithState writes the block and all associated state to the database,
 // but it expects the chain mutex to be held.
-func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.Receipt, state *state.StateDB) error {
+func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.Receipt, flare *FlareResults, state *state.StateDB) error {
 	// Irrelevant of the canonical status, write the block itself to the database.
 	//
-	// Note all the components of block(hash->number map, header, body, receipts)
+	// Note all the components of block(hash->number map, header, body, receipts, Flare results)
 	// should be written atomically. BlockBatch is used for containing all components.
 	blockBatch := bc.db.NewBatch()
 	rawdb.WriteBlock(blockBatch, block)
 	rawdb.WriteReceipts(blockBatch, block.Hash(), block.NumberU64(), receipts)
+	rawdb.WriteDaemonResults(blockBatch, block.Hash(), block.NumberU64(), flare.Daemons)
+	rawdb.WriteFeeTreatments(blockBatch, block.Hash(), block.NumberU64(), flare.Fees)
+	if len(flare.Divergences) > 0 {
+		rawdb.WriteAttestationDivergences(blockBatch, block.Hash(), block.NumberU64(), flare.Divergences)
+	}
 	rawdb.WritePreimages(blockBatch, state.Preimages())
 	if err := blockBatch.Write(); err != nil {
 		log.Crit("Failed to write block into disk", "err", err)
@@ -1169,6 +1274,9 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	if err != nil {
 		return err
 	}
+	if bc.stateArchiver != nil {
+		bc.stateArchiver.writeDiff(block, state.CommittedDiff())
+	}
 	// If node is running in path mode, skip explicit gc operation
 	// which is unnecessary in this mode.
 	if bc.triedb.Scheme() == rawdb.PathScheme {
@@ -1321,7 +1429,7 @@ func (bc *BlockChain) insertBlock(block *types.Block, writes bool) error {
 
 	// Process block using the parent state as reference point
 	pstart := time.Now()
-	receipts, logs, usedGas, err := bc.processor.Process(block, parent, statedb, bc.vmConfig)
+	receipts, logs, flare, usedGas, err := bc.processor.Process(block, parent, statedb, bc.vmConfig)
 	if serr := statedb.Error(); serr != nil {
 		log.Error("statedb error encountered", "err", serr, "number", block.Number(), "hash", block.Hash())
 	}
@@ -1368,7 +1476,7 @@ func (bc *BlockChain) insertBlock(block *types.Block, writes bool) error {
 	// will be cleaned up in Accept/Reject so we need to ensure an error cannot occur
 	// later in verification, since that would cause the referenced root to never be dereferenced.
 	wstart := time.Now()
-	if err := bc.writeBlockAndSetHead(block, receipts, logs, statedb); err != nil {
+	if err := bc.writeBlockAndSetHead(block, receipts, logs, flare, statedb); err != nil {
 		return err
 	}
 	// Update the metrics touched during block commit
@@ -1681,7 +1789,7 @@ func (bc *BlockChain) reprocessBlock(parent *types.Block, current *types.Block)
 	}()
 
 	// Process previously stored block
-	receipts, _, usedGas, err := bc.processor.Process(current, parent.Header(), statedb, vm.Config{})
+	receipts, _, _, usedGas, err := bc.processor.Process(current, parent.Header(), statedb, vm.Config{})
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("failed to re-process block (%s: %d): %v", current.Hash().Hex(), current.NumberU64(), err)
 	}
@@ -1780,8 +1888,24 @@ func (bc *BlockChain) reprocessState(current *types.Block, reexec uint64) error
 		if err == nil {
 			break
 		}
+		// The persisted state of the path-based scheme may be above the
+		// acceptor tip, its ancestors are restored from the state histories.
+		if bc.triedb.Scheme() == rawdb.PathScheme {
+			if recoverable, _ := bc.triedb.Recoverable(current.Root()); recoverable {
+				log.Info("Recovering state from state histories", "number", current.NumberU64(), "root", current.Root())
+				if err = bc.triedb.Recover(current.Root()); err != nil {
+					return fmt.Errorf("failed to recover state %s: %w", current.Root(), err)
+				}
+				break
+			}
+		}
 	}
 	if err != nil {
+		// The persisted state of the path-based scheme is neither within
+		// [reexec] blocks nor recoverable from the state histories.
+		if bc.triedb.Scheme() == rawdb.PathScheme {
+			return fmt.Errorf("no persisted or recoverable state within %d blocks of the last accepted block %d, the state must be resynced: %w", reexec, origin, err)
+		}
 		switch err.(type) {
 		case *trie.MissingNodeError:
 			return fmt.Errorf("required historical state unavailable (reexec=%d)", reexec)
@@ -2072,6 +2196,13 @@ func (bc *BlockChain) ResetToStateSyncedBlock(block *types.Block) error {
 	bc.currentBlock.Store(block.Header())
 	bc.hc.SetCurrentHeader(block.Header())
 
+	// The path-based trie database is disabled during the sync, it is rebuilt
+	// on top of the synced state.
+	if bc.triedb.Scheme() == rawdb.PathScheme {
+		if err := bc.triedb.Enable(block.Root()); err != nil {
+			return err
+		}
+	}
 	lastAcceptedHash := block.Hash()
 	bc.stateCache = state.NewDatabaseWithNodeDB(bc.db, bc.triedb)
 
@@ -2087,7 +2218,21 @@ func (bc *BlockChain) ResetToStateSyncedBlock(block *types.Block) error {
 		return fmt.Errorf("head state missing %d:%s", head.Number, head.Hash())
 	}
 
+	// The states of the blocks before the synced block are not available, the
+	// state archive starts over from the synced state if it can be rebuilt.
+	if bc.stateArchiver != nil {
+		if err := bc.stateArchiver.init(); err != nil {
+			return err
+		}
+	}
+
 	bc.initSnapshot(head)
+
+	// The trie nodes written by state sync are not marked by the running
+	// online pruning session.
+	if bc.onlinePruner != nil {
+		bc.onlinePruner.abort()
+	}
 	return nil
 }
 
@@ -27,6 +27,8 @@
 package core
 
 import (
+	"errors"
+
 	"github.com/ava-labs/coreth/consensus"
 	"github.com/ava-labs/coreth/core/rawdb"
 	"github.com/ava-labs/coreth/core/state"
@@ -192,6 +194,16 @@ func (bc *BlockChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
 	return receipts
 }
 
+// GetFeeTreatmentsByHash retrieves the Flare fee treatments of all transactions
+// in a given block, or nil if they were not recorded.
+func (bc *BlockChain) GetFeeTreatmentsByHash(hash common.Hash) types.FeeTreatments {
+	number := rawdb.ReadHeaderNumber(bc.db, hash)
+	if number == nil {
+		return nil
+	}
+	return rawdb.ReadFeeTreatments(bc.db, hash, *number)
+}
+
 // GetCanonicalHash returns the canonical hash for a given block number
 func (bc *BlockChain) GetCanonicalHash(number uint64) common.Hash {
 	return bc.hc.GetCanonicalHash(number)
@@ -257,6 +269,16 @@ func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return state.New(root, bc.stateCache, bc.snaps)
 }
 
+// HistoricalStateAt returns a new mutable state at the accepted block of
+// [header] read from the state archive, for the blocks whose state tries are
+// not kept. The state cannot be committed.
+func (bc *BlockChain) HistoricalStateAt(header *types.Header) (*state.StateDB, error) {
+	if bc.stateArchiver == nil {
+		return nil, errors.New("state archive is not enabled")
+	}
+	return bc.stateArchiver.stateAt(header)
+}
+
 // Config retrieves the chain's fork configuration.
 func (bc *BlockChain) Config() *params.ChainConfig { return bc.chainConfig }
 
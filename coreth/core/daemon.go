@@ -6,120 +6,23 @@ package core
 import (
 	"bytes"
 	"fmt"
-	"math"
 	"math/big"
-	"os"
-	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/holiman/uint256"
 
+	"github.com/ava-labs/coreth/core/types"
 	"github.com/ava-labs/coreth/core/vm"
 	"github.com/ava-labs/coreth/params"
-	"github.com/ava-labs/coreth/utils"
-)
-
-var (
-	// Define activation times for submitter contract
-	submitterContractActivationTimeFlare  = uint64(time.Date(2024, time.March, 26, 12, 0, 0, 0, time.UTC).Unix())
-	submitterContractActivationTimeCostwo = uint64(time.Date(2024, time.March, 7, 12, 0, 0, 0, time.UTC).Unix())
-
-	submitterContractActivationTimeSongbird = uint64(time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC).Unix())
-	submitterContractActivationTimeCoston   = uint64(time.Date(2024, time.February, 29, 12, 0, 0, 0, time.UTC).Unix())
-
-	// Define ftso and submitter contract addresses
-	prioritisedFTSOContractAddress = common.HexToAddress("0x1000000000000000000000000000000000000003")
-
-	prioritisedSubmitterContractAddress    = common.HexToAddress("0x2cA6571Daa15ce734Bbd0Bf27D5C9D16787fc33f") // for flare, costwo, songbird and coston
-	prioritisedSubmitterContractAddressEnv = common.HexToAddress(os.Getenv("SUBMITTER_CONTRACT_ADDRESS"))      // for local and staging chains
-
-	// Define data prefixes for submitter and prioritized ftso contracts
-	submitterDataPrefixes = [][4]byte{
-		{0x6c, 0x53, 0x2f, 0xae},
-		{0x9d, 0x00, 0xc9, 0xfd},
-		{0xe1, 0xb1, 0x57, 0xe7},
-		{0x57, 0xee, 0xd5, 0x80},
-		{0x83, 0x3b, 0xf6, 0xc0},
-	}
-
-	prioritisedFTSOContractDataPrefixesFlareNetworks = [][4]byte{
-		{0x8f, 0xc6, 0xf6, 0x67},
-		{0xe2, 0xdb, 0x5a, 0x52},
-	}
-
-	prioritisedFTSOContractDataPrefixesSongbirdNetworks = [][4]byte{
-		{0xc5, 0xad, 0xc5, 0x39},
-		{0x60, 0x84, 0x8b, 0x44},
-	}
 )
 
 const (
 	prioritisedCallDataCap = 4500 // 4500 bytes
 )
 
-type prioritisedParams struct {
-	submitterActivationTime  uint64
-	submitterAddress         common.Address
-	maxGasLimit              uint64
-	dataPrefixActivationTime uint64
-	submitterDataPrefixes    [][4]byte
-	ftsoDataPrefixes         [][4]byte
-}
-
-var (
-	prioritisedContractVariants = utils.NewChainValue(&prioritisedParams{
-		0, common.Address{}, 0, 0, [][4]byte{}, [][4]byte{},
-	}).
-		AddValue(params.FlareChainID, &prioritisedParams{
-			submitterContractActivationTimeFlare,
-			prioritisedSubmitterContractAddress,
-			3000000,
-			uint64(time.Date(2024, time.October, 10, 15, 0, 0, 0, time.UTC).Unix()),
-			submitterDataPrefixes,
-			prioritisedFTSOContractDataPrefixesFlareNetworks,
-		}).
-		AddValue(params.CostwoChainID, &prioritisedParams{
-			submitterContractActivationTimeCostwo,
-			prioritisedSubmitterContractAddress,
-			3000000,
-			uint64(time.Date(2024, time.October, 10, 10, 0, 0, 0, time.UTC).Unix()),
-			submitterDataPrefixes,
-			prioritisedFTSOContractDataPrefixesFlareNetworks,
-		}).
-		AddValue(params.SongbirdChainID, &prioritisedParams{
-			submitterContractActivationTimeSongbird,
-			prioritisedSubmitterContractAddress,
-			math.MaxUint64,
-			uint64(time.Date(2024, time.October, 10, 13, 0, 0, 0, time.UTC).Unix()),
-			submitterDataPrefixes,
-			prioritisedFTSOContractDataPrefixesSongbirdNetworks,
-		}).
-		AddValue(params.CostonChainID, &prioritisedParams{
-			submitterContractActivationTimeCoston,
-			prioritisedSubmitterContractAddress,
-			math.MaxUint64,
-			uint64(time.Date(2024, time.October, 10, 8, 0, 0, 0, time.UTC).Unix()),
-			submitterDataPrefixes,
-			prioritisedFTSOContractDataPrefixesSongbirdNetworks,
-		}).
-		AddValue(params.LocalFlareChainID, &prioritisedParams{
-			0,
-			prioritisedSubmitterContractAddressEnv,
-			3000000,
-			0,
-			[][4]byte{},
-			[][4]byte{},
-		}).
-		AddValue(params.LocalChainID, &prioritisedParams{
-			0,
-			prioritisedSubmitterContractAddressEnv,
-			math.MaxUint64,
-			0,
-			[][4]byte{},
-			[][4]byte{},
-		})
-)
+// nonZeroReturn stands in for the return data of a call that has not been executed yet.
+var nonZeroReturn = []byte{1}
 
 // Define errors
 type ErrInvalidDaemonData struct{}
@@ -141,7 +44,7 @@ func (e *ErrMaxMintExceeded) Error() string {
 
 // Define interface for dependencies
 type EVMCaller interface {
-	GetChainID() *big.Int
+	SystemContracts() *params.FlareSystemContracts
 	DaemonCall(caller vm.ContractRef, addr common.Address, input []byte, gas uint64) (snapshot int, ret []byte, leftOverGas uint64, err error)
 	DaemonRevertToSnapshot(snapshot int)
 	GetBlockTime() uint64
@@ -149,74 +52,98 @@ type EVMCaller interface {
 	AddBalance(addr common.Address, amount *uint256.Int)
 }
 
-func GetDaemonGasMultiplier(blockTime uint64) uint64 {
-	switch {
-	default:
-		return 100
-	}
-}
+// Rules of the prioritised contract check, reported in the fee treatment of
+// a transaction.
+const (
+	PrioritisedRuleFailedCall          = "failed-call"
+	PrioritisedRuleNotPrioritised      = "not-prioritised-contract"
+	PrioritisedRuleGasLimit            = "gas-limit-exceeded"
+	PrioritisedRuleFTSO                = "ftso"
+	PrioritisedRuleFTSODataPrefix      = "ftso-data-prefix-mismatch"
+	PrioritisedRuleSubmitter           = "submitter"
+	PrioritisedRuleSubmitterNotActive  = "submitter-not-activated"
+	PrioritisedRuleSubmitterZeroReturn = "submitter-zero-return"
+	PrioritisedRuleSubmitterDataCap    = "submitter-data-too-long"
+	PrioritisedRuleSubmitterDataPrefix = "submitter-data-prefix-mismatch"
+)
 
-func GetDaemonContractAddr(blockTime uint64) string {
-	switch {
-	default:
-		return "0x1000000000000000000000000000000000000002"
-	}
+func IsPrioritisedContractCall(contracts *params.FlareSystemContracts, blockTime uint64, to *common.Address, data []byte, ret []byte, initialGas uint64) bool {
+	prioritised, _ := ClassifyPrioritisedContractCall(contracts, blockTime, to, data, ret, initialGas)
+	return prioritised
 }
 
-func GetDaemonSelector(blockTime uint64) []byte {
-	switch {
-	default:
-		return []byte{0x7f, 0xec, 0x8d, 0x38}
+// IsPrioritisedTransaction returns whether a transaction qualifies for the
+// prioritised lane of the transaction pool and block builder. It applies the
+// rules of ClassifyPrioritisedContractCall that can be checked before execution;
+// a submitter call is assumed to return a non-zero value.
+func IsPrioritisedTransaction(contracts *params.FlareSystemContracts, blockTime uint64, tx *types.Transaction) bool {
+	if !contracts.IsFlare() && !contracts.IsSongbird() {
+		return false
+	}
+	// An unset contract address is zero, calls to it are never prioritised
+	if to := tx.To(); to == nil || *to == (common.Address{}) {
+		return false
 	}
+	prioritised, _ := ClassifyPrioritisedContractCall(contracts, blockTime, tx.To(), tx.Data(), nonZeroReturn, tx.Gas())
+	return prioritised
 }
 
-func IsPrioritisedContractCall(chainID *big.Int, blockTime uint64, to *common.Address, data []byte, ret []byte, initialGas uint64) bool {
-	if to == nil || chainID == nil {
-		return false
+// ClassifyPrioritisedContractCall returns whether a successful call is charged
+// the nominal fee and the rule that matched or failed.
+func ClassifyPrioritisedContractCall(contracts *params.FlareSystemContracts, blockTime uint64, to *common.Address, data []byte, ret []byte, initialGas uint64) (bool, string) {
+	if to == nil || contracts == nil {
+		return false, PrioritisedRuleNotPrioritised
 	}
 
-	chainValue := prioritisedContractVariants.GetValue(chainID)
+	prioritised := &contracts.Prioritised
+	isFTSO := *to == prioritised.FTSOAddress
+	isSubmitter := *to == prioritised.SubmitterAddress
 
 	switch {
-	case initialGas > chainValue.maxGasLimit:
-		return false
-	case *to == prioritisedFTSOContractAddress:
-		if blockTime > chainValue.dataPrefixActivationTime {
-			return checkDataPrefix(data, chainValue.ftsoDataPrefixes)
-		}
-		return true
-	case *to == chainValue.submitterAddress && blockTime > chainValue.submitterActivationTime && !isZeroSlice(ret):
-		if blockTime > chainValue.dataPrefixActivationTime {
-			return len(data) <= prioritisedCallDataCap && checkDataPrefix(data, chainValue.submitterDataPrefixes)
+	case !isFTSO && !isSubmitter:
+		return false, PrioritisedRuleNotPrioritised
+	case initialGas > prioritised.MaxGasLimit:
+		return false, PrioritisedRuleGasLimit
+	case isFTSO:
+		if blockTime > prioritised.DataPrefixActivationTime && !checkDataPrefix(data, prioritised.FTSODataPrefixes) {
+			return false, PrioritisedRuleFTSODataPrefix
 		}
-		return true
+		return true, PrioritisedRuleFTSO
+	case blockTime <= prioritised.SubmitterActivationTime:
+		return false, PrioritisedRuleSubmitterNotActive
+	case isZeroSlice(ret):
+		return false, PrioritisedRuleSubmitterZeroReturn
+	case blockTime > prioritised.DataPrefixActivationTime && len(data) > prioritisedCallDataCap:
+		return false, PrioritisedRuleSubmitterDataCap
+	case blockTime > prioritised.DataPrefixActivationTime && !checkDataPrefix(data, prioritised.SubmitterDataPrefixes):
+		return false, PrioritisedRuleSubmitterDataPrefix
 	default:
-		return false
+		return true, PrioritisedRuleSubmitter
 	}
 }
 
-func GetMaximumMintRequest(chainID *big.Int, blockTime uint64) *uint256.Int {
-	maxRequest := new(uint256.Int)
-	switch {
-	case chainID.Cmp(params.FlareChainID) == 0 || chainID.Cmp(params.CostwoChainID) == 0 || chainID.Cmp(params.LocalFlareChainID) == 0:
-		maxRequest.SetFromDecimal("60000000000000000000000000") // we ignore error, the number fits into uint256
-	default: // Songbird, Coston
-		maxRequest.SetFromDecimal("50000000000000000000000000") // we ignore error, the number fits into uint256
-	}
+func GetMaximumMintRequest(contracts *params.FlareSystemContracts) *uint256.Int {
+	maxRequest, _ := uint256.FromBig((*big.Int)(contracts.Daemon.MaxMintRequest)) // the verified schedule fits into uint256
 	return maxRequest
 }
 
-func daemon(evm EVMCaller) (int, *uint256.Int, error) {
+func daemon(evm EVMCaller) (int, *uint256.Int, uint64, error) {
 	bigZero := uint256.NewInt(0)
 	// Get the contract to call
-	daemonContract := common.HexToAddress(GetDaemonContractAddr(evm.GetBlockTime()))
+	contract := evm.SystemContracts().Daemon
+	daemonContract := contract.Address
+	daemonGas := contract.GasMultiplier * evm.GetGasLimit()
 
 	// Call the method
-	daemonSnapshot, daemonRet, _, daemonErr := evm.DaemonCall(
+	daemonSnapshot, daemonRet, leftOverGas, daemonErr := evm.DaemonCall(
 		vm.AccountRef(daemonContract),
 		daemonContract,
-		GetDaemonSelector(evm.GetBlockTime()),
-		GetDaemonGasMultiplier(evm.GetBlockTime())*evm.GetGasLimit())
+		contract.Selector[:],
+		daemonGas)
+	var gasUsed uint64
+	if leftOverGas < daemonGas {
+		gasUsed = daemonGas - leftOverGas
+	}
 	// If no error and a value came back...
 	if daemonErr == nil && daemonRet != nil {
 		// Did we get one big int?
@@ -225,27 +152,28 @@ func daemon(evm EVMCaller) (int, *uint256.Int, error) {
 			// Mint request cannot be less than 0 as SetBytes treats value as unsigned
 			mintRequest := new(uint256.Int).SetBytes32(daemonRet)
 			// return the mint request
-			return daemonSnapshot, mintRequest, nil
+			return daemonSnapshot, mintRequest, gasUsed, nil
 		} else {
 			// Returned length was not 32 bytes
-			return 0, bigZero, &ErrInvalidDaemonData{}
+			return 0, bigZero, gasUsed, &ErrInvalidDaemonData{}
 		}
 	} else {
 		if daemonErr != nil {
-			return 0, bigZero, daemonErr
+			return 0, bigZero, gasUsed, daemonErr
 		} else {
-			return 0, bigZero, &ErrDaemonDataEmpty{}
+			return 0, bigZero, gasUsed, &ErrDaemonDataEmpty{}
 		}
 	}
 }
 
 func mint(evm EVMCaller, mintRequest *uint256.Int) error {
 	// If the mint request is greater than zero and less than max
-	max := GetMaximumMintRequest(evm.GetChainID(), evm.GetBlockTime())
+	contracts := evm.SystemContracts()
+	max := GetMaximumMintRequest(contracts)
 	if mintRequest.Cmp(uint256.NewInt(0)) > 0 &&
 		mintRequest.Cmp(max) <= 0 {
 		// Mint the amount asked for on to the daemon contract
-		evm.AddBalance(common.HexToAddress(GetDaemonContractAddr(evm.GetBlockTime())), mintRequest)
+		evm.AddBalance(contracts.Daemon.Address, mintRequest)
 	} else if mintRequest.Cmp(max) > 0 {
 		// Return error
 		return &ErrMaxMintExceeded{
@@ -256,9 +184,39 @@ func mint(evm EVMCaller, mintRequest *uint256.Int) error {
 	return nil
 }
 
-func atomicDaemonAndMint(evm EVMCaller, log log.Logger) {
+// daemonResult describes the outcome of atomicDaemonAndMint.
+type daemonResult struct {
+	mintRequest *uint256.Int // Amount of inflation requested by the daemon
+	minted      *uint256.Int // Amount minted on to the daemon contract
+	gasUsed     uint64       // Gas used by the daemon call
+	reverted    bool         // Whether the state changes of the daemon call were reverted
+	daemonErr   error        // Error returned by the daemon call, if any
+	mintErr     error        // Error that caused the daemon state transition to be reverted, if any
+}
+
+// record converts the outcome into the receipt-like record that is persisted
+// next to the block. Transaction fields are filled in by the state processor.
+func (r *daemonResult) record() *types.DaemonResult {
+	record := &types.DaemonResult{
+		MintRequest: r.mintRequest.ToBig(),
+		Minted:      r.minted.ToBig(),
+		GasUsed:     r.gasUsed,
+		Reverted:    r.reverted,
+	}
+	if r.daemonErr != nil {
+		record.Error = r.daemonErr.Error()
+	} else if r.mintErr != nil {
+		record.Error = r.mintErr.Error()
+	}
+	return record
+}
+
+func atomicDaemonAndMint(evm EVMCaller, log log.Logger) *daemonResult {
+	result := &daemonResult{minted: new(uint256.Int)}
 	// Call the daemon
-	daemonSnapshot, mintRequest, daemonErr := daemon(evm)
+	daemonSnapshot, mintRequest, gasUsed, daemonErr := daemon(evm)
+	result.mintRequest = mintRequest
+	result.gasUsed = gasUsed
 	// If no error...
 	if daemonErr == nil {
 		// time to mint
@@ -266,10 +224,23 @@ func atomicDaemonAndMint(evm EVMCaller, log log.Logger) {
 			log.Warn("Error minting inflation request", "error", mintError)
 			// Revert to snapshot to unwind daemon state transition
 			evm.DaemonRevertToSnapshot(daemonSnapshot)
+			result.mintErr = mintError
+			result.reverted = true
+		} else {
+			result.minted = mintRequest
 		}
 	} else {
 		log.Warn("Daemon error", "error", daemonErr)
+		result.daemonErr = daemonErr
+		// Errors raised by the EVM revert the daemon call, malformed return
+		// data leaves its state changes in place.
+		switch daemonErr.(type) {
+		case *ErrInvalidDaemonData, *ErrDaemonDataEmpty:
+		default:
+			result.reverted = true
+		}
 	}
+	return result
 }
 
 func isZeroSlice(s []byte) bool {
@@ -281,7 +252,7 @@ func isZeroSlice(s []byte) bool {
 	return true
 }
 
-func checkDataPrefix(data []byte, prefixes [][4]byte) bool {
+func checkDataPrefix(data []byte, prefixes []params.FunctionSelector) bool {
 	if len(data) < 4 {
 		return false
 	}
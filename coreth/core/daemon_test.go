@@ -6,7 +6,6 @@ package core
 import (
 	"context"
 	"errors"
-	"math/big"
 	"testing"
 	"time"
 
@@ -86,8 +85,8 @@ func (e *DefaultEVMMock) GetGasLimit() uint64 {
 	return defaultGetGasLimit(&e.mockEVMCallerData)
 }
 
-func (e *DefaultEVMMock) GetChainID() *big.Int {
-	return params.FlareChainID
+func (e *DefaultEVMMock) SystemContracts() *params.FlareSystemContracts {
+	return params.FlareSystemContractsFlare
 }
 
 func (e *DefaultEVMMock) AddBalance(addr common.Address, amount *uint256.Int) {
@@ -106,7 +105,7 @@ func TestDaemonShouldReturnMintRequest(t *testing.T) {
 		mockEVMCallerData: *mockEVMCallerData,
 	}
 
-	_, mintRequest, _ := daemon(defaultEVMMock)
+	_, mintRequest, _, _ := daemon(defaultEVMMock)
 
 	if mintRequest.Cmp(mintRequestReturn) != 0 {
 		t.Errorf("got %s want %q", mintRequest.String(), "60000000000000000000000000")
@@ -128,7 +127,7 @@ func TestDaemonShouldNotLetMintRequestOverflow(t *testing.T) {
 		mockEVMCallerData: *mockEVMCallerData,
 	}
 
-	snapshot, mintRequest, mintRequestError := daemon(defaultEVMMock)
+	snapshot, mintRequest, _, mintRequestError := daemon(defaultEVMMock)
 
 	if mintRequestError != nil {
 		t.Errorf("received unexpected error %s", mintRequestError)
@@ -166,8 +165,8 @@ func (e *BadMintReturnSizeEVMMock) GetGasLimit() uint64 {
 	return defaultGetGasLimit(&e.mockEVMCallerData)
 }
 
-func (e *BadMintReturnSizeEVMMock) GetChainID() *big.Int {
-	return params.FlareChainID
+func (e *BadMintReturnSizeEVMMock) SystemContracts() *params.FlareSystemContracts {
+	return params.FlareSystemContractsFlare
 }
 
 func (e *BadMintReturnSizeEVMMock) AddBalance(addr common.Address, amount *uint256.Int) {
@@ -189,7 +188,7 @@ func TestDaemonValidatesMintRequestReturnValueSize(t *testing.T) {
 		mockEVMCallerData: *mockEVMCallerData,
 	}
 	// Call to return less than 32 bytes
-	_, _, err := daemon(badMintReturnSizeEVMMock)
+	_, _, _, err := daemon(badMintReturnSizeEVMMock)
 
 	if err != nil {
 		if err, ok := err.(*ErrInvalidDaemonData); !ok {
@@ -225,8 +224,8 @@ func (e *BadDaemonCallEVMMock) GetGasLimit() uint64 {
 	return defaultGetGasLimit(&e.mockEVMCallerData)
 }
 
-func (e *BadDaemonCallEVMMock) GetChainID() *big.Int {
-	return params.FlareChainID
+func (e *BadDaemonCallEVMMock) SystemContracts() *params.FlareSystemContracts {
+	return params.FlareSystemContractsFlare
 }
 
 func (e *BadDaemonCallEVMMock) AddBalance(addr common.Address, amount *uint256.Int) {
@@ -239,7 +238,7 @@ func TestDaemonReturnsCallError(t *testing.T) {
 		mockEVMCallerData: *mockEVMCallerData,
 	}
 	// Call to return less than 32 bytes
-	_, _, err := daemon(badDaemonCallEVMMock)
+	_, _, _, err := daemon(badDaemonCallEVMMock)
 
 	if err == nil {
 		t.Errorf("no error received")
@@ -327,8 +326,8 @@ func (e *ReturnNilMintRequestEVMMock) GetGasLimit() uint64 {
 	return defaultGetGasLimit(&e.mockEVMCallerData)
 }
 
-func (e *ReturnNilMintRequestEVMMock) GetChainID() *big.Int {
-	return params.FlareChainID
+func (e *ReturnNilMintRequestEVMMock) SystemContracts() *params.FlareSystemContracts {
+	return params.FlareSystemContractsFlare
 }
 
 func (e *ReturnNilMintRequestEVMMock) AddBalance(addr common.Address, amount *uint256.Int) {
@@ -341,7 +340,7 @@ func TestDaemonHandlesNilMintRequest(t *testing.T) {
 		mockEVMCallerData: *mockEVMCallerData,
 	}
 	// Call to return less than 32 bytes
-	_, _, err := daemon(returnNilMintRequestEVMMock)
+	_, _, _, err := daemon(returnNilMintRequestEVMMock)
 
 	if err != nil {
 		if err, ok := err.(*ErrDaemonDataEmpty); !ok {
@@ -371,7 +370,7 @@ func TestDaemonShouldNotMintMoreThanMax(t *testing.T) {
 		if err, ok := err.(*ErrMaxMintExceeded); !ok {
 			want := &ErrMaxMintExceeded{
 				mintRequest: mintRequest,
-				mintMax:     GetMaximumMintRequest(params.FlareChainID, 0),
+				mintMax:     GetMaximumMintRequest(params.FlareSystemContractsFlare),
 			}
 			t.Errorf("got '%s' want '%s'", err.Error(), want.Error())
 		}
@@ -401,8 +400,8 @@ func TestDaemonShouldMint(t *testing.T) {
 		if defaultEVMMock.mockEVMCallerData.addBalanceCalls != 1 {
 			t.Errorf("AddBalance not called as expected")
 		}
-		if defaultEVMMock.mockEVMCallerData.lastAddBalanceAddr.String() != GetDaemonContractAddr(0) {
-			t.Errorf("wanted addr %s; got addr %s", GetDaemonContractAddr(0), defaultEVMMock.mockEVMCallerData.lastAddBalanceAddr)
+		if defaultEVMMock.mockEVMCallerData.lastAddBalanceAddr != params.FlareSystemContractsFlare.Daemon.Address {
+			t.Errorf("wanted addr %s; got addr %s", params.FlareSystemContractsFlare.Daemon.Address, defaultEVMMock.mockEVMCallerData.lastAddBalanceAddr)
 		}
 		if defaultEVMMock.mockEVMCallerData.lastAddBalanceAmount.Cmp(mintRequest) != 0 {
 			t.Errorf("wanted amount %s; got amount %s", mintRequest.String(), defaultEVMMock.mockEVMCallerData.lastAddBalanceAmount.String())
@@ -450,7 +449,7 @@ func TestDaemonFiredAndMinted(t *testing.T) {
 	}
 
 	log := log.New()
-	atomicDaemonAndMint(defaultEVMMock, log)
+	result := atomicDaemonAndMint(defaultEVMMock, log)
 
 	// EVM Call function calling the daemon should have been cqlled
 	if defaultEVMMock.mockEVMCallerData.callCalls != 1 {
@@ -460,6 +459,10 @@ func TestDaemonFiredAndMinted(t *testing.T) {
 	if defaultEVMMock.mockEVMCallerData.addBalanceCalls != 1 {
 		t.Errorf("Add balance call count not as expected. got %d want 1", defaultEVMMock.mockEVMCallerData.addBalanceCalls)
 	}
+	// The minted amount should be reported in the result
+	if result.minted.Cmp(mintRequestReturn) != 0 || result.daemonErr != nil || result.mintErr != nil {
+		t.Errorf("Daemon result not as expected. got minted %s, daemon error %v, mint error %v", result.minted, result.daemonErr, result.mintErr)
+	}
 }
 
 func TestDaemonShouldNotMintMoreThanLimit(t *testing.T) {
@@ -475,7 +478,7 @@ func TestDaemonShouldNotMintMoreThanLimit(t *testing.T) {
 	}
 
 	log := log.New()
-	atomicDaemonAndMint(defaultEVMMock, log)
+	result := atomicDaemonAndMint(defaultEVMMock, log)
 
 	// EVM Call function calling the daemon should have been called
 	if defaultEVMMock.mockEVMCallerData.callCalls != 1 {
@@ -485,6 +488,10 @@ func TestDaemonShouldNotMintMoreThanLimit(t *testing.T) {
 	if defaultEVMMock.mockEVMCallerData.addBalanceCalls != 0 {
 		t.Errorf("Add balance call count not as expected. got %d want 1", defaultEVMMock.mockEVMCallerData.addBalanceCalls)
 	}
+	// The revert should be reported in the result
+	if !result.minted.IsZero() || result.mintErr == nil {
+		t.Errorf("Daemon result not as expected. got minted %s, mint error %v", result.minted, result.mintErr)
+	}
 }
 
 func TestPrioritisedContract(t *testing.T) {
@@ -497,38 +504,87 @@ func TestPrioritisedContract(t *testing.T) {
 	ret1 := [32]byte{}
 	ret1[31] = 1
 	data := []byte{0x01, 0x02, 0x03, 0x04, 0x05}
+	contracts := params.FlareSystemContractsFlare
+	prioritisedFTSOContractAddress := contracts.Prioritised.FTSOAddress
+	prioritisedSubmitterContractAddress := contracts.Prioritised.SubmitterAddress
 
-	if IsPrioritisedContractCall(params.FlareChainID, preForkTime, &address, data, nil, initialGas) {
+	if IsPrioritisedContractCall(contracts, preForkTime, &address, data, nil, initialGas) {
 		t.Errorf("Expected false for wrong address")
 	}
-	if !IsPrioritisedContractCall(params.FlareChainID, preForkTime, &prioritisedFTSOContractAddress, nil, nil, initialGas) {
+	if !IsPrioritisedContractCall(contracts, preForkTime, &prioritisedFTSOContractAddress, nil, nil, initialGas) {
 		t.Errorf("Expected true for FTSO contract")
 	}
-	if IsPrioritisedContractCall(params.FlareChainID, preForkTime, &prioritisedSubmitterContractAddress, data, ret1[:], initialGas) {
+	if IsPrioritisedContractCall(contracts, preForkTime, &prioritisedSubmitterContractAddress, data, ret1[:], initialGas) {
 		t.Errorf("Expected false for submitter contract before activation")
 	}
-	if !IsPrioritisedContractCall(params.FlareChainID, postForkTime, &prioritisedSubmitterContractAddress, data, ret1[:], initialGas) {
+	if !IsPrioritisedContractCall(contracts, postForkTime, &prioritisedSubmitterContractAddress, data, ret1[:], initialGas) {
 		t.Errorf("Expected true for submitter contract after activation")
 	}
-	if IsPrioritisedContractCall(params.FlareChainID, postForkTime, &prioritisedSubmitterContractAddress, data, ret0[:], initialGas) {
+	if IsPrioritisedContractCall(contracts, postForkTime, &prioritisedSubmitterContractAddress, data, ret0[:], initialGas) {
 		t.Errorf("Expected false for submitter contract with wrong return value")
 	}
-	if IsPrioritisedContractCall(params.FlareChainID, postForkTime, &prioritisedSubmitterContractAddress, data, nil, initialGas) {
+	if IsPrioritisedContractCall(contracts, postForkTime, &prioritisedSubmitterContractAddress, data, nil, initialGas) {
 		t.Errorf("Expected false for submitter contract with no return value")
 	}
-	if IsPrioritisedContractCall(params.FlareChainID, postPrefixForkTime, &prioritisedSubmitterContractAddress, data, ret1[:], initialGas) {
+	if IsPrioritisedContractCall(contracts, postPrefixForkTime, &prioritisedSubmitterContractAddress, data, ret1[:], initialGas) {
 		t.Errorf("Expected false for submitter contract after prefix activation with wrong data")
 	}
-	if !IsPrioritisedContractCall(params.FlareChainID, postPrefixForkTime, &prioritisedSubmitterContractAddress, []byte{0xe1, 0xb1, 0x57, 0xe7, 0x00, 0x00}, ret1[:], initialGas) {
+	if !IsPrioritisedContractCall(contracts, postPrefixForkTime, &prioritisedSubmitterContractAddress, []byte{0xe1, 0xb1, 0x57, 0xe7, 0x00, 0x00}, ret1[:], initialGas) {
 		t.Errorf("Expected true for submitter contract after prefix activation with correct data")
 	}
-	if IsPrioritisedContractCall(params.FlareChainID, postPrefixForkTime, &prioritisedSubmitterContractAddress, make([]byte, prioritisedCallDataCap+1), ret1[:], initialGas) {
+	if IsPrioritisedContractCall(contracts, postPrefixForkTime, &prioritisedSubmitterContractAddress, make([]byte, prioritisedCallDataCap+1), ret1[:], initialGas) {
 		t.Errorf("Expected false for submitter contract after prefix activation with too long data")
 	}
-	if IsPrioritisedContractCall(params.FlareChainID, postPrefixForkTime, &prioritisedFTSOContractAddress, data, nil, initialGas) {
+	if IsPrioritisedContractCall(contracts, postPrefixForkTime, &prioritisedFTSOContractAddress, data, nil, initialGas) {
 		t.Errorf("Expected false for FTSO contract after prefix activation with wrong data")
 	}
-	if !IsPrioritisedContractCall(params.FlareChainID, postPrefixForkTime, &prioritisedFTSOContractAddress, []byte{0x8f, 0xc6, 0xf6, 0x67, 0x05}, nil, initialGas) {
+	if !IsPrioritisedContractCall(contracts, postPrefixForkTime, &prioritisedFTSOContractAddress, []byte{0x8f, 0xc6, 0xf6, 0x67, 0x05}, nil, initialGas) {
 		t.Errorf("Expected true for FTSO contract after prefix activation with correct data")
 	}
 }
+
+func TestClassifyPrioritisedContractCall(t *testing.T) {
+	contracts := params.FlareSystemContractsFlare
+	ftso := contracts.Prioritised.FTSOAddress
+	submitter := contracts.Prioritised.SubmitterAddress
+	other := common.HexToAddress("0x123456789aBCdEF123456789aBCdef123456789A")
+	preForkTime := uint64(time.Date(2024, time.March, 20, 12, 0, 0, 0, time.UTC).Unix())
+	postForkTime := uint64(time.Date(2024, time.March, 27, 12, 0, 0, 0, time.UTC).Unix())
+	postPrefixForkTime := uint64(time.Date(2024, time.October, 11, 0, 0, 0, 0, time.UTC).Unix())
+	ret1 := make([]byte, 32)
+	ret1[31] = 1
+	data := []byte{0x01, 0x02, 0x03, 0x04, 0x05}
+	submitterData := []byte{0xe1, 0xb1, 0x57, 0xe7, 0x00, 0x00}
+
+	tests := []struct {
+		to          *common.Address
+		blockTime   uint64
+		data        []byte
+		ret         []byte
+		gas         uint64
+		prioritised bool
+		rule        string
+	}{
+		{nil, postPrefixForkTime, data, nil, 0, false, PrioritisedRuleNotPrioritised},
+		{&other, postPrefixForkTime, data, ret1, 0, false, PrioritisedRuleNotPrioritised},
+		{&ftso, preForkTime, data, nil, 3000001, false, PrioritisedRuleGasLimit},
+		{&ftso, preForkTime, data, nil, 3000000, true, PrioritisedRuleFTSO},
+		{&ftso, postPrefixForkTime, data, nil, 0, false, PrioritisedRuleFTSODataPrefix},
+		{&ftso, postPrefixForkTime, []byte{0x8f, 0xc6, 0xf6, 0x67}, nil, 0, true, PrioritisedRuleFTSO},
+		{&submitter, preForkTime, data, ret1, 0, false, PrioritisedRuleSubmitterNotActive},
+		{&submitter, postForkTime, data, make([]byte, 32), 0, false, PrioritisedRuleSubmitterZeroReturn},
+		{&submitter, postForkTime, data, ret1, 0, true, PrioritisedRuleSubmitter},
+		{&submitter, postPrefixForkTime, make([]byte, prioritisedCallDataCap+1), ret1, 0, false, PrioritisedRuleSubmitterDataCap},
+		{&submitter, postPrefixForkTime, data, ret1, 0, false, PrioritisedRuleSubmitterDataPrefix},
+		{&submitter, postPrefixForkTime, submitterData, ret1, 0, true, PrioritisedRuleSubmitter},
+	}
+	for i, test := range tests {
+		prioritised, rule := ClassifyPrioritisedContractCall(contracts, test.blockTime, test.to, test.data, test.ret, test.gas)
+		if prioritised != test.prioritised || rule != test.rule {
+			t.Errorf("test %d: have (%t, %s), want (%t, %s)", i, prioritised, rule, test.prioritised, test.rule)
+		}
+		if IsPrioritisedContractCall(contracts, test.blockTime, test.to, test.data, test.ret, test.gas) != prioritised {
+			t.Errorf("test %d: IsPrioritisedContractCall disagrees with classification", i)
+		}
+	}
+}
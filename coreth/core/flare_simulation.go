@@ -0,0 +1,112 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/holiman/uint256"
+)
+
+// Reasons of the balances credited by the Flare system contract side effects.
+const (
+	FlareCreditFee            = "fee"            // transaction fee paid to the burn address
+	FlareCreditFeeRefund      = "feeRefund"      // fee refunded to the sender of a prioritised contract call
+	FlareCreditMint           = "mint"           // inflation minted on to the daemon contract
+	FlareCreditInitialAirdrop = "initialAirdrop" // initial airdrop balance moved to its target contract
+	FlareCreditDistribution   = "distribution"   // distribution balance moved to its target contract
+)
+
+// Kinds of the signals emitted to Flare system contracts by switching the
+// block coinbase to their signal address.
+const (
+	FlareSignalFinaliseRound        = "finaliseRound"
+	FlareSignalGovernanceAddress    = "setGovernanceAddress"
+	FlareSignalGovernanceTimelock   = "setTimelock"
+	FlareSignalInitialAirdropChange = "updateInitialAirdrop"
+	FlareSignalDistributionChange   = "updateDistribution"
+)
+
+// FlareSideEffects selects the Flare system contract side effects that are
+// applied after a successful call. Blocks always apply all of them, simulated
+// calls may disable some of them to isolate the effect of the call itself.
+type FlareSideEffects struct {
+	Daemon         bool `json:"daemon"`         // call the daemon and mint the requested inflation
+	StateConnector bool `json:"stateConnector"` // finalise the state connector round on attestation submissions
+	Governance     bool `json:"governance"`     // apply governance settings, initial airdrop and distribution changes
+}
+
+// FlareCredit is a balance credited by a Flare side effect.
+type FlareCredit struct {
+	Address common.Address `json:"address"`
+	Amount  *hexutil.Big   `json:"amount"`
+	Reason  string         `json:"reason"`
+}
+
+// FlareSignal is a system call signalled to a Flare system contract.
+type FlareSignal struct {
+	Kind     string         `json:"kind"`
+	Contract common.Address `json:"contract"`
+	Coinbase common.Address `json:"coinbase"`        // signal address the coinbase was switched to
+	Error    string         `json:"error,omitempty"` // why the signalled call failed, if it did
+}
+
+// FlareEffects reports the Flare system contract side effects applied after a
+// simulated call.
+type FlareEffects struct {
+	Applied          FlareSideEffects    `json:"applied"`
+	Daemon           *types.DaemonResult `json:"daemon"`           // nil if the daemon was not called
+	FeeTreatment     *types.FeeTreatment `json:"feeTreatment"`     // nil on networks other than Flare and Songbird
+	AttestationRound *AttestationRound   `json:"attestationRound"` // nil if no state connector round was finalised
+	Credits          []*FlareCredit      `json:"credits"`
+	Signals          []*FlareSignal      `json:"signals"`
+}
+
+// appliesDaemon reports whether the daemon is called after the message.
+func (m *Message) appliesDaemon() bool {
+	return m.FlareSideEffects == nil || m.FlareSideEffects.Daemon
+}
+
+// appliesStateConnector reports whether state connector rounds are finalised
+// after the message.
+func (m *Message) appliesStateConnector() bool {
+	return m.FlareSideEffects == nil || m.FlareSideEffects.StateConnector
+}
+
+// appliesGovernance reports whether the governance hooks are applied after the
+// message.
+func (m *Message) appliesGovernance() bool {
+	return m.FlareSideEffects == nil || m.FlareSideEffects.Governance
+}
+
+// recordCredit reports a balance credited by a Flare side effect of a
+// simulated call.
+func (st *StateTransition) recordCredit(addr common.Address, amount *uint256.Int, reason string) {
+	if st.effects == nil || amount.IsZero() {
+		return
+	}
+	st.effects.Credits = append(st.effects.Credits, &FlareCredit{
+		Address: addr,
+		Amount:  (*hexutil.Big)(amount.ToBig()),
+		Reason:  reason,
+	})
+}
+
+// recordSignal reports a system call signalled by a Flare side effect of a
+// simulated call.
+func (st *StateTransition) recordSignal(kind string, coinbase common.Address, err error) {
+	if st.effects == nil {
+		return
+	}
+	signal := &FlareSignal{
+		Kind:     kind,
+		Contract: st.to(),
+		Coinbase: coinbase,
+	}
+	if err != nil {
+		signal.Error = err.Error()
+	}
+	st.effects.Signals = append(st.effects.Signals, signal)
+}
@@ -0,0 +1,80 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that simulated calls apply only the selected Flare side effects and
+// report the applied ones.
+func TestFlareSideEffectsSimulation(t *testing.T) {
+	config := params.TestFlareChainConfig
+	apply := func(sideEffects *FlareSideEffects) *ExecutionResult {
+		statedb, err := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		require.NoError(t, err)
+		statedb.SetBalance(testAddr, uint256.NewInt(params.Ether))
+
+		blockCtx := vm.BlockContext{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			Coinbase:    common.HexToAddress("0x0100000000000000000000000000000000000000"),
+			BlockNumber: big.NewInt(1),
+			Time:        1,
+			GasLimit:    params.ApricotPhase1GasLimit,
+			BaseFee:     big.NewInt(params.ApricotPhase3InitialBaseFee),
+		}
+		to := common.Address{0xaa}
+		msg := &Message{
+			From:              testAddr,
+			To:                &to,
+			Value:             new(big.Int),
+			GasLimit:          params.TxGas,
+			GasPrice:          big.NewInt(params.ApricotPhase3InitialBaseFee),
+			GasFeeCap:         big.NewInt(params.ApricotPhase3InitialBaseFee),
+			GasTipCap:         new(big.Int),
+			SkipAccountChecks: true,
+			FlareSideEffects:  sideEffects,
+		}
+		evm := vm.NewEVM(blockCtx, NewEVMTxContext(msg), statedb, config, vm.Config{NoBaseFee: true})
+		result, err := ApplyMessage(evm, msg, new(GasPool).AddGas(math.MaxUint64))
+		require.NoError(t, err)
+		require.NoError(t, result.Err)
+		return result
+	}
+
+	// Without a selection all side effects are applied, but none are reported
+	result := apply(nil)
+	require.Nil(t, result.FlareEffects)
+	require.NotNil(t, result.DaemonResult)
+
+	// Disabling the daemon skips the daemon call and the mint
+	result = apply(&FlareSideEffects{StateConnector: true, Governance: true})
+	require.Nil(t, result.DaemonResult)
+	effects := result.FlareEffects
+	require.NotNil(t, effects)
+	require.Equal(t, FlareSideEffects{StateConnector: true, Governance: true}, effects.Applied)
+	require.Nil(t, effects.Daemon)
+	require.NotNil(t, effects.FeeTreatment)
+	require.Len(t, effects.Credits, 1)
+	require.Equal(t, FlareCreditFee, effects.Credits[0].Reason)
+	require.Equal(t, effects.FeeTreatment.Fee, effects.Credits[0].Amount.ToInt())
+	require.Empty(t, effects.Signals)
+
+	// Enabling the daemon reports its outcome
+	result = apply(&FlareSideEffects{Daemon: true})
+	require.NotNil(t, result.FlareEffects.Daemon)
+	require.Equal(t, result.DaemonResult, result.FlareEffects.Daemon)
+}
@@ -0,0 +1,285 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// systemContractsSnapshot captures how the node interacts with the Flare
+// system contracts of a network at a given block time.
+type systemContractsSnapshot struct {
+	Time uint64 `json:"time"`
+
+	Rules string `json:"rules"`
+
+	DaemonAddress       common.Address `json:"daemonAddress"`
+	DaemonSelector      hexutil.Bytes  `json:"daemonSelector"`
+	DaemonGasMultiplier uint64         `json:"daemonGasMultiplier"`
+	MaxMintRequest      string         `json:"maxMintRequest"`
+
+	PrioritisedCalls []string `json:"prioritisedCalls"`
+
+	StateConnectorCalled         []common.Address `json:"stateConnectorCalled"`
+	StateConnectorCoinbaseSignal common.Address   `json:"stateConnectorCoinbaseSignal"`
+	SubmitAttestationSelector    hexutil.Bytes    `json:"submitAttestationSelector"`
+	GetAttestationSelector       hexutil.Bytes    `json:"getAttestationSelector"`
+	FinaliseRoundSelector        hexutil.Bytes    `json:"finaliseRoundSelector"`
+	DefaultAttestors             []common.Address `json:"defaultAttestors"`
+
+	// Governance, airdrop and distribution changes are only handled under
+	// the Flare rules.
+	Governance *governanceSnapshot `json:"governance,omitempty"`
+}
+
+type governanceSnapshot struct {
+	GovernanceCalled             []common.Address `json:"governanceCalled"`
+	GovernanceCoinbaseSignal     common.Address   `json:"governanceCoinbaseSignal"`
+	SetGovernanceAddressSelector hexutil.Bytes    `json:"setGovernanceAddressSelector"`
+	SetTimelockSelector          hexutil.Bytes    `json:"setTimelockSelector"`
+	PermittedGovernanceAddresses []common.Address `json:"permittedGovernanceAddresses"`
+	PermittedTimelocks           []uint64         `json:"permittedTimelocks"`
+
+	InitialAirdropCalled         []common.Address `json:"initialAirdropCalled"`
+	InitialAirdropCoinbaseSignal common.Address   `json:"initialAirdropCoinbaseSignal"`
+	InitialAirdropSelector       hexutil.Bytes    `json:"initialAirdropSelector"`
+	InitialAirdropAddress        common.Address   `json:"initialAirdropAddress"`
+	TargetAirdropAddress         common.Address   `json:"targetAirdropAddress"`
+
+	DistributionCalled         []common.Address `json:"distributionCalled"`
+	DistributionCoinbaseSignal common.Address   `json:"distributionCoinbaseSignal"`
+	DistributionSelector       hexutil.Bytes    `json:"distributionSelector"`
+	DistributionAddress        common.Address   `json:"distributionAddress"`
+	TargetDistributionAddress  common.Address   `json:"targetDistributionAddress"`
+}
+
+var (
+	// Addresses probed for the "is activated and called" checks, every address
+	// that is or was used by a system contract on any of the networks.
+	systemContractsProbeAddresses = []common.Address{
+		common.HexToAddress("0x1000000000000000000000000000000000000001"),
+		common.HexToAddress("0x1000000000000000000000000000000000000002"),
+		common.HexToAddress("0x1000000000000000000000000000000000000003"),
+		common.HexToAddress("0x1000000000000000000000000000000000000007"),
+		common.HexToAddress("0x1000000000000000000000000000000000000008"),
+		common.HexToAddress("0x1000000000000000000000000000000000000009"),
+		common.HexToAddress("0x100000000000000000000000000000000000000f"),
+		common.HexToAddress("0x0c13aDA1C7143Cf0a0795FFaB93eEBb6FAD6e4e3"),
+		common.HexToAddress("0x3A1b3220527aBA427d1e13e4b4c48c31460B4d91"),
+		common.HexToAddress("0x947c76694491d3fD67a73688003c4d36C8780A97"),
+		common.HexToAddress("0x4AeE563140E36abA778944E2Ca68c3988CAd5730"),
+		common.HexToAddress("0x28561B938342efD0677f60Fd0912e1931367a612"),
+		common.HexToAddress("0x4d1c42F41555Ae35DfC1819bd718f7D9Fb28abdD"),
+		common.HexToAddress("0xdF1deD5f1905C5012cbeE8367e3F4849afEAE545"),
+		common.HexToAddress("0x2cA6571Daa15ce734Bbd0Bf27D5C9D16787fc33f"),
+		common.HexToAddress("0x123456789aBCdEF123456789aBCdef123456789A"),
+	}
+
+	systemContractsProbeTimelocks = []uint64{0, 3600, 1000000}
+
+	// Calls probed against IsPrioritisedContractCall.
+	systemContractsProbePrioritised = []common.Address{
+		common.HexToAddress("0x1000000000000000000000000000000000000003"),
+		common.HexToAddress("0x2cA6571Daa15ce734Bbd0Bf27D5C9D16787fc33f"),
+		common.HexToAddress("0x123456789aBCdEF123456789aBCdef123456789A"),
+	}
+	systemContractsProbeData = map[string][]byte{
+		"none":       nil,
+		"other":      {0x01, 0x02, 0x03, 0x04, 0x05},
+		"submitter":  {0xe1, 0xb1, 0x57, 0xe7, 0x00, 0x00},
+		"flareFtso":  {0x8f, 0xc6, 0xf6, 0x67, 0x05},
+		"sgbFtso":    {0xc5, 0xad, 0xc5, 0x39, 0x05},
+		"submitLong": append([]byte{0xe1, 0xb1, 0x57, 0xe7}, make([]byte, 4500)...),
+	}
+	systemContractsProbeGas = []uint64{21000, 3000000, 3000001}
+)
+
+// systemContractsProbeTimes returns the block times at which the system
+// contract interactions of all networks are compared: every activation time,
+// one second before and after it, and a time well after all activations.
+func systemContractsProbeTimes() []uint64 {
+	activations := []time.Time{
+		time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, time.February, 25, 17, 0, 0, 0, time.UTC),
+		time.Date(2022, time.March, 28, 14, 0, 0, 0, time.UTC),
+		time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, time.September, 8, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, time.September, 9, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, time.October, 6, 15, 0, 0, 0, time.UTC),
+		time.Date(2022, time.October, 19, 15, 0, 0, 0, time.UTC),
+		time.Date(2022, time.October, 27, 20, 0, 0, 0, time.UTC),
+		time.Date(2022, time.November, 10, 15, 0, 0, 0, time.UTC),
+		time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, time.January, 26, 15, 0, 0, 0, time.UTC),
+		time.Date(2023, time.March, 1, 15, 0, 0, 0, time.UTC),
+		time.Date(2024, time.February, 29, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, time.March, 7, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, time.March, 26, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, time.October, 10, 8, 0, 0, 0, time.UTC),
+		time.Date(2024, time.October, 10, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, time.October, 10, 13, 0, 0, 0, time.UTC),
+		time.Date(2024, time.October, 10, 15, 0, 0, 0, time.UTC),
+	}
+	times := []uint64{0}
+	for _, activation := range activations {
+		t := uint64(activation.Unix())
+		times = append(times, t-1, t, t+1)
+	}
+	return append(times, uint64(time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()))
+}
+
+func takeSystemContractsSnapshot(contracts *params.FlareSystemContracts, blockTime uint64) systemContractsSnapshot {
+	s := systemContractsSnapshot{
+		Time:                         blockTime,
+		DaemonAddress:                contracts.Daemon.Address,
+		DaemonSelector:               contracts.Daemon.Selector[:],
+		DaemonGasMultiplier:          contracts.Daemon.GasMultiplier,
+		MaxMintRequest:               GetMaximumMintRequest(contracts).Dec(),
+		StateConnectorCoinbaseSignal: GetStateConnectorCoinbaseSignalAddr(contracts, blockTime),
+		SubmitAttestationSelector:    contracts.StateConnector.SubmitAttestationSelector[:],
+		GetAttestationSelector:       contracts.StateConnector.GetAttestationSelector[:],
+		FinaliseRoundSelector:        contracts.StateConnector.FinaliseRoundSelector[:],
+		DefaultAttestors:             GetDefaultAttestors(contracts, blockTime),
+	}
+	st := &StateTransition{evm: &vm.EVM{Context: vm.BlockContext{Coinbase: common.HexToAddress("0x0100000000000000000000000000000000000000")}}}
+	if _, _, isFlare, isSongbird, _ := stateTransitionVariant(contracts)(st); isFlare {
+		s.Rules = "flare"
+	} else if isSongbird {
+		s.Rules = "songbird"
+	}
+	for _, addr := range systemContractsProbeAddresses {
+		if GetStateConnectorIsActivatedAndCalled(false, contracts, blockTime, addr) {
+			s.StateConnectorCalled = append(s.StateConnectorCalled, addr)
+		}
+	}
+	if s.Rules == "flare" {
+		g := &governanceSnapshot{
+			GovernanceCoinbaseSignal:     contracts.GovernanceSettings.CoinbaseSignal,
+			SetGovernanceAddressSelector: contracts.GovernanceSettings.SetGovernanceAddressSelector[:],
+			SetTimelockSelector:          contracts.GovernanceSettings.SetTimelockSelector[:],
+			InitialAirdropCoinbaseSignal: contracts.InitialAirdrop.CoinbaseSignal,
+			InitialAirdropSelector:       contracts.InitialAirdrop.Selector[:],
+			InitialAirdropAddress:        contracts.InitialAirdrop.Source,
+			TargetAirdropAddress:         contracts.InitialAirdrop.Target,
+			DistributionCoinbaseSignal:   contracts.Distribution.CoinbaseSignal,
+			DistributionSelector:         contracts.Distribution.Selector[:],
+			DistributionAddress:          contracts.Distribution.Source,
+			TargetDistributionAddress:    contracts.Distribution.Target,
+		}
+		for _, addr := range systemContractsProbeAddresses {
+			if GetGovernanceSettingIsActivatedAndCalled(contracts, blockTime, addr) {
+				g.GovernanceCalled = append(g.GovernanceCalled, addr)
+			}
+			if GetInitialAirdropChangeIsActivatedAndCalled(contracts, blockTime, addr) {
+				g.InitialAirdropCalled = append(g.InitialAirdropCalled, addr)
+			}
+			if GetDistributionChangeIsActivatedAndCalled(contracts, blockTime, addr) {
+				g.DistributionCalled = append(g.DistributionCalled, addr)
+			}
+			if NewGovernanceAddressIsPermitted(contracts, blockTime, addr) {
+				g.PermittedGovernanceAddresses = append(g.PermittedGovernanceAddresses, addr)
+			}
+		}
+		for _, timelock := range systemContractsProbeTimelocks {
+			if NewTimelockIsPermitted(contracts, blockTime, timelock) {
+				g.PermittedTimelocks = append(g.PermittedTimelocks, timelock)
+			}
+		}
+		s.Governance = g
+	}
+	ret := make([]byte, 32)
+	ret[31] = 1
+	for _, to := range systemContractsProbePrioritised {
+		to := to
+		for name, data := range systemContractsProbeData {
+			for _, gas := range systemContractsProbeGas {
+				for _, ret := range [][]byte{nil, ret} {
+					if IsPrioritisedContractCall(contracts, blockTime, &to, data, ret, gas) {
+						s.PrioritisedCalls = append(s.PrioritisedCalls, fmt.Sprintf("%s/%s/%d/%t", to.Hex(), name, gas, ret != nil))
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(s.PrioritisedCalls)
+	return s
+}
+
+// TestFlareSystemContractsGolden checks that the built-in system contract
+// schedules of the production and local networks keep the values recorded in
+// testdata, which were taken before the schedules were moved to params.
+// Snapshots are taken around every activation time and only the ones that
+// differ from their predecessor are recorded.
+func TestFlareSystemContractsGolden(t *testing.T) {
+	networks := map[string]*big.Int{
+		"flare":       params.FlareChainID,
+		"costwo":      params.CostwoChainID,
+		"songbird":    params.SongbirdChainID,
+		"coston":      params.CostonChainID,
+		"local_flare": params.LocalFlareChainID,
+		"local":       params.LocalChainID,
+	}
+	for name, chainID := range networks {
+		t.Run(name, func(t *testing.T) {
+			var (
+				have []systemContractsSnapshot
+				last []byte
+			)
+			for _, blockTime := range systemContractsProbeTimes() {
+				snapshot := takeSystemContractsSnapshot(params.GetFlareSystemContracts(chainID), blockTime)
+				snapshot.Time = 0
+				data, err := json.Marshal(snapshot)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != string(last) {
+					snapshot.Time = blockTime
+					have = append(have, snapshot)
+				}
+				last = data
+			}
+			path := filepath.Join("testdata", "flare_system_contracts", name+".json")
+			if os.Getenv("UPDATE_GOLDEN") != "" {
+				data, err := json.MarshalIndent(have, "", "  ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []systemContractsSnapshot
+			if err := json.Unmarshal(data, &want); err != nil {
+				t.Fatal(err)
+			}
+			if len(have) != len(want) {
+				t.Fatalf("snapshot count mismatch: have %d, want %d", len(have), len(want))
+			}
+			for i := range want {
+				haveJSON, _ := json.Marshal(have[i])
+				wantJSON, _ := json.Marshal(want[i])
+				if string(haveJSON) != string(wantJSON) {
+					t.Errorf("snapshot at time %d mismatch:\nhave %s\nwant %s", want[i].Time, haveJSON, wantJSON)
+				}
+			}
+		})
+	}
+}
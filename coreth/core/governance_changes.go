@@ -0,0 +1,118 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// HasGovernanceCalls reports whether one of the successful transactions of the
+// block called a governance hook of the Flare system contracts, in which case
+// replaying the block may report governance changes.
+func (bc *BlockChain) HasGovernanceCalls(block *types.Block) bool {
+	contracts := bc.chainConfig.SystemContracts()
+	if !contracts.IsFlare() {
+		return false
+	}
+	var receipts types.Receipts
+	for i, tx := range block.Transactions() {
+		if tx.To() == nil || !isGovernanceCall(contracts, block.Time(), *tx.To(), tx.Data()) {
+			continue
+		}
+		if receipts == nil {
+			if receipts = bc.GetReceiptsByHash(block.Hash()); receipts == nil {
+				return true // receipts not available, assume the call succeeded
+			}
+		}
+		if i < len(receipts) && receipts[i].Status == types.ReceiptStatusSuccessful {
+			return true
+		}
+	}
+	return false
+}
+
+// isGovernanceCall reports whether a call to the given contract triggers one of
+// the governance hooks of the Flare rules.
+func isGovernanceCall(contracts *params.FlareSystemContracts, timestamp uint64, to common.Address, data []byte) bool {
+	switch {
+	case GetGovernanceSettingIsActivatedAndCalled(contracts, timestamp, to) && len(data) == 36:
+		return bytes.Equal(data[0:4], contracts.GovernanceSettings.SetGovernanceAddressSelector[:]) ||
+			bytes.Equal(data[0:4], contracts.GovernanceSettings.SetTimelockSelector[:])
+	case GetInitialAirdropChangeIsActivatedAndCalled(contracts, timestamp, to) && len(data) == 4:
+		return bytes.Equal(data, contracts.InitialAirdrop.Selector[:])
+	case GetDistributionChangeIsActivatedAndCalled(contracts, timestamp, to) && len(data) == 4:
+		return bytes.Equal(data, contracts.Distribution.Selector[:])
+	}
+	return false
+}
+
+// ReplayGovernanceChanges replays the block on top of the given state of its
+// parent and returns the governance changes signalled by its transactions.
+func (bc *BlockChain) ReplayGovernanceChanges(block *types.Block, statedb *state.StateDB) (types.GovernanceChanges, error) {
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	parentState := statedb.Copy()
+	_, _, flare, _, err := bc.processor.Process(block, parent, statedb, vm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	bc.setGovernanceOldValues(block.Header(), parentState, flare.Governance)
+	return flare.Governance, nil
+}
+
+// Getters of the GovernanceSettings contract, read to report the value replaced
+// by a change, and the part of their result holding the value.
+// function getGovernanceAddress() external view returns (address)
+// function getTimelock() external view returns (uint256)
+var governanceSettingGetters = map[string]struct {
+	selector []byte
+	offset   int
+}{
+	types.GovernanceAddressChange:  {crypto.Keccak256([]byte("getGovernanceAddress()"))[:4], 12},
+	types.GovernanceTimelockChange: {crypto.Keccak256([]byte("getTimelock()"))[:4], 24},
+}
+
+// setGovernanceOldValues fills in the values replaced by the changes of the
+// governance settings in [changes], signalled by the block of [header]. They
+// are not read during the execution of the block, so that reporting them has
+// no effect on it. The first change of a setting replaces its value in the
+// parent state [statedb], the following ones the value set by the last
+// successful change, since the settings only change through these hooks.
+func (bc *BlockChain) setGovernanceOldValues(header *types.Header, statedb *state.StateDB, changes types.GovernanceChanges) {
+	var (
+		evm    *vm.EVM
+		values = make(map[string][]byte)
+	)
+	for _, change := range changes {
+		getter, ok := governanceSettingGetters[change.Kind]
+		if !ok {
+			continue
+		}
+		value, ok := values[change.Kind]
+		if !ok {
+			if evm == nil {
+				evm = vm.NewEVM(NewEVMBlockContext(header, bc, nil), vm.TxContext{}, statedb, bc.chainConfig, vm.Config{})
+			}
+			ret, _, err := evm.StaticCall(vm.AccountRef(common.Address{}), change.Contract, getter.selector, header.GasLimit)
+			if err == nil && len(ret) == 32 {
+				value = ret[getter.offset:]
+			}
+		}
+		change.OldValue = common.CopyBytes(value)
+		if change.Error == "" {
+			value = change.NewValue
+		}
+		values[change.Kind] = value
+	}
+}
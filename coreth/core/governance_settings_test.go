@@ -4,17 +4,24 @@
 package core
 
 import (
+	"bytes"
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/types"
 	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 // TestNewTimelockIsPermittedCostwo checks each new timelock update
 // with permitted values and non-permitted values for the Costwo chainID
 func TestNewTimelockIsPermittedCostwo(t *testing.T) {
 
-	chainID := params.CostwoChainID
+	contracts := params.FlareSystemContractsCostwo
 
 	// ====================================================================================
 	// Test Case #1 --- timelock: 3600 seconds, valid from: September 8th, 2022 to present
@@ -24,7 +31,7 @@ func TestNewTimelockIsPermittedCostwo(t *testing.T) {
 	blockTime := uint64(time.Date(2022, time.September, 8, 0, 0, 0, 0, time.UTC).Unix())
 	newTimelock := uint64(3600)
 	want := true
-	have := NewTimelockIsPermitted(chainID, blockTime, newTimelock)
+	have := NewTimelockIsPermitted(contracts, blockTime, newTimelock)
 	if want != have {
 		t.Fatalf(`NewTimelockIsPermitted = %t, want %t.`, have, want)
 	}
@@ -33,7 +40,7 @@ func TestNewTimelockIsPermittedCostwo(t *testing.T) {
 	blockTime = uint64(time.Date(2022, time.September, 8, 0, 0, 0, 0, time.UTC).Unix())
 	newTimelock = uint64(0)
 	want = false
-	have = NewTimelockIsPermitted(chainID, blockTime, newTimelock)
+	have = NewTimelockIsPermitted(contracts, blockTime, newTimelock)
 	if want != have {
 		t.Fatalf(`NewTimelockIsPermitted = %t, want %t.`, have, want)
 	}
@@ -42,7 +49,7 @@ func TestNewTimelockIsPermittedCostwo(t *testing.T) {
 	blockTime = uint64(time.Date(2022, time.September, 8, 0, 0, 0, 0, time.UTC).Unix())
 	newTimelock = uint64(1000000)
 	want = false
-	have = NewTimelockIsPermitted(chainID, blockTime, newTimelock)
+	have = NewTimelockIsPermitted(contracts, blockTime, newTimelock)
 	if want != have {
 		t.Fatalf(`NewTimelockIsPermitted = %t, want %t.`, have, want)
 	}
@@ -51,7 +58,7 @@ func TestNewTimelockIsPermittedCostwo(t *testing.T) {
 	blockTime = uint64(time.Date(2021, time.September, 8, 0, 0, 0, 0, time.UTC).Unix())
 	newTimelock = uint64(3600)
 	want = false
-	have = NewTimelockIsPermitted(chainID, blockTime, newTimelock)
+	have = NewTimelockIsPermitted(contracts, blockTime, newTimelock)
 	if want != have {
 		t.Fatalf(`NewTimelockIsPermitted = %t, want %t.`, have, want)
 	}
@@ -62,7 +69,7 @@ func TestNewTimelockIsPermittedCostwo(t *testing.T) {
 // with permitted values and non-permitted values for the Flare chainID
 func TestNewTimelockIsPermittedFlare(t *testing.T) {
 
-	chainID := params.FlareChainID
+	contracts := params.FlareSystemContractsFlare
 
 	// ====================================================================================
 	// Test Case #1 --- timelock: 3600 seconds, valid from: September 9th, 2022 to present
@@ -72,7 +79,7 @@ func TestNewTimelockIsPermittedFlare(t *testing.T) {
 	blockTime := uint64(time.Date(2022, time.September, 9, 0, 0, 0, 0, time.UTC).Unix())
 	newTimelock := uint64(3600)
 	want := true
-	have := NewTimelockIsPermitted(chainID, blockTime, newTimelock)
+	have := NewTimelockIsPermitted(contracts, blockTime, newTimelock)
 	if want != have {
 		t.Fatalf(`NewTimelockIsPermitted = %t, want %t.`, have, want)
 	}
@@ -81,7 +88,7 @@ func TestNewTimelockIsPermittedFlare(t *testing.T) {
 	blockTime = uint64(time.Date(2022, time.September, 9, 0, 0, 0, 0, time.UTC).Unix())
 	newTimelock = uint64(0)
 	want = false
-	have = NewTimelockIsPermitted(chainID, blockTime, newTimelock)
+	have = NewTimelockIsPermitted(contracts, blockTime, newTimelock)
 	if want != have {
 		t.Fatalf(`NewTimelockIsPermitted = %t, want %t.`, have, want)
 	}
@@ -90,7 +97,7 @@ func TestNewTimelockIsPermittedFlare(t *testing.T) {
 	blockTime = uint64(time.Date(2022, time.September, 9, 0, 0, 0, 0, time.UTC).Unix())
 	newTimelock = uint64(1000000)
 	want = false
-	have = NewTimelockIsPermitted(chainID, blockTime, newTimelock)
+	have = NewTimelockIsPermitted(contracts, blockTime, newTimelock)
 	if want != have {
 		t.Fatalf(`NewTimelockIsPermitted = %t, want %t.`, have, want)
 	}
@@ -99,9 +106,72 @@ func TestNewTimelockIsPermittedFlare(t *testing.T) {
 	blockTime = uint64(time.Date(2021, time.September, 9, 0, 0, 0, 0, time.UTC).Unix())
 	newTimelock = uint64(3600)
 	want = false
-	have = NewTimelockIsPermitted(chainID, blockTime, newTimelock)
+	have = NewTimelockIsPermitted(contracts, blockTime, newTimelock)
 	if want != have {
 		t.Fatalf(`NewTimelockIsPermitted = %t, want %t.`, have, want)
 	}
 
 }
+
+// TestIsGovernanceCall checks that only calls to the governance hooks of the
+// Flare system contracts are reported as governance calls
+func TestIsGovernanceCall(t *testing.T) {
+	contracts := params.FlareSystemContractsFlare
+	blockTime := uint64(time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC).Unix())
+
+	governance, activated := contracts.GovernanceSettings.Address.At(blockTime)
+	if !activated {
+		t.Fatal(`governance settings not activated`)
+	}
+	setTimelock := append(contracts.GovernanceSettings.SetTimelockSelector[:], make([]byte, 32)...)
+	if !isGovernanceCall(contracts, blockTime, governance, setTimelock) {
+		t.Fatal(`setTimelock call not reported as governance call`)
+	}
+	if isGovernanceCall(contracts, blockTime, governance, setTimelock[:4]) {
+		t.Fatal(`setTimelock call without argument reported as governance call`)
+	}
+	if isGovernanceCall(contracts, blockTime, common.Address{0xaa}, setTimelock) {
+		t.Fatal(`call to another contract reported as governance call`)
+	}
+	distribution, activated := contracts.Distribution.Address.At(blockTime)
+	if activated && !isGovernanceCall(contracts, blockTime, distribution, contracts.Distribution.Selector[:]) {
+		t.Fatal(`distribution change not reported as governance call`)
+	}
+}
+
+// TestSetGovernanceOldValues checks that the values replaced by governance
+// setting changes are read from the parent state for the first change of a
+// setting and follow the successful changes of the block afterwards
+func TestSetGovernanceOldValues(t *testing.T) {
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both getters return 3600: PUSH2 0x0e10 PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	settings := common.Address{0xaa}
+	statedb.SetCode(settings, []byte{0x61, 0x0e, 0x10, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3})
+
+	bc := &BlockChain{chainConfig: params.TestFlareChainConfig, engine: dummy.NewFaker()}
+	header := &types.Header{Number: big.NewInt(1), Difficulty: common.Big1, GasLimit: 8_000_000}
+	changes := types.GovernanceChanges{
+		{Kind: types.GovernanceTimelockChange, Contract: settings, NewValue: []byte{0, 0, 0, 0, 0, 0, 0x1c, 0x20}},
+		{Kind: types.GovernanceTimelockChange, Contract: settings, NewValue: []byte{0, 0, 0, 0, 0, 0, 0x2a, 0x30}, Error: "execution reverted"},
+		{Kind: types.GovernanceTimelockChange, Contract: settings, NewValue: []byte{0, 0, 0, 0, 0, 0, 0x38, 0x40}},
+		{Kind: types.GovernanceAddressChange, Contract: settings, NewValue: common.Address{0xbb}.Bytes()},
+		{Kind: types.DistributionChange, Contract: settings, OldValue: common.Address{0xcc}.Bytes(), NewValue: common.Address{0xdd}.Bytes()},
+	}
+	bc.setGovernanceOldValues(header, statedb, changes)
+
+	want := [][]byte{
+		{0, 0, 0, 0, 0, 0, 0x0e, 0x10},
+		{0, 0, 0, 0, 0, 0, 0x1c, 0x20},
+		{0, 0, 0, 0, 0, 0, 0x1c, 0x20},
+		common.BigToAddress(big.NewInt(3600)).Bytes(),
+		common.Address{0xcc}.Bytes(),
+	}
+	for i, change := range changes {
+		if !bytes.Equal(change.OldValue, want[i]) {
+			t.Fatalf(`change %d: old value %x, want %x`, i, change.OldValue, want[i])
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// GovernanceReplayer returns the governance changes signalled by an accepted
+// block. It is expected to only replay the blocks in which a governance hook
+// was called.
+type GovernanceReplayer func(block *types.Block) (types.GovernanceChanges, error)
+
+// governanceIndexer is the module responsible for indexing the governance
+// changes of every accepted block in the background. Blocks are indexed from
+// the last accepted block at the time the index was first enabled, the older
+// ones are not replayed.
+type governanceIndexer struct {
+	db       ethdb.Database
+	replayer GovernanceReplayer
+	term     chan chan struct{}
+	closed   chan struct{}
+
+	chain *BlockChain
+}
+
+// newGovernanceIndexer initializes the governance indexer.
+func newGovernanceIndexer(replayer GovernanceReplayer, chain *BlockChain) *governanceIndexer {
+	indexer := &governanceIndexer{
+		db:       chain.db,
+		replayer: replayer,
+		term:     make(chan chan struct{}),
+		closed:   make(chan struct{}),
+		chain:    chain,
+	}
+	chain.wg.Add(1)
+	go func() {
+		defer chain.wg.Done()
+		indexer.loop()
+	}()
+	log.Info("Initialized governance indexer")
+
+	return indexer
+}
+
+// run indexes the governance changes of the accepted blocks up to [head] that
+// have not been indexed yet. If a block cannot be replayed the task stops
+// there, so that the index never has gaps, and the block is retried by the
+// next task. If the stop channel is closed, the task is terminated after the
+// block being indexed, the done channel will be closed once the task is
+// finished.
+func (indexer *governanceIndexer) run(head uint64, stop chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	var (
+		tail = rawdb.ReadGovernanceIndexTail(indexer.db)
+		last = rawdb.ReadGovernanceIndexHead(indexer.db)
+		from = head
+	)
+	if last != nil {
+		from = *last + 1
+	}
+	for number := from; number <= head; number++ {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		var changes types.GovernanceChanges
+		if number > 0 {
+			block := indexer.chain.GetBlockByNumber(number)
+			if block == nil {
+				log.Error("Failed to find accepted block to index governance changes", "number", number)
+				return
+			}
+			var err error
+			if changes, err = indexer.replayer(block); err != nil {
+				log.Error("Failed to index governance changes", "number", number, "hash", block.Hash(), "err", err)
+				return
+			}
+		}
+		batch := indexer.db.NewBatch()
+		if len(changes) > 0 {
+			rawdb.WriteGovernanceChanges(batch, number, changes)
+		}
+		rawdb.WriteGovernanceIndexHead(batch, number)
+		if tail == nil {
+			rawdb.WriteGovernanceIndexTail(batch, number)
+			tail = &number
+		}
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to write governance changes", "err", err)
+		}
+	}
+}
+
+// loop is the scheduler of the indexer, indexing the accepted blocks in a
+// background task.
+func (indexer *governanceIndexer) loop() {
+	defer close(indexer.closed)
+	var (
+		stop     chan struct{} // Non-nil if background routine is active.
+		done     chan struct{} // Non-nil if background routine is active.
+		lastHead uint64        // The latest accepted block
+		runHead  uint64        // The head of the running task
+
+		headCh = make(chan ChainEvent)
+		sub    = indexer.chain.SubscribeChainAcceptedEvent(headCh)
+	)
+	if sub == nil {
+		log.Warn("could not create chain accepted subscription to index governance changes")
+		return
+	}
+	defer sub.Unsubscribe()
+
+	launch := func(head uint64) {
+		stop = make(chan struct{})
+		done = make(chan struct{})
+		runHead = head
+		indexer.chain.wg.Add(1)
+		go func() {
+			defer indexer.chain.wg.Done()
+			indexer.run(head, stop, done)
+		}()
+	}
+	// Index the blocks accepted while the node was down.
+	lastHead = indexer.chain.LastAcceptedBlock().NumberU64()
+	launch(lastHead)
+	for {
+		select {
+		case head := <-headCh:
+			lastHead = head.Block.NumberU64()
+			if done == nil {
+				launch(lastHead)
+			}
+		case <-done:
+			stop = nil
+			done = nil
+			if lastHead > runHead {
+				launch(lastHead)
+			}
+		case ch := <-indexer.term:
+			if stop != nil {
+				close(stop)
+			}
+			if done != nil {
+				log.Info("Waiting background governance indexer to exit")
+				<-done
+			}
+			close(ch)
+			return
+		}
+	}
+}
+
+// close shutdown the indexer. Safe to be called for multiple times.
+func (indexer *governanceIndexer) close() {
+	ch := make(chan struct{})
+	select {
+	case indexer.term <- ch:
+		<-ch
+	case <-indexer.closed:
+	}
+}
@@ -0,0 +1,100 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGovernanceIndexer(t *testing.T) {
+	require := require.New(t)
+	gspec := &Genesis{
+		Config: &params.ChainConfig{HomesteadBlock: new(big.Int)},
+		Alloc:  types.GenesisAlloc{},
+	}
+	_, blocks, _, err := GenerateChainWithGenesis(gspec, dummy.NewFakerWithCallbacks(TestCallbacks), 8, 10, func(i int, block *BlockGen) {})
+	require.NoError(err)
+
+	// replayer reports a timelock change in every even block, and fails on
+	// block 5 while [failing] is set.
+	var failing atomic.Bool
+	failing.Store(true)
+	replayer := func(block *types.Block) (types.GovernanceChanges, error) {
+		if block.NumberU64() == 5 && failing.Load() {
+			return nil, errors.New("state not available")
+		}
+		if block.NumberU64()%2 != 0 {
+			return nil, nil
+		}
+		return types.GovernanceChanges{{Kind: types.GovernanceTimelockChange, NewValue: []byte{byte(block.NumberU64())}, Amount: new(big.Int)}}, nil
+	}
+	conf := &CacheConfig{
+		TrieCleanLimit:            256,
+		TrieDirtyLimit:            256,
+		TrieDirtyCommitTarget:     20,
+		TriePrefetcherParallelism: 4,
+		Pruning:                   true,
+		CommitInterval:            4096,
+		SnapshotLimit:             256,
+		SnapshotNoBuild:           true, // Ensure the test errors if snapshot initialization fails
+		AcceptorQueueLimit:        64,
+	}
+	waitIndex := func(chain *BlockChain, tail, head uint64) {
+		require.Eventually(func() bool {
+			t, h := rawdb.ReadGovernanceIndexTail(chain.db), rawdb.ReadGovernanceIndexHead(chain.db)
+			return t != nil && *t == tail && h != nil && *h == head
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+	insertAndAccept := func(chain *BlockChain, blocks []*types.Block) {
+		_, err := chain.InsertChain(blocks)
+		require.NoError(err)
+		for _, block := range blocks {
+			require.NoError(chain.Accept(block))
+		}
+		chain.DrainAcceptorQueue()
+	}
+
+	// The index stops before the block that cannot be replayed, without
+	// losing the blocks indexed before it.
+	chainDB := rawdb.NewMemoryDatabase()
+	chain, err := createBlockChain(chainDB, conf, gspec, common.Hash{})
+	require.NoError(err)
+	chain.StartGovernanceIndexer(replayer)
+	insertAndAccept(chain, blocks[:6])
+	waitIndex(chain, 0, 4)
+	time.Sleep(50 * time.Millisecond)
+	waitIndex(chain, 0, 4)
+	chain.Stop()
+
+	// The failed block is retried once the chain is restarted, and the
+	// blocks accepted afterwards are indexed.
+	failing.Store(false)
+	chain, err = createBlockChain(chainDB, conf, gspec, blocks[5].Hash())
+	require.NoError(err)
+	defer chain.Stop()
+	chain.StartGovernanceIndexer(replayer)
+	waitIndex(chain, 0, 6)
+	insertAndAccept(chain, blocks[6:])
+	waitIndex(chain, 0, 8)
+
+	changes := rawdb.ReadGovernanceChangesInRange(chainDB, 0, 8)
+	require.Len(changes, 4)
+	for i, change := range changes {
+		number := uint64(2 * (i + 1))
+		require.Equal(number, change.BlockNumber)
+		require.Equal(blocks[number-1].Hash(), change.BlockHash)
+		require.Equal([]byte{byte(number)}, change.NewValue)
+	}
+}
@@ -0,0 +1,218 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// logIndexBackfillBlocks is the maximum number of blocks accepted before the
+// log index was enabled that are indexed by a single task, so that the newly
+// accepted blocks are not delayed by the backfill.
+const logIndexBackfillBlocks = 4096
+
+// logIndexer is the module responsible for maintaining the exact log index of
+// the accepted blocks. The index grows forward with every accepted block and
+// is backfilled towards genesis in the background, so that it always covers a
+// contiguous range of blocks [tail, head].
+type logIndexer struct {
+	rebuild    bool        // drop the existing index before indexing
+	incomplete atomic.Bool // set if the backfill stopped at an unavailable block
+	db         ethdb.Database
+	term       chan chan struct{}
+	closed     chan struct{}
+
+	chain *BlockChain
+}
+
+// newLogIndexer initializes the log indexer.
+func newLogIndexer(rebuild bool, chain *BlockChain) *logIndexer {
+	indexer := &logIndexer{
+		rebuild: rebuild,
+		db:      chain.db,
+		term:    make(chan chan struct{}),
+		closed:  make(chan struct{}),
+		chain:   chain,
+	}
+	chain.wg.Add(1)
+	go func() {
+		defer chain.wg.Done()
+		indexer.loop()
+	}()
+	log.Info("Initialized log indexer", "rebuild", rebuild)
+
+	return indexer
+}
+
+// run indexes the accepted blocks up to [head] that have not been indexed yet,
+// then backfills up to logIndexBackfillBlocks blocks below the index tail. If
+// the stop channel is closed, the task is terminated after the block being
+// indexed, the done channel will be closed once the task is finished.
+func (indexer *logIndexer) run(head uint64, stop chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	var (
+		tail  = rawdb.ReadLogIndexTail(indexer.db)
+		last  = rawdb.ReadLogIndexHead(indexer.db)
+		from  = head
+		batch = indexer.db.NewBatch()
+	)
+	if last != nil {
+		from = *last + 1
+	}
+	flush := func() {
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to write log index", "err", err)
+		}
+		batch.Reset()
+	}
+	for number := from; number <= head; number++ {
+		select {
+		case <-stop:
+			flush()
+			return
+		default:
+		}
+		logs, err := indexer.blockLogs(number)
+		if err != nil {
+			log.Error("Failed to index block logs", "number", number, "err", err)
+			flush()
+			return
+		}
+		rawdb.WriteLogIndexEntries(batch, number, logs)
+		rawdb.WriteLogIndexHead(batch, number)
+		if tail == nil {
+			rawdb.WriteLogIndexTail(batch, number)
+			tail = &number
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	// Backfill the blocks accepted before the index was enabled.
+	first := *tail
+	for number := first; number > 0 && first-number < logIndexBackfillBlocks; number-- {
+		select {
+		case <-stop:
+			flush()
+			return
+		default:
+		}
+		logs, err := indexer.blockLogs(number - 1)
+		if err != nil {
+			// Blocks older than the state synced to are not available.
+			log.Warn("Stopped backfilling log index", "tail", number, "err", err)
+			indexer.incomplete.Store(true)
+			break
+		}
+		rawdb.WriteLogIndexEntries(batch, number-1, logs)
+		rawdb.WriteLogIndexTail(batch, number-1)
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			flush()
+		}
+	}
+	flush()
+}
+
+// blockLogs reads the logs of the transactions of the accepted block at the
+// given height.
+func (indexer *logIndexer) blockLogs(number uint64) ([][]*types.Log, error) {
+	hash := rawdb.ReadCanonicalHash(indexer.db, number)
+	if hash == (common.Hash{}) {
+		return nil, fmt.Errorf("accepted block %d not found", number)
+	}
+	return rawdb.ReadLogs(indexer.db, hash, number), nil
+}
+
+// backfilling returns whether blocks below the index tail remain to be
+// indexed.
+func (indexer *logIndexer) backfilling() bool {
+	tail := rawdb.ReadLogIndexTail(indexer.db)
+	return tail != nil && *tail > 0 && !indexer.incomplete.Load()
+}
+
+// loop is the scheduler of the indexer, indexing the accepted blocks in a
+// background task.
+func (indexer *logIndexer) loop() {
+	defer close(indexer.closed)
+	var (
+		stop     chan struct{} // Non-nil if background routine is active.
+		done     chan struct{} // Non-nil if background routine is active.
+		lastHead uint64        // The latest accepted block
+		runHead  uint64        // The head of the running task
+
+		headCh = make(chan ChainEvent)
+		sub    = indexer.chain.SubscribeChainAcceptedEvent(headCh)
+	)
+	if sub == nil {
+		log.Warn("could not create chain accepted subscription to index logs")
+		return
+	}
+	defer sub.Unsubscribe()
+
+	if indexer.rebuild {
+		log.Info("Dropping log index to rebuild it")
+		if err := rawdb.ClearLogIndex(indexer.db); err != nil {
+			log.Error("Failed to drop log index", "err", err)
+			return
+		}
+	}
+	launch := func(head uint64) {
+		stop = make(chan struct{})
+		done = make(chan struct{})
+		runHead = head
+		indexer.chain.wg.Add(1)
+		go func() {
+			defer indexer.chain.wg.Done()
+			indexer.run(head, stop, done)
+		}()
+	}
+	// Index the blocks accepted while the node was down.
+	lastHead = indexer.chain.LastAcceptedBlock().NumberU64()
+	launch(lastHead)
+	for {
+		select {
+		case head := <-headCh:
+			lastHead = head.Block.NumberU64()
+			if done == nil {
+				launch(lastHead)
+			}
+		case <-done:
+			stop = nil
+			done = nil
+			if lastHead > runHead || indexer.backfilling() {
+				launch(lastHead)
+			}
+		case ch := <-indexer.term:
+			if stop != nil {
+				close(stop)
+			}
+			if done != nil {
+				log.Info("Waiting background log indexer to exit")
+				<-done
+			}
+			close(ch)
+			return
+		}
+	}
+}
+
+// close shutdown the indexer. Safe to be called for multiple times.
+func (indexer *logIndexer) close() {
+	ch := make(chan struct{})
+	select {
+	case indexer.term <- ch:
+		<-ch
+	case <-indexer.closed:
+	}
+}
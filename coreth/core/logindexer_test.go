@@ -0,0 +1,95 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogIndexer(t *testing.T) {
+	require := require.New(t)
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		emitter = common.Address{0xaa}
+		topic   = common.BigToHash(big.NewInt(0xff))
+		funds   = big.NewInt(10000000000000)
+		gspec   = &Genesis{
+			Config: &params.ChainConfig{HomesteadBlock: new(big.Int)},
+			Alloc: types.GenesisAlloc{
+				addr: {Balance: funds},
+				// emitter logs an empty event with a single topic on every call.
+				emitter: {Code: []byte{byte(vm.PUSH1), 0xff, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.LOG1), byte(vm.STOP)}},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _, err := GenerateChainWithGenesis(gspec, dummy.NewFakerWithCallbacks(TestCallbacks), 12, 10, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), emitter, new(big.Int), 100000, nil, nil), signer, key)
+		require.NoError(err)
+		block.AddTx(tx)
+	})
+	require.NoError(err)
+
+	conf := &CacheConfig{
+		TrieCleanLimit:            256,
+		TrieDirtyLimit:            256,
+		TrieDirtyCommitTarget:     20,
+		TriePrefetcherParallelism: 4,
+		Pruning:                   true,
+		CommitInterval:            4096,
+		SnapshotLimit:             256,
+		SnapshotNoBuild:           true, // Ensure the test errors if snapshot initialization fails
+		AcceptorQueueLimit:        64,
+	}
+	waitIndex := func(chain *BlockChain, tail, head uint64) {
+		require.Eventually(func() bool {
+			t, h := rawdb.ReadLogIndexTail(chain.db), rawdb.ReadLogIndexHead(chain.db)
+			return t != nil && *t == tail && h != nil && *h == head
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+	insertAndAccept := func(chain *BlockChain, blocks []*types.Block) {
+		_, err := chain.InsertChain(blocks)
+		require.NoError(err)
+		for _, block := range blocks {
+			require.NoError(chain.Accept(block))
+		}
+		chain.DrainAcceptorQueue()
+	}
+
+	// The blocks accepted before the index is enabled are backfilled.
+	chainDB := rawdb.NewMemoryDatabase()
+	chain, err := createBlockChain(chainDB, conf, gspec, common.Hash{})
+	require.NoError(err)
+	insertAndAccept(chain, blocks[:6])
+	chain.StartLogIndexer(false)
+	insertAndAccept(chain, blocks[6:])
+	waitIndex(chain, 0, 12)
+	positions := rawdb.ReadAddressLogPositions(chainDB, emitter, 0, 12)
+	require.Len(positions, 12)
+	require.Equal(rawdb.LogPosition{Number: 1}, positions[0])
+	require.Equal(positions, rawdb.ReadTopicLogPositions(chainDB, 0, topic, 0, 12))
+	chain.Stop()
+
+	// A rebuild drops the index and indexes all the blocks again.
+	rawdb.WriteLogIndexEntries(chainDB, 3, [][]*types.Log{{{Address: common.Address{0xbb}}}})
+	chain, err = createBlockChain(chainDB, conf, gspec, blocks[11].Hash())
+	require.NoError(err)
+	chain.StartLogIndexer(true)
+	waitIndex(chain, 0, 12)
+	require.Empty(rawdb.ReadAddressLogPositions(chainDB, common.Address{0xbb}, 0, 12))
+	require.Len(rawdb.ReadAddressLogPositions(chainDB, emitter, 0, 12), 12)
+	chain.Stop()
+}
@@ -0,0 +1,177 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/state/pruner"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// onlinePruningTargetDelay is the number of blocks accepted after a session
+// begins before its target state is selected. The blocks processed before the
+// session began are accepted by then, so the states built on the target
+// state only reference trie nodes flushed after the session began.
+const onlinePruningTargetDelay = 256
+
+// onlinePruner is the module responsible for scheduling the online pruning
+// sessions. A session begins [interval] blocks after the previous one
+// completed, selects as its target the first state committed to disk at least
+// onlinePruningTargetDelay blocks later, then marks and sweeps in a background
+// task.
+type onlinePruner struct {
+	pruner   *pruner.OnlinePruner
+	interval uint64 // Blocks accepted between sessions
+	reset    chan chan struct{}
+	term     chan chan struct{}
+	closed   chan struct{}
+
+	chain *BlockChain
+}
+
+// newOnlinePruner starts the scheduler of the sessions of [p].
+func newOnlinePruner(p *pruner.OnlinePruner, interval uint64, chain *BlockChain) *onlinePruner {
+	scheduler := &onlinePruner{
+		pruner:   p,
+		interval: interval,
+		reset:    make(chan chan struct{}),
+		term:     make(chan chan struct{}),
+		closed:   make(chan struct{}),
+		chain:    chain,
+	}
+	chain.wg.Add(1)
+	go func() {
+		defer chain.wg.Done()
+		scheduler.loop()
+	}()
+	log.Info("Initialized online pruning", "interval", interval)
+
+	return scheduler
+}
+
+// loop is the scheduler of the sessions, running their tasks in the
+// background.
+func (p *onlinePruner) loop() {
+	defer close(p.closed)
+	var (
+		stop     chan struct{} // Non-nil if background routine is active.
+		done     chan error    // Non-nil if background routine is active.
+		lastHead uint64        // The latest accepted block
+		target   uint64        // The first block whose state may be the target, zero if not waiting
+		next     uint64        // The block at which the next session begins, zero if not scheduled
+		resume   bool          // Whether a marked session awaits its sweep
+
+		headCh = make(chan ChainEvent)
+		sub    = p.chain.SubscribeChainAcceptedEvent(headCh)
+	)
+	if sub == nil {
+		log.Warn("could not create chain accepted subscription to prune state")
+		return
+	}
+	defer sub.Unsubscribe()
+
+	launch := func(root common.Hash, mark bool) {
+		stop = make(chan struct{})
+		done = make(chan error, 1)
+		p.chain.wg.Add(1)
+		go func() {
+			defer p.chain.wg.Done()
+			if mark {
+				if err := p.pruner.Mark(root, stop); err != nil {
+					done <- err
+					return
+				}
+			}
+			done <- p.pruner.Sweep(stop)
+		}()
+	}
+	begin := func(head uint64) {
+		if err := p.pruner.Begin(); err != nil {
+			log.Error("Failed to begin online pruning session", "err", err)
+			next = head + p.interval
+			return
+		}
+		target = head + onlinePruningTargetDelay
+	}
+	halt := func() {
+		if stop != nil {
+			close(stop)
+			log.Info("Waiting background online pruning to exit")
+			<-done
+			stop, done = nil, nil
+		}
+	}
+	// A marked session is swept again once blocks are accepted, so that it
+	// does not run while the state is synced.
+	lastHead = p.chain.LastAcceptedBlock().NumberU64()
+	if p.pruner.Status().Phase == pruner.OnlinePruningSweeping {
+		resume = true
+	} else {
+		begin(lastHead)
+	}
+	for {
+		select {
+		case head := <-headCh:
+			lastHead = head.Block.NumberU64()
+			switch {
+			case done != nil:
+			case resume:
+				resume = false
+				launch(common.Hash{}, false)
+			case target != 0 && lastHead >= target && rawdb.HasLegacyTrieNode(p.chain.db, head.Block.Root()):
+				target = 0
+				launch(head.Block.Root(), true)
+			case target == 0 && next != 0 && lastHead >= next:
+				next = 0
+				begin(lastHead)
+			}
+		case err := <-done:
+			stop, done = nil, nil
+			if err != nil {
+				log.Error("Online pruning session failed", "err", err)
+				if err := p.pruner.Abort(); err != nil {
+					log.Error("Failed to drop online pruning session", "err", err)
+				}
+			}
+			next = lastHead + max(p.interval, 1)
+		case ch := <-p.reset:
+			halt()
+			if err := p.pruner.Abort(); err != nil {
+				log.Error("Failed to drop online pruning session", "err", err)
+			}
+			resume, next = false, 0
+			lastHead = p.chain.LastAcceptedBlock().NumberU64()
+			begin(lastHead)
+			close(ch)
+		case ch := <-p.term:
+			halt()
+			close(ch)
+			return
+		}
+	}
+}
+
+// abort drops the running session and begins a new one, as the trie nodes
+// written by state sync are not marked.
+func (p *onlinePruner) abort() {
+	ch := make(chan struct{})
+	select {
+	case p.reset <- ch:
+		<-ch
+	case <-p.closed:
+	}
+}
+
+// close shutdown the scheduler, stopping the running task. The task is
+// continued after a restart if the session was marked. Safe to be called for
+// multiple times.
+func (p *onlinePruner) close() {
+	ch := make(chan struct{})
+	select {
+	case p.term <- ch:
+		<-ch
+	case <-p.closed:
+	}
+}
@@ -0,0 +1,96 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnlinePruner(t *testing.T) {
+	require := require.New(t)
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		funds  = new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(100))
+		gspec  = &Genesis{
+			Config: &params.ChainConfig{HomesteadBlock: new(big.Int)},
+			Alloc:  types.GenesisAlloc{addr: {Balance: funds}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _, err := GenerateChainWithGenesis(gspec, dummy.NewFakerWithCallbacks(TestCallbacks), 300, 10, func(i int, block *BlockGen) {
+		to := common.BigToAddress(big.NewInt(int64(i + 1)))
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), to, big.NewInt(1), 21000, big.NewInt(225000000000), nil), signer, key)
+		require.NoError(err)
+		block.AddTx(tx)
+	})
+	require.NoError(err)
+
+	conf := &CacheConfig{
+		TrieCleanLimit:            256,
+		TrieDirtyLimit:            256,
+		TrieDirtyCommitTarget:     20,
+		TriePrefetcherParallelism: 4,
+		Pruning:                   true,
+		CommitInterval:            16,
+		SnapshotLimit:             256,
+		SnapshotNoBuild:           true, // Ensure the test errors if snapshot initialization fails
+		AcceptorQueueLimit:        64,
+	}
+	insertAndAccept := func(chain *BlockChain, blocks []*types.Block) {
+		_, err := chain.InsertChain(blocks)
+		require.NoError(err)
+		for _, block := range blocks {
+			require.NoError(chain.Accept(block))
+		}
+		chain.DrainAcceptorQueue()
+	}
+
+	// The states committed before online pruning is enabled are stale.
+	chainDB := rawdb.NewMemoryDatabase()
+	chain, err := createBlockChain(chainDB, conf, gspec, common.Hash{})
+	require.NoError(err)
+	insertAndAccept(chain, blocks[:32])
+	chain.Stop()
+	require.True(rawdb.HasLegacyTrieNode(chainDB, blocks[15].Root()))
+
+	// The session begins at block 32 and selects the state of block 288, the
+	// first one committed onlinePruningTargetDelay blocks later.
+	online := *conf
+	online.OnlinePruning = true
+	online.OnlinePruningDataDirectory = t.TempDir()
+	online.OnlinePruningBloomFilterSize = 256
+	online.OnlinePruningInterval = 1000
+	chain, err = createBlockChain(chainDB, &online, gspec, blocks[31].Hash())
+	require.NoError(err)
+	insertAndAccept(chain, blocks[32:])
+	require.Eventually(func() bool {
+		return chain.OnlinePruningStatus().Sessions == 1
+	}, 2*time.Minute, 10*time.Millisecond)
+	status := chain.OnlinePruningStatus()
+	require.Equal(blocks[287].Root(), status.Root)
+	require.NotZero(status.Deleted)
+	require.False(rawdb.HasLegacyTrieNode(chainDB, blocks[15].Root()))
+	chain.Stop()
+
+	// The retained states are complete, the chain is loaded again from them.
+	chain, err = createBlockChain(chainDB, conf, gspec, blocks[299].Hash())
+	require.NoError(err)
+	for _, block := range []*types.Block{blocks[287], blocks[299]} {
+		statedb, err := chain.StateAt(block.Root())
+		require.NoError(err)
+		require.Equal(uint64(block.NumberU64()), statedb.GetNonce(addr))
+	}
+	chain.Stop()
+}
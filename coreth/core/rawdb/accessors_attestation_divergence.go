@@ -0,0 +1,88 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// HasAttestationDivergences verifies the existence of attestation divergences
+// journaled for a block.
+func HasAttestationDivergences(db ethdb.Reader, hash common.Hash, number uint64) bool {
+	if has, err := db.Has(attestationDivergencesKey(number, hash)); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// ReadAttestationDivergences retrieves the attestation divergences of a block,
+// including their block metadata. It returns nil if none were journaled.
+func ReadAttestationDivergences(db ethdb.Reader, hash common.Hash, number uint64) types.AttestationDivergences {
+	data, _ := db.Get(attestationDivergencesKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	return decodeAttestationDivergences(data, hash, number)
+}
+
+// ReadAttestationDivergencesInRange retrieves the attestation divergences
+// journaled for blocks at certain heights, both canonical and reorged forks
+// included, in ascending block number order.
+// This method considers both limits to be _inclusive_.
+func ReadAttestationDivergencesInRange(db ethdb.Iteratee, first, last uint64) types.AttestationDivergences {
+	var (
+		keyLength   = len(attestationDivergencesPrefix) + 8 + common.HashLength
+		divergences types.AttestationDivergences
+		it          = db.NewIterator(attestationDivergencesPrefix, encodeBlockNumber(first))
+	)
+	defer it.Release()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != keyLength {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(attestationDivergencesPrefix) : len(attestationDivergencesPrefix)+8])
+		if number > last {
+			break
+		}
+		divergences = append(divergences, decodeAttestationDivergences(it.Value(), common.BytesToHash(key[len(key)-common.HashLength:]), number)...)
+	}
+	return divergences
+}
+
+func decodeAttestationDivergences(data []byte, hash common.Hash, number uint64) types.AttestationDivergences {
+	divergences := types.AttestationDivergences{}
+	if err := rlp.DecodeBytes(data, &divergences); err != nil {
+		log.Error("Invalid attestation divergence array RLP", "hash", hash, "err", err)
+		return nil
+	}
+	divergences.DeriveFields(hash, number)
+	return divergences
+}
+
+// WriteAttestationDivergences journals the attestation divergences of a block
+// into the database.
+func WriteAttestationDivergences(db ethdb.KeyValueWriter, hash common.Hash, number uint64, divergences types.AttestationDivergences) {
+	bytes, err := rlp.EncodeToBytes(divergences)
+	if err != nil {
+		log.Crit("Failed to encode block attestation divergences", "err", err)
+	}
+	if err := db.Put(attestationDivergencesKey(number, hash), bytes); err != nil {
+		log.Crit("Failed to store block attestation divergences", "err", err)
+	}
+}
+
+// DeleteAttestationDivergences removes all attestation divergence data
+// associated with a block hash.
+func DeleteAttestationDivergences(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(attestationDivergencesKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block attestation divergences", "err", err)
+	}
+}
@@ -0,0 +1,67 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests attestation divergence storage and retrieval operations.
+func TestAttestationDivergenceStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	hash, number := common.Hash{0x01}, uint64(7)
+	divergences := types.AttestationDivergences{
+		{
+			TxHash:                 common.Hash{0x11},
+			TxIndex:                2,
+			Round:                  big.NewInt(1200),
+			DefaultReachedMajority: true,
+			DefaultDecision:        "0x01",
+			Finalised:              true,
+		},
+	}
+	if ds := ReadAttestationDivergences(db, hash, number); ds != nil {
+		t.Fatalf("non existent attestation divergences returned: %v", ds)
+	}
+	if HasAttestationDivergences(db, hash, number) {
+		t.Fatal("attestation divergences reported as present in pristine database")
+	}
+	WriteAttestationDivergences(db, hash, number, divergences)
+	if !HasAttestationDivergences(db, hash, number) {
+		t.Fatal("attestation divergences not found after write")
+	}
+	ds := ReadAttestationDivergences(db, hash, number)
+	if len(ds) != len(divergences) {
+		t.Fatalf("attestation divergence count mismatch: have %d, want %d", len(ds), len(divergences))
+	}
+	want := *divergences[0]
+	want.BlockHash, want.BlockNumber = hash, number
+	if !reflect.DeepEqual(*ds[0], want) {
+		t.Fatalf("attestation divergence mismatch: have %+v, want %+v", ds[0], want)
+	}
+	// Journal a divergence on a later block and check range retrieval
+	WriteAttestationDivergences(db, common.Hash{0x02}, 9, types.AttestationDivergences{
+		{TxHash: common.Hash{0x22}, Round: big.NewInt(1201), LocalReachedMajority: true, LocalDecision: "0x02"},
+	})
+	if ds := ReadAttestationDivergencesInRange(db, 0, 8); len(ds) != 1 || ds[0].BlockNumber != 7 {
+		t.Fatalf("attestation divergence range mismatch: %v", ds)
+	}
+	if ds := ReadAttestationDivergencesInRange(db, 7, 9); len(ds) != 2 || ds[1].BlockHash != (common.Hash{0x02}) {
+		t.Fatalf("attestation divergence range mismatch: %v", ds)
+	}
+	if ds := ReadAttestationDivergencesInRange(db, 10, 20); len(ds) != 0 {
+		t.Fatalf("attestation divergences returned out of range: %v", ds)
+	}
+	// Delete the attestation divergences and check purge
+	DeleteBlock(db, hash, number)
+	if ds := ReadAttestationDivergences(db, hash, number); ds != nil {
+		t.Fatalf("deleted attestation divergences returned: %v", ds)
+	}
+}
@@ -0,0 +1,37 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ReadChainImportProgress retrieves the hash of the manifest of the chain
+// archive being imported and the index of the next chunk to import from it.
+func ReadChainImportProgress(db ethdb.KeyValueReader) (common.Hash, uint64, bool) {
+	data, _ := db.Get(chainImportProgressKey)
+	if len(data) != common.HashLength+8 {
+		return common.Hash{}, 0, false
+	}
+	return common.BytesToHash(data[:common.HashLength]), binary.BigEndian.Uint64(data[common.HashLength:]), true
+}
+
+// WriteChainImportProgress stores the hash of the manifest of the chain archive
+// being imported and the index of the next chunk to import from it.
+func WriteChainImportProgress(db ethdb.KeyValueWriter, manifest common.Hash, chunk uint64) {
+	if err := db.Put(chainImportProgressKey, append(manifest.Bytes(), encodeBlockNumber(chunk)...)); err != nil {
+		log.Crit("Failed to store chain import progress", "err", err)
+	}
+}
+
+// DeleteChainImportProgress removes the progress of the chain archive import.
+func DeleteChainImportProgress(db ethdb.KeyValueWriter) {
+	if err := db.Delete(chainImportProgressKey); err != nil {
+		log.Crit("Failed to delete chain import progress", "err", err)
+	}
+}
@@ -0,0 +1,55 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// HasDaemonResults verifies the existence of the daemon results belonging to
+// a block.
+func HasDaemonResults(db ethdb.Reader, hash common.Hash, number uint64) bool {
+	if has, err := db.Has(daemonResultsKey(number, hash)); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// ReadDaemonResults retrieves the daemon results of a block, including their
+// block metadata. It returns nil if no results were recorded for the block.
+func ReadDaemonResults(db ethdb.Reader, hash common.Hash, number uint64) types.DaemonResults {
+	data, _ := db.Get(daemonResultsKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	results := types.DaemonResults{}
+	if err := rlp.DecodeBytes(data, &results); err != nil {
+		log.Error("Invalid daemon result array RLP", "hash", hash, "err", err)
+		return nil
+	}
+	results.DeriveFields(hash, number)
+	return results
+}
+
+// WriteDaemonResults stores the daemon results of a block into the database.
+func WriteDaemonResults(db ethdb.KeyValueWriter, hash common.Hash, number uint64, results types.DaemonResults) {
+	bytes, err := rlp.EncodeToBytes(results)
+	if err != nil {
+		log.Crit("Failed to encode block daemon results", "err", err)
+	}
+	if err := db.Put(daemonResultsKey(number, hash), bytes); err != nil {
+		log.Crit("Failed to store block daemon results", "err", err)
+	}
+}
+
+// DeleteDaemonResults removes all daemon result data associated with a block hash.
+func DeleteDaemonResults(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(daemonResultsKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block daemon results", "err", err)
+	}
+}
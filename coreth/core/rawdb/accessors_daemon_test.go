@@ -0,0 +1,69 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests daemon result storage and retrieval operations.
+func TestDaemonResultStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	hash, number := common.Hash{0x01}, uint64(7)
+	results := types.DaemonResults{
+		{
+			TxHash:      common.Hash{0x11},
+			TxIndex:     0,
+			MintRequest: big.NewInt(1000),
+			Minted:      big.NewInt(1000),
+			GasUsed:     52000,
+		},
+		{
+			TxHash:      common.Hash{0x22},
+			TxIndex:     2,
+			MintRequest: big.NewInt(5000),
+			Minted:      new(big.Int),
+			GasUsed:     61000,
+			Reverted:    true,
+			Error:       "mint request of 5000 exceeded max of 1000",
+		},
+	}
+	if rs := ReadDaemonResults(db, hash, number); rs != nil {
+		t.Fatalf("non existent daemon results returned: %v", rs)
+	}
+	if HasDaemonResults(db, hash, number) {
+		t.Fatal("daemon results reported as present in pristine database")
+	}
+	WriteDaemonResults(db, hash, number, results)
+	if !HasDaemonResults(db, hash, number) {
+		t.Fatal("daemon results not found after write")
+	}
+	rs := ReadDaemonResults(db, hash, number)
+	if len(rs) != len(results) {
+		t.Fatalf("daemon result count mismatch: have %d, want %d", len(rs), len(results))
+	}
+	for i, result := range rs {
+		want := *results[i]
+		want.BlockHash, want.BlockNumber = hash, number
+		if !reflect.DeepEqual(*result, want) {
+			t.Fatalf("daemon result %d mismatch: have %+v, want %+v", i, result, want)
+		}
+	}
+	// Blocks without daemon calls are recorded as an empty list
+	WriteDaemonResults(db, common.Hash{0x02}, number, nil)
+	if rs := ReadDaemonResults(db, common.Hash{0x02}, number); rs == nil || len(rs) != 0 {
+		t.Fatalf("empty daemon results mismatch: %v", rs)
+	}
+	// Delete the results and check purge
+	DeleteBlock(db, hash, number)
+	if rs := ReadDaemonResults(db, hash, number); rs != nil {
+		t.Fatalf("deleted daemon results returned: %v", rs)
+	}
+}
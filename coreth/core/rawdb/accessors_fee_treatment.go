@@ -0,0 +1,55 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// HasFeeTreatments verifies the existence of the fee treatments belonging to
+// a block.
+func HasFeeTreatments(db ethdb.Reader, hash common.Hash, number uint64) bool {
+	if has, err := db.Has(feeTreatmentsKey(number, hash)); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// ReadFeeTreatments retrieves the fee treatments of a block, including their
+// block metadata. It returns nil if no fee treatments were recorded for the block.
+func ReadFeeTreatments(db ethdb.Reader, hash common.Hash, number uint64) types.FeeTreatments {
+	data, _ := db.Get(feeTreatmentsKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	treatments := types.FeeTreatments{}
+	if err := rlp.DecodeBytes(data, &treatments); err != nil {
+		log.Error("Invalid fee treatment array RLP", "hash", hash, "err", err)
+		return nil
+	}
+	treatments.DeriveFields(hash, number)
+	return treatments
+}
+
+// WriteFeeTreatments stores the fee treatments of a block into the database.
+func WriteFeeTreatments(db ethdb.KeyValueWriter, hash common.Hash, number uint64, treatments types.FeeTreatments) {
+	bytes, err := rlp.EncodeToBytes(treatments)
+	if err != nil {
+		log.Crit("Failed to encode block fee treatments", "err", err)
+	}
+	if err := db.Put(feeTreatmentsKey(number, hash), bytes); err != nil {
+		log.Crit("Failed to store block fee treatments", "err", err)
+	}
+}
+
+// DeleteFeeTreatments removes all fee treatment data associated with a block hash.
+func DeleteFeeTreatments(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(feeTreatmentsKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block fee treatments", "err", err)
+	}
+}
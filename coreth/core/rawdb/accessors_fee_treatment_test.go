@@ -0,0 +1,75 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests fee treatment storage and retrieval operations.
+func TestFeeTreatmentStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	hash, number := common.Hash{0x01}, uint64(7)
+	treatments := types.FeeTreatments{
+		{
+			TxHash:      common.Hash{0x11},
+			TxIndex:     0,
+			Prioritised: true,
+			Rule:        "submitter",
+			GasUsed:     150000,
+			GasPrice:    big.NewInt(25_000_000_000),
+			Fee:         big.NewInt(3_750_000_000_000_000),
+			NominalFee:  big.NewInt(525_000_000_000_000),
+			Refund:      big.NewInt(3_225_000_000_000_000),
+		},
+		{
+			TxHash:     common.Hash{0x22},
+			TxIndex:    1,
+			Rule:       "not-prioritised-contract",
+			GasUsed:    21000,
+			GasPrice:   big.NewInt(25_000_000_000),
+			Fee:        big.NewInt(525_000_000_000_000),
+			NominalFee: big.NewInt(525_000_000_000_000),
+			Refund:     new(big.Int),
+		},
+	}
+	if ts := ReadFeeTreatments(db, hash, number); ts != nil {
+		t.Fatalf("non existent fee treatments returned: %v", ts)
+	}
+	if HasFeeTreatments(db, hash, number) {
+		t.Fatal("fee treatments reported as present in pristine database")
+	}
+	WriteFeeTreatments(db, hash, number, treatments)
+	if !HasFeeTreatments(db, hash, number) {
+		t.Fatal("fee treatments not found after write")
+	}
+	ts := ReadFeeTreatments(db, hash, number)
+	if len(ts) != len(treatments) {
+		t.Fatalf("fee treatment count mismatch: have %d, want %d", len(ts), len(treatments))
+	}
+	for i, treatment := range ts {
+		want := *treatments[i]
+		want.BlockHash, want.BlockNumber = hash, number
+		if !reflect.DeepEqual(*treatment, want) {
+			t.Fatalf("fee treatment %d mismatch: have %+v, want %+v", i, treatment, want)
+		}
+	}
+	if treatment := ts.ByTxIndex(1); treatment == nil || treatment.TxHash != (common.Hash{0x22}) {
+		t.Fatalf("fee treatment by index mismatch: %v", treatment)
+	}
+	if treatment := ts.ByTxIndex(2); treatment != nil {
+		t.Fatalf("fee treatment of unknown index returned: %v", treatment)
+	}
+	// Delete the fee treatments and check purge
+	DeleteBlock(db, hash, number)
+	if ts := ReadFeeTreatments(db, hash, number); ts != nil {
+		t.Fatalf("deleted fee treatments returned: %v", ts)
+	}
+}
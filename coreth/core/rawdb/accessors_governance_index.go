@@ -0,0 +1,93 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ReadGovernanceIndexTail retrieves the number of the oldest accepted block
+// whose governance changes have been indexed.
+func ReadGovernanceIndexTail(db ethdb.KeyValueReader) *uint64 {
+	return readIndexMarker(db, governanceIndexTailKey)
+}
+
+// ReadGovernanceIndexHead retrieves the number of the latest accepted block
+// whose governance changes have been indexed.
+func ReadGovernanceIndexHead(db ethdb.KeyValueReader) *uint64 {
+	return readIndexMarker(db, governanceIndexHeadKey)
+}
+
+func readIndexMarker(db ethdb.KeyValueReader, key []byte) *uint64 {
+	data, _ := db.Get(key)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteGovernanceIndexTail stores the number of the oldest accepted block whose
+// governance changes have been indexed.
+func WriteGovernanceIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(governanceIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the governance index tail", "err", err)
+	}
+}
+
+// WriteGovernanceIndexHead stores the number of the latest accepted block whose
+// governance changes have been indexed.
+func WriteGovernanceIndexHead(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(governanceIndexHeadKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the governance index head", "err", err)
+	}
+}
+
+// WriteGovernanceChanges indexes the governance changes of the accepted block
+// at the given height.
+func WriteGovernanceChanges(db ethdb.KeyValueWriter, number uint64, changes types.GovernanceChanges) {
+	bytes, err := rlp.EncodeToBytes(changes)
+	if err != nil {
+		log.Crit("Failed to encode governance changes", "err", err)
+	}
+	if err := db.Put(governanceChangesKey(number), bytes); err != nil {
+		log.Crit("Failed to store governance changes", "err", err)
+	}
+}
+
+// ReadGovernanceChangesInRange retrieves the indexed governance changes of the
+// accepted blocks at certain heights, in ascending block number order. The
+// block hashes of the changes are filled in from the canonical chain.
+// This method considers both limits to be _inclusive_.
+func ReadGovernanceChangesInRange(db ethdb.Database, first, last uint64) types.GovernanceChanges {
+	var (
+		keyLength = len(governanceChangesPrefix) + 8
+		changes   = types.GovernanceChanges{}
+		it        = db.NewIterator(governanceChangesPrefix, encodeBlockNumber(first))
+	)
+	defer it.Release()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != keyLength {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(governanceChangesPrefix):])
+		if number > last {
+			break
+		}
+		var block types.GovernanceChanges
+		if err := rlp.DecodeBytes(it.Value(), &block); err != nil {
+			log.Error("Invalid governance change array RLP", "number", number, "err", err)
+			continue
+		}
+		block.DeriveFields(ReadCanonicalHash(db, number), number)
+		changes = append(changes, block...)
+	}
+	return changes
+}
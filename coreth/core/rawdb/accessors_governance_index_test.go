@@ -0,0 +1,55 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests governance change index storage and retrieval operations.
+func TestGovernanceChangeIndex(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if tail, head := ReadGovernanceIndexTail(db), ReadGovernanceIndexHead(db); tail != nil || head != nil {
+		t.Fatalf("governance index markers returned from pristine database: %v %v", tail, head)
+	}
+	WriteGovernanceIndexTail(db, 5)
+	WriteGovernanceIndexHead(db, 12)
+	if tail, head := ReadGovernanceIndexTail(db), ReadGovernanceIndexHead(db); tail == nil || *tail != 5 || head == nil || *head != 12 {
+		t.Fatalf("governance index markers mismatch: %v %v", tail, head)
+	}
+
+	WriteCanonicalHash(db, common.Hash{0x07}, 7)
+	WriteGovernanceChanges(db, 7, types.GovernanceChanges{
+		{
+			TxHash:   common.Hash{0x11},
+			TxIndex:  1,
+			Kind:     types.GovernanceTimelockChange,
+			Contract: common.Address{0x01},
+			OldValue: []byte{0, 0, 0, 0, 0, 0, 0x0e, 0x10},
+			NewValue: []byte{0, 0, 0, 0, 0, 0, 0x1c, 0x20},
+			Amount:   new(big.Int),
+		},
+	})
+	WriteGovernanceChanges(db, 10, types.GovernanceChanges{
+		{TxHash: common.Hash{0x22}, Kind: types.DistributionChange, Amount: big.NewInt(100)},
+	})
+	changes := ReadGovernanceChangesInRange(db, 5, 9)
+	if len(changes) != 1 {
+		t.Fatalf("governance change count mismatch: have %d, want 1", len(changes))
+	}
+	if change := changes[0]; change.BlockNumber != 7 || change.BlockHash != (common.Hash{0x07}) || change.TxHash != (common.Hash{0x11}) || len(change.NewValue) != 8 {
+		t.Fatalf("governance change mismatch: %+v", change)
+	}
+	if changes := ReadGovernanceChangesInRange(db, 7, 10); len(changes) != 2 || changes[1].Amount.Int64() != 100 {
+		t.Fatalf("governance change range mismatch: %v", changes)
+	}
+	if changes := ReadGovernanceChangesInRange(db, 11, 12); len(changes) != 0 {
+		t.Fatalf("governance changes returned out of range: %v", changes)
+	}
+}
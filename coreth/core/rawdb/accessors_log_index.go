@@ -0,0 +1,158 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// LogField is the field of a log an index entry is found by.
+type LogField byte
+
+const (
+	LogFieldAddress LogField = iota // address of the emitting contract
+	LogFieldTopic0                  // topic at position 0, usually the event signature
+	LogFieldTopic1
+	LogFieldTopic2
+	LogFieldTopic3
+)
+
+// LogIndexTopics is the number of topic positions indexed.
+const LogIndexTopics = 4
+
+// LogTopicField returns the field of the topic at position [i], which must be
+// below LogIndexTopics.
+func LogTopicField(i int) LogField {
+	return LogFieldTopic0 + LogField(i)
+}
+
+var logIndexKeyLength = len(logIndexPrefix) + 1 + common.HashLength + 8 + 4 + 4
+
+// LogPosition locates an indexed log: the log at LogIndex in the logs of the
+// block, which is emitted by the transaction at TxIndex.
+type LogPosition struct {
+	Number   uint64
+	TxIndex  uint32
+	LogIndex uint32
+}
+
+// ReadLogIndexTail retrieves the number of the oldest accepted block whose
+// logs have been indexed.
+func ReadLogIndexTail(db ethdb.KeyValueReader) *uint64 {
+	return readIndexMarker(db, logIndexTailKey)
+}
+
+// ReadLogIndexHead retrieves the number of the latest accepted block whose
+// logs have been indexed.
+func ReadLogIndexHead(db ethdb.KeyValueReader) *uint64 {
+	return readIndexMarker(db, logIndexHeadKey)
+}
+
+// WriteLogIndexTail stores the number of the oldest accepted block whose logs
+// have been indexed.
+func WriteLogIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(logIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the log index tail", "err", err)
+	}
+}
+
+// WriteLogIndexHead stores the number of the latest accepted block whose logs
+// have been indexed.
+func WriteLogIndexHead(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(logIndexHeadKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the log index head", "err", err)
+	}
+}
+
+// WriteLogIndexEntries indexes the [logs] of the transactions of the accepted
+// block at the given height by their address and by each of their topics.
+func WriteLogIndexEntries(db ethdb.KeyValueWriter, number uint64, logs [][]*types.Log) {
+	forEachLogIndexKey(number, logs, func(key []byte) {
+		if err := db.Put(key, nil); err != nil {
+			log.Crit("Failed to store log index entry", "err", err)
+		}
+	})
+}
+
+// DeleteLogIndexEntries removes the index entries of the [logs] of the
+// transactions of the accepted block at the given height.
+func DeleteLogIndexEntries(db ethdb.KeyValueWriter, number uint64, logs [][]*types.Log) {
+	forEachLogIndexKey(number, logs, func(key []byte) {
+		if err := db.Delete(key); err != nil {
+			log.Crit("Failed to delete log index entry", "err", err)
+		}
+	})
+}
+
+// ClearLogIndex removes all the log index entries and markers.
+func ClearLogIndex(db ethdb.KeyValueStore) error {
+	if err := db.Delete(logIndexTailKey); err != nil {
+		return err
+	}
+	if err := db.Delete(logIndexHeadKey); err != nil {
+		return err
+	}
+	return ClearPrefix(db, logIndexPrefix, logIndexKeyLength)
+}
+
+func forEachLogIndexKey(number uint64, logs [][]*types.Log, fn func(key []byte)) {
+	var index uint32
+	for txIndex, txLogs := range logs {
+		for _, l := range txLogs {
+			pos := LogPosition{Number: number, TxIndex: uint32(txIndex), LogIndex: index}
+			fn(logIndexKey(LogFieldAddress, common.BytesToHash(l.Address.Bytes()), pos))
+			for i, topic := range l.Topics {
+				if i >= LogIndexTopics {
+					break
+				}
+				fn(logIndexKey(LogTopicField(i), topic, pos))
+			}
+			index++
+		}
+	}
+}
+
+// ReadAddressLogPositions retrieves the positions of the indexed logs emitted
+// by [addr], in ascending order.
+// This method considers both limits to be _inclusive_.
+func ReadAddressLogPositions(db ethdb.Iteratee, addr common.Address, first, last uint64) []LogPosition {
+	return readLogPositions(db, LogFieldAddress, common.BytesToHash(addr.Bytes()), first, last)
+}
+
+// ReadTopicLogPositions retrieves the positions of the indexed logs having
+// [topic] at the given position, in ascending order.
+// This method considers both limits to be _inclusive_.
+func ReadTopicLogPositions(db ethdb.Iteratee, position int, topic common.Hash, first, last uint64) []LogPosition {
+	return readLogPositions(db, LogTopicField(position), topic, first, last)
+}
+
+func readLogPositions(db ethdb.Iteratee, field LogField, value common.Hash, first, last uint64) []LogPosition {
+	var (
+		prefix    = append(append(append([]byte{}, logIndexPrefix...), byte(field)), value.Bytes()...)
+		positions []LogPosition
+		it        = db.NewIterator(prefix, encodeBlockNumber(first))
+	)
+	defer it.Release()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != logIndexKeyLength {
+			continue
+		}
+		pos := LogPosition{
+			Number:   binary.BigEndian.Uint64(key[len(prefix):]),
+			TxIndex:  binary.BigEndian.Uint32(key[len(prefix)+8:]),
+			LogIndex: binary.BigEndian.Uint32(key[len(prefix)+12:]),
+		}
+		if pos.Number > last {
+			break
+		}
+		positions = append(positions, pos)
+	}
+	return positions
+}
@@ -0,0 +1,82 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests log index storage, retrieval and deletion operations.
+func TestLogIndex(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if tail, head := ReadLogIndexTail(db), ReadLogIndexHead(db); tail != nil || head != nil {
+		t.Fatalf("log index markers returned from pristine database: %v %v", tail, head)
+	}
+	WriteLogIndexTail(db, 2)
+	WriteLogIndexHead(db, 8)
+	if tail, head := ReadLogIndexTail(db), ReadLogIndexHead(db); tail == nil || *tail != 2 || head == nil || *head != 8 {
+		t.Fatalf("log index markers mismatch: %v %v", tail, head)
+	}
+
+	var (
+		token    = common.Address{0xaa}
+		registry = common.Address{0xbb}
+		transfer = common.Hash{0x01}
+		approval = common.Hash{0x02}
+		alice    = common.Hash{0xa1}
+	)
+	block3 := [][]*types.Log{
+		{{Address: token, Topics: []common.Hash{transfer, alice}}},
+		{},
+		{{Address: registry}, {Address: token, Topics: []common.Hash{approval, alice}}},
+	}
+	block5 := [][]*types.Log{
+		{{Address: token, Topics: []common.Hash{transfer, {0xa2}, alice}}},
+	}
+	WriteLogIndexEntries(db, 3, block3)
+	WriteLogIndexEntries(db, 5, block5)
+
+	want := []LogPosition{{Number: 3, TxIndex: 0, LogIndex: 0}, {Number: 3, TxIndex: 2, LogIndex: 2}, {Number: 5, TxIndex: 0, LogIndex: 0}}
+	if positions := ReadAddressLogPositions(db, token, 0, 8); !reflect.DeepEqual(positions, want) {
+		t.Fatalf("address positions mismatch: have %v, want %v", positions, want)
+	}
+	want = []LogPosition{{Number: 3, TxIndex: 2, LogIndex: 1}}
+	if positions := ReadAddressLogPositions(db, registry, 0, 8); !reflect.DeepEqual(positions, want) {
+		t.Fatalf("address positions mismatch: have %v, want %v", positions, want)
+	}
+	want = []LogPosition{{Number: 3, TxIndex: 0, LogIndex: 0}, {Number: 3, TxIndex: 2, LogIndex: 2}}
+	if positions := ReadTopicLogPositions(db, 1, alice, 0, 8); !reflect.DeepEqual(positions, want) {
+		t.Fatalf("topic positions mismatch: have %v, want %v", positions, want)
+	}
+	want = []LogPosition{{Number: 5, TxIndex: 0, LogIndex: 0}}
+	if positions := ReadTopicLogPositions(db, 2, alice, 0, 8); !reflect.DeepEqual(positions, want) {
+		t.Fatalf("topic positions mismatch: have %v, want %v", positions, want)
+	}
+	if positions := ReadTopicLogPositions(db, 0, transfer, 4, 8); len(positions) != 1 || positions[0].Number != 5 {
+		t.Fatalf("topic range mismatch: %v", positions)
+	}
+
+	DeleteLogIndexEntries(db, 3, block3)
+	if positions := ReadAddressLogPositions(db, token, 0, 8); len(positions) != 1 || positions[0].Number != 5 {
+		t.Fatalf("positions of other block deleted: %v", positions)
+	}
+	if positions := ReadAddressLogPositions(db, registry, 0, 8); len(positions) != 0 {
+		t.Fatalf("address positions returned after deletion: %v", positions)
+	}
+
+	if err := ClearLogIndex(db); err != nil {
+		t.Fatal(err)
+	}
+	if tail, head := ReadLogIndexTail(db), ReadLogIndexHead(db); tail != nil || head != nil {
+		t.Fatalf("log index markers returned after clearing: %v %v", tail, head)
+	}
+	if positions := ReadTopicLogPositions(db, 0, transfer, 0, 8); len(positions) != 0 {
+		t.Fatalf("topic positions returned after clearing: %v", positions)
+	}
+}
@@ -0,0 +1,62 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var onlinePruningMarkKeyLength = len(onlinePruningMarkPrefix) + common.HashLength
+
+// WriteOnlinePruningMark marks the trie node with the given hash as written to
+// disk during the running online pruning session, so that it is kept by the
+// session after a restart.
+func WriteOnlinePruningMark(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Put(onlinePruningMarkKey(hash), nil); err != nil {
+		log.Crit("Failed to store online pruning mark", "err", err)
+	}
+}
+
+// IterateOnlinePruningMarks calls [fn] with the hash of every trie node marked
+// during the running online pruning session.
+func IterateOnlinePruningMarks(db ethdb.Iteratee, fn func(hash common.Hash)) error {
+	it := db.NewIterator(onlinePruningMarkPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		if key := it.Key(); len(key) == onlinePruningMarkKeyLength {
+			fn(common.BytesToHash(key[len(onlinePruningMarkPrefix):]))
+		}
+	}
+	return it.Error()
+}
+
+// ReadOnlinePruningProgress retrieves the last key swept by the running online
+// pruning session, nil if the sweep has not started.
+func ReadOnlinePruningProgress(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(onlinePruningProgressKey)
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+// WriteOnlinePruningProgress stores the last key swept by the running online
+// pruning session.
+func WriteOnlinePruningProgress(db ethdb.KeyValueWriter, key []byte) {
+	if err := db.Put(onlinePruningProgressKey, key); err != nil {
+		log.Crit("Failed to store online pruning progress", "err", err)
+	}
+}
+
+// ClearOnlinePruning removes the marks and the progress of the online pruning
+// session.
+func ClearOnlinePruning(db ethdb.KeyValueStore) error {
+	if err := db.Delete(onlinePruningProgressKey); err != nil {
+		return err
+	}
+	return ClearPrefix(db, onlinePruningMarkPrefix, onlinePruningMarkKeyLength)
+}
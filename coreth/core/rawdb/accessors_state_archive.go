@@ -0,0 +1,240 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	stateArchiveAccountKeyLength  = len(stateArchiveAccountPrefix) + common.HashLength + 8
+	stateArchiveStorageKeyLength  = len(stateArchiveStoragePrefix) + 2*common.HashLength + 8
+	stateArchiveDestructKeyLength = len(stateArchiveDestructPrefix) + common.HashLength + 8
+	stateArchiveDiffKeyLength     = len(stateArchiveDiffPrefix) + 8 + common.HashLength
+)
+
+// StateArchiveDiff is the change of the state by a block, as kept in the state
+// archive: the destructed accounts, whose storage is wiped, and the new values
+// of the mutated accounts in 'slim RLP' encoding and of the mutated slots in
+// prefix-zero trimmed rlp format, empty if deleted.
+type StateArchiveDiff struct {
+	Destructs []common.Hash
+	Accounts  []StateArchiveAccount
+	Storages  []StateArchiveStorage
+}
+
+// StateArchiveAccount is the new value of a mutated account.
+type StateArchiveAccount struct {
+	Hash common.Hash
+	Blob []byte
+}
+
+// StateArchiveStorage is the new values of the mutated slots of an account.
+type StateArchiveStorage struct {
+	Account common.Hash
+	Hashes  []common.Hash
+	Values  [][]byte
+}
+
+// ReadStateArchiveTail retrieves the number of the oldest accepted block whose
+// state can be read from the state archive.
+func ReadStateArchiveTail(db ethdb.KeyValueReader) *uint64 {
+	return readIndexMarker(db, stateArchiveTailKey)
+}
+
+// ReadStateArchiveHead retrieves the number of the latest accepted block whose
+// state can be read from the state archive.
+func ReadStateArchiveHead(db ethdb.KeyValueReader) *uint64 {
+	return readIndexMarker(db, stateArchiveHeadKey)
+}
+
+// WriteStateArchiveTail stores the number of the oldest accepted block whose
+// state can be read from the state archive.
+func WriteStateArchiveTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(stateArchiveTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the state archive tail", "err", err)
+	}
+}
+
+// WriteStateArchiveHead stores the number of the latest accepted block whose
+// state can be read from the state archive.
+func WriteStateArchiveHead(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(stateArchiveHeadKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the state archive head", "err", err)
+	}
+}
+
+// ReadStateArchiveBaseProgress retrieves the number of the block whose state is
+// being copied into the state archive, and the hash of the last account copied.
+func ReadStateArchiveBaseProgress(db ethdb.KeyValueReader) (uint64, []byte, bool) {
+	data, _ := db.Get(stateArchiveBaseProgressKey)
+	if len(data) < 8 {
+		return 0, nil, false
+	}
+	return binary.BigEndian.Uint64(data), data[8:], true
+}
+
+// WriteStateArchiveBaseProgress stores the number of the block whose state is
+// being copied into the state archive, and the hash of the last account copied.
+func WriteStateArchiveBaseProgress(db ethdb.KeyValueWriter, number uint64, marker []byte) {
+	if err := db.Put(stateArchiveBaseProgressKey, append(encodeBlockNumber(number), marker...)); err != nil {
+		log.Crit("Failed to store the state archive base progress", "err", err)
+	}
+}
+
+// DeleteStateArchiveBaseProgress removes the progress of the copy of the state
+// the archive starts from.
+func DeleteStateArchiveBaseProgress(db ethdb.KeyValueWriter) {
+	if err := db.Delete(stateArchiveBaseProgressKey); err != nil {
+		log.Crit("Failed to delete the state archive base progress", "err", err)
+	}
+}
+
+// ReadStateArchiveDiff retrieves the state diff of the block, which is kept
+// until the block is archived.
+func ReadStateArchiveDiff(db ethdb.KeyValueReader, hash common.Hash, number uint64) *StateArchiveDiff {
+	data, _ := db.Get(stateArchiveDiffKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	diff := new(StateArchiveDiff)
+	if err := rlp.DecodeBytes(data, diff); err != nil {
+		log.Error("Invalid state archive diff RLP", "hash", hash, "number", number, "err", err)
+		return nil
+	}
+	return diff
+}
+
+// ReadStateArchiveDiffHashes retrieves the hashes of the blocks at the given
+// height whose state diffs are kept.
+func ReadStateArchiveDiffHashes(db ethdb.Iteratee, number uint64) []common.Hash {
+	prefix := append(append([]byte{}, stateArchiveDiffPrefix...), encodeBlockNumber(number)...)
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var hashes []common.Hash
+	for it.Next() {
+		if key := it.Key(); len(key) == stateArchiveDiffKeyLength {
+			hashes = append(hashes, common.BytesToHash(key[len(prefix):]))
+		}
+	}
+	return hashes
+}
+
+// WriteStateArchiveDiff stores the state diff of the block until it is
+// archived.
+func WriteStateArchiveDiff(db ethdb.KeyValueWriter, hash common.Hash, number uint64, diff *StateArchiveDiff) {
+	data, err := rlp.EncodeToBytes(diff)
+	if err != nil {
+		log.Crit("Failed to RLP encode state archive diff", "err", err)
+	}
+	if err := db.Put(stateArchiveDiffKey(number, hash), data); err != nil {
+		log.Crit("Failed to store state archive diff", "err", err)
+	}
+}
+
+// DeleteStateArchiveDiff removes the state diff of the block.
+func DeleteStateArchiveDiff(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(stateArchiveDiffKey(number, hash)); err != nil {
+		log.Crit("Failed to delete state archive diff", "err", err)
+	}
+}
+
+// WriteStateArchiveEntries archives the state diff of the accepted block at the
+// given height.
+func WriteStateArchiveEntries(db ethdb.KeyValueWriter, number uint64, diff *StateArchiveDiff) {
+	// The destructed accounts are deleted, unless they are resurrected in the
+	// same block in which case the entry is overwritten below.
+	for _, hash := range diff.Destructs {
+		if err := db.Put(stateArchiveDestructKey(hash, number), nil); err != nil {
+			log.Crit("Failed to store state archive destruct", "err", err)
+		}
+		WriteStateArchiveAccount(db, hash, number, nil)
+	}
+	for _, account := range diff.Accounts {
+		WriteStateArchiveAccount(db, account.Hash, number, account.Blob)
+	}
+	for _, storage := range diff.Storages {
+		for i, hash := range storage.Hashes {
+			WriteStateArchiveStorage(db, storage.Account, hash, number, storage.Values[i])
+		}
+	}
+}
+
+// WriteStateArchiveAccount archives the account in 'slim RLP' encoding written
+// at the given height, empty if deleted.
+func WriteStateArchiveAccount(db ethdb.KeyValueWriter, accountHash common.Hash, number uint64, blob []byte) {
+	if err := db.Put(stateArchiveAccountKey(accountHash, number), blob); err != nil {
+		log.Crit("Failed to store state archive account", "err", err)
+	}
+}
+
+// WriteStateArchiveStorage archives the slot of the account in prefix-zero
+// trimmed rlp format written at the given height, empty if deleted.
+func WriteStateArchiveStorage(db ethdb.KeyValueWriter, accountHash, storageHash common.Hash, number uint64, blob []byte) {
+	if err := db.Put(stateArchiveStorageKey(accountHash, storageHash, number), blob); err != nil {
+		log.Crit("Failed to store state archive slot", "err", err)
+	}
+}
+
+// ReadStateArchiveAccount retrieves the account in 'slim RLP' encoding at the
+// given height from the state archive, nil if it does not exist. The height
+// must be in the range of the archive.
+func ReadStateArchiveAccount(db ethdb.Iteratee, accountHash common.Hash, number uint64) []byte {
+	blob, _, _ := readLatestStateArchiveEntry(db, stateArchiveAccountKey(accountHash, number), stateArchiveAccountKeyLength)
+	return blob
+}
+
+// ReadStateArchiveStorage retrieves the slot of the account in prefix-zero
+// trimmed rlp format at the given height from the state archive, nil if it does
+// not exist. The height must be in the range of the archive.
+func ReadStateArchiveStorage(db ethdb.Iteratee, accountHash, storageHash common.Hash, number uint64) []byte {
+	blob, written, ok := readLatestStateArchiveEntry(db, stateArchiveStorageKey(accountHash, storageHash, number), stateArchiveStorageKeyLength)
+	if !ok {
+		return nil
+	}
+	// The slots written in the block the storage is wiped are written after.
+	if _, wiped, ok := readLatestStateArchiveEntry(db, stateArchiveDestructKey(accountHash, number), stateArchiveDestructKeyLength); ok && wiped > written {
+		return nil
+	}
+	return blob
+}
+
+// readLatestStateArchiveEntry returns the value and the height of the latest
+// entry written at or below the height of [key], whose last 8 bytes are the
+// inverted height so that the entries are ordered from the latest.
+func readLatestStateArchiveEntry(db ethdb.Iteratee, key []byte, keyLen int) ([]byte, uint64, bool) {
+	prefix := key[:len(key)-8]
+	it := db.NewIterator(prefix, key[len(prefix):])
+	defer it.Release()
+
+	for it.Next() {
+		if entry := it.Key(); len(entry) == keyLen {
+			return common.CopyBytes(it.Value()), ^binary.BigEndian.Uint64(entry[len(prefix):]), true
+		}
+	}
+	return nil, 0, false
+}
+
+// ClearStateArchive removes all the state archive entries and markers. The
+// state diffs of the blocks not archived yet are kept.
+func ClearStateArchive(db ethdb.KeyValueStore) error {
+	for _, key := range [][]byte{stateArchiveTailKey, stateArchiveHeadKey, stateArchiveBaseProgressKey} {
+		if err := db.Delete(key); err != nil {
+			return err
+		}
+	}
+	if err := ClearPrefix(db, stateArchiveAccountPrefix, stateArchiveAccountKeyLength); err != nil {
+		return err
+	}
+	if err := ClearPrefix(db, stateArchiveStoragePrefix, stateArchiveStorageKeyLength); err != nil {
+		return err
+	}
+	return ClearPrefix(db, stateArchiveDestructPrefix, stateArchiveDestructKeyLength)
+}
@@ -0,0 +1,107 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests state archive storage, retrieval and deletion operations.
+func TestStateArchive(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if tail, head := ReadStateArchiveTail(db), ReadStateArchiveHead(db); tail != nil || head != nil {
+		t.Fatalf("state archive markers returned from pristine database: %v %v", tail, head)
+	}
+	WriteStateArchiveTail(db, 2)
+	WriteStateArchiveHead(db, 8)
+	if tail, head := ReadStateArchiveTail(db), ReadStateArchiveHead(db); tail == nil || *tail != 2 || head == nil || *head != 8 {
+		t.Fatalf("state archive markers mismatch: %v %v", tail, head)
+	}
+
+	var (
+		alice = common.Hash{0xa1}
+		bob   = common.Hash{0xb0}
+		slot  = common.Hash{0x01}
+		other = common.Hash{0x02}
+	)
+	// Alice is created at 2 and destructed at 5, then resurrected at 7 with a
+	// slot written again in the same block.
+	WriteStateArchiveEntries(db, 2, &StateArchiveDiff{
+		Accounts: []StateArchiveAccount{{Hash: alice, Blob: []byte{0x02}}, {Hash: bob, Blob: []byte{0x0b}}},
+		Storages: []StateArchiveStorage{{Account: alice, Hashes: []common.Hash{slot, other}, Values: [][]byte{{0x12}, {0x22}}}},
+	})
+	WriteStateArchiveEntries(db, 3, &StateArchiveDiff{
+		Storages: []StateArchiveStorage{{Account: alice, Hashes: []common.Hash{slot}, Values: [][]byte{{0x13}}}},
+	})
+	WriteStateArchiveEntries(db, 5, &StateArchiveDiff{Destructs: []common.Hash{alice}})
+	WriteStateArchiveEntries(db, 7, &StateArchiveDiff{
+		Destructs: []common.Hash{alice},
+		Accounts:  []StateArchiveAccount{{Hash: alice, Blob: []byte{0x07}}},
+		Storages:  []StateArchiveStorage{{Account: alice, Hashes: []common.Hash{slot}, Values: [][]byte{{0x17}}}},
+	})
+
+	accounts := []struct {
+		hash   common.Hash
+		number uint64
+		want   []byte
+	}{
+		{alice, 2, []byte{0x02}}, {alice, 4, []byte{0x02}}, {alice, 5, nil}, {alice, 6, nil},
+		{alice, 7, []byte{0x07}}, {alice, 8, []byte{0x07}}, {bob, 8, []byte{0x0b}}, {common.Hash{0xc0}, 8, nil},
+	}
+	for _, tt := range accounts {
+		if blob := ReadStateArchiveAccount(db, tt.hash, tt.number); !bytes.Equal(blob, tt.want) {
+			t.Errorf("account %x at %d mismatch: have %x, want %x", tt.hash, tt.number, blob, tt.want)
+		}
+	}
+	slots := []struct {
+		hash   common.Hash
+		number uint64
+		want   []byte
+	}{
+		{slot, 2, []byte{0x12}}, {slot, 3, []byte{0x13}}, {slot, 4, []byte{0x13}}, {slot, 5, nil},
+		{slot, 7, []byte{0x17}}, {other, 4, []byte{0x22}}, {other, 5, nil}, {other, 8, nil},
+	}
+	for _, tt := range slots {
+		if blob := ReadStateArchiveStorage(db, alice, tt.hash, tt.number); !bytes.Equal(blob, tt.want) {
+			t.Errorf("slot %x at %d mismatch: have %x, want %x", tt.hash, tt.number, blob, tt.want)
+		}
+	}
+
+	// The state diffs are kept per block until they are archived.
+	diff := &StateArchiveDiff{
+		Destructs: []common.Hash{bob},
+		Accounts:  []StateArchiveAccount{{Hash: alice, Blob: []byte{0x09}}},
+		Storages:  []StateArchiveStorage{{Account: alice, Hashes: []common.Hash{slot}, Values: [][]byte{{}}}},
+	}
+	WriteStateArchiveDiff(db, common.Hash{0x09}, 9, diff)
+	WriteStateArchiveDiff(db, common.Hash{0x0a}, 9, &StateArchiveDiff{})
+	if have := ReadStateArchiveDiff(db, common.Hash{0x09}, 9); !reflect.DeepEqual(have, diff) {
+		t.Fatalf("state diff mismatch: have %v, want %v", have, diff)
+	}
+	if hashes := ReadStateArchiveDiffHashes(db, 9); !reflect.DeepEqual(hashes, []common.Hash{{0x09}, {0x0a}}) {
+		t.Fatalf("state diff hashes mismatch: %v", hashes)
+	}
+	DeleteStateArchiveDiff(db, common.Hash{0x09}, 9)
+	if have := ReadStateArchiveDiff(db, common.Hash{0x09}, 9); have != nil {
+		t.Fatalf("deleted state diff returned: %v", have)
+	}
+
+	if err := ClearStateArchive(db); err != nil {
+		t.Fatal(err)
+	}
+	if tail, head := ReadStateArchiveTail(db), ReadStateArchiveHead(db); tail != nil || head != nil {
+		t.Fatalf("state archive markers returned after clearing: %v %v", tail, head)
+	}
+	if blob := ReadStateArchiveAccount(db, bob, 8); blob != nil {
+		t.Fatalf("account returned after clearing: %x", blob)
+	}
+	if hashes := ReadStateArchiveDiffHashes(db, 9); len(hashes) != 1 {
+		t.Fatalf("state diffs removed by clearing: %v", hashes)
+	}
+}
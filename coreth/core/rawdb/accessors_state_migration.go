@@ -0,0 +1,57 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ReadStateSchemeMigration retrieves the root of the state being migrated to
+// the path-based scheme, or the empty hash if no migration is running.
+func ReadStateSchemeMigration(db ethdb.KeyValueReader) common.Hash {
+	data, _ := db.Get(stateSchemeMigrationKey)
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteStateSchemeMigration stores the root of the state being migrated to the
+// path-based scheme.
+func WriteStateSchemeMigration(db ethdb.KeyValueWriter, root common.Hash) {
+	if err := db.Put(stateSchemeMigrationKey, root.Bytes()); err != nil {
+		log.Crit("Failed to store state scheme migration", "err", err)
+	}
+}
+
+// ReadStateSchemeMigrationProgress retrieves the progress of the running
+// state scheme migration, nil if it has not started.
+func ReadStateSchemeMigrationProgress(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(stateSchemeMigrationProgressKey)
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+// WriteStateSchemeMigrationProgress stores the progress of the running state
+// scheme migration.
+func WriteStateSchemeMigrationProgress(db ethdb.KeyValueWriter, progress []byte) {
+	if err := db.Put(stateSchemeMigrationProgressKey, progress); err != nil {
+		log.Crit("Failed to store state scheme migration progress", "err", err)
+	}
+}
+
+// DeleteStateSchemeMigration removes the root and the progress of the state
+// scheme migration.
+func DeleteStateSchemeMigration(db ethdb.KeyValueWriter) {
+	if err := db.Delete(stateSchemeMigrationKey); err != nil {
+		log.Crit("Failed to delete state scheme migration", "err", err)
+	}
+	if err := db.Delete(stateSchemeMigrationProgressKey); err != nil {
+		log.Crit("Failed to delete state scheme migration progress", "err", err)
+	}
+}
@@ -0,0 +1,160 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
+)
+
+// TraceRole is the role of an address in an indexed flat call trace.
+type TraceRole byte
+
+const (
+	TraceRoleFrom TraceRole = iota // sender of the call, or the self destructed contract
+	TraceRoleTo                    // recipient of the call, the created contract or the refund address
+)
+
+var traceAddressIndexKeyLength = len(traceAddressIndexPrefix) + common.AddressLength + 1 + 8 + 4 + 4
+
+// IndexedTrace is a flat call trace of an accepted block, stored by the trace
+// indexer along with the addresses it is found by.
+type IndexedTrace struct {
+	TxIndex uint32
+	From    *common.Address `rlp:"nil"`
+	To      *common.Address `rlp:"nil"`
+	Trace   []byte          // JSON encoded flat trace
+}
+
+// TracePosition locates an indexed flat call trace: the trace at TraceIndex in
+// the traces of the block, which belongs to the transaction at TxIndex.
+type TracePosition struct {
+	Number     uint64
+	TxIndex    uint32
+	TraceIndex uint32
+}
+
+// ReadTraceIndexTail retrieves the number of the oldest accepted block whose
+// flat call traces have been indexed.
+func ReadTraceIndexTail(db ethdb.KeyValueReader) *uint64 {
+	return readIndexMarker(db, traceIndexTailKey)
+}
+
+// ReadTraceIndexHead retrieves the number of the latest accepted block whose
+// flat call traces have been indexed.
+func ReadTraceIndexHead(db ethdb.KeyValueReader) *uint64 {
+	return readIndexMarker(db, traceIndexHeadKey)
+}
+
+// WriteTraceIndexTail stores the number of the oldest accepted block whose flat
+// call traces have been indexed.
+func WriteTraceIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(traceIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the trace index tail", "err", err)
+	}
+}
+
+// WriteTraceIndexHead stores the number of the latest accepted block whose flat
+// call traces have been indexed.
+func WriteTraceIndexHead(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(traceIndexHeadKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the trace index head", "err", err)
+	}
+}
+
+// ReadBlockTraces retrieves the indexed flat call traces of the accepted block
+// at the given height.
+func ReadBlockTraces(db ethdb.KeyValueReader, number uint64) []IndexedTrace {
+	data, _ := db.Get(blockTracesKey(number))
+	if len(data) == 0 {
+		return nil
+	}
+	blob, err := snappy.Decode(nil, data)
+	if err != nil {
+		log.Error("Invalid compressed block traces", "number", number, "err", err)
+		return nil
+	}
+	var traces []IndexedTrace
+	if err := rlp.DecodeBytes(blob, &traces); err != nil {
+		log.Error("Invalid block trace array RLP", "number", number, "err", err)
+		return nil
+	}
+	return traces
+}
+
+// WriteBlockTraces stores the flat call traces of the accepted block at the
+// given height, compressed, and indexes them by their addresses.
+func WriteBlockTraces(db ethdb.KeyValueWriter, number uint64, traces []IndexedTrace) {
+	blob, err := rlp.EncodeToBytes(traces)
+	if err != nil {
+		log.Crit("Failed to encode block traces", "err", err)
+	}
+	if err := db.Put(blockTracesKey(number), snappy.Encode(nil, blob)); err != nil {
+		log.Crit("Failed to store block traces", "err", err)
+	}
+	forEachTraceAddress(number, traces, func(key []byte) {
+		if err := db.Put(key, nil); err != nil {
+			log.Crit("Failed to store trace address index", "err", err)
+		}
+	})
+}
+
+// DeleteBlockTraces removes the flat call traces of the accepted block at the
+// given height and the address index entries of [traces], which are the ones
+// stored for the block.
+func DeleteBlockTraces(db ethdb.KeyValueWriter, number uint64, traces []IndexedTrace) {
+	forEachTraceAddress(number, traces, func(key []byte) {
+		if err := db.Delete(key); err != nil {
+			log.Crit("Failed to delete trace address index", "err", err)
+		}
+	})
+	if err := db.Delete(blockTracesKey(number)); err != nil {
+		log.Crit("Failed to delete block traces", "err", err)
+	}
+}
+
+func forEachTraceAddress(number uint64, traces []IndexedTrace, fn func(key []byte)) {
+	for i, trace := range traces {
+		pos := TracePosition{Number: number, TxIndex: trace.TxIndex, TraceIndex: uint32(i)}
+		if trace.From != nil {
+			fn(traceAddressIndexKey(*trace.From, TraceRoleFrom, pos))
+		}
+		if trace.To != nil {
+			fn(traceAddressIndexKey(*trace.To, TraceRoleTo, pos))
+		}
+	}
+}
+
+// ReadTracePositions retrieves the positions of the indexed flat call traces
+// in which [addr] has the given role, in ascending order.
+// This method considers both limits to be _inclusive_.
+func ReadTracePositions(db ethdb.Iteratee, addr common.Address, role TraceRole, first, last uint64) []TracePosition {
+	var (
+		prefix    = append(append(append([]byte{}, traceAddressIndexPrefix...), addr.Bytes()...), byte(role))
+		positions []TracePosition
+		it        = db.NewIterator(prefix, encodeBlockNumber(first))
+	)
+	defer it.Release()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != traceAddressIndexKeyLength {
+			continue
+		}
+		pos := TracePosition{
+			Number:     binary.BigEndian.Uint64(key[len(prefix):]),
+			TxIndex:    binary.BigEndian.Uint32(key[len(prefix)+8:]),
+			TraceIndex: binary.BigEndian.Uint32(key[len(prefix)+12:]),
+		}
+		if pos.Number > last {
+			break
+		}
+		positions = append(positions, pos)
+	}
+	return positions
+}
@@ -0,0 +1,69 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests trace index storage, retrieval and deletion operations.
+func TestTraceIndex(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if tail, head := ReadTraceIndexTail(db), ReadTraceIndexHead(db); tail != nil || head != nil {
+		t.Fatalf("trace index markers returned from pristine database: %v %v", tail, head)
+	}
+	WriteTraceIndexTail(db, 3)
+	WriteTraceIndexHead(db, 9)
+	if tail, head := ReadTraceIndexTail(db), ReadTraceIndexHead(db); tail == nil || *tail != 3 || head == nil || *head != 9 {
+		t.Fatalf("trace index markers mismatch: %v %v", tail, head)
+	}
+
+	var (
+		alice = common.Address{0xaa}
+		bob   = common.Address{0xbb}
+	)
+	block4 := []IndexedTrace{
+		{TxIndex: 0, From: &alice, To: &bob, Trace: []byte(`{"type":"call"}`)},
+		{TxIndex: 0, From: &bob, To: &alice, Trace: []byte(`{"type":"call","traceAddress":[0]}`)},
+		{TxIndex: 1, From: &alice, Trace: []byte(`{"type":"suicide"}`)},
+	}
+	block6 := []IndexedTrace{
+		{TxIndex: 2, From: &bob, To: &bob, Trace: []byte(`{"type":"call"}`)},
+	}
+	WriteBlockTraces(db, 4, block4)
+	WriteBlockTraces(db, 6, block6)
+
+	if traces := ReadBlockTraces(db, 4); !reflect.DeepEqual(traces, block4) {
+		t.Fatalf("block traces mismatch: have %v, want %v", traces, block4)
+	}
+	if traces := ReadBlockTraces(db, 5); traces != nil {
+		t.Fatalf("block traces returned for unindexed block: %v", traces)
+	}
+	want := []TracePosition{{Number: 4, TxIndex: 0, TraceIndex: 0}, {Number: 4, TxIndex: 1, TraceIndex: 2}}
+	if positions := ReadTracePositions(db, alice, TraceRoleFrom, 3, 9); !reflect.DeepEqual(positions, want) {
+		t.Fatalf("sender positions mismatch: have %v, want %v", positions, want)
+	}
+	want = []TracePosition{{Number: 4, TxIndex: 0, TraceIndex: 0}, {Number: 6, TxIndex: 2, TraceIndex: 0}}
+	if positions := ReadTracePositions(db, bob, TraceRoleTo, 0, 9); !reflect.DeepEqual(positions, want) {
+		t.Fatalf("recipient positions mismatch: have %v, want %v", positions, want)
+	}
+	if positions := ReadTracePositions(db, bob, TraceRoleTo, 5, 9); len(positions) != 1 || positions[0].Number != 6 {
+		t.Fatalf("recipient range mismatch: %v", positions)
+	}
+
+	DeleteBlockTraces(db, 4, ReadBlockTraces(db, 4))
+	if traces := ReadBlockTraces(db, 4); traces != nil {
+		t.Fatalf("block traces returned after deletion: %v", traces)
+	}
+	if positions := ReadTracePositions(db, alice, TraceRoleFrom, 0, 9); len(positions) != 0 {
+		t.Fatalf("sender positions returned after deletion: %v", positions)
+	}
+	if positions := ReadTracePositions(db, bob, TraceRoleFrom, 0, 9); len(positions) != 1 || positions[0].Number != 6 {
+		t.Fatalf("positions of other block deleted: %v", positions)
+	}
+}
@@ -0,0 +1,289 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// ChainFreezerHashTable indicates the name of the freezer canonical hash table.
+	ChainFreezerHashTable = "hashes"
+
+	// ChainFreezerHeaderTable indicates the name of the freezer header table.
+	ChainFreezerHeaderTable = "headers"
+
+	// ChainFreezerBodiesTable indicates the name of the freezer block body table.
+	ChainFreezerBodiesTable = "bodies"
+
+	// ChainFreezerReceiptTable indicates the name of the freezer receipts table.
+	ChainFreezerReceiptTable = "receipts"
+)
+
+// chainFreezerNoSnappy configures whether compression is disabled for the ancient-tables.
+// Hashes don't compress well.
+var chainFreezerNoSnappy = map[string]bool{
+	ChainFreezerHashTable:    true,
+	ChainFreezerHeaderTable:  false,
+	ChainFreezerBodiesTable:  false,
+	ChainFreezerReceiptTable: false,
+}
+
+const (
+	// freezerRecheckInterval is the frequency to check the key-value database for
+	// chain progression that might permit new blocks to be frozen into immutable
+	// storage.
+	freezerRecheckInterval = time.Minute
+
+	// freezerBatchLimit is the maximum number of blocks to freeze in one batch
+	// before doing an fsync and deleting it from the key-value store. The
+	// reads of the chain wait for the batch, so it is kept small enough not to
+	// delay the processing of new blocks.
+	freezerBatchLimit = 2048
+
+	// freezerTableSize defines the maximum size of freezer data files.
+	freezerTableSize = 2 * 1000 * 1000 * 1000
+)
+
+// chainFreezer is a freezer of the accepted blocks. A background routine
+// moves the headers, bodies and receipts of the blocks accepted more than
+// [depth] blocks ago from the key-value store into the ancient tables, which
+// only hold canonical blocks. The genesis block is never frozen, so the
+// tables start at block 1, or at the first block available on a state synced
+// node. As the tables hold a contiguous range of blocks, they are restarted at
+// the synced block if a state sync leaves a gap above the frozen blocks.
+type chainFreezer struct {
+	*Freezer
+	depth uint64
+
+	quit    chan struct{}
+	wg      sync.WaitGroup
+	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
+}
+
+// newChainFreezer opens the ancient tables of the chain in [datadir].
+func newChainFreezer(datadir string, depth uint64) (*chainFreezer, error) {
+	freezer, err := NewFreezer(datadir, freezerTableSize, chainFreezerNoSnappy)
+	if err != nil {
+		return nil, err
+	}
+	return &chainFreezer{
+		Freezer: freezer,
+		depth:   depth,
+		quit:    make(chan struct{}),
+		trigger: make(chan chan struct{}),
+	}, nil
+}
+
+// Close closes the chain freezer instance and terminates the background thread.
+func (f *chainFreezer) Close() error {
+	select {
+	case <-f.quit:
+	default:
+		close(f.quit)
+	}
+	f.wg.Wait()
+	return f.Freezer.Close()
+}
+
+// threshold returns the number of the last block that can be frozen, which is
+// [depth] blocks below the last accepted block processed by the acceptor.
+func (f *chainFreezer) threshold(db ethdb.KeyValueReader) (uint64, bool) {
+	hash, err := ReadAcceptorTip(db)
+	if err != nil || hash == (common.Hash{}) {
+		hash = ReadHeadBlockHash(db)
+	}
+	number := ReadHeaderNumber(db, hash)
+	if number == nil || *number <= f.depth {
+		return 0, false
+	}
+	return *number - f.depth, true
+}
+
+// freeze is a background thread that periodically checks the blockchain for
+// any import progress and moves ancient data from the fast database into the
+// freezer.
+//
+// This functionality is deliberately broken off from block importing to avoid
+// incurring additional data shuffling delays on block propagation.
+func (f *chainFreezer) freeze(db ethdb.KeyValueStore) {
+	var (
+		backoff   bool
+		triggered chan struct{} // Used in tests
+		nfdb      = &nofreezedb{KeyValueStore: db}
+	)
+	timer := time.NewTimer(freezerRecheckInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-f.quit:
+			log.Info("Freezer shutting down")
+			return
+		default:
+		}
+		if backoff {
+			// If we were doing a manual trigger, notify it
+			if triggered != nil {
+				triggered <- struct{}{}
+				triggered = nil
+			}
+			timer.Reset(freezerRecheckInterval)
+			select {
+			case <-timer.C:
+				backoff = false
+			case triggered = <-f.trigger:
+				backoff = false
+			case <-f.quit:
+				return
+			}
+		}
+		threshold, ok := f.threshold(db)
+		if !ok {
+			backoff = true
+			continue
+		}
+		first := f.frozen.Load()
+		if empty := first == f.tail.Load(); empty || ReadCanonicalHash(nfdb, first) == (common.Hash{}) {
+			// The tables are empty, or the canonical chain resumes above them
+			// after a state sync, start them at the first block available.
+			numbers, _ := ReadAllCanonicalHashes(db, max(first, 1), threshold+1, 1)
+			if len(numbers) == 0 {
+				backoff = true
+				continue
+			}
+			if numbers[0] != first {
+				if err := f.restart(first, numbers[0], empty); err != nil {
+					log.Error("Failed to initialize ancient tables", "tail", numbers[0], "err", err)
+					backoff = true
+					continue
+				}
+				first = numbers[0]
+			}
+		}
+		if first > threshold {
+			backoff = true
+			continue
+		}
+		limit := min(threshold, first+freezerBatchLimit-1)
+
+		// Seems we have data ready to be frozen, process in usable batches
+		start := time.Now()
+		hashes, err := f.freezeRange(nfdb, first, limit)
+		if err != nil {
+			log.Error("Error in block freeze operation", "err", err)
+			backoff = true
+			continue
+		}
+		limit = first + uint64(len(hashes)) - 1
+		// Batch of blocks have been frozen, flush them before wiping from key-value store
+		if err := f.SyncAncient(); err != nil {
+			log.Crit("Failed to flush frozen tables", "err", err)
+		}
+		// Wipe out all data from the active database. The hash to number
+		// mappings are kept to look up the frozen blocks by hash, and the Flare
+		// data of the blocks is kept as it is not frozen.
+		batch := db.NewBatch()
+		for i, hash := range hashes {
+			number := first + uint64(i)
+			deleteHeaderWithoutNumber(batch, hash, number)
+			DeleteBody(batch, hash, number)
+			DeleteReceipts(batch, hash, number)
+			DeleteCanonicalHash(batch, number)
+
+			// Wipe out side chains at the frozen height.
+			for _, side := range ReadAllHashes(db, number) {
+				if side != hash {
+					DeleteBlock(batch, side, number)
+				}
+			}
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					log.Crit("Failed to delete frozen canonical blocks", "err", err)
+				}
+				batch.Reset()
+			}
+		}
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to delete frozen canonical blocks", "err", err)
+		}
+		log.Info("Moved blocks into the ancient store", "from", first, "to", limit, "elapsed", common.PrettyDuration(time.Since(start)))
+
+		// Avoid database thrashing with tiny writes
+		if limit == threshold {
+			backoff = true
+		}
+	}
+}
+
+// restart moves the tail of the tables to [tail], the first block available
+// above the gap starting at [frozen]. The blocks of non-[empty] tables are
+// discarded first, as the tables cannot hold the gap.
+func (f *chainFreezer) restart(frozen, tail uint64, empty bool) error {
+	if !empty {
+		log.Warn("Discarding the ancient blocks below a gap in the canonical chain", "frozen", frozen, "first", tail)
+		if _, err := f.TruncateTail(frozen); err != nil {
+			return err
+		}
+	}
+	_, err := f.TruncateTail(tail)
+	return err
+}
+
+// freezeRange moves the canonical blocks from [number] to [limit] into the
+// ancient tables, returning their hashes. The range ends early at a gap in the
+// canonical chain.
+func (f *chainFreezer) freezeRange(nfdb *nofreezedb, number, limit uint64) (hashes []common.Hash, err error) {
+	hashes = make([]common.Hash, 0, limit-number+1)
+
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for ; number <= limit; number++ {
+			// Retrieve all the components of the canonical block.
+			hash := ReadCanonicalHash(nfdb, number)
+			if hash == (common.Hash{}) {
+				// A gap left by a state sync ends the range, the tables
+				// are restarted above it by the next freeze.
+				if len(hashes) > 0 {
+					break
+				}
+				return fmt.Errorf("canonical hash missing, can't freeze block %d", number)
+			}
+			header := ReadHeaderRLP(nfdb, hash, number)
+			if len(header) == 0 {
+				return fmt.Errorf("block header missing, can't freeze block %d", number)
+			}
+			body := ReadBodyRLP(nfdb, hash, number)
+			if len(body) == 0 {
+				return fmt.Errorf("block body missing, can't freeze block %d", number)
+			}
+			receipts := ReadReceiptsRLP(nfdb, hash, number)
+			if len(receipts) == 0 {
+				return fmt.Errorf("block receipts missing, can't freeze block %d", number)
+			}
+
+			// Write to the batch.
+			if err := op.AppendRaw(ChainFreezerHashTable, number, hash[:]); err != nil {
+				return fmt.Errorf("can't write hash to Freezer: %v", err)
+			}
+			if err := op.AppendRaw(ChainFreezerHeaderTable, number, header); err != nil {
+				return fmt.Errorf("can't write header to Freezer: %v", err)
+			}
+			if err := op.AppendRaw(ChainFreezerBodiesTable, number, body); err != nil {
+				return fmt.Errorf("can't write body to Freezer: %v", err)
+			}
+			if err := op.AppendRaw(ChainFreezerReceiptTable, number, receipts); err != nil {
+				return fmt.Errorf("can't write receipts to Freezer: %v", err)
+			}
+			hashes = append(hashes, hash)
+		}
+		return nil
+	})
+	return hashes, err
+}
@@ -0,0 +1,325 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+// Tests that the items of a freezer table survive data file rollovers, an
+// interrupted write and truncation.
+func TestFreezerTable(t *testing.T) {
+	dir := t.TempDir()
+	table, err := newFreezerTable(dir, "test", false, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := func(i uint64) []byte { return bytes.Repeat([]byte{byte(i)}, 15) }
+
+	batch := table.newBatch()
+	for i := uint64(0); i < 10; i++ {
+		if err := batch.appendItem(i, item(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := batch.appendItem(11, item(11)); err == nil {
+		t.Fatal("out of order item appended")
+	}
+	if err := batch.commit(); err != nil {
+		t.Fatal(err)
+	}
+	if table.headId == 0 {
+		t.Fatal("data files not rolled over")
+	}
+	check := func(items uint64) {
+		t.Helper()
+		if have := table.items.Load(); have != items {
+			t.Fatalf("item count mismatch: have %d, want %d", have, items)
+		}
+		for i := uint64(0); i < items; i++ {
+			blob, err := table.retrieve(i)
+			if err != nil {
+				t.Fatalf("item %d: %v", i, err)
+			}
+			if !bytes.Equal(blob, item(i)) {
+				t.Fatalf("item %d mismatch: have %x, want %x", i, blob, item(i))
+			}
+		}
+		if _, err := table.retrieve(items); err != errOutOfBounds {
+			t.Fatalf("item %d past the head returned: %v", items, err)
+		}
+	}
+	check(10)
+	if blobs, err := table.retrieveItems(2, 5, 1); err != nil || len(blobs) != 1 {
+		t.Fatalf("size limited retrieval mismatch: have %d items, %v", len(blobs), err)
+	}
+
+	// Simulate a write interrupted after the data of an item.
+	table.close()
+	head, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("test.%04d.cdat", table.headId)), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head.Write([]byte{0x01, 0x02})
+	head.Close()
+	if table, err = newFreezerTable(dir, "test", false, 50); err != nil {
+		t.Fatal(err)
+	}
+	check(10)
+
+	if err := table.truncateHead(3); err != nil {
+		t.Fatal(err)
+	}
+	check(3)
+	batch = table.newBatch()
+	if err := batch.appendItem(3, item(3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.commit(); err != nil {
+		t.Fatal(err)
+	}
+	check(4)
+	table.close()
+}
+
+// Tests that the items below the tail of a freezer are discarded, and that the
+// data files only holding them are deleted.
+func TestFreezerTruncateTail(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		tables = map[string]bool{"raw": true, "compressed": false}
+		item   = func(i uint64) []byte { return bytes.Repeat([]byte{byte(i)}, 15) }
+	)
+	f, err := NewFreezer(dir, 50, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendItems := func(from, to uint64) {
+		t.Helper()
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				for name := range tables {
+					if err := op.AppendRaw(name, i, item(i)); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(tail, head uint64) {
+		t.Helper()
+		if have, _ := f.Tail(); have != tail {
+			t.Fatalf("tail mismatch: have %d, want %d", have, tail)
+		}
+		if have, _ := f.Ancients(); have != head {
+			t.Fatalf("head mismatch: have %d, want %d", have, head)
+		}
+		for name := range tables {
+			if tail > 0 {
+				if _, err := f.Ancient(name, tail-1); err == nil {
+					t.Fatalf("item %d of %s below the tail returned", tail-1, name)
+				}
+			}
+			for i := tail; i < head; i++ {
+				blob, err := f.Ancient(name, i)
+				if err != nil {
+					t.Fatalf("item %d of %s: %v", i, name, err)
+				}
+				if !bytes.Equal(blob, item(i)) {
+					t.Fatalf("item %d of %s mismatch: have %x, want %x", i, name, blob, item(i))
+				}
+			}
+		}
+	}
+	reopen := func() {
+		t.Helper()
+		f.Close()
+		if f, err = NewFreezer(dir, 50, tables); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The raw table stores three items per data file.
+	appendItems(0, 10)
+	if _, err := f.TruncateTail(4); err != nil {
+		t.Fatal(err)
+	}
+	check(4, 10)
+	if _, err := os.Stat(filepath.Join(dir, "raw.0000.rdat")); !os.IsNotExist(err) {
+		t.Fatalf("data file of discarded items not deleted: %v", err)
+	}
+	reopen()
+	check(4, 10)
+
+	if _, err := f.TruncateTail(11); err == nil {
+		t.Fatal("tail truncated above the head")
+	}
+	if _, err := f.TruncateTail(10); err != nil {
+		t.Fatal(err)
+	}
+	check(10, 10)
+	appendItems(10, 12)
+	check(10, 12)
+	reopen()
+	check(10, 12)
+	f.Close()
+}
+
+// Tests that the freezer moves the accepted blocks below the depth into the
+// ancient tables, and that they are still read transparently.
+func TestChainFreezer(t *testing.T) {
+	var (
+		kvdb   = memorydb.New()
+		blocks []*types.Block
+		parent common.Hash
+	)
+	for i := int64(0); i <= 10; i++ {
+		header := &types.Header{Number: big.NewInt(i), ParentHash: parent, Extra: []byte("test block")}
+		txs := []*types.Transaction{types.NewTransaction(uint64(i), common.Address{0x11}, big.NewInt(i), 21000, big.NewInt(1), nil)}
+		block := types.NewBlockWithHeader(header).WithBody(txs, nil)
+		receipts := types.Receipts{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: uint64(i), Logs: []*types.Log{}}}
+
+		WriteBlock(kvdb, block)
+		WriteReceipts(kvdb, block.Hash(), block.NumberU64(), receipts)
+		WriteCanonicalHash(kvdb, block.Hash(), block.NumberU64())
+		blocks = append(blocks, block)
+		parent = block.Hash()
+	}
+	// A rejected block at a height to freeze.
+	side := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(3), Extra: []byte("side block")})
+	WriteBlock(kvdb, side)
+	WriteAcceptorTip(kvdb, blocks[10].Hash())
+
+	dir := t.TempDir()
+	db, err := NewDatabaseWithFreezer(kvdb, dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.(*freezerdb).Freeze(); err != nil {
+		t.Fatal(err)
+	}
+	if tail, _ := db.Tail(); tail != 1 {
+		t.Fatalf("ancient tail mismatch: have %d, want 1", tail)
+	}
+	if frozen, _ := db.Ancients(); frozen != 7 {
+		t.Fatalf("ancient items mismatch: have %d, want 7", frozen)
+	}
+	for _, block := range blocks {
+		hash, number := block.Hash(), block.NumberU64()
+		frozen := number > 0 && number < 7
+		if has, _ := kvdb.Has(headerKey(number, hash)); has == frozen {
+			t.Fatalf("block %d: header in key-value store %t, frozen %t", number, has, frozen)
+		}
+		if ReadCanonicalHash(db, number) != hash {
+			t.Fatalf("block %d: canonical hash mismatch", number)
+		}
+		if entry := ReadBlock(db, hash, number); entry == nil || entry.Hash() != hash || entry.Transactions()[0].Hash() != block.Transactions()[0].Hash() {
+			t.Fatalf("block %d: retrieved block mismatch: %v", number, entry)
+		}
+		if body := ReadCanonicalBodyRLP(db, number); len(body) == 0 {
+			t.Fatalf("block %d: canonical body not found", number)
+		}
+		if !HasHeader(db, hash, number) || !HasBody(db, hash, number) || !HasReceipts(db, hash, number) {
+			t.Fatalf("block %d: stored data not found", number)
+		}
+		if receipts := ReadRawReceipts(db, hash, number); len(receipts) != 1 || receipts[0].CumulativeGasUsed != number {
+			t.Fatalf("block %d: retrieved receipts mismatch: %v", number, receipts)
+		}
+		if entry := ReadHeaderNumber(db, hash); entry == nil || *entry != number {
+			t.Fatalf("block %d: hash to number mapping missing", number)
+		}
+	}
+	if HasHeader(db, side.Hash(), 3) {
+		t.Fatal("side chain block not deleted")
+	}
+	db.(*freezerdb).chainFreezer.Close()
+
+	// The frozen blocks are read again after a restart, and a key-value store
+	// of another chain is rejected.
+	if db, err = NewDatabaseWithFreezer(kvdb, dir, 4); err != nil {
+		t.Fatal(err)
+	}
+	if entry := ReadBlock(db, blocks[5].Hash(), 5); entry == nil || entry.Hash() != blocks[5].Hash() {
+		t.Fatalf("retrieved block mismatch after restart: %v", entry)
+	}
+	db.(*freezerdb).chainFreezer.Close()
+
+	other := memorydb.New()
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7), Extra: []byte("other chain")})
+	WriteBlock(other, block)
+	WriteCanonicalHash(other, block.Hash(), 7)
+	if _, err := NewDatabaseWithFreezer(other, dir, 4); err == nil {
+		t.Fatal("ancient store of another chain opened")
+	}
+}
+
+// Tests that the freezer restarts the ancient tables above the gap left in the
+// canonical chain by a state sync, instead of failing on the missing blocks.
+func TestChainFreezerStateSyncGap(t *testing.T) {
+	var (
+		kvdb   = memorydb.New()
+		blocks = make(map[uint64]*types.Block)
+	)
+	writeBlocks := func(from, to uint64) {
+		var parent common.Hash
+		for i := from; i <= to; i++ {
+			header := &types.Header{Number: new(big.Int).SetUint64(i), ParentHash: parent, Extra: []byte("test block")}
+			block := types.NewBlockWithHeader(header)
+			receipts := types.Receipts{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: i, Logs: []*types.Log{}}}
+
+			WriteBlock(kvdb, block)
+			WriteReceipts(kvdb, block.Hash(), i, receipts)
+			WriteCanonicalHash(kvdb, block.Hash(), i)
+			WriteAcceptorTip(kvdb, block.Hash())
+			blocks[i] = block
+			parent = block.Hash()
+		}
+	}
+	writeBlocks(0, 10)
+	db, err := NewDatabaseWithFreezer(kvdb, t.TempDir(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	freeze := func(tail, frozen uint64) {
+		t.Helper()
+		if err := db.(*freezerdb).Freeze(); err != nil {
+			t.Fatal(err)
+		}
+		if have, _ := db.Tail(); have != tail {
+			t.Fatalf("ancient tail mismatch: have %d, want %d", have, tail)
+		}
+		if have, _ := db.Ancients(); have != frozen {
+			t.Fatalf("ancient items mismatch: have %d, want %d", have, frozen)
+		}
+	}
+	freeze(1, 7)
+
+	// The state synced chain resumes at block 20, the blocks before the gap
+	// are frozen first.
+	writeBlocks(20, 30)
+	freeze(20, 27)
+	for number := uint64(20); number <= 30; number++ {
+		block := blocks[number]
+		if entry := ReadBlock(db, block.Hash(), number); entry == nil || entry.Hash() != block.Hash() {
+			t.Fatalf("block %d: retrieved block mismatch: %v", number, entry)
+		}
+	}
+	if ReadCanonicalHash(db, 5) != (common.Hash{}) {
+		t.Fatal("block below the gap still frozen")
+	}
+}
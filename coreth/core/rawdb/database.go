@@ -28,6 +28,7 @@ package rawdb
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -42,6 +43,36 @@ import (
 	"github.com/olekukonko/tablewriter"
 )
 
+// freezerdb is a database wrapper that enables ancient chain segment freezing.
+type freezerdb struct {
+	ethdb.KeyValueStore
+	*chainFreezer
+}
+
+// Close implements io.Closer, closing both the fast key-value store as well as
+// the slow ancient tables.
+func (frdb *freezerdb) Close() error {
+	var errs []error
+	if err := frdb.chainFreezer.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := frdb.KeyValueStore.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Freeze is a helper method used for external testing to trigger and block until
+// a freeze cycle completes, without having to sleep for a minute to trigger the
+// automatic background run.
+func (frdb *freezerdb) Freeze() error {
+	// Trigger a freeze cycle and block until it's done
+	trigger := make(chan struct{}, 1)
+	frdb.chainFreezer.trigger <- trigger
+	<-trigger
+	return nil
+}
+
 // nofreezedb is a database wrapper that disables freezer data retrievals.
 type nofreezedb struct {
 	ethdb.KeyValueStore
@@ -134,6 +165,39 @@ func NewDatabase(db ethdb.KeyValueStore) ethdb.Database {
 	return &nofreezedb{KeyValueStore: db}
 }
 
+// NewDatabaseWithFreezer creates a high level database on top of a given
+// key-value data store with a freezer moving the blocks accepted more than
+// [depth] blocks ago into the ancient tables in [ancient]. Reads of the frozen
+// blocks transparently fall back to the ancient tables.
+func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, depth uint64) (ethdb.Database, error) {
+	frdb, err := newChainFreezer(ancient, depth)
+	if err != nil {
+		return nil, err
+	}
+	// Since the freezer can be stored separately from the key-value database,
+	// ensure that the ancient tables belong to the chain stored in it, so that
+	// conflicting data is never served.
+	if frozen, tail := frdb.frozen.Load(), frdb.tail.Load(); frozen > tail {
+		last, err := frdb.Ancient(ChainFreezerHashTable, frozen-1)
+		if err != nil {
+			frdb.Close()
+			return nil, err
+		}
+		if hash := ReadCanonicalHash(&nofreezedb{KeyValueStore: db}, frozen); hash != (common.Hash{}) {
+			if header := ReadHeader(&nofreezedb{KeyValueStore: db}, hash, frozen); header != nil && header.ParentHash != common.BytesToHash(last) {
+				frdb.Close()
+				return nil, fmt.Errorf("ancient chain segments do not match the key-value store: block %d in ancient store %#x, parent of block %d %#x", frozen-1, last, frozen, header.ParentHash)
+			}
+		}
+	}
+	frdb.wg.Add(1)
+	go func() {
+		defer frdb.wg.Done()
+		frdb.freeze(db)
+	}()
+	return &freezerdb{KeyValueStore: db, chainFreezer: frdb}, nil
+}
+
 // NewMemoryDatabase creates an ephemeral in-memory key-value database without a
 // freezer moving immutable chain segments into cold storage.
 func NewMemoryDatabase() ethdb.Database {
@@ -291,6 +355,14 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		headers         stat
 		bodies          stat
 		receipts        stat
+		daemonResults   stat
+		feeTreatments   stat
+		divergences     stat
+		governance      stat
+		traces          stat
+		logIndex        stat
+		pruningMarks    stat
+		stateArchive    stat
 		numHashPairings stat
 		hashNumPairings stat
 		legacyTries     stat
@@ -336,6 +408,27 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			bodies.Add(size)
 		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == (len(blockReceiptsPrefix)+8+common.HashLength):
 			receipts.Add(size)
+		case bytes.HasPrefix(key, daemonResultsPrefix) && len(key) == (len(daemonResultsPrefix)+8+common.HashLength):
+			daemonResults.Add(size)
+		case bytes.HasPrefix(key, feeTreatmentsPrefix) && len(key) == (len(feeTreatmentsPrefix)+8+common.HashLength):
+			feeTreatments.Add(size)
+		case bytes.HasPrefix(key, attestationDivergencesPrefix) && len(key) == (len(attestationDivergencesPrefix)+8+common.HashLength):
+			divergences.Add(size)
+		case bytes.HasPrefix(key, governanceChangesPrefix) && len(key) == (len(governanceChangesPrefix)+8):
+			governance.Add(size)
+		case bytes.HasPrefix(key, blockTracesPrefix) && len(key) == (len(blockTracesPrefix)+8):
+			traces.Add(size)
+		case bytes.HasPrefix(key, traceAddressIndexPrefix) && len(key) == traceAddressIndexKeyLength:
+			traces.Add(size)
+		case bytes.HasPrefix(key, logIndexPrefix) && len(key) == logIndexKeyLength:
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, onlinePruningMarkPrefix) && len(key) == onlinePruningMarkKeyLength:
+			pruningMarks.Add(size)
+		case bytes.HasPrefix(key, stateArchiveAccountPrefix) && len(key) == stateArchiveAccountKeyLength,
+			bytes.HasPrefix(key, stateArchiveStoragePrefix) && len(key) == stateArchiveStorageKeyLength,
+			bytes.HasPrefix(key, stateArchiveDestructPrefix) && len(key) == stateArchiveDestructKeyLength,
+			bytes.HasPrefix(key, stateArchiveDiffPrefix) && len(key) == stateArchiveDiffKeyLength:
+			stateArchive.Add(size)
 		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix):
 			numHashPairings.Add(size)
 		case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == (len(headerNumberPrefix)+common.HashLength):
@@ -379,6 +472,11 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 				snapshotRootKey, snapshotBlockHashKey, snapshotGeneratorKey,
 				uncleanShutdownKey, syncRootKey, txIndexTailKey,
 				persistentStateIDKey, trieJournalKey,
+				governanceIndexTailKey, governanceIndexHeadKey,
+				traceIndexTailKey, traceIndexHeadKey, logIndexTailKey, logIndexHeadKey,
+				onlinePruningProgressKey, stateSchemeMigrationKey, stateSchemeMigrationProgressKey,
+				stateArchiveTailKey, stateArchiveHeadKey, stateArchiveBaseProgressKey,
+				chainImportProgressKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
@@ -401,6 +499,14 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Headers", headers.Size(), headers.Count()},
 		{"Key-Value store", "Bodies", bodies.Size(), bodies.Count()},
 		{"Key-Value store", "Receipt lists", receipts.Size(), receipts.Count()},
+		{"Key-Value store", "Daemon result lists", daemonResults.Size(), daemonResults.Count()},
+		{"Key-Value store", "Fee treatment lists", feeTreatments.Size(), feeTreatments.Count()},
+		{"Key-Value store", "Attestation divergences", divergences.Size(), divergences.Count()},
+		{"Key-Value store", "Governance change index", governance.Size(), governance.Count()},
+		{"Key-Value store", "Trace index", traces.Size(), traces.Count()},
+		{"Key-Value store", "Log index", logIndex.Size(), logIndex.Count()},
+		{"Key-Value store", "Online pruning marks", pruningMarks.Size(), pruningMarks.Count()},
+		{"Key-Value store", "State archive", stateArchive.Size(), stateArchive.Count()},
 		{"Key-Value store", "Block number->hash", numHashPairings.Size(), numHashPairings.Count()},
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
@@ -422,6 +528,23 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"State sync", "Code to fetch", codeToFetch.Size(), codeToFetch.Count()},
 		{"State sync", "Block numbers synced to", syncPerformed.Size(), syncPerformed.Count()},
 	}
+	// Inspect the ancient tables, if the database has any.
+	if frozen, err := db.Ancients(); err == nil {
+		tail, _ := db.Tail()
+		for _, category := range []struct{ table, name string }{
+			{ChainFreezerHeaderTable, "Headers"},
+			{ChainFreezerBodiesTable, "Bodies"},
+			{ChainFreezerReceiptTable, "Receipt lists"},
+			{ChainFreezerHashTable, "Block number->hash"},
+		} {
+			size, err := db.AncientSize(category.table)
+			if err != nil {
+				return err
+			}
+			stats = append(stats, []string{"Ancient store", category.name, common.StorageSize(size).String(), counter(frozen - tail).String()})
+			total += common.StorageSize(size)
+		}
+	}
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetHeader([]string{"Database", "Category", "Size", "Items"})
 	table.SetFooter([]string{"", "Total", total.String(), " "})
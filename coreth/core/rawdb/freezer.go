@@ -16,6 +16,385 @@
 
 package rawdb
 
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// errUnknownTable is returned if the user attempts to read from a table that is
+	// not tracked by the freezer.
+	errUnknownTable = errors.New("unknown table")
+
+	// errOutOrderInsertion is returned if the user attempts to inject out-of-order
+	// binary blobs into the freezer.
+	errOutOrderInsertion = errors.New("the append operation is out-order")
+)
+
+// freezerTailFile is the name of the file persisting the tail of a freezer, the
+// tables may still store some of the items below it.
+const freezerTailFile = "TAIL"
+
 // convertLegacyFn takes a raw freezer entry in an older format and
 // returns it in the new format.
 type convertLegacyFn = func([]byte) ([]byte, error)
+
+// Freezer is an append-only database to store immutable ordered data into
+// flat files:
+//
+//   - The append-only nature ensures that disk writes are minimized.
+//   - The in-order data ensures that disk reads are always optimized.
+//
+// All the tables of a freezer hold the same items, appended together by
+// ModifyAncients.
+type Freezer struct {
+	frozen atomic.Uint64 // Number of items already frozen
+	tail   atomic.Uint64 // Number of the first stored item in the freezer
+
+	// This lock synchronizes writers and the truncate operation, as well as
+	// the "atomic" (batched) read operations.
+	writeLock  sync.RWMutex
+	writeBatch *freezerBatch
+
+	datadir string
+	tables  map[string]*freezerTable // Data tables for storing everything
+}
+
+// NewFreezer creates a freezer instance for maintaining immutable ordered
+// data according to the given parameters.
+//
+// The 'tables' argument defines the data tables. If the value of a map
+// entry is true, snappy compression is disabled for the table.
+func NewFreezer(datadir string, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+	freezer := &Freezer{
+		datadir: datadir,
+		tables:  make(map[string]*freezerTable),
+	}
+	for name, disableSnappy := range tables {
+		table, err := newFreezerTable(datadir, name, disableSnappy, maxTableSize)
+		if err != nil {
+			for _, table := range freezer.tables {
+				table.close()
+			}
+			return nil, err
+		}
+		freezer.tables[name] = table
+	}
+	if err := freezer.repair(); err != nil {
+		freezer.Close()
+		return nil, err
+	}
+	freezer.writeBatch = newFreezerBatch(freezer)
+
+	log.Info("Opened ancient database", "database", datadir, "tail", freezer.tail.Load(), "frozen", freezer.frozen.Load())
+	return freezer, nil
+}
+
+// repair truncates all data tables to the same length, which may differ if a
+// write was interrupted, and hides the items below the freezer tail.
+func (f *Freezer) repair() error {
+	head := uint64(math.MaxUint64)
+	tail, err := readFreezerTail(f.datadir)
+	if err != nil {
+		return err
+	}
+	for _, table := range f.tables {
+		tail = max(tail, table.offset)
+	}
+	for _, table := range f.tables {
+		// A table may hold fewer items than the tail if moving the tails of
+		// the tables was interrupted.
+		if table.items.Load() < tail {
+			if err := table.resetTail(tail); err != nil {
+				return err
+			}
+		} else if err := table.truncateTail(tail); err != nil {
+			return err
+		}
+	}
+	for _, table := range f.tables {
+		head = min(head, table.items.Load())
+	}
+	for _, table := range f.tables {
+		if err := table.truncateHead(head); err != nil {
+			return err
+		}
+	}
+	f.frozen.Store(head)
+	f.tail.Store(tail)
+	return nil
+}
+
+// readFreezerTail returns the tail persisted in [datadir], or zero if none.
+func readFreezerTail(datadir string) (uint64, error) {
+	blob, err := os.ReadFile(filepath.Join(datadir, freezerTailFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(blob) != 8 {
+		return 0, fmt.Errorf("invalid freezer tail of %d bytes", len(blob))
+	}
+	return binary.BigEndian.Uint64(blob), nil
+}
+
+// writeTail persists the tail of the freezer. The file is replaced atomically,
+// so that an interrupted write leaves the previous tail.
+func (f *Freezer) writeTail(tail uint64) error {
+	name := filepath.Join(f.datadir, freezerTailFile)
+	if err := writeFileSync(name+tmpSuffix, binary.BigEndian.AppendUint64(nil, tail)); err != nil {
+		return err
+	}
+	return os.Rename(name+tmpSuffix, name)
+}
+
+// Close terminates the chain freezer, closing all the data files.
+func (f *Freezer) Close() error {
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AncientDatadir returns the path of the ancient store.
+func (f *Freezer) AncientDatadir() (string, error) {
+	return f.datadir, nil
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
+func (f *Freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.retrieve(number)
+	}
+	return nil, errUnknownTable
+}
+
+// AncientRange retrieves multiple items in sequence, starting from the index 'start'.
+// It will return
+//   - at most 'count' items,
+//   - if maxBytes is specified: at least 1 item (even if exceeding the maxByteSize),
+//     but will otherwise return as many items as fit into maxByteSize.
+//   - if maxBytes is not specified, 'count' items will be returned if they are present.
+func (f *Freezer) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.retrieveItems(start, count, maxBytes)
+	}
+	return nil, errUnknownTable
+}
+
+// Ancients returns the length of the frozen items.
+func (f *Freezer) Ancients() (uint64, error) {
+	return f.frozen.Load(), nil
+}
+
+// Tail returns the number of first stored item in the freezer.
+func (f *Freezer) Tail() (uint64, error) {
+	return f.tail.Load(), nil
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (f *Freezer) AncientSize(kind string) (uint64, error) {
+	// This needs the write lock to avoid data races on table fields.
+	// Speed doesn't matter here, AncientSize is for debugging.
+	f.writeLock.RLock()
+	defer f.writeLock.RUnlock()
+
+	if table := f.tables[kind]; table != nil {
+		return table.size()
+	}
+	return 0, errUnknownTable
+}
+
+// ReadAncients runs the given read operation while ensuring that no writes take place
+// on the underlying freezer.
+func (f *Freezer) ReadAncients(fn func(ethdb.AncientReaderOp) error) (err error) {
+	f.writeLock.RLock()
+	defer f.writeLock.RUnlock()
+
+	return fn(f)
+}
+
+// ModifyAncients runs the given write operation.
+func (f *Freezer) ModifyAncients(fn func(ethdb.AncientWriteOp) error) (writeSize int64, err error) {
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	// Roll back all tables to the starting position in case of error.
+	prevItem := f.frozen.Load()
+	defer func() {
+		if err != nil {
+			// The write operation has failed. Go back to the previous item position.
+			for name, table := range f.tables {
+				err := table.truncateHead(prevItem)
+				if err != nil {
+					log.Error("Freezer table roll-back failed", "table", name, "index", prevItem, "err", err)
+				}
+			}
+		}
+	}()
+
+	f.writeBatch.reset()
+	if err := fn(f.writeBatch); err != nil {
+		return 0, err
+	}
+	item, writeSize, err := f.writeBatch.commit()
+	if err != nil {
+		return 0, err
+	}
+	f.frozen.Store(item)
+	return writeSize, nil
+}
+
+// TruncateHead discards any recent data above the provided threshold number.
+// It returns the previous head number.
+func (f *Freezer) TruncateHead(items uint64) (uint64, error) {
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	oitems := f.frozen.Load()
+	if oitems <= items {
+		return oitems, nil
+	}
+	if items < f.tail.Load() {
+		return oitems, fmt.Errorf("truncating head %d below tail %d", items, f.tail.Load())
+	}
+	for _, table := range f.tables {
+		if err := table.truncateHead(items); err != nil {
+			return 0, err
+		}
+	}
+	f.frozen.Store(items)
+	return oitems, nil
+}
+
+// TruncateTail discards the items below [tail]. The tail of an empty freezer
+// may be moved anywhere, so that the first item appended to it is [tail]. It
+// returns the previous tail number.
+func (f *Freezer) TruncateTail(tail uint64) (uint64, error) {
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	old, frozen := f.tail.Load(), f.frozen.Load()
+	if old == tail {
+		return old, nil
+	}
+	empty := frozen == old
+	if !empty {
+		if tail < old {
+			return old, nil
+		}
+		if tail > frozen {
+			return old, fmt.Errorf("truncating tail %d above head %d", tail, frozen)
+		}
+	}
+	// Persist the tail first, the items below it are hidden by the repair on
+	// open if discarding them is interrupted.
+	if err := f.writeTail(tail); err != nil {
+		return old, err
+	}
+	for _, table := range f.tables {
+		var err error
+		if empty {
+			err = table.resetTail(tail)
+		} else {
+			err = table.truncateTail(tail)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	f.tail.Store(tail)
+	if empty {
+		f.frozen.Store(tail)
+	}
+	return old, nil
+}
+
+// SyncAncient flushes all data tables to disk.
+func (f *Freezer) SyncAncient() error {
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// freezerBatch is a write operation of multiple items on a freezer.
+type freezerBatch struct {
+	tables map[string]*freezerTableBatch
+}
+
+func newFreezerBatch(f *Freezer) *freezerBatch {
+	batch := &freezerBatch{tables: make(map[string]*freezerTableBatch, len(f.tables))}
+	for kind, table := range f.tables {
+		batch.tables[kind] = table.newBatch()
+	}
+	return batch
+}
+
+// Append adds an RLP-encoded item of the given kind.
+func (batch *freezerBatch) Append(kind string, num uint64, item interface{}) error {
+	blob, err := rlp.EncodeToBytes(item)
+	if err != nil {
+		return err
+	}
+	return batch.AppendRaw(kind, num, blob)
+}
+
+// AppendRaw adds an item of the given kind.
+func (batch *freezerBatch) AppendRaw(kind string, num uint64, item []byte) error {
+	table := batch.tables[kind]
+	if table == nil {
+		return errUnknownTable
+	}
+	return table.appendItem(num, item)
+}
+
+// reset initializes the batch.
+func (batch *freezerBatch) reset() {
+	for _, tb := range batch.tables {
+		tb.reset()
+	}
+}
+
+// commit is called at the end of a write operation and
+// writes all remaining data to tables.
+func (batch *freezerBatch) commit() (item uint64, writeSize int64, err error) {
+	// Check that count agrees on all batches.
+	item = uint64(math.MaxUint64)
+	for name, tb := range batch.tables {
+		if item != math.MaxUint64 && tb.curItem != item {
+			return 0, 0, fmt.Errorf("table %s is at item %d, want %d", name, tb.curItem, item)
+		}
+		item = tb.curItem
+	}
+
+	// Commit all table batches.
+	for _, tb := range batch.tables {
+		if err := tb.commit(); err != nil {
+			return 0, 0, err
+		}
+		writeSize += tb.totalBytes
+	}
+	return item, writeSize, nil
+}
@@ -16,9 +16,679 @@
 
 package rawdb
 
-import "errors"
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/golang/snappy"
+)
 
 var (
+	// errClosed is returned if an operation attempts to read from or write to the
+	// freezer table after it has already been closed.
+	errClosed = errors.New("closed")
+
+	// errOutOfBounds is returned if the item requested is not contained within the
+	// freezer table.
+	errOutOfBounds = errors.New("out of bounds")
+
 	// errNotSupported is returned if the database doesn't support the required operation.
 	errNotSupported = errors.New("this operation is not supported")
 )
+
+// indexEntrySize is the size of an entry of the index file of a freezer table.
+const indexEntrySize = 6
+
+// freezerTableBatchLimit is the amount of data buffered by a table batch
+// before it is written to the files.
+const freezerTableBatchLimit = 2 * 1024 * 1024
+
+// indexEntry locates the end of an item in the data files of a freezer table:
+// the number of the file holding it and the offset following it. The first
+// entry of an index instead holds the number of the first data file and the
+// number of the first item stored in the table (the tail).
+type indexEntry struct {
+	filenum uint32 // stored as uint16 ( 2 bytes )
+	offset  uint32 // stored as uint32 ( 4 bytes )
+}
+
+// unmarshalBinary deserializes binary b into the index entry.
+func (i *indexEntry) unmarshalBinary(b []byte) {
+	i.filenum = uint32(binary.BigEndian.Uint16(b[:2]))
+	i.offset = binary.BigEndian.Uint32(b[2:6])
+}
+
+// append adds the encoded entry to the end of b.
+func (i *indexEntry) append(b []byte) []byte {
+	offset := len(b)
+	out := append(b, make([]byte, indexEntrySize)...)
+	binary.BigEndian.PutUint16(out[offset:], uint16(i.filenum))
+	binary.BigEndian.PutUint32(out[offset+2:], i.offset)
+	return out
+}
+
+// freezerTable is an append-only table of items stored in a set of data files
+// of bounded size, along with an index file locating every item. Items are
+// addressed by their number, the items before the tail are not accessible.
+// The items between the offset and the tail are hidden, they are deleted
+// along with the data file holding them once no item following the tail is
+// stored in it.
+type freezerTable struct {
+	items  atomic.Uint64 // Number of the next item to append (tail + stored items)
+	tail   atomic.Uint64 // Number of the first item accessible in the table
+	offset uint64        // Number of the first item stored in the table
+
+	name          string
+	path          string
+	noCompression bool   // if true, disables snappy compression
+	maxFileSize   uint32 // Max file size for data files
+
+	index     *os.File            // File descriptor for the index file of the table
+	files     map[uint32]*os.File // Open data files, by number
+	first     uint32              // Number of the first data file
+	headId    uint32              // Number of the data file being appended to
+	headBytes int64               // Number of bytes written to the head data file
+
+	lock sync.RWMutex // Mutex protecting the files and the head position
+}
+
+// newFreezerTable opens the given table, creating it if it does not exist,
+// and repairs any inconsistency between its index and data files left by an
+// interrupted write.
+func newFreezerTable(path, name string, noCompression bool, maxFileSize uint32) (*freezerTable, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	idxName := fmt.Sprintf("%s.cidx", name)
+	if noCompression {
+		idxName = fmt.Sprintf("%s.ridx", name)
+	}
+	index, err := os.OpenFile(filepath.Join(path, idxName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	t := &freezerTable{
+		name:          name,
+		path:          path,
+		noCompression: noCompression,
+		maxFileSize:   maxFileSize,
+		index:         index,
+		files:         make(map[uint32]*os.File),
+	}
+	if err := t.repair(); err != nil {
+		t.close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// repair truncates the index and the head data file to the last item fully
+// written to both, and removes the data files past it.
+func (t *freezerTable) repair() error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	size := stat.Size()
+	if size < indexEntrySize {
+		// Initialize an empty table starting at item 0.
+		if err := t.index.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := t.index.WriteAt(new(indexEntry).append(nil), 0); err != nil {
+			return err
+		}
+		size = indexEntrySize
+	}
+	// Drop a partially written index entry.
+	size -= size % indexEntrySize
+	if err := t.index.Truncate(size); err != nil {
+		return err
+	}
+	var first indexEntry
+	if err := t.readEntry(0, &first); err != nil {
+		return err
+	}
+	t.first = first.filenum
+	t.offset = uint64(first.offset)
+	t.tail.Store(t.offset)
+
+	// Drop the index entries of the items missing from the data files, then
+	// the data following the last item.
+	for {
+		last, err := t.lastEntry(size)
+		if err != nil {
+			return err
+		}
+		head, err := t.openFile(last.filenum, os.O_RDWR|os.O_CREATE)
+		if err != nil {
+			return err
+		}
+		stat, err := head.Stat()
+		if err != nil {
+			return err
+		}
+		if stat.Size() < int64(last.offset) {
+			size -= indexEntrySize
+			if err := t.index.Truncate(size); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := head.Truncate(int64(last.offset)); err != nil {
+			return err
+		}
+		t.headId, t.headBytes = last.filenum, int64(last.offset)
+		break
+	}
+	if err := t.removeFiles(t.headId + 1); err != nil {
+		return err
+	}
+	// Drop the data files left behind by an interrupted tail truncation.
+	for num := t.first; num > 0; num-- {
+		err := os.Remove(filepath.Join(t.path, t.fileName(num-1)))
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for num := t.first; num < t.headId; num++ {
+		if _, err := t.openFile(num, os.O_RDWR); err != nil {
+			return err
+		}
+	}
+	t.items.Store(t.offset + uint64(size/indexEntrySize) - 1)
+	return nil
+}
+
+// readEntry reads the index entry at the given position of the index file.
+func (t *freezerTable) readEntry(pos uint64, entry *indexEntry) error {
+	buf := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buf, int64(pos*indexEntrySize)); err != nil {
+		return err
+	}
+	entry.unmarshalBinary(buf)
+	return nil
+}
+
+// lastEntry returns the position following the last item of an index file of
+// the given size.
+func (t *freezerTable) lastEntry(size int64) (indexEntry, error) {
+	if size == indexEntrySize {
+		return indexEntry{filenum: t.first}, nil
+	}
+	var last indexEntry
+	err := t.readEntry(uint64(size/indexEntrySize)-1, &last)
+	return last, err
+}
+
+// fileName returns the name of the data file with the given number.
+func (t *freezerTable) fileName(num uint32) string {
+	if t.noCompression {
+		return fmt.Sprintf("%s.%04d.rdat", t.name, num)
+	}
+	return fmt.Sprintf("%s.%04d.cdat", t.name, num)
+}
+
+// openFile returns the data file with the given number, opening it if needed.
+func (t *freezerTable) openFile(num uint32, flag int) (*os.File, error) {
+	if f, ok := t.files[num]; ok {
+		return f, nil
+	}
+	f, err := os.OpenFile(filepath.Join(t.path, t.fileName(num)), flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	t.files[num] = f
+	return f, nil
+}
+
+// removeFiles closes and deletes the data files from the given number on.
+func (t *freezerTable) removeFiles(from uint32) error {
+	for num := from; ; num++ {
+		if f, ok := t.files[num]; ok {
+			f.Close()
+			delete(t.files, num)
+		}
+		err := os.Remove(filepath.Join(t.path, t.fileName(num)))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// retrieve looks up the data of the given item.
+func (t *freezerTable) retrieve(item uint64) ([]byte, error) {
+	items, err := t.retrieveItems(item, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+// retrieveItems returns at most [count] items starting from [start]. If
+// [maxBytes] is not zero, the items following the first one are only returned
+// as long as their accumulated size does not exceed it.
+func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([][]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil {
+		return nil, errClosed
+	}
+	items, tail := t.items.Load(), t.tail.Load()
+	if start < tail || start >= items || count == 0 {
+		return nil, errOutOfBounds
+	}
+	count = min(count, items-start)
+
+	var (
+		output = make([][]byte, 0, count)
+		size   uint64
+		prev   indexEntry
+	)
+	if start == t.offset {
+		prev = indexEntry{filenum: t.first}
+	} else if err := t.readEntry(start-t.offset, &prev); err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < count; i++ {
+		var next indexEntry
+		if err := t.readEntry(start-t.offset+i+1, &next); err != nil {
+			return nil, err
+		}
+		// An item starts at the beginning of a new data file if it did not fit
+		// into the previous one.
+		from := prev.offset
+		if prev.filenum != next.filenum {
+			from = 0
+		}
+		if maxBytes != 0 && i > 0 && size+uint64(next.offset-from) > maxBytes {
+			break
+		}
+		f, ok := t.files[next.filenum]
+		if !ok {
+			return nil, fmt.Errorf("missing data file %d", next.filenum)
+		}
+		blob := make([]byte, next.offset-from)
+		if _, err := f.ReadAt(blob, int64(from)); err != nil {
+			return nil, err
+		}
+		size += uint64(len(blob))
+		if !t.noCompression {
+			var err error
+			if blob, err = snappy.Decode(nil, blob); err != nil {
+				return nil, err
+			}
+		}
+		output = append(output, blob)
+		prev = next
+	}
+	return output, nil
+}
+
+// truncateHead discards any recent data above the provided threshold number.
+func (t *freezerTable) truncateHead(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	if items >= t.items.Load() {
+		return nil
+	}
+	tail := t.tail.Load()
+	if items < tail {
+		return fmt.Errorf("truncating head %d below tail %d", items, tail)
+	}
+	size := int64(items-t.offset+1) * indexEntrySize
+	last, err := t.lastEntry(size)
+	if err != nil {
+		return err
+	}
+	if err := t.index.Truncate(size); err != nil {
+		return err
+	}
+	if err := t.removeFiles(last.filenum + 1); err != nil {
+		return err
+	}
+	head, err := t.openFile(last.filenum, os.O_RDWR|os.O_CREATE)
+	if err != nil {
+		return err
+	}
+	if err := head.Truncate(int64(last.offset)); err != nil {
+		return err
+	}
+	t.headId, t.headBytes = last.filenum, int64(last.offset)
+	t.items.Store(items)
+	return nil
+}
+
+// resetTail discards all the items of the table and moves its tail to [tail],
+// so that the first item appended to it is [tail].
+func (t *freezerTable) resetTail(tail uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	if tail > math.MaxUint32 {
+		return fmt.Errorf("tail %d out of range", tail)
+	}
+	first := indexEntry{filenum: t.headId, offset: uint32(tail)}
+	if _, err := t.index.WriteAt(first.append(nil), 0); err != nil {
+		return err
+	}
+	if err := t.index.Truncate(indexEntrySize); err != nil {
+		return err
+	}
+	if err := t.files[t.headId].Truncate(0); err != nil {
+		return err
+	}
+	if err := t.removeFilesBelow(t.headId); err != nil {
+		return err
+	}
+	t.first, t.offset, t.headBytes = t.headId, tail, 0
+	t.tail.Store(tail)
+	t.items.Store(tail)
+	return nil
+}
+
+// truncateTail discards the items below [tail]. The items are hidden at
+// first, the data files only holding discarded items are deleted along with
+// their index entries.
+func (t *freezerTable) truncateTail(tail uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	items := t.items.Load()
+	if tail <= t.tail.Load() {
+		return nil
+	}
+	if tail > items {
+		return fmt.Errorf("truncating tail %d above head %d", tail, items)
+	}
+	t.tail.Store(tail)
+
+	// Find the data file holding the new tail item, and the first item stored
+	// in it, which is the first item kept.
+	filenum := t.headId
+	if tail < items {
+		var entry indexEntry
+		if err := t.readEntry(tail-t.offset+1, &entry); err != nil {
+			return err
+		}
+		filenum = entry.filenum
+	}
+	if filenum == t.first {
+		return nil
+	}
+	offset := tail
+	for offset > t.offset {
+		var entry indexEntry
+		if err := t.readEntry(offset-t.offset, &entry); err != nil {
+			return err
+		}
+		if entry.filenum != filenum {
+			break
+		}
+		offset--
+	}
+	if offset > math.MaxUint32 {
+		return fmt.Errorf("tail %d out of range", offset)
+	}
+	// Replace the index with one starting at the first item kept, the data
+	// files preceding it are deleted afterwards.
+	first := indexEntry{filenum: filenum, offset: uint32(offset)}
+	blob := make([]byte, (items-offset+1)*indexEntrySize)
+	first.append(blob[:0])
+	if _, err := t.index.ReadAt(blob[indexEntrySize:], int64(offset-t.offset+1)*indexEntrySize); err != nil {
+		return err
+	}
+	name := t.index.Name()
+	if err := writeFileSync(name+tmpSuffix, blob); err != nil {
+		return err
+	}
+	if err := t.index.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(name+tmpSuffix, name); err != nil {
+		return err
+	}
+	index, err := os.OpenFile(name, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	t.index = index
+	if err := t.removeFilesBelow(filenum); err != nil {
+		return err
+	}
+	t.first, t.offset = filenum, offset
+	return nil
+}
+
+// removeFilesBelow closes and deletes the data files from the first one up to
+// the given number.
+func (t *freezerTable) removeFilesBelow(to uint32) error {
+	for num := t.first; num < to; num++ {
+		if f, ok := t.files[num]; ok {
+			f.Close()
+			delete(t.files, num)
+		}
+		err := os.Remove(filepath.Join(t.path, t.fileName(num)))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFileSync writes [data] to the named file and flushes it to disk.
+func writeFileSync(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// size returns the total data size in the freezer table.
+func (t *freezerTable) size() (uint64, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil {
+		return 0, errClosed
+	}
+	stat, err := t.index.Stat()
+	if err != nil {
+		return 0, err
+	}
+	total := uint64(stat.Size())
+	for num := t.first; num <= t.headId; num++ {
+		f, ok := t.files[num]
+		if !ok {
+			continue
+		}
+		stat, err := f.Stat()
+		if err != nil {
+			return 0, err
+		}
+		total += uint64(stat.Size())
+	}
+	return total, nil
+}
+
+// sync pushes any pending data from memory out to disk.
+func (t *freezerTable) sync() error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	if err := t.index.Sync(); err != nil {
+		return err
+	}
+	return t.files[t.headId].Sync()
+}
+
+// close closes all opened files.
+func (t *freezerTable) close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errs []error
+	if t.index != nil {
+		if err := t.index.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		t.index = nil
+	}
+	for num, f := range t.files {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(t.files, num)
+	}
+	return errors.Join(errs...)
+}
+
+// freezerTableBatch is a batch of items appended to a freezer table. The
+// items are buffered and written to the files when the buffer is full or the
+// batch is committed.
+type freezerTableBatch struct {
+	t *freezerTable
+
+	dataBuffer  []byte
+	indexBuffer []byte
+	curItem     uint64 // expected number of the next item appended
+	totalBytes  int64  // amount of data written to the files
+}
+
+// newBatch creates a new batch for the freezer table.
+func (t *freezerTable) newBatch() *freezerTableBatch {
+	batch := &freezerTableBatch{t: t}
+	batch.reset()
+	return batch
+}
+
+// reset clears the batch for reuse.
+func (batch *freezerTableBatch) reset() {
+	batch.dataBuffer = batch.dataBuffer[:0]
+	batch.indexBuffer = batch.indexBuffer[:0]
+	batch.curItem = batch.t.items.Load()
+	batch.totalBytes = 0
+}
+
+// appendItem adds an item to the batch, which must be the next one of the
+// table.
+func (batch *freezerTableBatch) appendItem(item uint64, blob []byte) error {
+	if item != batch.curItem {
+		return fmt.Errorf("%w: have %d want %d", errOutOrderInsertion, item, batch.curItem)
+	}
+	if !batch.t.noCompression {
+		blob = snappy.Encode(nil, blob)
+	}
+	if uint64(len(blob)) > uint64(batch.t.maxFileSize) {
+		return fmt.Errorf("item %d of %d bytes exceeds the file size limit", item, len(blob))
+	}
+	// Start a new data file if the item does not fit into the head one.
+	if batch.t.headBytes+int64(len(batch.dataBuffer)+len(blob)) > int64(batch.t.maxFileSize) {
+		if err := batch.commit(); err != nil {
+			return err
+		}
+		if err := batch.t.advanceHead(); err != nil {
+			return err
+		}
+	}
+	batch.dataBuffer = append(batch.dataBuffer, blob...)
+	entry := indexEntry{
+		filenum: batch.t.headId,
+		offset:  uint32(batch.t.headBytes + int64(len(batch.dataBuffer))),
+	}
+	batch.indexBuffer = entry.append(batch.indexBuffer)
+	batch.curItem++
+
+	if len(batch.dataBuffer) > freezerTableBatchLimit {
+		return batch.commit()
+	}
+	return nil
+}
+
+// commit writes the buffered items to the files. The data is written before
+// the index, so that an interrupted commit is undone by the repair on open.
+func (batch *freezerTableBatch) commit() error {
+	t := batch.t
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	if len(batch.indexBuffer) == 0 {
+		return nil
+	}
+	if _, err := t.files[t.headId].WriteAt(batch.dataBuffer, t.headBytes); err != nil {
+		return err
+	}
+	indexOffset := int64(t.items.Load()-t.offset+1) * indexEntrySize
+	if _, err := t.index.WriteAt(batch.indexBuffer, indexOffset); err != nil {
+		return err
+	}
+	t.headBytes += int64(len(batch.dataBuffer))
+	t.items.Add(uint64(len(batch.indexBuffer) / indexEntrySize))
+	batch.totalBytes += int64(len(batch.dataBuffer))
+
+	batch.dataBuffer = batch.dataBuffer[:0]
+	batch.indexBuffer = batch.indexBuffer[:0]
+	return nil
+}
+
+// advanceHead starts appending to a new data file.
+func (t *freezerTable) advanceHead() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	if t.headId+1 > math.MaxUint16 {
+		return errors.New("too many data files")
+	}
+	if err := t.files[t.headId].Sync(); err != nil {
+		return err
+	}
+	head, err := os.OpenFile(filepath.Join(t.path, t.fileName(t.headId+1)), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	t.headId++
+	t.files[t.headId] = head
+	t.headBytes = 0
+	return nil
+}
@@ -66,6 +66,43 @@ var (
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// governanceIndexTailKey and governanceIndexHeadKey track the oldest and the
+	// latest accepted block whose governance changes have been indexed.
+	governanceIndexTailKey = []byte("GovernanceIndexTail")
+	governanceIndexHeadKey = []byte("GovernanceIndexHead")
+
+	// traceIndexTailKey and traceIndexHeadKey track the oldest and the latest
+	// accepted block whose flat call traces have been indexed.
+	traceIndexTailKey = []byte("TraceIndexTail")
+	traceIndexHeadKey = []byte("TraceIndexHead")
+
+	// logIndexTailKey and logIndexHeadKey track the oldest and the latest
+	// accepted block whose logs have been indexed.
+	logIndexTailKey = []byte("LogIndexTail")
+	logIndexHeadKey = []byte("LogIndexHead")
+
+	// onlinePruningProgressKey tracks the last key swept by the running online
+	// pruning session.
+	onlinePruningProgressKey = []byte("OnlinePruningProgress")
+
+	// stateSchemeMigrationKey tracks the root of the state being migrated to
+	// the path-based scheme, and stateSchemeMigrationProgressKey the progress
+	// of the migration.
+	stateSchemeMigrationKey         = []byte("StateSchemeMigration")
+	stateSchemeMigrationProgressKey = []byte("StateSchemeMigrationProgress")
+
+	// stateArchiveTailKey and stateArchiveHeadKey track the oldest and the
+	// latest accepted block whose state can be read from the state archive,
+	// and stateArchiveBaseProgressKey the progress of the copy of the state
+	// the archive starts from.
+	stateArchiveTailKey         = []byte("StateArchiveTail")
+	stateArchiveHeadKey         = []byte("StateArchiveHead")
+	stateArchiveBaseProgressKey = []byte("StateArchiveBaseProgress")
+
+	// chainImportProgressKey tracks the manifest of the chain archive being
+	// imported and the next chunk to import from it.
+	chainImportProgressKey = []byte("ChainImportProgress")
+
 	// uncleanShutdownKey tracks the list of local crashes
 	uncleanShutdownKey = []byte("unclean-shutdown") // config prefix for the db
 
@@ -89,6 +126,19 @@ var (
 
 	blockBodyPrefix     = []byte("b") // blockBodyPrefix + num (uint64 big endian) + hash -> block body
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
+	daemonResultsPrefix = []byte("d") // daemonResultsPrefix + num (uint64 big endian) + hash -> block daemon results
+	feeTreatmentsPrefix = []byte("f") // feeTreatmentsPrefix + num (uint64 big endian) + hash -> block fee treatments
+
+	attestationDivergencesPrefix = []byte("v") // attestationDivergencesPrefix + num (uint64 big endian) + hash -> block attestation divergences
+	governanceChangesPrefix      = []byte("g") // governanceChangesPrefix + num (uint64 big endian) -> accepted block governance changes
+	blockTracesPrefix            = []byte("X") // blockTracesPrefix + num (uint64 big endian) -> accepted block compressed flat traces
+	traceAddressIndexPrefix      = []byte("x") // traceAddressIndexPrefix + address + role + num (uint64 big endian) + tx index (uint32 big endian) + trace index (uint32 big endian) -> nil
+	logIndexPrefix               = []byte("q") // logIndexPrefix + field + value (32 bytes) + num (uint64 big endian) + tx index (uint32 big endian) + log index (uint32 big endian) -> nil
+	onlinePruningMarkPrefix      = []byte("P") // onlinePruningMarkPrefix + hash -> nil, trie nodes written during an online pruning session
+	stateArchiveAccountPrefix    = []byte("Y") // stateArchiveAccountPrefix + account hash + ^num (uint64 big endian) -> account written at num, empty if deleted
+	stateArchiveStoragePrefix    = []byte("y") // stateArchiveStoragePrefix + account hash + storage hash + ^num (uint64 big endian) -> slot written at num, empty if deleted
+	stateArchiveDestructPrefix   = []byte("Z") // stateArchiveDestructPrefix + account hash + ^num (uint64 big endian) -> nil, storage of the account wiped at num
+	stateArchiveDiffPrefix       = []byte("z") // stateArchiveDiffPrefix + num (uint64 big endian) + hash -> state diff of a block not archived yet
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
@@ -171,6 +221,84 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// daemonResultsKey = daemonResultsPrefix + num (uint64 big endian) + hash
+func daemonResultsKey(number uint64, hash common.Hash) []byte {
+	return append(append(daemonResultsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// feeTreatmentsKey = feeTreatmentsPrefix + num (uint64 big endian) + hash
+func feeTreatmentsKey(number uint64, hash common.Hash) []byte {
+	return append(append(feeTreatmentsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// attestationDivergencesKey = attestationDivergencesPrefix + num (uint64 big endian) + hash
+func attestationDivergencesKey(number uint64, hash common.Hash) []byte {
+	return append(append(attestationDivergencesPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// governanceChangesKey = governanceChangesPrefix + num (uint64 big endian)
+func governanceChangesKey(number uint64) []byte {
+	return append(governanceChangesPrefix, encodeBlockNumber(number)...)
+}
+
+// blockTracesKey = blockTracesPrefix + num (uint64 big endian)
+func blockTracesKey(number uint64) []byte {
+	return append(blockTracesPrefix, encodeBlockNumber(number)...)
+}
+
+// traceAddressIndexKey = traceAddressIndexPrefix + address + role + num (uint64 big endian) + tx index (uint32 big endian) + trace index (uint32 big endian)
+func traceAddressIndexKey(addr common.Address, role TraceRole, pos TracePosition) []byte {
+	key := make([]byte, traceAddressIndexKeyLength)
+	copy(key, traceAddressIndexPrefix)
+	copy(key[len(traceAddressIndexPrefix):], addr.Bytes())
+	key[len(traceAddressIndexPrefix)+common.AddressLength] = byte(role)
+	binary.BigEndian.PutUint64(key[len(traceAddressIndexPrefix)+common.AddressLength+1:], pos.Number)
+	binary.BigEndian.PutUint32(key[len(traceAddressIndexPrefix)+common.AddressLength+9:], pos.TxIndex)
+	binary.BigEndian.PutUint32(key[len(traceAddressIndexPrefix)+common.AddressLength+13:], pos.TraceIndex)
+	return key
+}
+
+// logIndexKey = logIndexPrefix + field + value (32 bytes) + num (uint64 big endian) + tx index (uint32 big endian) + log index (uint32 big endian)
+func logIndexKey(field LogField, value common.Hash, pos LogPosition) []byte {
+	key := make([]byte, logIndexKeyLength)
+	copy(key, logIndexPrefix)
+	key[len(logIndexPrefix)] = byte(field)
+	copy(key[len(logIndexPrefix)+1:], value.Bytes())
+	binary.BigEndian.PutUint64(key[len(logIndexPrefix)+1+common.HashLength:], pos.Number)
+	binary.BigEndian.PutUint32(key[len(logIndexPrefix)+9+common.HashLength:], pos.TxIndex)
+	binary.BigEndian.PutUint32(key[len(logIndexPrefix)+13+common.HashLength:], pos.LogIndex)
+	return key
+}
+
+// stateArchiveAccountKey = stateArchiveAccountPrefix + account hash + ^num (uint64 big endian)
+func stateArchiveAccountKey(accountHash common.Hash, number uint64) []byte {
+	key := append(append([]byte{}, stateArchiveAccountPrefix...), accountHash.Bytes()...)
+	return append(key, encodeBlockNumber(^number)...)
+}
+
+// stateArchiveStorageKey = stateArchiveStoragePrefix + account hash + storage hash + ^num (uint64 big endian)
+func stateArchiveStorageKey(accountHash, storageHash common.Hash, number uint64) []byte {
+	key := append(append([]byte{}, stateArchiveStoragePrefix...), accountHash.Bytes()...)
+	key = append(key, storageHash.Bytes()...)
+	return append(key, encodeBlockNumber(^number)...)
+}
+
+// stateArchiveDestructKey = stateArchiveDestructPrefix + account hash + ^num (uint64 big endian)
+func stateArchiveDestructKey(accountHash common.Hash, number uint64) []byte {
+	key := append(append([]byte{}, stateArchiveDestructPrefix...), accountHash.Bytes()...)
+	return append(key, encodeBlockNumber(^number)...)
+}
+
+// stateArchiveDiffKey = stateArchiveDiffPrefix + num (uint64 big endian) + hash
+func stateArchiveDiffKey(number uint64, hash common.Hash) []byte {
+	return append(append(append([]byte{}, stateArchiveDiffPrefix...), encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// onlinePruningMarkKey = onlinePruningMarkPrefix + hash
+func onlinePruningMarkKey(hash common.Hash) []byte {
+	return append(onlinePruningMarkPrefix, hash.Bytes()...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
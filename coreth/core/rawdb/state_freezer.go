@@ -0,0 +1,35 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rawdb
+
+import "github.com/ethereum/go-ethereum/ethdb"
+
+const (
+	// stateHistoryTableSize defines the maximum size of freezer data files.
+	stateHistoryTableSize = 2 * 1000 * 1000 * 1000
+
+	// stateHistoryMeta indicates the name of the freezer state history table.
+	stateHistoryMeta         = "history.meta"
+	stateHistoryAccountIndex = "account.index"
+	stateHistoryStorageIndex = "storage.index"
+	stateHistoryAccountData  = "account.data"
+	stateHistoryStorageData  = "storage.data"
+)
+
+// stateFreezerNoSnappy configures whether compression is disabled for the state
+// freezer.
+var stateFreezerNoSnappy = map[string]bool{
+	stateHistoryMeta:         true,
+	stateHistoryAccountIndex: false,
+	stateHistoryStorageIndex: false,
+	stateHistoryAccountData:  false,
+	stateHistoryStorageData:  false,
+}
+
+// NewStateFreezer opens the ancient store of the state histories of the
+// path-based scheme in [datadir], which must be exclusively occupied by it so
+// that the store can be reset.
+func NewStateFreezer(datadir string) (ethdb.ResettableAncientStore, error) {
+	return newResettableFreezer(datadir, stateHistoryTableSize, stateFreezerNoSnappy)
+}
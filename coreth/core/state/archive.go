@@ -0,0 +1,179 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/trie"
+	"github.com/ava-labs/coreth/trie/trienode"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// errArchiveUnsupported is returned by the operations of the historical state
+// that require the trie nodes, which are not kept by the state archive.
+var errArchiveUnsupported = errors.New("not supported by archived state")
+
+// archiveDatabase is a Database reading the state at a block from the state
+// archive instead of the tries, the contract codes are read from the wrapped
+// database. The state can be modified in memory, e.g. to execute calls and
+// transactions on top of it, but it cannot be committed.
+type archiveDatabase struct {
+	Database
+	number uint64
+}
+
+// NewArchiveDatabase creates a Database reading the state at the block at the
+// given height from the state archive of the disk database of [db]. The height
+// must be in the range of the archive.
+func NewArchiveDatabase(db Database, number uint64) Database {
+	return &archiveDatabase{Database: db, number: number}
+}
+
+// OpenTrie opens the accounts at the block, [root] is returned as the hash of
+// the trie.
+func (db *archiveDatabase) OpenTrie(root common.Hash) (Trie, error) {
+	return &archiveTrie{
+		db:       db,
+		root:     root,
+		accounts: make(map[common.Address]*types.StateAccount),
+	}, nil
+}
+
+// OpenStorageTrie opens the storage of the account at the block, [root] is
+// returned as the hash of the trie.
+func (db *archiveDatabase) OpenStorageTrie(stateRoot common.Hash, address common.Address, root common.Hash, self Trie) (Trie, error) {
+	return &archiveTrie{
+		db:      db,
+		root:    root,
+		owner:   crypto.Keccak256Hash(address.Bytes()),
+		storage: make(map[common.Hash][]byte),
+	}, nil
+}
+
+// CopyTrie returns an independent copy of the given trie.
+func (db *archiveDatabase) CopyTrie(t Trie) Trie {
+	switch t := t.(type) {
+	case *archiveTrie:
+		return t.copy()
+	default:
+		panic(fmt.Errorf("unknown trie type %T", t))
+	}
+}
+
+// archiveTrie is the Trie of the accounts, or of the storage of an account, at
+// a block of the state archive. The updates are kept in memory on top of it.
+type archiveTrie struct {
+	db    *archiveDatabase
+	root  common.Hash
+	owner common.Hash // Hash of the account for a storage trie
+
+	accounts map[common.Address]*types.StateAccount // Updated accounts, nil if deleted
+	storage  map[common.Hash][]byte                 // Updated slots by hash, nil if deleted
+}
+
+// GetKey returns nil, the preimages are not tracked by the archive.
+func (t *archiveTrie) GetKey([]byte) []byte {
+	return nil
+}
+
+// GetAccount returns the account at the block, nil if it does not exist.
+func (t *archiveTrie) GetAccount(address common.Address) (*types.StateAccount, error) {
+	if account, ok := t.accounts[address]; ok {
+		return account, nil
+	}
+	blob := rawdb.ReadStateArchiveAccount(t.db.DiskDB(), crypto.Keccak256Hash(address.Bytes()), t.db.number)
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	return types.FullAccount(blob)
+}
+
+// GetStorage returns the value of the slot at the block, nil if it does not
+// exist.
+func (t *archiveTrie) GetStorage(addr common.Address, key []byte) ([]byte, error) {
+	hash := crypto.Keccak256Hash(key)
+	if value, ok := t.storage[hash]; ok {
+		return value, nil
+	}
+	enc := rawdb.ReadStateArchiveStorage(t.db.DiskDB(), t.owner, hash, t.db.number)
+	if len(enc) == 0 {
+		return nil, nil
+	}
+	_, content, _, err := rlp.Split(enc)
+	return content, err
+}
+
+// UpdateAccount updates the account in memory.
+func (t *archiveTrie) UpdateAccount(address common.Address, account *types.StateAccount) error {
+	t.accounts[address] = account.Copy()
+	return nil
+}
+
+// UpdateStorage updates the slot in memory.
+func (t *archiveTrie) UpdateStorage(addr common.Address, key, value []byte) error {
+	t.storage[crypto.Keccak256Hash(key)] = common.CopyBytes(value)
+	return nil
+}
+
+// DeleteAccount deletes the account in memory.
+func (t *archiveTrie) DeleteAccount(address common.Address) error {
+	t.accounts[address] = nil
+	return nil
+}
+
+// DeleteStorage deletes the slot in memory.
+func (t *archiveTrie) DeleteStorage(addr common.Address, key []byte) error {
+	t.storage[crypto.Keccak256Hash(key)] = nil
+	return nil
+}
+
+// UpdateContractCode does nothing, the codes are kept by the state object.
+func (t *archiveTrie) UpdateContractCode(address common.Address, codeHash common.Hash, code []byte) error {
+	return nil
+}
+
+// Hash returns the root the trie was opened with, it does not reflect the
+// updates.
+func (t *archiveTrie) Hash() common.Hash {
+	return t.root
+}
+
+// Commit is not supported, the archived state is read-only.
+func (t *archiveTrie) Commit(collectLeaf bool) (common.Hash, *trienode.NodeSet, error) {
+	return common.Hash{}, nil, errArchiveUnsupported
+}
+
+// NodeIterator is not supported, the trie nodes are not archived.
+func (t *archiveTrie) NodeIterator(startKey []byte) (trie.NodeIterator, error) {
+	return nil, errArchiveUnsupported
+}
+
+// Prove is not supported, the trie nodes are not archived.
+func (t *archiveTrie) Prove(key []byte, proofDb ethdb.KeyValueWriter) error {
+	return errArchiveUnsupported
+}
+
+func (t *archiveTrie) copy() *archiveTrie {
+	cpy := &archiveTrie{db: t.db, root: t.root, owner: t.owner}
+	if t.accounts != nil {
+		cpy.accounts = make(map[common.Address]*types.StateAccount, len(t.accounts))
+		for addr, account := range t.accounts {
+			cpy.accounts[addr] = account
+		}
+	}
+	if t.storage != nil {
+		cpy.storage = make(map[common.Hash][]byte, len(t.storage))
+		for hash, value := range t.storage {
+			cpy.storage[hash] = value
+		}
+	}
+	return cpy
+}
@@ -0,0 +1,233 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package pruner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/trie"
+	"github.com/ava-labs/coreth/triedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The phases of a state scheme migration, the progress stored in the database
+// starts with one of them, followed by the last account copied or the last key
+// deleted.
+const (
+	migrationCopying  byte = 'c'
+	migrationDeleting byte = 'd'
+)
+
+// MigrateToPathScheme converts the hash-based state with the given root to the
+// path-based scheme. The trie nodes of the state are copied under their paths,
+// the root node last so that the database is only recognized as path-based once
+// the copy is complete, then all the hash-based trie nodes are deleted. An
+// interrupted migration is resumed by RecoverStateSchemeMigration.
+func MigrateToPathScheme(db ethdb.Database, root common.Hash) error {
+	if rawdb.ReadStateScheme(db) == rawdb.PathScheme {
+		return errors.New("state is already stored with the path-based scheme")
+	}
+	if !rawdb.HasLegacyTrieNode(db, root) {
+		return fmt.Errorf("state %x is not stored", root)
+	}
+	batch := db.NewBatch()
+	rawdb.WriteStateSchemeMigration(batch, root)
+	rawdb.WriteStateSchemeMigrationProgress(batch, []byte{migrationCopying})
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	return migrate(db, root)
+}
+
+// RecoverStateSchemeMigration resumes the state scheme migration interrupted
+// in a previous run, if any.
+func RecoverStateSchemeMigration(db ethdb.Database) error {
+	root := rawdb.ReadStateSchemeMigration(db)
+	if root == (common.Hash{}) {
+		return nil
+	}
+	log.Info("Resuming state scheme migration", "root", root)
+	return migrate(db, root)
+}
+
+// migrate runs the state scheme migration of [root] from its stored progress.
+func migrate(db ethdb.Database, root common.Hash) error {
+	var (
+		start    = time.Now()
+		progress = rawdb.ReadStateSchemeMigrationProgress(db)
+	)
+	if len(progress) == 0 || progress[0] == migrationCopying {
+		var cursor []byte
+		if len(progress) > 1 {
+			cursor = progress[1:]
+		}
+		if err := copyToPathScheme(db, root, cursor); err != nil {
+			return fmt.Errorf("failed to copy state %x: %w", root, err)
+		}
+		progress = []byte{migrationDeleting}
+	}
+	count, err := deleteHashScheme(db, progress[1:])
+	if err != nil {
+		return fmt.Errorf("failed to delete hash-based state: %w", err)
+	}
+	rawdb.DeleteStateSchemeMigration(db)
+	log.Info("Migrated state to the path-based scheme", "root", root, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	if count >= rangeCompactionThreshold {
+		return compactDatabase(db)
+	}
+	return nil
+}
+
+// copyToPathScheme writes the trie nodes of the state with the given root under
+// their paths, starting from the account [cursor].
+func copyToPathScheme(db ethdb.Database, root common.Hash, cursor []byte) error {
+	var (
+		tdb    = triedb.NewDatabase(db, triedb.HashDefaults)
+		batch  = db.NewBatch()
+		nodes  int
+		logged = time.Now()
+	)
+	rootBlob := rawdb.ReadLegacyTrieNode(db, root)
+	if len(rootBlob) == 0 {
+		return fmt.Errorf("missing root node %x", root)
+	}
+	accTrie, err := trie.NewStateTrie(trie.StateTrieID(root), tdb)
+	if err != nil {
+		return err
+	}
+	accIter, err := accTrie.NodeIterator(cursor)
+	if err != nil {
+		return err
+	}
+	for accIter.Next(true) {
+		// Embedded nodes are stored within their parent, the root node is
+		// written once all the other nodes are.
+		if hash, path := accIter.Hash(), accIter.Path(); hash != (common.Hash{}) && len(path) > 0 {
+			rawdb.WriteAccountTrieNode(batch, path, accIter.NodeBlob())
+			nodes++
+		}
+		if !accIter.Leaf() {
+			continue
+		}
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(accIter.LeafBlob(), &acc); err != nil {
+			return err
+		}
+		key := common.CopyBytes(accIter.LeafKey())
+		if acc.Root != types.EmptyRootHash {
+			owner := common.BytesToHash(key)
+			storageTrie, err := trie.NewStateTrie(trie.StorageTrieID(root, owner, acc.Root), tdb)
+			if err != nil {
+				return err
+			}
+			storageIter, err := storageTrie.NodeIterator(nil)
+			if err != nil {
+				return err
+			}
+			for storageIter.Next(true) {
+				if storageIter.Hash() == (common.Hash{}) {
+					continue
+				}
+				rawdb.WriteStorageTrieNode(batch, owner, storageIter.Path(), storageIter.NodeBlob())
+				nodes++
+
+				// The progress is only advanced once the whole storage trie
+				// is copied, the nodes written before are copied again.
+				if batch.ValueSize() >= ethdb.IdealBatchSize {
+					if err := batch.Write(); err != nil {
+						return err
+					}
+					batch.Reset()
+				}
+			}
+			if err := storageIter.Error(); err != nil {
+				return err
+			}
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			rawdb.WriteStateSchemeMigrationProgress(batch, append([]byte{migrationCopying}, key...))
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Copying state to the path-based scheme", "nodes", nodes, "at", common.BytesToHash(key))
+			logged = time.Now()
+		}
+	}
+	if err := accIter.Error(); err != nil {
+		return err
+	}
+	rawdb.WriteAccountTrieNode(batch, nil, rootBlob)
+	rawdb.WritePersistentStateID(batch, 0)
+	rawdb.WriteStateSchemeMigrationProgress(batch, []byte{migrationDeleting})
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Copied state to the path-based scheme", "nodes", nodes)
+	return nil
+}
+
+// deleteHashScheme deletes the hash-based trie nodes from the key [cursor] on,
+// and returns the number of deleted nodes.
+func deleteHashScheme(db ethdb.Database, cursor []byte) (int, error) {
+	var (
+		count  int
+		size   common.StorageSize
+		logged = time.Now()
+		batch  = db.NewBatch()
+		iter   = db.NewIterator(nil, cursor)
+	)
+	defer func() {
+		iter.Release()
+	}()
+
+	for iter.Next() {
+		// The keys of the path-based trie nodes may have the length of a hash
+		// as well, the hash of the value tells them apart.
+		key := iter.Key()
+		if len(key) != common.HashLength || crypto.Keccak256Hash(iter.Value()) != common.BytesToHash(key) {
+			continue
+		}
+		count++
+		size += common.StorageSize(len(key) + len(iter.Value()))
+		if err := batch.Delete(key); err != nil {
+			return 0, err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			rawdb.WriteStateSchemeMigrationProgress(batch, append([]byte{migrationDeleting}, key...))
+			if err := batch.Write(); err != nil {
+				return 0, err
+			}
+			batch.Reset()
+
+			// Recreate the iterator after every batch commit in order
+			// to allow the underlying compactor to delete the entries.
+			iter.Release()
+			iter = db.NewIterator(nil, key)
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Deleting hash-based state", "nodes", count, "size", size)
+			logged = time.Now()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return 0, err
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	log.Info("Deleted hash-based state", "nodes", count, "size", size)
+	return count, nil
+}
@@ -0,0 +1,87 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/triedb"
+	"github.com/ava-labs/coreth/triedb/pathdb"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that an interrupted migration of a hash-based state to the path-based
+// scheme is resumed, and that the migrated state is complete.
+func TestMigrateToPathScheme(t *testing.T) {
+	var (
+		db  = rawdb.NewMemoryDatabase()
+		tdb = triedb.NewDatabase(db, triedb.HashDefaults)
+		sdb = state.NewDatabaseWithNodeDB(db, tdb)
+	)
+	// Accounts sharing a storage trie and with their own one are migrated.
+	statedb, err := state.New(types.EmptyRootHash, sdb, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(0); i < 100; i++ {
+		addr := common.BigToAddress(big.NewInt(i))
+		statedb.SetNonce(addr, uint64(i))
+		statedb.SetState(addr, common.Hash{1}, common.BigToHash(big.NewInt(i%2+1)))
+	}
+	root, err := statedb.Commit(0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tdb.Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	genesis := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0), Root: root})
+	rawdb.WriteBlock(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	// Simulate a migration interrupted before the copy completed.
+	rawdb.WriteStateSchemeMigration(db, root)
+	rawdb.WriteStateSchemeMigrationProgress(db, []byte{migrationCopying})
+	if scheme := rawdb.ReadStateScheme(db); scheme != rawdb.HashScheme {
+		t.Fatalf("scheme mismatch during the copy: have %q, want %q", scheme, rawdb.HashScheme)
+	}
+	if err := RecoverStateSchemeMigration(db); err != nil {
+		t.Fatal(err)
+	}
+	if scheme := rawdb.ReadStateScheme(db); scheme != rawdb.PathScheme {
+		t.Fatalf("scheme mismatch: have %q, want %q", scheme, rawdb.PathScheme)
+	}
+	if rawdb.HasLegacyTrieNode(db, root) {
+		t.Fatal("hash-based state not deleted")
+	}
+	if migration := rawdb.ReadStateSchemeMigration(db); migration != (common.Hash{}) {
+		t.Fatalf("migration %x not removed", migration)
+	}
+	if err := MigrateToPathScheme(db, root); err == nil {
+		t.Fatal("path-based state migrated again")
+	}
+
+	pdb := triedb.NewDatabase(db, &triedb.Config{PathDB: pathdb.Defaults})
+	defer pdb.Close()
+	statedb, err = state.New(root, state.NewDatabaseWithNodeDB(db, pdb), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(0); i < 100; i++ {
+		addr := common.BigToAddress(big.NewInt(i))
+		if nonce := statedb.GetNonce(addr); nonce != uint64(i) {
+			t.Fatalf("account %d nonce mismatch: have %d, want %d", i, nonce, i)
+		}
+		if value, want := statedb.GetState(addr, common.Hash{1}), common.BigToHash(big.NewInt(i%2+1)); value != want {
+			t.Fatalf("account %d storage mismatch: have %x, want %x", i, value, want)
+		}
+	}
+	if err := statedb.Error(); err != nil {
+		t.Fatal(err)
+	}
+}
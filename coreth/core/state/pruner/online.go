@@ -0,0 +1,547 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package pruner
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/metrics"
+	"github.com/ava-labs/coreth/trie"
+	"github.com/ava-labs/coreth/triedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// onlineBloomFilePrefix is the filename prefix of the state bloom filter of
+// an online pruning session.
+const onlineBloomFilePrefix = "onlinebloom"
+
+// The phases of an online pruning session, reported by the phase metric in
+// this order.
+const (
+	OnlinePruningIdle     = "idle"     // no session is running
+	OnlinePruningWaiting  = "waiting"  // recording the flushed trie nodes until the target state is committed
+	OnlinePruningMarking  = "marking"  // adding the trie nodes of the target state to the bloom filter
+	OnlinePruningSweeping = "sweeping" // deleting the trie nodes not in the bloom filter
+)
+
+var onlinePruningPhases = []string{OnlinePruningIdle, OnlinePruningWaiting, OnlinePruningMarking, OnlinePruningSweeping}
+
+// ErrOnlinePruningStopped is returned if an online pruning task is stopped
+// before it completes. The session can be continued by running the task again.
+var ErrOnlinePruningStopped = errors.New("online pruning stopped")
+
+var (
+	onlinePruningPhaseGauge         = metrics.NewRegisteredGauge("state/pruner/online/phase", nil)
+	onlinePruningProgressGauge      = metrics.NewRegisteredGaugeFloat64("state/pruner/online/progress", nil)
+	onlinePruningMarkedCounter      = metrics.NewRegisteredCounter("state/pruner/online/marked", nil)
+	onlinePruningScannedCounter     = metrics.NewRegisteredCounter("state/pruner/online/scanned", nil)
+	onlinePruningDeletedCounter     = metrics.NewRegisteredCounter("state/pruner/online/deleted", nil)
+	onlinePruningDeletedBytes       = metrics.NewRegisteredCounter("state/pruner/online/deleted/size", nil)
+	onlinePruningSessionsCounter    = metrics.NewRegisteredCounter("state/pruner/online/sessions", nil)
+	onlinePruningBarrierWaitCounter = metrics.NewRegisteredCounter("state/pruner/online/barrier/wait", nil)
+)
+
+// OnlineConfig includes the configurations of online pruning.
+type OnlineConfig struct {
+	Datadir   string // The directory of the state bloom filter
+	BloomSize uint64 // The Megabytes of memory allocated to bloom-filter
+}
+
+// OnlineStatus is the progress of the online pruning session.
+type OnlineStatus struct {
+	Phase        string      `json:"phase"`
+	Root         common.Hash `json:"root"`              // Target state of the session, zero until it is selected
+	Started      time.Time   `json:"started,omitempty"` // Start of the session, zero if it was resumed
+	Marked       uint64      `json:"marked"`            // Trie nodes added to the bloom filter
+	Scanned      uint64      `json:"scanned"`           // Database entries swept
+	Deleted      uint64      `json:"deleted"`           // Stale trie nodes deleted
+	DeletedBytes uint64      `json:"deletedBytes"`      // Size of the stale trie nodes deleted
+	Progress     float64     `json:"progress"`          // Percentage of the database swept
+	Sessions     uint64      `json:"sessions"`          // Sessions completed since the node started
+	Error        string      `json:"error,omitempty"`   // Failure of the last session
+}
+
+// OnlinePruner deletes the stale trie nodes of the hash scheme while the chain
+// keeps accepting blocks. A session runs in three phases:
+//
+//   - a write barrier is installed on the trie database, so that every trie
+//     node flushed to disk from then on is recorded in the bloom filter and
+//     marked in the database atomically with the node
+//   - once a state committed after the barrier is selected as the target, its
+//     trie nodes and the genesis state are added to the bloom filter, which is
+//     then persisted
+//   - the database is swept, deleting the trie nodes not in the bloom filter
+//
+// The states newer than the target only reference the trie nodes of the
+// target and the nodes flushed after the barrier, so they are all retained,
+// while the states older than the target are pruned. Contract code is kept,
+// as it is not written through the trie database.
+//
+// A session whose bloom filter was persisted is resumed after a restart, like
+// RecoverPruning resumes offline pruning, otherwise it is dropped.
+type OnlinePruner struct {
+	config OnlineConfig
+	db     ethdb.Database
+
+	// lock serializes the marks of the flushed trie nodes with the deletions
+	// of the sweep, so that a node is never deleted once it is marked.
+	lock  sync.Mutex
+	bloom *stateBloom // Retained trie nodes, nil if no session is running
+
+	statusLock sync.RWMutex
+	phase      string
+	root       common.Hash
+	started    time.Time
+	err        error
+
+	marked, scanned, deleted, deletedBytes, progress, sessions atomic.Uint64
+}
+
+// NewOnlinePruner creates the online pruner and installs its write barrier on
+// [triedb], which must use the hash scheme. If a session was interrupted after
+// its bloom filter was persisted, it is loaded to be swept again.
+func NewOnlinePruner(db ethdb.Database, triedb *triedb.Database, config OnlineConfig) (*OnlinePruner, error) {
+	// Sanitize the bloom filter size if it's too small.
+	if config.BloomSize < 256 {
+		log.Warn("Sanitizing bloomfilter size", "provided(MB)", config.BloomSize, "updated(MB)", 256)
+		config.BloomSize = 256
+	}
+	p := &OnlinePruner{
+		config: config,
+		db:     db,
+		phase:  OnlinePruningIdle,
+	}
+	bloomPath, root, err := findBloomFilter(config.Datadir, onlineBloomFilePrefix)
+	if err != nil {
+		return nil, err
+	}
+	if bloomPath == "" {
+		// Drop the marks of a session interrupted before its target was marked.
+		if err := rawdb.ClearOnlinePruning(db); err != nil {
+			return nil, fmt.Errorf("failed to drop online pruning session: %w", err)
+		}
+	} else {
+		bloom, err := NewStateBloomFromDisk(bloomPath)
+		if err != nil {
+			return nil, err
+		}
+		var marks int
+		err = rawdb.IterateOnlinePruningMarks(db, func(hash common.Hash) {
+			bloom.Put(hash.Bytes(), nil)
+			marks++
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load online pruning marks: %w", err)
+		}
+		p.bloom = bloom
+		p.root = root
+		p.setPhase(OnlinePruningSweeping)
+		log.Info("Resuming online pruning", "root", root, "marks", marks, "progress", fmt.Sprintf("%.2f%%", sweepProgress(rawdb.ReadOnlinePruningProgress(db))))
+	}
+	if err := triedb.SetFlushHook(p.markFlushed); err != nil {
+		return nil, fmt.Errorf("online pruning requires the hash scheme: %w", err)
+	}
+	return p, nil
+}
+
+// markFlushed is the write barrier, called for every trie node flushed to
+// disk by the trie database.
+func (p *OnlinePruner) markFlushed(batch ethdb.KeyValueWriter, hash common.Hash) {
+	start := time.Now()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	onlinePruningBarrierWaitCounter.Inc(time.Since(start).Microseconds())
+
+	if p.bloom == nil {
+		return
+	}
+	p.bloom.Put(hash.Bytes(), nil)
+	rawdb.WriteOnlinePruningMark(batch, hash)
+}
+
+// Status returns the progress of the running session.
+func (p *OnlinePruner) Status() OnlineStatus {
+	p.statusLock.RLock()
+	defer p.statusLock.RUnlock()
+
+	status := OnlineStatus{
+		Phase:        p.phase,
+		Root:         p.root,
+		Started:      p.started,
+		Marked:       p.marked.Load(),
+		Scanned:      p.scanned.Load(),
+		Deleted:      p.deleted.Load(),
+		DeletedBytes: p.deletedBytes.Load(),
+		Progress:     math.Float64frombits(p.progress.Load()),
+		Sessions:     p.sessions.Load(),
+	}
+	if p.err != nil {
+		status.Error = p.err.Error()
+	}
+	return status
+}
+
+func (p *OnlinePruner) setPhase(phase string) {
+	p.statusLock.Lock()
+	defer p.statusLock.Unlock()
+
+	p.phase = phase
+	onlinePruningPhaseGauge.Update(int64(slices.Index(onlinePruningPhases, phase)))
+}
+
+func (p *OnlinePruner) setProgress(progress float64) {
+	p.progress.Store(math.Float64bits(progress))
+	onlinePruningProgressGauge.Update(progress)
+}
+
+// fail records the error of the session.
+func (p *OnlinePruner) fail(err error) error {
+	if errors.Is(err, ErrOnlinePruningStopped) {
+		return err
+	}
+	p.statusLock.Lock()
+	p.err = err
+	p.statusLock.Unlock()
+	return err
+}
+
+// Begin starts a new session by installing the write barrier. The target
+// state must be committed after Begin returns.
+func (p *OnlinePruner) Begin() error {
+	bloom, err := newStateBloomWithSize(p.config.BloomSize)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.bloom != nil {
+		return errors.New("online pruning session already running")
+	}
+	p.bloom = bloom
+
+	p.statusLock.Lock()
+	p.root = common.Hash{}
+	p.started = time.Now()
+	p.err = nil
+	p.statusLock.Unlock()
+
+	p.marked.Store(0)
+	p.scanned.Store(0)
+	p.deleted.Store(0)
+	p.deletedBytes.Store(0)
+	p.setProgress(0)
+	p.setPhase(OnlinePruningWaiting)
+
+	log.Info("Started online pruning session")
+	return nil
+}
+
+// session returns the bloom filter of the running session.
+func (p *OnlinePruner) session() (*stateBloom, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.bloom == nil {
+		return nil, errors.New("no online pruning session running")
+	}
+	return p.bloom, nil
+}
+
+// Mark selects the state with the given root, which must be committed to disk
+// after Begin, as the target of the session. Its trie nodes and the ones of the
+// genesis state are added to the bloom filter, which is persisted so that the
+// session is resumed after a restart.
+func (p *OnlinePruner) Mark(root common.Hash, stop <-chan struct{}) error {
+	bloom, err := p.session()
+	if err != nil {
+		return err
+	}
+	// The weak assumption is the presence of root can indicate the presence
+	// of the entire trie.
+	if !rawdb.HasLegacyTrieNode(p.db, root) {
+		return p.fail(fmt.Errorf("associated state[%x] is not present", root))
+	}
+	p.statusLock.Lock()
+	p.root = root
+	p.statusLock.Unlock()
+	p.setPhase(OnlinePruningMarking)
+
+	start := time.Now()
+	log.Info("Marking online pruning target", "root", root)
+	if err := p.markState(root, bloom, stop); err != nil {
+		return p.fail(err)
+	}
+	if err := extractGenesis(p.db, bloom); err != nil {
+		return p.fail(err)
+	}
+	filterName := bloomFilterName(p.config.Datadir, onlineBloomFilePrefix, root)
+	if err := bloom.Commit(filterName, filterName+stateBloomFileTempSuffix); err != nil {
+		return p.fail(err)
+	}
+	log.Info("Marked online pruning target", "root", root, "nodes", p.marked.Load(), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	p.setPhase(OnlinePruningSweeping)
+	return nil
+}
+
+// markState adds the trie nodes of the state with the given root, including
+// its storage tries, to the bloom filter.
+func (p *OnlinePruner) markState(root common.Hash, bloom *stateBloom, stop <-chan struct{}) error {
+	var (
+		db     = triedb.NewDatabase(p.db, triedb.HashDefaults)
+		logged = time.Now()
+	)
+	mark := func(it trie.NodeIterator, leaf func() error) error {
+		for it.Next(true) {
+			select {
+			case <-stop:
+				return ErrOnlinePruningStopped
+			default:
+			}
+			// Embedded nodes don't have hash.
+			if hash := it.Hash(); hash != (common.Hash{}) {
+				bloom.Put(hash.Bytes(), nil)
+				p.marked.Add(1)
+				onlinePruningMarkedCounter.Inc(1)
+			}
+			if leaf != nil && it.Leaf() {
+				if err := leaf(); err != nil {
+					return err
+				}
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Marking online pruning target", "root", root, "nodes", p.marked.Load())
+				logged = time.Now()
+			}
+		}
+		return it.Error()
+	}
+	t, err := trie.NewStateTrie(trie.StateTrieID(root), db)
+	if err != nil {
+		return err
+	}
+	accIter, err := t.NodeIterator(nil)
+	if err != nil {
+		return err
+	}
+	return mark(accIter, func() error {
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(accIter.LeafBlob(), &acc); err != nil {
+			return err
+		}
+		if acc.Root == types.EmptyRootHash {
+			return nil
+		}
+		id := trie.StorageTrieID(root, common.BytesToHash(accIter.LeafKey()), acc.Root)
+		storageTrie, err := trie.NewStateTrie(id, db)
+		if err != nil {
+			return err
+		}
+		storageIter, err := storageTrie.NodeIterator(nil)
+		if err != nil {
+			return err
+		}
+		return mark(storageIter, nil)
+	})
+}
+
+// Sweep deletes the trie nodes not in the bloom filter of the session, which
+// must be marked, then completes the session. The deletions are persisted
+// with the swept position, so that a stopped or interrupted sweep is
+// continued where it left off.
+func (p *OnlinePruner) Sweep(stop <-chan struct{}) error {
+	bloom, err := p.session()
+	if err != nil {
+		return err
+	}
+	p.statusLock.RLock()
+	root := p.root
+	p.statusLock.RUnlock()
+
+	var (
+		start   = time.Now()
+		logged  = time.Now()
+		pending []sweepCandidate
+		size    int
+		last    = rawdb.ReadOnlinePruningProgress(p.db)
+		iter    = p.db.NewIterator(nil, last)
+	)
+	// We wrap iter.Release() in an anonymous function so that the [iter]
+	// value captured is the value of [iter] at the end of the function as opposed
+	// to incorrectly capturing the first iterator immediately.
+	defer func() {
+		iter.Release()
+	}()
+
+	// flush deletes the pending trie nodes that have not been marked since
+	// they were swept, along with moving the progress to [last]. The write
+	// barrier waits for the deletions, so a node flushed meanwhile is either
+	// marked before and kept, or written again after.
+	flush := func() error {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+
+		if p.bloom != bloom {
+			return errors.New("online pruning session dropped")
+		}
+		batch := p.db.NewBatch()
+		for _, candidate := range pending {
+			if bloom.Contain(candidate.key) {
+				continue
+			}
+			if err := batch.Delete(candidate.key); err != nil {
+				return err
+			}
+			p.deleted.Add(1)
+			p.deletedBytes.Add(uint64(candidate.size))
+			onlinePruningDeletedCounter.Inc(1)
+			onlinePruningDeletedBytes.Inc(int64(candidate.size))
+		}
+		rawdb.WriteOnlinePruningProgress(batch, last)
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		pending, size = pending[:0], 0
+		p.setProgress(sweepProgress(last))
+		return nil
+	}
+	for iter.Next() {
+		key := iter.Key()
+		p.scanned.Add(1)
+		onlinePruningScannedCounter.Inc(1)
+
+		// Contract code is not written through the trie database, so only the
+		// trie nodes are deleted.
+		if len(key) == common.HashLength && !bloom.Contain(key) {
+			pending = append(pending, sweepCandidate{key: common.CopyBytes(key), size: len(key) + len(iter.Value())})
+			size += len(key)
+		}
+		var stopped bool
+		select {
+		case <-stop:
+			stopped = true
+		default:
+		}
+		if !stopped && size < ethdb.IdealBatchSize && time.Since(logged) < 8*time.Second {
+			continue
+		}
+		last = common.CopyBytes(key)
+		if err := flush(); err != nil {
+			return p.fail(err)
+		}
+		if stopped {
+			return ErrOnlinePruningStopped
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Sweeping stale state", "root", root, "nodes", p.deleted.Load(), "size", common.StorageSize(p.deletedBytes.Load()),
+				"progress", fmt.Sprintf("%.2f%%", sweepProgress(last)), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		// Recreate the iterator after every batch commit in order
+		// to allow the underlying compactor to delete the entries.
+		iter.Release()
+		iter = p.db.NewIterator(nil, last)
+	}
+	if err := iter.Error(); err != nil {
+		return p.fail(fmt.Errorf("failed to iterate db during online pruning: %w", err))
+	}
+	if len(pending) > 0 {
+		if err := flush(); err != nil {
+			return p.fail(err)
+		}
+	}
+	log.Info("Swept stale state", "root", root, "nodes", p.deleted.Load(), "size", common.StorageSize(p.deletedBytes.Load()),
+		"elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Abort deletes the state bloom before removing the write barrier, so
+	// that a session resumed after a crash never misses a trie node flushed
+	// since the bloom was persisted.
+	if err := p.Abort(); err != nil {
+		return p.fail(err)
+	}
+	p.sessions.Add(1)
+	onlinePruningSessionsCounter.Inc(1)
+	p.setProgress(100)
+
+	// Start compactions, will remove the deleted data from the disk immediately.
+	// Note for small pruning, the compaction is skipped.
+	if p.deleted.Load() >= rangeCompactionThreshold {
+		if err := compactDatabase(p.db); err != nil {
+			return p.fail(err)
+		}
+	}
+	log.Info("Online pruning session completed", "root", root, "pruned", common.StorageSize(p.deletedBytes.Load()), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// sweepCandidate is a trie node not marked when it was swept.
+type sweepCandidate struct {
+	key  []byte
+	size int
+}
+
+// Abort drops the running session, if any. The trie nodes deleted by the
+// session are not restored, which is safe as they are not referenced by the
+// states retained by the session.
+func (p *OnlinePruner) Abort() error {
+	bloomPath, _, err := findBloomFilter(p.config.Datadir, onlineBloomFilePrefix)
+	if err != nil {
+		return err
+	}
+	if bloomPath != "" {
+		if err := os.Remove(bloomPath); err != nil {
+			return fmt.Errorf("failed to remove bloom filter from disk: %w", err)
+		}
+	}
+	p.lock.Lock()
+	p.bloom = nil
+	p.lock.Unlock()
+
+	if err := rawdb.ClearOnlinePruning(p.db); err != nil {
+		return err
+	}
+	p.setPhase(OnlinePruningIdle)
+	return nil
+}
+
+// DropOnlinePruning drops the online pruning session interrupted with the
+// bloom filter in [datadir], if any. It must be called before any trie node is
+// flushed to [db] without the write barrier of the session.
+func DropOnlinePruning(datadir string, db ethdb.Database) error {
+	bloomPath, root, err := findBloomFilter(datadir, onlineBloomFilePrefix)
+	if err != nil {
+		return err
+	}
+	if bloomPath != "" {
+		log.Warn("Dropping online pruning session", "root", root)
+		if err := os.Remove(bloomPath); err != nil {
+			return fmt.Errorf("failed to remove bloom filter from disk: %w", err)
+		}
+	}
+	return rawdb.ClearOnlinePruning(db)
+}
+
+// sweepProgress returns the percentage of the key space before [key].
+func sweepProgress(key []byte) float64 {
+	if len(key) == 0 {
+		return 0
+	}
+	var prefix [8]byte
+	copy(prefix[:], key)
+	return float64(binary.BigEndian.Uint64(prefix[:])) / math.MaxUint64 * 100
+}
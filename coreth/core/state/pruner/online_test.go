@@ -0,0 +1,141 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/trie"
+	"github.com/ava-labs/coreth/triedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that an online pruning session resumed after a restart deletes the
+// states older than its target, and keeps the genesis state, the target and
+// the states committed after the session began.
+func TestOnlinePruning(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		tdb    = triedb.NewDatabase(db, triedb.HashDefaults)
+		sdb    = state.NewDatabaseWithNodeDB(db, tdb)
+		config = OnlineConfig{Datadir: t.TempDir(), BloomSize: 256}
+	)
+	// commit derives a state from [parent] and flushes it to disk.
+	commit := func(parent common.Hash, seed int64) common.Hash {
+		t.Helper()
+		statedb, err := state.New(parent, sdb, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := int64(0); i < 50; i++ {
+			addr := common.BigToAddress(big.NewInt(i))
+			statedb.SetNonce(addr, uint64(seed))
+			statedb.SetState(addr, common.BigToHash(big.NewInt(seed%3)), common.BigToHash(big.NewInt(seed+i+1)))
+		}
+		root, err := statedb.Commit(uint64(seed), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tdb.Commit(root, false); err != nil {
+			t.Fatal(err)
+		}
+		return root
+	}
+	genesisRoot := commit(types.EmptyRootHash, 0)
+	genesis := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0), Root: genesisRoot})
+	rawdb.WriteBlock(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	stale := commit(genesisRoot, 1)
+	p, err := NewOnlinePruner(db, tdb, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	target := commit(stale, 2)
+	if err := p.Mark(target, nil); err != nil {
+		t.Fatal(err)
+	}
+	newer := commit(target, 3)
+
+	// Restart with the marked session, which is resumed.
+	if p, err = NewOnlinePruner(db, tdb, config); err != nil {
+		t.Fatal(err)
+	}
+	if status := p.Status(); status.Phase != OnlinePruningSweeping || status.Root != target {
+		t.Fatalf("resumed session mismatch: have %s of %x, want %s of %x", status.Phase, status.Root, OnlinePruningSweeping, target)
+	}
+	if err := p.Sweep(nil); err != nil {
+		t.Fatal(err)
+	}
+	status := p.Status()
+	if status.Phase != OnlinePruningIdle || status.Deleted == 0 || status.Sessions != 1 {
+		t.Fatalf("completed session mismatch: %+v", status)
+	}
+	for _, root := range []common.Hash{genesisRoot, target, newer} {
+		checkState(t, db, root)
+	}
+	if rawdb.HasLegacyTrieNode(db, stale) {
+		t.Fatal("stale state not pruned")
+	}
+	if path, _, _ := findBloomFilter(config.Datadir, onlineBloomFilePrefix); path != "" {
+		t.Fatalf("bloom filter %s not removed", path)
+	}
+	if progress := rawdb.ReadOnlinePruningProgress(db); progress != nil {
+		t.Fatalf("sweep progress %x not removed", progress)
+	}
+}
+
+// checkState verifies that all the trie nodes of the state with the given root
+// are stored in [db].
+func checkState(t *testing.T, db ethdb.Database, root common.Hash) {
+	t.Helper()
+
+	tdb := triedb.NewDatabase(db, triedb.HashDefaults)
+	accTrie, err := trie.NewStateTrie(trie.StateTrieID(root), tdb)
+	if err != nil {
+		t.Fatalf("state %x: %v", root, err)
+	}
+	accIter, err := accTrie.NodeIterator(nil)
+	if err != nil {
+		t.Fatalf("state %x: %v", root, err)
+	}
+	for accIter.Next(true) {
+		if !accIter.Leaf() {
+			continue
+		}
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(accIter.LeafBlob(), &acc); err != nil {
+			t.Fatal(err)
+		}
+		if acc.Root == types.EmptyRootHash {
+			continue
+		}
+		id := trie.StorageTrieID(root, common.BytesToHash(accIter.LeafKey()), acc.Root)
+		storageTrie, err := trie.NewStateTrie(id, tdb)
+		if err != nil {
+			t.Fatalf("state %x: %v", root, err)
+		}
+		storageIter, err := storageTrie.NodeIterator(nil)
+		if err != nil {
+			t.Fatalf("state %x: %v", root, err)
+		}
+		for storageIter.Next(true) {
+		}
+		if err := storageIter.Error(); err != nil {
+			t.Fatalf("state %x: %v", root, err)
+		}
+	}
+	if err := accIter.Error(); err != nil {
+		t.Fatalf("state %x: %v", root, err)
+	}
+}
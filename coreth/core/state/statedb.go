@@ -88,6 +88,9 @@ type StateDB struct {
 	accountsOrigin map[common.Address][]byte                 // The original value of mutated accounts in 'slim RLP' encoding
 	storagesOrigin map[common.Address]map[common.Hash][]byte // The original value of mutated slots in prefix-zero trimmed rlp format
 
+	// committedDiff holds the state changes of the last commit.
+	committedDiff *Diff
+
 	// This map holds 'live' objects, which will get modified while processing
 	// a state transition.
 	stateObjects         map[common.Address]*stateObject
@@ -1366,12 +1369,13 @@ func (s *StateDB) commit(block uint64, deleteEmptyObjects bool, snaps *snapshot.
 		s.StorageUpdated, s.StorageDeleted = 0, 0
 	}
 	// If snapshotting is enabled, update the snapshot tree with this new version
+	destructs := s.convertAccountSet(s.stateObjectsDestruct)
 	if snaps != nil {
 		start := time.Now()
 		if s.snap == nil {
 			log.Error(fmt.Sprintf("cannot commit with snaps without a pre-existing snap layer, parentHash: %s, blockHash: %s", parentHash, blockHash))
 		}
-		if err := snaps.Update(blockHash, root, parentHash, s.convertAccountSet(s.stateObjectsDestruct), s.accounts, s.storages); err != nil {
+		if err := snaps.Update(blockHash, root, parentHash, destructs, s.accounts, s.storages); err != nil {
 			log.Warn("Failed to update snapshot tree", "to", root, "err", err)
 		}
 		if metrics.EnabledExpensive {
@@ -1400,6 +1404,8 @@ func (s *StateDB) commit(block uint64, deleteEmptyObjects bool, snaps *snapshot.
 			s.onCommit(set)
 		}
 	}
+	s.committedDiff = &Diff{Destructs: destructs, Accounts: s.accounts, Storages: s.storages}
+
 	// Clear all internal flags at the end of commit operation.
 	s.accounts = make(map[common.Hash][]byte)
 	s.storages = make(map[common.Hash]map[common.Hash][]byte)
@@ -1410,6 +1416,22 @@ func (s *StateDB) commit(block uint64, deleteEmptyObjects bool, snaps *snapshot.
 	return root, nil
 }
 
+// Diff is the change of the state by a commit, in the form of a snapshot diff
+// layer: the destructed accounts, whose storage is wiped, and the new values of
+// the mutated accounts in 'slim RLP' encoding and of the mutated slots in
+// prefix-zero trimmed rlp format, keyed by hash.
+type Diff struct {
+	Destructs map[common.Hash]struct{}
+	Accounts  map[common.Hash][]byte
+	Storages  map[common.Hash]map[common.Hash][]byte
+}
+
+// CommittedDiff returns the change of the state by the last commit, nil if the
+// state has not been committed. The returned diff must not be modified.
+func (s *StateDB) CommittedDiff() *Diff {
+	return s.committedDiff
+}
+
 // Prepare handles the preparatory steps for executing a state transition with.
 // This method must be invoked before state transition.
 //
@@ -0,0 +1,299 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/trie"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// stateArchiver is the module responsible for maintaining the state archive,
+// from which the historical states are read instead of the tries, so that
+// only the recent tries need to be kept. The archive holds the value of every
+// account and slot written by the accepted blocks, by height, on top of a copy
+// of the state of the block it starts from.
+//
+// The state diff of a block is kept once the block is inserted, and moved into
+// the archive when it is accepted, together with the acceptor tip.
+type stateArchiver struct {
+	db    ethdb.Database
+	chain *BlockChain
+}
+
+// newStateArchiver initializes the state archiver, the archive is only built
+// by init.
+func newStateArchiver(chain *BlockChain) *stateArchiver {
+	return &stateArchiver{
+		db:    chain.db,
+		chain: chain,
+	}
+}
+
+// init builds the state archive from the state of the last accepted block if
+// the archive does not exist yet, e.g. when the archive is enabled on an
+// existing chain. An interrupted build is resumed. The accepted blocks not
+// archived yet are archived from their kept state diffs. An archive that does
+// not reach the last accepted block otherwise, e.g. after a state sync, is
+// only discarded and rebuilt if StateArchiveRebuild is set or it holds no
+// history, as the states of the blocks accepted before the last accepted
+// block are not available afterwards.
+func (a *stateArchiver) init() error {
+	var (
+		block  = a.chain.LastAcceptedBlock()
+		number = block.NumberU64()
+		gapErr error
+	)
+	head := rawdb.ReadStateArchiveHead(a.db)
+	if head != nil && *head < number {
+		if gapErr = a.archiveKeptDiffs(*head, number); gapErr == nil {
+			head = &number
+		} else {
+			head = rawdb.ReadStateArchiveHead(a.db)
+		}
+	}
+	if head != nil && *head == number {
+		log.Info("Initialized state archiver", "tail", *rawdb.ReadStateArchiveTail(a.db), "head", number)
+		return nil
+	}
+	if head != nil {
+		tail := *rawdb.ReadStateArchiveTail(a.db)
+		if tail != *head && !a.chain.cacheConfig.StateArchiveRebuild {
+			if gapErr != nil {
+				return fmt.Errorf("state archive of blocks [%d, %d] does not reach the last accepted block %d: %w, the archive must be rebuilt explicitly", tail, *head, number, gapErr)
+			}
+			return fmt.Errorf("state archive of blocks [%d, %d] does not match the last accepted block %d, the archive must be rebuilt explicitly", tail, *head, number)
+		}
+		log.Warn("Rebuilding state archive not reaching the last accepted block", "tail", tail, "head", *head, "number", number)
+	}
+	progress, marker, ok := rawdb.ReadStateArchiveBaseProgress(a.db)
+	if !ok || progress != number {
+		if err := rawdb.ClearStateArchive(a.db); err != nil {
+			return err
+		}
+		rawdb.WriteStateArchiveBaseProgress(a.db, number, nil)
+		marker = nil
+	}
+	return a.copyState(block, marker)
+}
+
+// archiveKeptDiffs archives the state diffs kept for the accepted blocks
+// after [head] up to [number], which were accepted while the archive did not
+// reach their parents. It returns an error if the diff of a block is missing,
+// the blocks before it are archived.
+func (a *stateArchiver) archiveKeptDiffs(head, number uint64) error {
+	batch := a.db.NewBatch()
+	for n := head + 1; n <= number; n++ {
+		hash := rawdb.ReadCanonicalHash(a.db, n)
+		diff := rawdb.ReadStateArchiveDiff(a.db, hash, n)
+		if diff == nil {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			return fmt.Errorf("missing state diff of accepted block %d %s", n, hash)
+		}
+		rawdb.WriteStateArchiveEntries(batch, n, diff)
+		rawdb.WriteStateArchiveHead(batch, n)
+		for _, hash := range rawdb.ReadStateArchiveDiffHashes(a.db, n) {
+			rawdb.DeleteStateArchiveDiff(batch, hash, n)
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Archived kept state diffs", "from", head+1, "to", number)
+	return nil
+}
+
+// copyState copies the state of [block] into the archive, starting from the
+// account [marker].
+func (a *stateArchiver) copyState(block *types.Block, marker []byte) error {
+	var (
+		number   = block.NumberU64()
+		start    = time.Now()
+		logged   = time.Now()
+		accounts int
+		slots    int
+		batch    = a.db.NewBatch()
+	)
+	log.Info("Copying state into the state archive", "number", number, "root", block.Root())
+	accTrie, err := trie.NewStateTrie(trie.StateTrieID(block.Root()), a.chain.triedb)
+	if err != nil {
+		return err
+	}
+	accIter, err := accTrie.NodeIterator(marker)
+	if err != nil {
+		return err
+	}
+	it := trie.NewIterator(accIter)
+	for it.Next() {
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(it.Value, &acc); err != nil {
+			return err
+		}
+		accountHash := common.BytesToHash(it.Key)
+		rawdb.WriteStateArchiveAccount(batch, accountHash, number, types.SlimAccountRLP(acc))
+		accounts++
+
+		if acc.Root != types.EmptyRootHash {
+			storageTrie, err := trie.NewStateTrie(trie.StorageTrieID(block.Root(), accountHash, acc.Root), a.chain.triedb)
+			if err != nil {
+				return err
+			}
+			storageIter, err := storageTrie.NodeIterator(nil)
+			if err != nil {
+				return err
+			}
+			storageIt := trie.NewIterator(storageIter)
+			for storageIt.Next() {
+				rawdb.WriteStateArchiveStorage(batch, accountHash, common.BytesToHash(storageIt.Key), number, storageIt.Value)
+				slots++
+
+				// The progress is only advanced once the whole storage is
+				// copied, the slots written before are copied again.
+				if batch.ValueSize() >= ethdb.IdealBatchSize {
+					if err := batch.Write(); err != nil {
+						return err
+					}
+					batch.Reset()
+				}
+			}
+			if storageIt.Err != nil {
+				return storageIt.Err
+			}
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			rawdb.WriteStateArchiveBaseProgress(batch, number, it.Key)
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Copying state into the state archive", "accounts", accounts, "slots", slots, "at", accountHash, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if it.Err != nil {
+		return it.Err
+	}
+	rawdb.WriteStateArchiveTail(batch, number)
+	rawdb.WriteStateArchiveHead(batch, number)
+	rawdb.DeleteStateArchiveBaseProgress(batch)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Copied state into the state archive", "number", number, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// writeDiff keeps the state diff of the inserted block until the block is
+// accepted or rejected.
+func (a *stateArchiver) writeDiff(block *types.Block, diff *state.Diff) {
+	rawdb.WriteStateArchiveDiff(a.db, block.Hash(), block.NumberU64(), newStateArchiveDiff(diff))
+}
+
+// batchAccepted archives the state diff of the accepted block into [batch],
+// and discards the state diffs of the other blocks at its height. The diff of
+// an accepted block that does not follow the head of the archive is kept, for
+// init to archive it on restart once the blocks before it are archived.
+func (a *stateArchiver) batchAccepted(batch ethdb.Batch, block *types.Block) {
+	var (
+		number = block.NumberU64()
+		keep   common.Hash
+	)
+	if head := rawdb.ReadStateArchiveHead(a.db); head != nil && *head+1 == number {
+		if diff := rawdb.ReadStateArchiveDiff(a.db, block.Hash(), number); diff != nil {
+			rawdb.WriteStateArchiveEntries(batch, number, diff)
+			rawdb.WriteStateArchiveHead(batch, number)
+		} else {
+			log.Error("Missing state diff of accepted block, state archive stops at its parent", "number", number, "hash", block.Hash())
+		}
+	} else if head != nil && *head < number {
+		keep = block.Hash()
+	}
+	for _, hash := range rawdb.ReadStateArchiveDiffHashes(a.db, number) {
+		if hash != keep {
+			rawdb.DeleteStateArchiveDiff(batch, hash, number)
+		}
+	}
+}
+
+// stateAt returns a new mutable state at the accepted block of [header], read
+// from the state archive.
+func (a *stateArchiver) stateAt(header *types.Header) (*state.StateDB, error) {
+	var (
+		number = header.Number.Uint64()
+		tail   = rawdb.ReadStateArchiveTail(a.db)
+		head   = rawdb.ReadStateArchiveHead(a.db)
+	)
+	if tail == nil || head == nil || number < *tail || number > *head {
+		return nil, fmt.Errorf("state of block %d is not archived", number)
+	}
+	if hash := rawdb.ReadCanonicalHash(a.db, number); hash != header.Hash() {
+		return nil, fmt.Errorf("block %d %s is not accepted", number, header.Hash())
+	}
+	return state.New(header.Root, state.NewArchiveDatabase(a.chain.stateCache, number), nil)
+}
+
+// newStateArchiveDiff converts the state diff of a commit to its archived
+// form, sorted by hash.
+func newStateArchiveDiff(diff *state.Diff) *rawdb.StateArchiveDiff {
+	archived := &rawdb.StateArchiveDiff{
+		Destructs: make([]common.Hash, 0, len(diff.Destructs)),
+		Accounts:  make([]rawdb.StateArchiveAccount, 0, len(diff.Accounts)),
+		Storages:  make([]rawdb.StateArchiveStorage, 0, len(diff.Storages)),
+	}
+	for hash := range diff.Destructs {
+		archived.Destructs = append(archived.Destructs, hash)
+	}
+	sortHashes(archived.Destructs)
+	for hash, blob := range diff.Accounts {
+		archived.Accounts = append(archived.Accounts, rawdb.StateArchiveAccount{Hash: hash, Blob: blob})
+	}
+	sort.Slice(archived.Accounts, func(i, j int) bool {
+		return bytes.Compare(archived.Accounts[i].Hash[:], archived.Accounts[j].Hash[:]) < 0
+	})
+	for account, slots := range diff.Storages {
+		storage := rawdb.StateArchiveStorage{
+			Account: account,
+			Hashes:  make([]common.Hash, 0, len(slots)),
+			Values:  make([][]byte, 0, len(slots)),
+		}
+		for hash := range slots {
+			storage.Hashes = append(storage.Hashes, hash)
+		}
+		sortHashes(storage.Hashes)
+		for _, hash := range storage.Hashes {
+			storage.Values = append(storage.Values, slots[hash])
+		}
+		archived.Storages = append(archived.Storages, storage)
+	}
+	sort.Slice(archived.Storages, func(i, j int) bool {
+		return bytes.Compare(archived.Storages[i].Account[:], archived.Storages[j].Account[:]) < 0
+	})
+	return archived
+}
+
+func sortHashes(hashes []common.Hash) {
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+}
@@ -0,0 +1,188 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+var stateArchiveTestCounter = common.HexToAddress("0xc0de")
+
+// newStateArchiveTestChain generates 60 blocks transferring 1 wei to a new
+// account each and storing their number in a counter contract, and returns
+// the genesis, the blocks and a pruning cache config without archive.
+func newStateArchiveTestChain(t *testing.T) (*Genesis, []*types.Block, *CacheConfig) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		funds  = new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(100))
+		gspec  = &Genesis{
+			Config: &params.ChainConfig{HomesteadBlock: new(big.Int)},
+			Alloc: types.GenesisAlloc{
+				addr: {Balance: funds},
+				// NUMBER PUSH1 0 SSTORE STOP
+				stateArchiveTestCounter: {Balance: common.Big0, Code: []byte{0x43, 0x60, 0x00, 0x55, 0x00}},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _, err := GenerateChainWithGenesis(gspec, dummy.NewFakerWithCallbacks(TestCallbacks), 60, 10, func(i int, block *BlockGen) {
+		to := common.BigToAddress(big.NewInt(int64(i + 1)))
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), to, big.NewInt(1), 21000, big.NewInt(225000000000), nil), signer, key)
+		require.NoError(t, err)
+		block.AddTx(tx)
+		tx, err = types.SignTx(types.NewTransaction(block.TxNonce(addr), stateArchiveTestCounter, common.Big0, 50000, big.NewInt(225000000000), nil), signer, key)
+		require.NoError(t, err)
+		block.AddTx(tx)
+	})
+	require.NoError(t, err)
+	return gspec, blocks, &CacheConfig{
+		TrieCleanLimit:            256,
+		TrieDirtyLimit:            256,
+		TriePrefetcherParallelism: 4,
+		Pruning:                   true,
+		CommitInterval:            4096,
+		SnapshotLimit:             256,
+		SnapshotNoBuild:           true, // Ensure the test errors if snapshot initialization fails
+		AcceptorQueueLimit:        64,
+	}
+}
+
+// acceptStateArchiveTestBlocks inserts and accepts [blocks] into [chain].
+func acceptStateArchiveTestBlocks(t *testing.T, chain *BlockChain, blocks []*types.Block) {
+	_, err := chain.InsertChain(blocks)
+	require.NoError(t, err)
+	for _, block := range blocks {
+		require.NoError(t, chain.Accept(block))
+	}
+	chain.DrainAcceptorQueue()
+}
+
+// Tests that the state archive enabled on an existing chain serves the pruned
+// states of the blocks accepted since, across restarts.
+func TestStateArchive(t *testing.T) {
+	require := require.New(t)
+	var (
+		key, _              = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr                = crypto.PubkeyToAddress(key.PublicKey)
+		counter             = stateArchiveTestCounter
+		gspec, blocks, conf = newStateArchiveTestChain(t)
+		chainDB             = rawdb.NewMemoryDatabase()
+	)
+	chain, err := createBlockChain(chainDB, conf, gspec, common.Hash{})
+	require.NoError(err)
+	acceptStateArchiveTestBlocks(t, chain, blocks[:20])
+	chain.Stop()
+
+	// The archive starts from the last accepted block once enabled.
+	conf.StateArchive = true
+	chain, err = createBlockChain(chainDB, conf, gspec, blocks[19].Hash())
+	require.NoError(err)
+	acceptStateArchiveTestBlocks(t, chain, blocks[20:])
+	chain.Stop()
+
+	chain, err = createBlockChain(chainDB, conf, gspec, blocks[59].Hash())
+	require.NoError(err)
+	defer chain.Stop()
+	require.Equal(uint64(20), *rawdb.ReadStateArchiveTail(chainDB))
+	require.Equal(uint64(60), *rawdb.ReadStateArchiveHead(chainDB))
+	require.Empty(rawdb.ReadStateArchiveDiffHashes(chainDB, 60))
+
+	_, err = chain.StateAt(blocks[29].Root())
+	require.Error(err)
+	for _, block := range blocks[19:] {
+		number := block.NumberU64()
+		statedb, err := chain.HistoricalStateAt(block.Header())
+		require.NoError(err)
+		require.Equal(2*number, statedb.GetNonce(addr), "block %d", number)
+		require.Equal(common.Big1, statedb.GetBalance(common.BigToAddress(big.NewInt(int64(number)))).ToBig(), "block %d", number)
+		require.Equal(common.BigToHash(block.Number()), statedb.GetState(counter, common.Hash{}), "block %d", number)
+		require.Equal([]byte{0x43, 0x60, 0x00, 0x55, 0x00}, statedb.GetCode(counter))
+		require.False(statedb.Exist(common.BigToAddress(big.NewInt(int64(number+1)))), "block %d", number)
+	}
+	_, err = chain.HistoricalStateAt(blocks[10].Header())
+	require.Error(err)
+
+	// The archived state can be modified in memory on top of it.
+	statedb, err := chain.HistoricalStateAt(blocks[29].Header())
+	require.NoError(err)
+	statedb.SetState(counter, common.Hash{}, common.Hash{0x01})
+	require.Equal(common.Hash{0x01}, statedb.GetState(counter, common.Hash{}))
+	_, err = statedb.Commit(blocks[29].NumberU64(), true)
+	require.Error(err)
+}
+
+// Tests that the accepted blocks missing from the state archive are archived
+// from their kept state diffs on restart, and that an archive that cannot
+// reach the last accepted block is only discarded when its rebuild is
+// requested.
+func TestStateArchiveGap(t *testing.T) {
+	require := require.New(t)
+	var (
+		gspec, blocks, conf = newStateArchiveTestChain(t)
+		chainDB             = rawdb.NewMemoryDatabase()
+	)
+	conf.StateArchive = true
+	chain, err := createBlockChain(chainDB, conf, gspec, common.Hash{})
+	require.NoError(err)
+	acceptStateArchiveTestBlocks(t, chain, blocks[:10])
+
+	// The state diff of block 11 goes missing, the archive stops at block 10
+	// and the diffs of the blocks accepted after it are kept.
+	_, err = chain.InsertChain(blocks[10:20])
+	require.NoError(err)
+	missing := blocks[10]
+	diff := rawdb.ReadStateArchiveDiff(chainDB, missing.Hash(), missing.NumberU64())
+	require.NotNil(diff)
+	rawdb.DeleteStateArchiveDiff(chainDB, missing.Hash(), missing.NumberU64())
+	for _, block := range blocks[10:20] {
+		require.NoError(chain.Accept(block))
+	}
+	chain.DrainAcceptorQueue()
+	chain.Stop()
+	require.Equal(uint64(10), *rawdb.ReadStateArchiveHead(chainDB))
+	require.Len(rawdb.ReadStateArchiveDiffHashes(chainDB, 20), 1)
+
+	// The node refuses to start instead of discarding the archive.
+	_, err = createBlockChain(chainDB, conf, gspec, blocks[19].Hash())
+	require.ErrorContains(err, "missing state diff of accepted block 11")
+	require.Equal(uint64(0), *rawdb.ReadStateArchiveTail(chainDB))
+	require.Equal(uint64(10), *rawdb.ReadStateArchiveHead(chainDB))
+
+	// Once the missing diff is found, the kept diffs are archived.
+	rawdb.WriteStateArchiveDiff(chainDB, missing.Hash(), missing.NumberU64(), diff)
+	chain, err = createBlockChain(chainDB, conf, gspec, blocks[19].Hash())
+	require.NoError(err)
+	require.Equal(uint64(0), *rawdb.ReadStateArchiveTail(chainDB))
+	require.Equal(uint64(20), *rawdb.ReadStateArchiveHead(chainDB))
+	require.Empty(rawdb.ReadStateArchiveDiffHashes(chainDB, 20))
+	for _, block := range blocks[:20] {
+		statedb, err := chain.HistoricalStateAt(block.Header())
+		require.NoError(err)
+		require.Equal(common.BigToHash(block.Number()), statedb.GetState(stateArchiveTestCounter, common.Hash{}), "block %d", block.NumberU64())
+	}
+	acceptStateArchiveTestBlocks(t, chain, blocks[20:30])
+	chain.Stop()
+
+	// An archive ahead of the last accepted block is not discarded either,
+	// unless its rebuild is requested.
+	rawdb.WriteStateArchiveHead(chainDB, 40)
+	_, err = createBlockChain(chainDB, conf, gspec, blocks[29].Hash())
+	require.ErrorContains(err, "does not match the last accepted block 30")
+	conf.StateArchiveRebuild = true
+	chain, err = createBlockChain(chainDB, conf, gspec, blocks[29].Hash())
+	require.NoError(err)
+	defer chain.Stop()
+	require.Equal(uint64(30), *rawdb.ReadStateArchiveTail(chainDB))
+	require.Equal(uint64(30), *rawdb.ReadStateArchiveHead(chainDB))
+}
@@ -4,9 +4,11 @@
 package core
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
 // TestCountAttestationsEmpty checks that the CountAttestations function in state_connector.go
@@ -118,3 +120,57 @@ func TestCountAttestationsBlankVotes(t *testing.T) {
 		t.Fatalf(`reachedMajority = %t, want %t`, returnedAttestationVotes.reachedMajority, want)
 	}
 }
+
+// TestAttestationVotesTally checks that the tally of the counted attestations
+// exposes the full vote breakdown
+func TestAttestationVotesTally(t *testing.T) {
+	var attestationVotes AttestationVotes
+	attestors := []common.Address{
+		common.HexToAddress("0x0c19f3B4927abFc596353B0f9Ddad5D817736F70"),
+		common.HexToAddress("0x3a6e101103ec3d9267d08f484a6b70e1440a8255"),
+		common.HexToAddress("0xe51605047a50fc70143d98cb0b090bb1b157b6ae"),
+	}
+	attestationVotes.abstainedAttestors = []common.Address{attestors[2]}
+	hashFrequencies := map[string][]common.Address{
+		"953fbdd4ac2d5a2f1e413cbd378be0f3135010d81b4b643c6020e96ca49fc0c9": {attestors[0], attestors[1]},
+		"": {attestors[2]},
+	}
+
+	tally := CountAttestations(attestationVotes, len(attestors), hashFrequencies).tally(attestors)
+
+	if !tally.ReachedMajority || tally.MajorityDecision != "953fbdd4ac2d5a2f1e413cbd378be0f3135010d81b4b643c6020e96ca49fc0c9" {
+		t.Fatalf(`ReachedMajority = %t and MajorityDecision = %s, want majority`, tally.ReachedMajority, tally.MajorityDecision)
+	}
+	if len(tally.Attestors) != 3 || len(tally.MajorityAttestors) != 2 || len(tally.DivergentAttestors) != 1 || len(tally.AbstainedAttestors) != 1 {
+		t.Fatalf(`unexpected vote breakdown %+v`, tally)
+	}
+	if tally.AbstainedAttestors[0] != attestors[2] {
+		t.Fatalf(`AbstainedAttestors = %v, want %v`, tally.AbstainedAttestors, attestors[2:])
+	}
+}
+
+// TestAttestationRoundDiverged checks that a round diverges only when local
+// attestors are configured and disagree with the default attestors
+func TestAttestationRoundDiverged(t *testing.T) {
+	decision := "953fbdd4ac2d5a2f1e413cbd378be0f3135010d81b4b643c6020e96ca49fc0c9"
+	round := &AttestationRound{
+		Round:     (*hexutil.Big)(big.NewInt(42)),
+		Default:   &AttestationTally{ReachedMajority: true, MajorityDecision: decision},
+		Finalised: true,
+	}
+	if round.Diverged() {
+		t.Fatal(`Diverged() = true without local attestors, want false`)
+	}
+	round.Local = &AttestationTally{ReachedMajority: true, MajorityDecision: decision}
+	if round.Diverged() {
+		t.Fatal(`Diverged() = true with matching decisions, want false`)
+	}
+	round.Local = &AttestationTally{}
+	if !round.Diverged() {
+		t.Fatal(`Diverged() = false when local attestors reached no majority, want true`)
+	}
+	divergence := round.divergence(common.Hash{0x01}, 3)
+	if divergence.Round.Uint64() != 42 || divergence.TxIndex != 3 || divergence.DefaultDecision != decision || divergence.LocalReachedMajority || !divergence.Finalised {
+		t.Fatalf(`unexpected divergence %+v`, divergence)
+	}
+}
@@ -0,0 +1,152 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that the path-based scheme keeps the state histories of the most recent
+// accepted blocks across restarts, and rolls the persisted state back to them.
+func TestPathSchemeStateHistory(t *testing.T) {
+	require := require.New(t)
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		funds  = new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(100))
+		gspec  = &Genesis{
+			Config: &params.ChainConfig{HomesteadBlock: new(big.Int)},
+			Alloc:  types.GenesisAlloc{addr: {Balance: funds}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _, err := GenerateChainWithGenesis(gspec, dummy.NewFakerWithCallbacks(TestCallbacks), 200, 10, func(i int, block *BlockGen) {
+		to := common.BigToAddress(big.NewInt(int64(i + 1)))
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), to, big.NewInt(1), 21000, big.NewInt(225000000000), nil), signer, key)
+		require.NoError(err)
+		block.AddTx(tx)
+	})
+	require.NoError(err)
+
+	var (
+		chainDB = rawdb.NewMemoryDatabase()
+		conf    = &CacheConfig{
+			TrieCleanLimit:            256,
+			TrieDirtyLimit:            256,
+			TriePrefetcherParallelism: 4,
+			Pruning:                   true,
+			CommitInterval:            4096,
+			SnapshotLimit:             256,
+			SnapshotNoBuild:           true, // Ensure the test errors if snapshot initialization fails
+			AcceptorQueueLimit:        64,
+			StateScheme:               rawdb.PathScheme,
+			StateHistory:              32,
+			StateHistoryDirectory:     t.TempDir(),
+		}
+	)
+	chain, err := createBlockChain(chainDB, conf, gspec, common.Hash{})
+	require.NoError(err)
+	_, err = chain.InsertChain(blocks)
+	require.NoError(err)
+	for _, block := range blocks {
+		require.NoError(chain.Accept(block))
+	}
+	chain.DrainAcceptorQueue()
+	chain.Stop()
+	require.Equal(rawdb.PathScheme, rawdb.ReadStateScheme(chainDB))
+
+	// The chain is loaded from the journaled layers, the states older than the
+	// retained histories can no longer be recovered.
+	chain, err = createBlockChain(chainDB, conf, gspec, blocks[199].Hash())
+	require.NoError(err)
+	defer chain.Stop()
+	statedb, err := chain.StateAt(blocks[199].Root())
+	require.NoError(err)
+	require.Equal(uint64(200), statedb.GetNonce(addr))
+
+	recoverable, err := chain.TrieDB().Recoverable(blocks[10].Root())
+	require.NoError(err)
+	require.False(recoverable)
+
+	// The layers above the target are discarded by the rollback.
+	target := blocks[60]
+	recoverable, err = chain.TrieDB().Recoverable(target.Root())
+	require.NoError(err)
+	require.True(recoverable)
+	require.NoError(chain.TrieDB().Recover(target.Root()))
+	statedb, err = chain.StateAt(target.Root())
+	require.NoError(err)
+	require.Equal(target.NumberU64(), statedb.GetNonce(addr))
+}
+
+// Tests that the path-based scheme persists the state at the commit interval,
+// so that the last accepted state is regenerated within the bounded reexec
+// after an unclean shutdown.
+func TestPathSchemeUncleanShutdown(t *testing.T) {
+	require := require.New(t)
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		funds  = new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(100))
+		gspec  = &Genesis{
+			Config: &params.ChainConfig{HomesteadBlock: new(big.Int)},
+			Alloc:  types.GenesisAlloc{addr: {Balance: funds}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _, err := GenerateChainWithGenesis(gspec, dummy.NewFakerWithCallbacks(TestCallbacks), 200, 10, func(i int, block *BlockGen) {
+		to := common.BigToAddress(big.NewInt(int64(i + 1)))
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), to, big.NewInt(1), 21000, big.NewInt(225000000000), nil), signer, key)
+		require.NoError(err)
+		block.AddTx(tx)
+	})
+	require.NoError(err)
+
+	var (
+		chainDB = rawdb.NewMemoryDatabase()
+		conf    = &CacheConfig{
+			TrieCleanLimit:            256,
+			TrieDirtyLimit:            256,
+			TriePrefetcherParallelism: 4,
+			Pruning:                   true,
+			CommitInterval:            32,
+			SnapshotLimit:             256,
+			SnapshotNoBuild:           true, // Ensure the test errors if snapshot initialization fails
+			AcceptorQueueLimit:        64,
+			StateScheme:               rawdb.PathScheme,
+			StateHistory:              32,
+			StateHistoryDirectory:     t.TempDir(),
+		}
+	)
+	chain, err := createBlockChain(chainDB, conf, gspec, common.Hash{})
+	require.NoError(err)
+	_, err = chain.InsertChain(blocks)
+	require.NoError(err)
+	for _, block := range blocks {
+		require.NoError(chain.Accept(block))
+	}
+	chain.DrainAcceptorQueue()
+
+	// Stop without journaling the in-memory layers, the state of the last
+	// committed block is the one persisted, within the reexec of twice the
+	// commit interval.
+	chain.stopWithoutSaving()
+	require.NoError(chain.TrieDB().Close())
+
+	chain, err = createBlockChain(chainDB, conf, gspec, blocks[199].Hash())
+	require.NoError(err)
+	defer chain.Stop()
+	statedb, err := chain.StateAt(blocks[199].Root())
+	require.NoError(err)
+	require.Equal(uint64(200), statedb.GetNonce(addr))
+}
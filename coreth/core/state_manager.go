@@ -31,6 +31,7 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/ava-labs/coreth/core/rawdb"
 	"github.com/ava-labs/coreth/core/types"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -72,6 +73,12 @@ type TrieDB interface {
 }
 
 func NewTrieWriter(db TrieDB, config *CacheConfig) TrieWriter {
+	if config.StateScheme == rawdb.PathScheme {
+		return &pathTrieWriter{
+			TrieDB:         db,
+			commitInterval: config.CommitInterval,
+		}
+	}
 	if config.Pruning {
 		cm := &cappedMemoryTrieWriter{
 			TrieDB:           db,
@@ -112,6 +119,31 @@ func (np *noPruningTrieWriter) RejectTrie(block *types.Block) error {
 
 func (np *noPruningTrieWriter) Shutdown() error { return nil }
 
+// pathTrieWriter is used with the path-based scheme, whose trie database keeps
+// the recent states in memory and flushes them to disk on its own. The state
+// of every [commitInterval]th accepted block, or of every accepted block if it
+// is zero, is flushed as well, so that the persisted state stays within reach
+// of the last accepted block after an unclean shutdown.
+type pathTrieWriter struct {
+	TrieDB
+	commitInterval uint64
+}
+
+func (*pathTrieWriter) InsertTrie(block *types.Block) error { return nil }
+
+func (pw *pathTrieWriter) AcceptTrie(block *types.Block) error {
+	if pw.commitInterval != 0 && block.NumberU64()%pw.commitInterval != 0 {
+		return nil
+	}
+	if err := pw.TrieDB.Commit(block.Root(), false); err != nil {
+		return fmt.Errorf("failed to commit trie for block %s: %w", block.Hash().Hex(), err)
+	}
+	return nil
+}
+
+func (*pathTrieWriter) RejectTrie(block *types.Block) error { return nil }
+func (*pathTrieWriter) Shutdown() error                     { return nil }
+
 type cappedMemoryTrieWriter struct {
 	TrieDB
 	memoryCap        common.StorageSize
@@ -62,16 +62,35 @@ func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consen
 	}
 }
 
+// FlareResults holds the Flare specific outcome of processing a block. All but
+// the governance changes are journaled by the node next to the receipts of the
+// block.
+type FlareResults struct {
+	Daemons     types.DaemonResults          // results of the daemon calls
+	Fees        types.FeeTreatments          // fee treatment of the transactions
+	Divergences types.AttestationDivergences // rounds on which the local attestors diverged
+	Governance  types.GovernanceChanges      // governance changes signalled by the transactions
+}
+
+// DeriveFields fills the results with their block metadata.
+func (r *FlareResults) DeriveFields(hash common.Hash, number uint64) {
+	r.Daemons.DeriveFields(hash, number)
+	r.Fees.DeriveFields(hash, number)
+	r.Divergences.DeriveFields(hash, number)
+	r.Governance.DeriveFields(hash, number)
+}
+
 // Process processes the state changes according to the Ethereum rules by running
 // the transaction messages using the statedb and applying any rewards to both
 // the processor (coinbase) and any included uncles.
 //
-// Process returns the receipts and logs accumulated during the process and
-// returns the amount of gas that was used in the process. If any of the
-// transactions failed to execute due to insufficient gas it will return an error.
-func (p *StateProcessor) Process(block *types.Block, parent *types.Header, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error) {
+// Process returns the receipts, logs and Flare results accumulated during the
+// process and returns the amount of gas that was used in the process. If any
+// of the transactions failed to execute due to insufficient gas it will return an error.
+func (p *StateProcessor) Process(block *types.Block, parent *types.Header, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, *FlareResults, uint64, error) {
 	var (
 		receipts    types.Receipts
+		flare       = new(FlareResults)
 		usedGas     = new(uint64)
 		header      = block.Header()
 		blockHash   = block.Hash()
@@ -84,7 +103,7 @@ func (p *StateProcessor) Process(block *types.Block, parent *types.Header, state
 	err := ApplyUpgrades(p.config, &parent.Time, block, statedb)
 	if err != nil {
 		log.Error("failed to configure precompiles processing block", "hash", block.Hash(), "number", block.NumberU64(), "timestamp", block.Time(), "err", err)
-		return nil, nil, 0, err
+		return nil, nil, nil, 0, err
 	}
 
 	var (
@@ -99,25 +118,39 @@ func (p *StateProcessor) Process(block *types.Block, parent *types.Header, state
 	for i, tx := range block.Transactions() {
 		msg, err := TransactionToMessage(tx, signer, header.BaseFee)
 		if err != nil {
-			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			return nil, nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
 		}
 		statedb.SetTxContext(tx.Hash(), i)
-		receipt, err := applyTransaction(msg, p.config, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
+		receipt, result, err := applyTransaction(msg, p.config, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
 		if err != nil {
-			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			return nil, nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
 		}
 		receipts = append(receipts, receipt)
 		allLogs = append(allLogs, receipt.Logs...)
+		if result.DaemonResult != nil {
+			flare.Daemons = append(flare.Daemons, result.DaemonResult)
+		}
+		if result.FeeTreatment != nil {
+			flare.Fees = append(flare.Fees, result.FeeTreatment)
+		}
+		if result.AttestationRound != nil && result.AttestationRound.Diverged() {
+			flare.Divergences = append(flare.Divergences, result.AttestationRound.divergence(tx.Hash(), i))
+		}
+		if result.GovernanceChange != nil {
+			flare.Governance = append(flare.Governance, result.GovernanceChange)
+		}
 	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
 	if err := p.engine.Finalize(p.bc, block, parent, statedb, receipts); err != nil {
-		return nil, nil, 0, fmt.Errorf("engine finalization check failed: %w", err)
+		return nil, nil, nil, 0, fmt.Errorf("engine finalization check failed: %w", err)
 	}
 
-	return receipts, allLogs, *usedGas, nil
+	flare.DeriveFields(blockHash, blockNumber.Uint64())
+
+	return receipts, allLogs, flare, *usedGas, nil
 }
 
-func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM) (*types.Receipt, error) {
+func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM) (*types.Receipt, *ExecutionResult, error) {
 	// Create a new context to be used in the EVM environment.
 	txContext := NewEVMTxContext(msg)
 	evm.Reset(txContext, statedb)
@@ -125,7 +158,7 @@ func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, sta
 	// Apply the transaction to the current state (included in the env).
 	result, err := ApplyMessage(evm, msg, gp)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Update the state with pending changes.
@@ -164,7 +197,22 @@ func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, sta
 	receipt.BlockHash = blockHash
 	receipt.BlockNumber = blockNumber
 	receipt.TransactionIndex = uint(statedb.TxIndex())
-	return receipt, err
+
+	// Attach the transaction to the outcome of the Flare daemon, if it was
+	// called, to its fee treatment and to the governance change it signalled.
+	if daemon := result.DaemonResult; daemon != nil {
+		daemon.TxHash = receipt.TxHash
+		daemon.TxIndex = receipt.TransactionIndex
+	}
+	if fee := result.FeeTreatment; fee != nil {
+		fee.TxHash = receipt.TxHash
+		fee.TxIndex = receipt.TransactionIndex
+	}
+	if change := result.GovernanceChange; change != nil {
+		change.TxHash = receipt.TxHash
+		change.TxIndex = receipt.TransactionIndex
+	}
+	return receipt, result, err
 }
 
 // ApplyTransaction attempts to apply a transaction to the given state database
@@ -179,7 +227,8 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, blockContext
 	// Create a new context to be used in the EVM environment
 	txContext := NewEVMTxContext(msg)
 	vmenv := vm.NewEVM(blockContext, txContext, statedb, config, cfg)
-	return applyTransaction(msg, config, gp, statedb, header.Number, header.Hash(), tx, usedGas, vmenv)
+	receipt, _, err := applyTransaction(msg, config, gp, statedb, header.Number, header.Hash(), tx, usedGas, vmenv)
+	return receipt, err
 }
 
 // ProcessBeaconBlockRoot applies the EIP-4788 system call to the beacon block root
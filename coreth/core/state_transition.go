@@ -53,6 +53,15 @@ type ExecutionResult struct {
 	RefundedGas uint64 // Total gas refunded after execution
 	Err         error  // Any error encountered during the execution(listed in core/vm/errors.go)
 	ReturnData  []byte // Returned data from evm(function result or data supplied with revert opcode)
+
+	DaemonResult *types.DaemonResult // Outcome of the Flare daemon call and mint, nil if the daemon was not called
+	FeeTreatment *types.FeeTreatment // How the fee was charged on Flare and Songbird networks, nil on other networks
+
+	AttestationRound *AttestationRound // Votes on the state connector round finalised after the call, nil if none
+
+	FlareEffects *FlareEffects // Flare side effects applied after a simulated call, nil unless the message selected them
+
+	GovernanceChange *types.GovernanceChange // Governance change signalled after the call, nil if none
 }
 
 // Unwrap returns the internal evm error which allows us for further
@@ -90,8 +99,8 @@ func (st *StateTransition) DaemonRevertToSnapshot(snapshot int) {
 	st.evm.StateDB.RevertToSnapshot(snapshot)
 }
 
-func (st *StateTransition) GetChainID() *big.Int {
-	return st.evm.ChainConfig().ChainID
+func (st *StateTransition) SystemContracts() *params.FlareSystemContracts {
+	return st.evm.ChainConfig().SystemContracts()
 }
 
 func (st *StateTransition) GetBlockTime() uint64 {
@@ -231,6 +240,12 @@ type Message struct {
 	// account nonce in state. It also disables checking that the sender is an EOA.
 	// This field will be set to true for operations like RPC eth_call.
 	SkipAccountChecks bool
+
+	// When FlareSideEffects is set, only the selected Flare system contract side
+	// effects are applied after the call and all of them are reported in the
+	// execution result. It is only set for simulated calls, when nil all side
+	// effects are applied.
+	FlareSideEffects *FlareSideEffects
 }
 
 // TransactionToMessage converts a transaction into a Message.
@@ -298,6 +313,9 @@ type StateTransition struct {
 	initialGas   uint64
 	state        vm.StateDB
 	evm          *vm.EVM
+	effects      *FlareEffects // Flare side effects of a simulated call, nil unless selected by the message
+
+	governanceChange *types.GovernanceChange // Governance change signalled after the message, if any
 }
 
 // NewStateTransition initialises and returns a new state transition object.
@@ -513,17 +531,23 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	// - reset transient storage(eip 1153)
 	st.state.Prepare(rules, msg.From, st.evm.Context.Coinbase, msg.To, vm.ActivePrecompiles(rules), msg.AccessList)
 
+	if msg.FlareSideEffects != nil {
+		st.effects = &FlareEffects{Applied: *msg.FlareSideEffects}
+	}
+
 	var (
 		ret       []byte
 		vmerr     error // vm errors do not affect consensus and are therefore not assigned to err
-		chainID   *big.Int
+		contracts *params.FlareSystemContracts
 		timestamp uint64
+
+		attestationRound *AttestationRound
 	)
 
-	chainID = st.evm.ChainConfig().ChainID
+	contracts = st.evm.ChainConfig().SystemContracts()
 	timestamp = st.evm.Context.Time
 
-	burnAddress, nominalGasPrice, isFlare, isSongbird, err := stateTransitionVariants.GetValue(chainID)(st)
+	burnAddress, nominalGasPrice, isFlare, isSongbird, err := stateTransitionVariant(contracts)(st)
 	if err != nil {
 		return nil, err
 	}
@@ -534,11 +558,11 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 		// Increment the nonce for the next transaction
 		st.state.SetNonce(msg.From, st.state.GetNonce(sender.Address())+1)
 		ret, st.gasRemaining, vmerr = st.evm.Call(sender, st.to(), msg.Data, st.gasRemaining, value)
-		if vmerr == nil && chainID != nil {
+		if vmerr == nil {
 			if isSongbird { // Songbird, Coston, Local (Songbird)
-				handleSongbirdTransitionDbContracts(st, rules.IsDurango, chainID, timestamp, msg, ret)
+				attestationRound = handleSongbirdTransitionDbContracts(st, rules.IsDurango, contracts, timestamp, msg, ret)
 			} else if isFlare { // Flare, Coston2, Local (Flare)
-				handleFlareTransitionDbContracts(st, rules.IsDurango, chainID, timestamp, msg, ret)
+				attestationRound = handleFlareTransitionDbContracts(st, rules.IsDurango, contracts, timestamp, msg, ret)
 			}
 		}
 	}
@@ -548,28 +572,57 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	}
 	gasRefund := st.refundGas(rules.IsApricotPhase1)
 
-	if vmerr == nil && IsPrioritisedContractCall(chainID, timestamp, msg.To, msg.Data, ret, st.initialGas) {
-		nominalGasUsed := params.TxGas // 21000
-		nominalFee := new(uint256.Int).Mul(uint256.NewInt(nominalGasUsed), uint256.NewInt(nominalGasPrice))
-		actualGasUsed := st.gasUsed()
-		actualFee := new(uint256.Int).Mul(uint256.NewInt(actualGasUsed), price)
-		if actualFee.Cmp(nominalFee) > 0 {
-			feeRefund := new(uint256.Int).Sub(actualFee, nominalFee)
-			st.state.AddBalance(st.msg.From, feeRefund)
-			st.state.AddBalance(burnAddress, nominalFee)
-		} else {
-			st.state.AddBalance(burnAddress, actualFee)
-		}
+	prioritised, prioritisedRule := false, PrioritisedRuleFailedCall
+	if vmerr == nil {
+		prioritised, prioritisedRule = ClassifyPrioritisedContractCall(contracts, timestamp, msg.To, msg.Data, ret, st.initialGas)
+	}
+	nominalGasUsed := params.TxGas // 21000
+	nominalFee := new(uint256.Int).Mul(uint256.NewInt(nominalGasUsed), uint256.NewInt(nominalGasPrice))
+	actualFee := new(uint256.Int).Mul(uint256.NewInt(st.gasUsed()), price)
+	feeRefund := new(uint256.Int)
+	if prioritised && actualFee.Cmp(nominalFee) > 0 {
+		feeRefund.Sub(actualFee, nominalFee)
+		st.state.AddBalance(st.msg.From, feeRefund)
+		st.state.AddBalance(burnAddress, nominalFee)
+		st.recordCredit(st.msg.From, feeRefund, FlareCreditFeeRefund)
+		st.recordCredit(burnAddress, nominalFee, FlareCreditFee)
 	} else {
-		fee := new(uint256.Int).SetUint64(st.gasUsed())
-		fee.Mul(fee, price)
-		st.state.AddBalance(burnAddress, fee)
+		st.state.AddBalance(burnAddress, actualFee)
+		st.recordCredit(burnAddress, actualFee, FlareCreditFee)
+	}
+	var feeTreatment *types.FeeTreatment
+	if isSongbird || isFlare {
+		feeTreatment = &types.FeeTreatment{
+			Prioritised: prioritised,
+			Rule:        prioritisedRule,
+			GasUsed:     st.gasUsed(),
+			GasPrice:    price.ToBig(),
+			Fee:         actualFee.ToBig(),
+			NominalFee:  nominalFee.ToBig(),
+			Refund:      feeRefund.ToBig(),
+		}
 	}
 
 	// Call the daemon if there is no vm error
-	if vmerr == nil && (isSongbird || isFlare) {
+	var daemonRecord *types.DaemonResult
+	if vmerr == nil && (isSongbird || isFlare) && msg.appliesDaemon() {
 		log := log.Root()
-		atomicDaemonAndMint(st, log)
+		result := atomicDaemonAndMint(st, log)
+		daemonRecord = result.record()
+		st.recordCredit(contracts.Daemon.Address, result.minted, FlareCreditMint)
+		if tracer := st.evm.SystemCallTracer(); tracer != nil {
+			if result.mintErr != nil {
+				tracer.CaptureSystemRevert(result.mintErr)
+			} else if !result.minted.IsZero() {
+				tracer.CaptureSystemMint(contracts.Daemon.Address, result.minted.ToBig())
+			}
+		}
+	}
+
+	if st.effects != nil {
+		st.effects.Daemon = daemonRecord
+		st.effects.FeeTreatment = feeTreatment
+		st.effects.AttestationRound = attestationRound
 	}
 
 	return &ExecutionResult{
@@ -577,55 +630,79 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 		RefundedGas: gasRefund,
 		Err:         vmerr,
 		ReturnData:  ret,
+
+		DaemonResult: daemonRecord,
+		FeeTreatment: feeTreatment,
+
+		AttestationRound: attestationRound,
+
+		FlareEffects: st.effects,
+
+		GovernanceChange: st.governanceChange,
 	}, nil
 }
 
-func handleSongbirdTransitionDbContracts(st *StateTransition, isDurango bool, chainID *big.Int, timestamp uint64, msg *Message, ret []byte) {
-	if GetStateConnectorIsActivatedAndCalled(isDurango, chainID, timestamp, *msg.To) &&
+func handleSongbirdTransitionDbContracts(st *StateTransition, isDurango bool, contracts *params.FlareSystemContracts, timestamp uint64, msg *Message, ret []byte) *AttestationRound {
+	if msg.appliesStateConnector() && GetStateConnectorIsActivatedAndCalled(isDurango, contracts, timestamp, *msg.To) &&
 		len(msg.Data) >= 36 && len(ret) == 32 &&
-		bytes.Equal(msg.Data[0:4], SubmitAttestationSelector(chainID, timestamp)) &&
+		bytes.Equal(msg.Data[0:4], contracts.StateConnector.SubmitAttestationSelector[:]) &&
 		binary.BigEndian.Uint64(ret[24:32]) > 0 {
-		if err := st.FinalisePreviousRound(chainID, timestamp, msg.Data[4:36]); err != nil {
-			log.Warn("Error finalising state connector round", "error", err)
-		}
+		return finaliseStateConnectorRound(st, contracts, timestamp, msg.Data[4:36])
 	}
+	return nil
 }
 
-func handleFlareTransitionDbContracts(st *StateTransition, isDurango bool, chainID *big.Int, timestamp uint64, msg *Message, ret []byte) {
+func handleFlareTransitionDbContracts(st *StateTransition, isDurango bool, contracts *params.FlareSystemContracts, timestamp uint64, msg *Message, ret []byte) *AttestationRound {
 	if st.evm.Context.Coinbase != common.HexToAddress("0x0100000000000000000000000000000000000000") {
-		return
+		return nil
 	}
 
-	if GetStateConnectorIsActivatedAndCalled(isDurango, chainID, timestamp, *msg.To) &&
+	if GetStateConnectorIsActivatedAndCalled(isDurango, contracts, timestamp, *msg.To) &&
 		len(msg.Data) >= 36 && len(ret) == 32 &&
-		bytes.Equal(msg.Data[0:4], SubmitAttestationSelector(chainID, timestamp)) &&
+		bytes.Equal(msg.Data[0:4], contracts.StateConnector.SubmitAttestationSelector[:]) &&
 		binary.BigEndian.Uint64(ret[24:32]) > 0 {
-		if err := st.FinalisePreviousRound(chainID, timestamp, msg.Data[4:36]); err != nil {
-			log.Warn("Error finalising state connector round", "error", err)
+		if !msg.appliesStateConnector() {
+			return nil
 		}
-	} else if GetGovernanceSettingIsActivatedAndCalled(chainID, timestamp, *msg.To) && len(msg.Data) == 36 {
-		if bytes.Equal(msg.Data[0:4], SetGovernanceAddressSelector(chainID, timestamp)) {
-			if err := st.SetGovernanceAddress(chainID, timestamp, msg.Data[4:36]); err != nil {
+		return finaliseStateConnectorRound(st, contracts, timestamp, msg.Data[4:36])
+	} else if !msg.appliesGovernance() {
+		return nil
+	} else if GetGovernanceSettingIsActivatedAndCalled(contracts, timestamp, *msg.To) && len(msg.Data) == 36 {
+		if bytes.Equal(msg.Data[0:4], contracts.GovernanceSettings.SetGovernanceAddressSelector[:]) {
+			if err := st.SetGovernanceAddress(contracts, timestamp, msg.Data[4:36]); err != nil {
 				log.Warn("Error setting governance address", "error", err)
 			}
-		} else if bytes.Equal(msg.Data[0:4], SetTimelockSelector(chainID, timestamp)) {
-			if err := st.SetTimelock(chainID, timestamp, msg.Data[4:36]); err != nil {
+		} else if bytes.Equal(msg.Data[0:4], contracts.GovernanceSettings.SetTimelockSelector[:]) {
+			if err := st.SetTimelock(contracts, timestamp, msg.Data[4:36]); err != nil {
 				log.Warn("Error setting governance timelock", "error", err)
 			}
 		}
-	} else if GetInitialAirdropChangeIsActivatedAndCalled(chainID, timestamp, *msg.To) && len(msg.Data) == 4 {
-		if bytes.Equal(msg.Data[0:4], UpdateInitialAirdropAddressSelector(chainID, timestamp)) {
-			if err := st.UpdateInitialAirdropAddress(chainID, timestamp); err != nil {
+	} else if GetInitialAirdropChangeIsActivatedAndCalled(contracts, timestamp, *msg.To) && len(msg.Data) == 4 {
+		if bytes.Equal(msg.Data[0:4], contracts.InitialAirdrop.Selector[:]) {
+			if err := st.UpdateInitialAirdropAddress(contracts); err != nil {
 				log.Warn("Error updating initialAirdrop contract", "error", err)
 			}
 		}
-	} else if GetDistributionChangeIsActivatedAndCalled(chainID, timestamp, *msg.To) && len(msg.Data) == 4 {
-		if bytes.Equal(msg.Data[0:4], UpdateDistributionAddressSelector(chainID, timestamp)) {
-			if err := st.UpdateDistributionAddress(chainID, timestamp); err != nil {
+	} else if GetDistributionChangeIsActivatedAndCalled(contracts, timestamp, *msg.To) && len(msg.Data) == 4 {
+		if bytes.Equal(msg.Data[0:4], contracts.Distribution.Selector[:]) {
+			if err := st.UpdateDistributionAddress(contracts); err != nil {
 				log.Warn("Error updating distribution contract", "error", err)
 			}
 		}
 	}
+	return nil
+}
+
+// finaliseStateConnectorRound finalises the given state connector round and
+// returns the votes on it. Finalisation errors are logged and recorded in the
+// returned round, but do not affect the transaction.
+func finaliseStateConnectorRound(st *StateTransition, contracts *params.FlareSystemContracts, timestamp uint64, currentRoundNumber []byte) *AttestationRound {
+	round, err := st.FinalisePreviousRound(contracts, timestamp, currentRoundNumber)
+	if err != nil {
+		log.Warn("Error finalising state connector round", "error", err)
+		round.Error = err.Error()
+	}
+	return round
 }
 
 func (st *StateTransition) refundGas(apricotPhase1 bool) uint64 {
@@ -33,8 +33,8 @@ func TestStateTransitionPrioritizedContract(t *testing.T) {
 		key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		from := crypto.PubkeyToAddress(key.PublicKey)
 		gas := uint64(3000000)
-		to := prioritisedSubmitterContractAddress
-		daemon := common.HexToAddress(GetDaemonContractAddr(0))
+		to := config.SystemContracts().Prioritised.SubmitterAddress
+		daemon := config.SystemContracts().Daemon.Address
 		signer := types.LatestSignerForChainID(config.ChainID)
 		tx, err := types.SignNewTx(key, signer,
 			&types.LegacyTx{
@@ -102,7 +102,7 @@ func TestStateTransitionPrioritizedContract(t *testing.T) {
 		balanceAfter := st.state.GetBalance(st.msg.From)
 
 		// max fee (funds above which are returned) depends on the chain used
-		_, limit, _, _, _ := stateTransitionVariants.GetValue(config.ChainID)(st)
+		_, limit, _, _, _ := stateTransitionVariant(config.SystemContracts())(st)
 		maxFee := new(uint256.Int).Mul(uint256.NewInt(params.TxGas), uint256.NewInt(limit))
 		diff := new(uint256.Int).Sub(balanceBefore, balanceAfter)
 
@@ -125,7 +125,7 @@ func TestStateTransitionDaemon(t *testing.T) {
 		key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		from := crypto.PubkeyToAddress(key.PublicKey)
 		gas := uint64(3000000)
-		daemon := common.HexToAddress(GetDaemonContractAddr(0))
+		daemon := config.SystemContracts().Daemon.Address
 		to := common.HexToAddress("0x7e22C4A78675ae3Be11Fb389Da9b9fb15996bb6a")
 		signer := types.LatestSignerForChainID(config.ChainID)
 		tx, err := types.SignNewTx(key, signer,
@@ -2,18 +2,24 @@ package core
 
 import (
 	"errors"
-	"math/big"
 
+	"github.com/ava-labs/coreth/core/vm"
 	"github.com/ava-labs/coreth/params"
-	"github.com/ava-labs/coreth/utils"
 	"github.com/ethereum/go-ethereum/common"
 )
 
-var (
-	stateTransitionVariants = utils.NewChainValue(nonFlareChain).
-		AddValues([]*big.Int{params.FlareChainID, params.CostwoChainID, params.LocalFlareChainID}, stateTransitionParamsFlare).
-		AddValues([]*big.Int{params.SongbirdChainID, params.CostonChainID, params.LocalChainID}, stateTransitionParamsSongbird)
-)
+// stateTransitionVariant returns the state transition parameters of the
+// system contract rules of the chain
+func stateTransitionVariant(contracts *params.FlareSystemContracts) func(st *StateTransition) (common.Address, uint64, bool, bool, error) {
+	switch {
+	case contracts.IsFlare():
+		return stateTransitionParamsFlare
+	case contracts.IsSongbird():
+		return stateTransitionParamsSongbird
+	default:
+		return nonFlareChain
+	}
+}
 
 // Used in tests
 func nonFlareChain(st *StateTransition) (common.Address, uint64, bool, bool, error) {
@@ -41,3 +47,10 @@ func stateTransitionParamsSongbird(st *StateTransition) (common.Address, uint64,
 	}
 	return burnAddress, 225_000_000_000, false, true, nil
 }
+
+// FeeParams returns the address the transaction fees are burnt to and the
+// nominal gas price charged to prioritised calls for the block of [evm].
+func FeeParams(evm *vm.EVM) (common.Address, uint64, error) {
+	burnAddress, nominalGasPrice, _, _, err := stateTransitionVariant(evm.ChainConfig().SystemContracts())(&StateTransition{evm: evm})
+	return burnAddress, nominalGasPrice, err
+}
@@ -116,7 +116,7 @@ func executeStateTransitionTest(t *testing.T, st stateTransitionTest) {
 	require.NoError(err)
 
 	block := GenerateBadBlock(genesis, engine, st.txs, blockchain.chainConfig)
-	receipts, _, _, err := blockchain.processor.Process(block, genesis.Header(), statedb, blockchain.vmConfig)
+	receipts, _, _, _, err := blockchain.processor.Process(block, genesis.Header(), statedb, blockchain.vmConfig)
 
 	if st.want == "" {
 		// If no error is expected, require no error and verify the correct gas used amounts from the receipts
@@ -0,0 +1,212 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// BlockTracer returns the flat call traces of the transactions of an accepted
+// block, along with the addresses they are indexed by.
+type BlockTracer func(block *types.Block) ([]rawdb.IndexedTrace, error)
+
+// traceIndexer is the module responsible for tracing every accepted block and
+// maintaining the trace index according to the configured retention window.
+// Blocks are indexed from the last accepted block at the time the index was
+// enabled, the older ones are not traced.
+type traceIndexer struct {
+	// limit is the maximum number of blocks from head whose traces are
+	// reserved:
+	//  * 0: means all the traced blocks are kept
+	//  * N: means the latest N blocks [HEAD-N+1, HEAD] should be indexed
+	//       and all others shouldn't.
+	limit  uint64
+	db     ethdb.Database
+	tracer BlockTracer
+	term   chan chan struct{}
+	closed chan struct{}
+
+	chain *BlockChain
+}
+
+// newTraceIndexer initializes the trace indexer.
+func newTraceIndexer(limit uint64, tracer BlockTracer, chain *BlockChain) *traceIndexer {
+	indexer := &traceIndexer{
+		limit:  limit,
+		db:     chain.db,
+		tracer: tracer,
+		term:   make(chan chan struct{}),
+		closed: make(chan struct{}),
+		chain:  chain,
+	}
+	chain.wg.Add(1)
+	go func() {
+		defer chain.wg.Done()
+		indexer.loop()
+	}()
+
+	var msg string
+	if limit == 0 {
+		msg = "all traced blocks"
+	} else {
+		msg = fmt.Sprintf("last %d blocks", limit)
+	}
+	log.Info("Initialized trace indexer", "range", msg)
+
+	return indexer
+}
+
+// run traces the accepted blocks up to [head] that have not been indexed yet
+// and unindexes the ones that fell out of the retention window. If the stop
+// channel is closed, the task is terminated after the block being traced, the
+// done channel will be closed once the task is finished.
+func (indexer *traceIndexer) run(head uint64, stop chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	var (
+		tail = rawdb.ReadTraceIndexTail(indexer.db)
+		last = rawdb.ReadTraceIndexHead(indexer.db)
+		from = head
+	)
+	if last != nil {
+		from = *last + 1
+	}
+	if indexer.limit != 0 && head+1 > indexer.limit && from < head+1-indexer.limit {
+		// The indexed blocks are all out of the window, they are dropped so
+		// that the index has no gap.
+		from = head + 1 - indexer.limit
+		if tail != nil && last != nil {
+			indexer.unindex(*tail, *last+1, stop)
+			tail = nil
+		}
+	}
+	for number := from; number <= head; number++ {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		var traces []rawdb.IndexedTrace
+		if number > 0 {
+			block := indexer.chain.GetBlockByNumber(number)
+			if block == nil {
+				log.Error("Failed to find accepted block to trace", "number", number)
+				return
+			}
+			var err error
+			if traces, err = indexer.tracer(block); err != nil {
+				log.Error("Failed to index block traces", "number", number, "err", err)
+				return
+			}
+		}
+		batch := indexer.db.NewBatch()
+		rawdb.WriteBlockTraces(batch, number, traces)
+		rawdb.WriteTraceIndexHead(batch, number)
+		if tail == nil {
+			rawdb.WriteTraceIndexTail(batch, number)
+			tail = &number
+		}
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to write block traces", "err", err)
+		}
+	}
+	if indexer.limit != 0 && tail != nil && head+1 > indexer.limit && *tail < head+1-indexer.limit {
+		indexer.unindex(*tail, head+1-indexer.limit, stop)
+	}
+}
+
+// unindex removes the traces of the blocks in [from, to) and forwards the
+// index tail.
+func (indexer *traceIndexer) unindex(from, to uint64, stop chan struct{}) {
+	batch := indexer.db.NewBatch()
+	for number := from; number < to; number++ {
+		select {
+		case <-stop:
+			to = number
+		default:
+			rawdb.DeleteBlockTraces(batch, number, rawdb.ReadBlockTraces(indexer.db, number))
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize || number+1 >= to {
+			rawdb.WriteTraceIndexTail(batch, min(number+1, to))
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to unindex block traces", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+}
+
+// loop is the scheduler of the indexer, tracing the accepted blocks in a
+// background task.
+func (indexer *traceIndexer) loop() {
+	defer close(indexer.closed)
+	var (
+		stop     chan struct{} // Non-nil if background routine is active.
+		done     chan struct{} // Non-nil if background routine is active.
+		lastHead uint64        // The latest accepted block
+		runHead  uint64        // The head of the running task
+
+		headCh = make(chan ChainEvent)
+		sub    = indexer.chain.SubscribeChainAcceptedEvent(headCh)
+	)
+	if sub == nil {
+		log.Warn("could not create chain accepted subscription to index traces")
+		return
+	}
+	defer sub.Unsubscribe()
+
+	launch := func(head uint64) {
+		stop = make(chan struct{})
+		done = make(chan struct{})
+		runHead = head
+		indexer.chain.wg.Add(1)
+		go func() {
+			defer indexer.chain.wg.Done()
+			indexer.run(head, stop, done)
+		}()
+	}
+	// Index the blocks accepted while the node was down.
+	lastHead = indexer.chain.LastAcceptedBlock().NumberU64()
+	launch(lastHead)
+	for {
+		select {
+		case head := <-headCh:
+			lastHead = head.Block.NumberU64()
+			if done == nil {
+				launch(lastHead)
+			}
+		case <-done:
+			stop = nil
+			done = nil
+			if lastHead > runHead {
+				launch(lastHead)
+			}
+		case ch := <-indexer.term:
+			if stop != nil {
+				close(stop)
+			}
+			if done != nil {
+				log.Info("Waiting background trace indexer to exit")
+				<-done
+			}
+			close(ch)
+			return
+		}
+	}
+}
+
+// close shutdown the indexer. Safe to be called for multiple times.
+func (indexer *traceIndexer) close() {
+	ch := make(chan struct{})
+	select {
+	case indexer.term <- ch:
+		<-ch
+	case <-indexer.closed:
+	}
+}
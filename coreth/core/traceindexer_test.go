@@ -0,0 +1,109 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceIndexer(t *testing.T) {
+	require := require.New(t)
+	var (
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		key2, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+		funds   = big.NewInt(10000000000000)
+		gspec   = &Genesis{
+			Config: &params.ChainConfig{HomesteadBlock: new(big.Int)},
+			Alloc:  types.GenesisAlloc{addr1: {Balance: funds}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _, err := GenerateChainWithGenesis(gspec, dummy.NewFakerWithCallbacks(TestCallbacks), 12, 10, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr1), addr2, big.NewInt(10000), params.TxGas, nil, nil), signer, key1)
+		require.NoError(err)
+		block.AddTx(tx)
+	})
+	require.NoError(err)
+
+	// tracer traces every transaction as a single call from its sender.
+	tracer := func(block *types.Block) ([]rawdb.IndexedTrace, error) {
+		traces := make([]rawdb.IndexedTrace, len(block.Transactions()))
+		for i, tx := range block.Transactions() {
+			traces[i] = rawdb.IndexedTrace{
+				TxIndex: uint32(i),
+				From:    &addr1,
+				To:      tx.To(),
+				Trace:   []byte(fmt.Sprintf(`{"blockNumber":%d}`, block.NumberU64())),
+			}
+		}
+		return traces, nil
+	}
+	conf := &CacheConfig{
+		TrieCleanLimit:            256,
+		TrieDirtyLimit:            256,
+		TrieDirtyCommitTarget:     20,
+		TriePrefetcherParallelism: 4,
+		Pruning:                   true,
+		CommitInterval:            4096,
+		SnapshotLimit:             256,
+		SnapshotNoBuild:           true, // Ensure the test errors if snapshot initialization fails
+		AcceptorQueueLimit:        64,
+	}
+	waitIndex := func(chain *BlockChain, tail, head uint64) {
+		require.Eventually(func() bool {
+			t, h := rawdb.ReadTraceIndexTail(chain.db), rawdb.ReadTraceIndexHead(chain.db)
+			return t != nil && *t == tail && h != nil && *h == head
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+	insertAndAccept := func(chain *BlockChain, blocks []*types.Block) {
+		_, err := chain.InsertChain(blocks)
+		require.NoError(err)
+		for _, block := range blocks {
+			require.NoError(chain.Accept(block))
+		}
+		chain.DrainAcceptorQueue()
+	}
+
+	// Index the last 4 accepted blocks from genesis.
+	chainDB := rawdb.NewMemoryDatabase()
+	chain, err := createBlockChain(chainDB, conf, gspec, common.Hash{})
+	require.NoError(err)
+	chain.StartTraceIndexer(4, tracer)
+	insertAndAccept(chain, blocks[:10])
+	waitIndex(chain, 7, 10)
+	for number := uint64(1); number < 7; number++ {
+		require.Nil(rawdb.ReadBlockTraces(chainDB, number), "block %d", number)
+	}
+	traces := rawdb.ReadBlockTraces(chainDB, 8)
+	require.Len(traces, 1)
+	require.Equal(`{"blockNumber":8}`, string(traces[0].Trace))
+	positions := rawdb.ReadTracePositions(chainDB, addr2, rawdb.TraceRoleTo, 0, 12)
+	require.Equal([]rawdb.TracePosition{{Number: 7}, {Number: 8}, {Number: 9}, {Number: 10}}, positions)
+	chain.Stop()
+
+	// Keep all the traces after a restart, the blocks accepted in the
+	// meantime are indexed on startup.
+	chain, err = createBlockChain(chainDB, conf, gspec, blocks[9].Hash())
+	require.NoError(err)
+	insertAndAccept(chain, blocks[10:11])
+	chain.StartTraceIndexer(0, tracer)
+	waitIndex(chain, 7, 11)
+	insertAndAccept(chain, blocks[11:])
+	waitIndex(chain, 7, 12)
+	require.Len(rawdb.ReadTracePositions(chainDB, addr1, rawdb.TraceRoleFrom, 0, 12), 6)
+	chain.Stop()
+}
@@ -28,6 +28,7 @@
 package legacypool
 
 import (
+	"container/heap"
 	"errors"
 	"math"
 	"math/big"
@@ -115,6 +116,11 @@ var (
 	slotsGauge   = metrics.NewRegisteredGauge("txpool/slots", nil)
 
 	reheapTimer = metrics.NewRegisteredTimer("txpool/reheap", nil)
+
+	// prioritised lane metrics, see core.IsPrioritisedTransaction
+	prioritisedGauge      = metrics.NewRegisteredGauge("txpool/prioritised", nil)
+	prioritisedSlotsGauge = metrics.NewRegisteredGauge("txpool/prioritised/slots", nil)
+	prioritisedFullMeter  = metrics.NewRegisteredMeter("txpool/prioritised/full", nil) // Prioritised but lane out of slots
 )
 
 // BlockChain defines the minimal set of methods needed to back a tx pool with
@@ -150,6 +156,8 @@ type Config struct {
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
+	PrioritisedSlots uint64 // Maximum number of slots of the global limits held by prioritised contract calls
+
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
 }
 
@@ -168,6 +176,8 @@ var DefaultConfig = Config{
 	AccountQueue: 64,
 	GlobalQueue:  1024,
 
+	PrioritisedSlots: 512,
+
 	Lifetime: 10 * time.Minute,
 }
 
@@ -293,6 +303,7 @@ func New(config Config, chain BlockChain) *LegacyPool {
 		pool.locals.add(addr)
 	}
 	pool.priced = newPricedList(pool.all)
+	pool.all.prioritise, pool.all.prioritisedCap = pool.isPrioritised, int(config.PrioritisedSlots)
 
 	if !config.NoLocals && config.Journal != "" {
 		pool.journal = newTxJournal(config.Journal)
@@ -778,10 +789,31 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 			}
 		}()
 	}
-	// If the transaction pool is full, discard underpriced transactions
+	// Prioritised contract calls are held in their own lane as long as it has
+	// room for them, making room by evicting cheaper calls from the lane if
+	// needed. The ones that don't fit are priced as any other transaction.
+	prioritised := !isLocal && pool.isPrioritised(tx)
+	if prioritised && !pool.all.AdmitsPrioritised(tx) {
+		drop, success := pool.discardPrioritised(tx)
+		if !success {
+			prioritisedFullMeter.Mark(1)
+			prioritised = false
+		}
+		for _, tx := range drop {
+			log.Trace("Discarding underpriced prioritised transaction", "hash", tx.Hash(), "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
+			underpricedTxMeter.Mark(1)
+
+			sender, _ := types.Sender(pool.signer, tx)
+			pool.changesSinceReorg += pool.removeTx(tx.Hash(), false, sender != from)
+		}
+	}
+	// If the transaction pool is full, discard underpriced transactions. The
+	// transactions of the prioritised lane count against the global limits,
+	// but are only evicted by other prioritised transactions.
 	if uint64(pool.all.Slots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
-		// If the new transaction is underpriced, don't accept it
-		if !isLocal && pool.priced.Underpriced(tx) {
+		// If the new transaction is underpriced, don't accept it. Transactions
+		// entering the prioritised lane make room regardless of their price.
+		if !isLocal && !prioritised && pool.priced.Underpriced(tx) {
 			log.Trace("Discarding underpriced transaction", "hash", hash, "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
 			underpricedTxMeter.Mark(1)
 			return false, txpool.ErrUnderpriced
@@ -884,6 +916,38 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 	return replaced, nil
 }
 
+// isPrioritised reports whether a transaction calls one of the Flare prioritised
+// contracts under the rules in effect at the current head.
+func (pool *LegacyPool) isPrioritised(tx *types.Transaction) bool {
+	head := pool.currentHead.Load()
+	if head == nil {
+		return false
+	}
+	return core.IsPrioritisedTransaction(pool.chainconfig.SystemContracts(), head.Time, tx)
+}
+
+// discardPrioritised finds the cheapest transactions of the prioritised lane
+// to evict to make room for the given one. It fails if the lane can't make
+// room for it with transactions paying less than it.
+func (pool *LegacyPool) discardPrioritised(tx *types.Transaction) (types.Transactions, bool) {
+	h := &priceHeap{baseFee: pool.priced.urgent.baseFee, list: pool.all.Prioritised()}
+	heap.Init(h)
+
+	var drop types.Transactions
+	for slots := pool.all.PrioritisedSlots() + numSlots(tx) - int(pool.config.PrioritisedSlots); slots > 0; {
+		if h.Len() == 0 {
+			return nil, false
+		}
+		cheapest := heap.Pop(h).(*types.Transaction)
+		if h.cmp(cheapest, tx) >= 0 {
+			return nil, false
+		}
+		drop = append(drop, cheapest)
+		slots -= numSlots(cheapest)
+	}
+	return drop, true
+}
+
 // isGapped reports whether the given transaction is immediately executable.
 func (pool *LegacyPool) isGapped(from common.Address, tx *types.Transaction) bool {
 	// Short circuit if transaction falls within the scope of the pending list
@@ -1932,18 +1996,27 @@ func (as *accountSet) merge(other *accountSet) {
 //
 // This lookup set combines the notion of "local transactions", which is useful
 // to build upper-level structure.
+//
+// Remote transactions accepted by the prioritise callback are additionally
+// tracked in the prioritised lane, as long as it has free slots.
 type lookup struct {
 	slots   int
 	lock    sync.RWMutex
 	locals  map[common.Hash]*types.Transaction
 	remotes map[common.Hash]*types.Transaction
+
+	prioritise       func(tx *types.Transaction) bool // Classifier of prioritised transactions, nil disables the lane
+	prioritisedCap   int                              // Maximum number of slots held in the prioritised lane
+	prioritisedSlots int                              // Current number of slots held in the prioritised lane
+	prioritised      map[common.Hash]struct{}         // Remote transactions held in the prioritised lane
 }
 
 // newLookup returns a new lookup structure.
 func newLookup() *lookup {
 	return &lookup{
-		locals:  make(map[common.Hash]*types.Transaction),
-		remotes: make(map[common.Hash]*types.Transaction),
+		locals:      make(map[common.Hash]*types.Transaction),
+		remotes:     make(map[common.Hash]*types.Transaction),
+		prioritised: make(map[common.Hash]struct{}),
 	}
 }
 
@@ -2029,6 +2102,51 @@ func (t *lookup) Slots() int {
 	return t.slots
 }
 
+// PrioritisedSlots returns the current number of slots used in the prioritised lane.
+func (t *lookup) PrioritisedSlots() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.prioritisedSlots
+}
+
+// IsPrioritised returns whether a transaction is held in the prioritised lane.
+func (t *lookup) IsPrioritised(hash common.Hash) bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	_, ok := t.prioritised[hash]
+	return ok
+}
+
+// Prioritised returns the transactions held in the prioritised lane.
+func (t *lookup) Prioritised() types.Transactions {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	txs := make(types.Transactions, 0, len(t.prioritised))
+	for hash := range t.prioritised {
+		txs = append(txs, t.remotes[hash])
+	}
+	return txs
+}
+
+// AdmitsPrioritised returns whether a remote transaction would be held in the
+// prioritised lane if it was added now.
+func (t *lookup) AdmitsPrioritised(tx *types.Transaction) bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.admitsPrioritised(tx)
+}
+
+func (t *lookup) admitsPrioritised(tx *types.Transaction) bool {
+	if t.prioritise == nil || t.prioritisedSlots+numSlots(tx) > t.prioritisedCap {
+		return false
+	}
+	return t.prioritise(tx)
+}
+
 // Add adds a transaction to the lookup.
 func (t *lookup) Add(tx *types.Transaction, local bool) {
 	t.lock.Lock()
@@ -2039,9 +2157,26 @@ func (t *lookup) Add(tx *types.Transaction, local bool) {
 
 	if local {
 		t.locals[tx.Hash()] = tx
-	} else {
-		t.remotes[tx.Hash()] = tx
+		return
+	}
+	t.remotes[tx.Hash()] = tx
+	if t.admitsPrioritised(tx) {
+		t.prioritised[tx.Hash()] = struct{}{}
+		t.prioritisedSlots += numSlots(tx)
+		prioritisedGauge.Inc(1)
+		prioritisedSlotsGauge.Update(int64(t.prioritisedSlots))
+	}
+}
+
+// removePrioritised drops a transaction from the prioritised lane, if held.
+func (t *lookup) removePrioritised(tx *types.Transaction) {
+	if _, ok := t.prioritised[tx.Hash()]; !ok {
+		return
 	}
+	delete(t.prioritised, tx.Hash())
+	t.prioritisedSlots -= numSlots(tx)
+	prioritisedGauge.Dec(1)
+	prioritisedSlotsGauge.Update(int64(t.prioritisedSlots))
 }
 
 // Remove removes a transaction from the lookup.
@@ -2059,6 +2194,7 @@ func (t *lookup) Remove(hash common.Hash) {
 	}
 	t.slots -= numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
+	t.removePrioritised(tx)
 
 	delete(t.locals, hash)
 	delete(t.remotes, hash)
@@ -2075,6 +2211,7 @@ func (t *lookup) RemoteToLocals(locals *accountSet) int {
 		if locals.containsTx(tx) {
 			t.locals[hash] = tx
 			delete(t.remotes, hash)
+			t.removePrioritised(tx)
 			migrated += 1
 		}
 	}
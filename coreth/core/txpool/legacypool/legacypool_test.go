@@ -129,6 +129,12 @@ func pricedTransaction(nonce uint64, gaslimit uint64, gasprice *big.Int, key *ec
 	return tx
 }
 
+func prioritisedTransaction(nonce uint64, gasprice *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
+	ftso := params.TestFlareChainConfig.SystemContracts().Prioritised.FTSOAddress
+	tx, _ := types.SignTx(types.NewTransaction(nonce, ftso, big.NewInt(0), 100000, gasprice, nil), types.HomesteadSigner{}, key)
+	return tx
+}
+
 func pricedDataTransaction(nonce uint64, gaslimit uint64, gasprice *big.Int, key *ecdsa.PrivateKey, bytes uint64) *types.Transaction {
 	data := make([]byte, bytes)
 	crand.Read(data)
@@ -206,7 +212,7 @@ func validatePoolInternals(pool *LegacyPool) error {
 		return fmt.Errorf("total transaction count %d != %d pending + %d queued", total, pending, queued)
 	}
 	pool.priced.Reheap()
-	priced, remote := pool.priced.urgent.Len()+pool.priced.floating.Len(), pool.all.RemoteCount()
+	priced, remote := pool.priced.urgent.Len()+pool.priced.floating.Len(), pool.all.RemoteCount()-len(pool.all.prioritised)
 	if priced != remote {
 		return fmt.Errorf("total priced transaction count %d != %d", priced, remote)
 	}
@@ -1874,6 +1880,176 @@ func TestUnderpricing(t *testing.T) {
 	}
 }
 
+// Tests that prioritised contract calls are held in their own lane, which counts
+// against the global limits but keeps them out of the underpricing rules until
+// the lane runs out of slots.
+func TestPrioritisedLane(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the lane with
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestFlareChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 2
+	config.GlobalQueue = 2
+	config.PrioritisedSlots = 1
+
+	pool := New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	// Create a number of test accounts and fund them
+	keys := make([]*ecdsa.PrivateKey, 5)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+	}
+	// Fill up the pool with well priced transactions
+	pool.addRemotesSync([]*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(2), keys[0]),
+		pricedTransaction(1, 100000, big.NewInt(2), keys[0]),
+		pricedTransaction(2, 100000, big.NewInt(2), keys[0]),
+		pricedTransaction(3, 100000, big.NewInt(2), keys[0]),
+	})
+	if pending, queued := pool.Stats(); pending+queued != 4 {
+		t.Fatalf("pooled transactions mismatched: have %d, want %d", pending+queued, 4)
+	}
+	// Ensure that a cheaper prioritised transaction is accepted into the lane,
+	// evicting a regular transaction to stay within the global limits
+	ptx := prioritisedTransaction(0, big.NewInt(1), keys[1])
+	if err := pool.addRemoteSync(ptx); err != nil {
+		t.Fatalf("failed to add prioritised transaction: %v", err)
+	}
+	if !pool.all.IsPrioritised(ptx.Hash()) {
+		t.Fatalf("prioritised transaction not held in the lane")
+	}
+	if slots := pool.all.PrioritisedSlots(); slots != 1 {
+		t.Fatalf("prioritised slots mismatched: have %d, want %d", slots, 1)
+	}
+	if pending, queued := pool.Stats(); pending+queued != 4 {
+		t.Fatalf("pooled transactions mismatched: have %d, want %d", pending+queued, 4)
+	}
+	// Ensure that once the lane is full, prioritised transactions not paying
+	// more than the lane are priced as any other
+	if err := pool.addRemoteSync(prioritisedTransaction(0, big.NewInt(1), keys[2])); !errors.Is(err, txpool.ErrUnderpriced) {
+		t.Fatalf("adding underpriced prioritised transaction error mismatch: have %v, want %v", err, txpool.ErrUnderpriced)
+	}
+	// Ensure that well priced transactions can't push out the lane
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(3), keys[3])); err != nil {
+		t.Fatalf("failed to add well priced transaction: %v", err)
+	}
+	if pool.Get(ptx.Hash()) == nil {
+		t.Fatalf("prioritised transaction evicted")
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Ensure that a better paying prioritised transaction evicts the lane
+	better := prioritisedTransaction(0, big.NewInt(2), keys[4])
+	if err := pool.addRemoteSync(better); err != nil {
+		t.Fatalf("failed to add better paying prioritised transaction: %v", err)
+	}
+	if pool.Get(ptx.Hash()) != nil {
+		t.Fatalf("underpriced prioritised transaction not evicted")
+	}
+	if !pool.all.IsPrioritised(better.Hash()) {
+		t.Fatalf("better paying prioritised transaction not held in the lane")
+	}
+	if pending, queued := pool.Stats(); pending+queued != 4 {
+		t.Fatalf("pooled transactions mismatched: have %d, want %d", pending+queued, 4)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Ensure that the lane is released when the transaction leaves the pool
+	pool.mu.Lock()
+	pool.removeTx(better.Hash(), true, true)
+	pool.mu.Unlock()
+	if slots := pool.all.PrioritisedSlots(); slots != 0 {
+		t.Fatalf("prioritised slots mismatched: have %d, want %d", slots, 0)
+	}
+}
+
+// Tests that a flood of cheap prioritised contract calls can neither grow the
+// pool beyond its global limits nor evict more regular transactions than the
+// lane holds, and that it is outbid by better paying prioritised calls.
+func TestPrioritisedLaneSpam(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the lane with
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestFlareChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 8
+	config.GlobalQueue = 8
+	config.PrioritisedSlots = 4
+
+	pool := New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	limit := int(config.GlobalSlots + config.GlobalQueue)
+	newKey := func() *ecdsa.PrivateKey {
+		key, _ := crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+		return key
+	}
+	// Fill up the pool with well priced transactions
+	for i := 0; i < limit; i++ {
+		if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(2), newKey())); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	// Flood the pool with cheap prioritised transactions from distinct accounts
+	spam := make([]*types.Transaction, 64)
+	for i := range spam {
+		spam[i] = prioritisedTransaction(0, big.NewInt(1), newKey())
+		err := pool.addRemoteSync(spam[i])
+		switch {
+		case i < int(config.PrioritisedSlots) && err != nil:
+			t.Fatalf("failed to add prioritised transaction %d: %v", i, err)
+		case i >= int(config.PrioritisedSlots) && !errors.Is(err, txpool.ErrUnderpriced):
+			t.Fatalf("adding prioritised transaction %d to full lane error mismatch: have %v, want %v", i, err, txpool.ErrUnderpriced)
+		}
+	}
+	if slots := pool.all.PrioritisedSlots(); slots != int(config.PrioritisedSlots) {
+		t.Fatalf("prioritised slots mismatched: have %d, want %d", slots, config.PrioritisedSlots)
+	}
+	if slots := pool.all.Slots(); slots > limit {
+		t.Fatalf("pool exceeds global limits: have %d slots, want at most %d", slots, limit)
+	}
+	if remotes := pool.all.RemoteCount() - len(pool.all.prioritised); remotes != limit-int(config.PrioritisedSlots) {
+		t.Fatalf("regular transactions mismatched: have %d, want %d", remotes, limit-int(config.PrioritisedSlots))
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Ensure that better paying prioritised transactions take over the lane
+	// without evicting any more regular transactions
+	for i := 0; i < int(config.PrioritisedSlots); i++ {
+		tx := prioritisedTransaction(0, big.NewInt(3), newKey())
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add better paying prioritised transaction %d: %v", i, err)
+		}
+		if !pool.all.IsPrioritised(tx.Hash()) {
+			t.Fatalf("better paying prioritised transaction %d not held in the lane", i)
+		}
+	}
+	for i, tx := range spam {
+		if pool.Get(tx.Hash()) != nil {
+			t.Fatalf("prioritised spam transaction %d not evicted", i)
+		}
+	}
+	if remotes := pool.all.RemoteCount() - len(pool.all.prioritised); remotes != limit-int(config.PrioritisedSlots) {
+		t.Fatalf("regular transactions mismatched: have %d, want %d", remotes, limit-int(config.PrioritisedSlots))
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that more expensive transactions push out cheap ones from the pool, but
 // without producing instability by creating gaps that start jumping transactions
 // back and forth between queued/pending.
@@ -566,8 +566,11 @@ func newPricedList(all *lookup) *pricedList {
 }
 
 // Put inserts a new transaction into the heap.
+//
+// Note transactions held in the prioritised lane are not tracked, which exempts
+// them from price based eviction just like local ones.
 func (l *pricedList) Put(tx *types.Transaction, local bool) {
-	if local {
+	if local || l.all.IsPrioritised(tx.Hash()) {
 		return
 	}
 	// Insert every new transaction to the urgent heap first; Discard will balance the heaps
@@ -670,7 +673,9 @@ func (l *pricedList) Reheap() {
 	l.stales.Store(0)
 	l.urgent.list = make([]*types.Transaction, 0, l.all.RemoteCount())
 	l.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
-		l.urgent.list = append(l.urgent.list, tx)
+		if _, ok := l.all.prioritised[hash]; !ok { // Range holds the lookup lock
+			l.urgent.list = append(l.urgent.list, tx)
+		}
 		return true
 	}, false, true) // Only iterate remotes
 	heap.Init(&l.urgent)
@@ -0,0 +1,57 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+//go:generate go run github.com/fjl/gencodec -type AttestationDivergence -field-override attestationDivergenceMarshaling -out gen_attestation_divergence_json.go
+
+// AttestationDivergence records a state connector round on which the local
+// attestors of the node reached a different decision than the default
+// attestors. Divergences depend on the local attestors configured on the node,
+// they are journaled by the node next to the receipts of the block in which the
+// round was finalised.
+type AttestationDivergence struct {
+	// hash of the submission that triggered the finalisation of the round
+	TxHash common.Hash `json:"transactionHash" gencodec:"required"`
+	// index of the submission in the block
+	TxIndex uint `json:"transactionIndex"`
+	// number of the state connector round
+	Round *big.Int `json:"round" gencodec:"required"`
+	// decision of the default attestors, empty if they reached no majority
+	DefaultReachedMajority bool   `json:"defaultReachedMajority"`
+	DefaultDecision        string `json:"defaultDecision"`
+	// decision of the local attestors, empty if they reached no majority
+	LocalReachedMajority bool   `json:"localReachedMajority"`
+	LocalDecision        string `json:"localDecision"`
+	// whether the decision of the default attestors was finalised regardless
+	Finalised bool `json:"finalised"`
+
+	// Derived fields. These fields are filled in when the divergences are
+	// read from the database.
+	BlockHash   common.Hash `json:"blockHash" rlp:"-"`
+	BlockNumber uint64      `json:"blockNumber" rlp:"-"`
+}
+
+type attestationDivergenceMarshaling struct {
+	TxIndex     hexutil.Uint
+	Round       *hexutil.Big
+	BlockNumber hexutil.Uint64
+}
+
+// AttestationDivergences is a list of attestation divergences of a block.
+type AttestationDivergences []*AttestationDivergence
+
+// DeriveFields fills the divergences with their block metadata.
+func (d AttestationDivergences) DeriveFields(hash common.Hash, number uint64) {
+	for _, divergence := range d {
+		divergence.BlockHash = hash
+		divergence.BlockNumber = number
+	}
+}
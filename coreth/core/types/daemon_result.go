@@ -0,0 +1,58 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+//go:generate go run github.com/fjl/gencodec -type DaemonResult -field-override daemonResultMarshaling -out gen_daemon_result_json.go
+
+// DaemonResult records the outcome of the Flare daemon call and the mint that
+// is executed after every successful transaction on the Flare and Songbird
+// networks. Daemon results are not secured by consensus, they are stored by the
+// node next to the receipts of the block they were produced in.
+type DaemonResult struct {
+	// hash of the transaction after which the daemon was called
+	TxHash common.Hash `json:"transactionHash" gencodec:"required"`
+	// index of the transaction in the block
+	TxIndex uint `json:"transactionIndex"`
+	// amount of inflation requested by the daemon
+	MintRequest *big.Int `json:"mintRequest" gencodec:"required"`
+	// amount minted on to the daemon contract
+	Minted *big.Int `json:"minted" gencodec:"required"`
+	// gas used by the daemon call
+	GasUsed uint64 `json:"gasUsed"`
+	// whether the state changes of the daemon call were reverted
+	Reverted bool `json:"reverted"`
+	// reason the daemon call or the mint failed
+	Error string `json:"error,omitempty"`
+
+	// Derived fields. These fields are filled in when the results are read
+	// from the database.
+	BlockHash   common.Hash `json:"blockHash" rlp:"-"`
+	BlockNumber uint64      `json:"blockNumber" rlp:"-"`
+}
+
+type daemonResultMarshaling struct {
+	TxIndex     hexutil.Uint
+	MintRequest *hexutil.Big
+	Minted      *hexutil.Big
+	GasUsed     hexutil.Uint64
+	BlockNumber hexutil.Uint64
+}
+
+// DaemonResults is a list of daemon results of a block.
+type DaemonResults []*DaemonResult
+
+// DeriveFields fills the daemon results with their block metadata.
+func (d DaemonResults) DeriveFields(hash common.Hash, number uint64) {
+	for _, result := range d {
+		result.BlockHash = hash
+		result.BlockNumber = number
+	}
+}
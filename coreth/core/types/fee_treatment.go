@@ -0,0 +1,75 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+//go:generate go run github.com/fjl/gencodec -type FeeTreatment -field-override feeTreatmentMarshaling -out gen_fee_treatment_json.go
+
+// FeeTreatment records how the fee of a transaction was charged on the Flare
+// and Songbird networks. Calls to a prioritised contract are charged the
+// nominal fee of a simple transfer and the rest of their fee is refunded to
+// the sender. Fee treatments are not secured by consensus, they are stored by
+// the node next to the receipts of the block they were produced in.
+type FeeTreatment struct {
+	// hash of the transaction
+	TxHash common.Hash `json:"transactionHash" gencodec:"required"`
+	// index of the transaction in the block
+	TxIndex uint `json:"transactionIndex"`
+	// whether the transaction was charged the nominal fee
+	Prioritised bool `json:"prioritised"`
+	// rule of the prioritised contract check that matched or failed
+	Rule string `json:"rule" gencodec:"required"`
+	// gas used by the transaction and the price it was charged at
+	GasUsed  uint64   `json:"gasUsed"`
+	GasPrice *big.Int `json:"gasPrice" gencodec:"required"`
+	// fee of the transaction before any refund
+	Fee *big.Int `json:"fee" gencodec:"required"`
+	// fee charged for prioritised calls
+	NominalFee *big.Int `json:"nominalFee" gencodec:"required"`
+	// part of the fee refunded to the sender
+	Refund *big.Int `json:"refund" gencodec:"required"`
+
+	// Derived fields. These fields are filled in when the fee treatments are
+	// read from the database.
+	BlockHash   common.Hash `json:"blockHash" rlp:"-"`
+	BlockNumber uint64      `json:"blockNumber" rlp:"-"`
+}
+
+type feeTreatmentMarshaling struct {
+	TxIndex     hexutil.Uint
+	GasUsed     hexutil.Uint64
+	GasPrice    *hexutil.Big
+	Fee         *hexutil.Big
+	NominalFee  *hexutil.Big
+	Refund      *hexutil.Big
+	BlockNumber hexutil.Uint64
+}
+
+// FeeTreatments is a list of fee treatments of a block.
+type FeeTreatments []*FeeTreatment
+
+// DeriveFields fills the fee treatments with their block metadata.
+func (f FeeTreatments) DeriveFields(hash common.Hash, number uint64) {
+	for _, treatment := range f {
+		treatment.BlockHash = hash
+		treatment.BlockNumber = number
+	}
+}
+
+// ByTxIndex returns the fee treatment of the transaction at [index] in the
+// block, or nil if none was recorded.
+func (f FeeTreatments) ByTxIndex(index uint) *FeeTreatment {
+	for _, treatment := range f {
+		if treatment.TxIndex == index {
+			return treatment
+		}
+	}
+	return nil
+}
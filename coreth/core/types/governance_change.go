@@ -0,0 +1,67 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+//go:generate go run github.com/fjl/gencodec -type GovernanceChange -field-override governanceChangeMarshaling -out gen_governance_change_json.go
+
+// Kinds of governance changes signalled to the Flare system contracts.
+const (
+	GovernanceAddressChange  = "governanceAddress" // values are 20 byte addresses
+	GovernanceTimelockChange = "timelock"          // values are 8 byte big endian durations in seconds
+	InitialAirdropChange     = "initialAirdrop"    // values are the 20 byte source and target addresses
+	DistributionChange       = "distribution"      // values are the 20 byte source and target addresses
+)
+
+// GovernanceChange records a change of the governance settings, the initial
+// airdrop or the distribution contract of the Flare network, triggered by a
+// transaction through the coinbase signal of the system contracts.
+type GovernanceChange struct {
+	// hash of the transaction that triggered the change
+	TxHash common.Hash `json:"transactionHash" gencodec:"required"`
+	// index of the transaction in the block
+	TxIndex uint `json:"transactionIndex"`
+	// kind of the change
+	Kind string `json:"kind" gencodec:"required"`
+	// system contract the change was signalled to
+	Contract common.Address `json:"contract"`
+	// value before and after the change, empty if unknown
+	OldValue []byte `json:"oldValue"`
+	NewValue []byte `json:"newValue"`
+	// balance moved from the source to the target contract, zero for changes
+	// of the governance settings
+	Amount *big.Int `json:"amount" gencodec:"required"`
+	// reason the signalled call failed, in which case nothing was changed
+	Error string `json:"error,omitempty"`
+
+	// Derived fields. These fields are filled in when the changes are read
+	// from the database.
+	BlockHash   common.Hash `json:"blockHash" rlp:"-"`
+	BlockNumber uint64      `json:"blockNumber" rlp:"-"`
+}
+
+type governanceChangeMarshaling struct {
+	TxIndex     hexutil.Uint
+	OldValue    hexutil.Bytes
+	NewValue    hexutil.Bytes
+	Amount      *hexutil.Big
+	BlockNumber hexutil.Uint64
+}
+
+// GovernanceChanges is a list of governance changes.
+type GovernanceChanges []*GovernanceChange
+
+// DeriveFields fills the governance changes with their block metadata.
+func (g GovernanceChanges) DeriveFields(hash common.Hash, number uint64) {
+	for _, change := range g {
+		change.BlockHash = hash
+		change.BlockNumber = number
+	}
+}
@@ -275,6 +275,49 @@ func ActivePrecompiles(rules params.Rules) []common.Address {
 	}
 }
 
+// PrecompiledContracts is a set of precompiled contracts by address.
+type PrecompiledContracts map[common.Address]contract.StatefulPrecompiledContract
+
+// nativePrecompiledContracts returns the native precompiles enabled with the
+// given rules.
+func nativePrecompiledContracts(rules params.Rules) PrecompiledContracts {
+	switch {
+	case rules.IsCancun:
+		return PrecompiledContractsCancun
+	case rules.IsBanff:
+		return PrecompiledContractsBanff
+	case rules.IsApricotPhase6:
+		return PrecompiledContractsApricotPhase6
+	case rules.IsApricotPhasePre6:
+		return PrecompiledContractsApricotPhasePre6
+	case rules.IsApricotPhase2:
+		return PrecompiledContractsApricotPhase2
+	case rules.IsIstanbul:
+		return PrecompiledContractsIstanbul
+	case rules.IsByzantium:
+		return PrecompiledContractsByzantium
+	default:
+		return PrecompiledContractsHomestead
+	}
+}
+
+// ActivePrecompiledContracts returns a copy of the native and configured
+// precompiles enabled with the given rules, which can be modified and passed
+// to [EVM.SetPrecompiles].
+func ActivePrecompiledContracts(rules params.Rules) PrecompiledContracts {
+	native := nativePrecompiledContracts(rules)
+	precompiles := make(PrecompiledContracts, len(native)+len(rules.ActivePrecompiles))
+	for addr, p := range native {
+		precompiles[addr] = p
+	}
+	for addr := range rules.ActivePrecompiles {
+		if module, ok := modules.GetPrecompileModuleByAddress(addr); ok {
+			precompiles[addr] = module.Contract
+		}
+	}
+	return precompiles
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 // It returns
 // - the returned bytes,
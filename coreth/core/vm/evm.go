@@ -73,28 +73,14 @@ type (
 )
 
 func (evm *EVM) precompile(addr common.Address) (contract.StatefulPrecompiledContract, bool) {
-	var precompiles map[common.Address]contract.StatefulPrecompiledContract
-	switch {
-	case evm.chainRules.IsCancun:
-		precompiles = PrecompiledContractsCancun
-	case evm.chainRules.IsBanff:
-		precompiles = PrecompiledContractsBanff
-	case evm.chainRules.IsApricotPhase6:
-		precompiles = PrecompiledContractsApricotPhase6
-	case evm.chainRules.IsApricotPhasePre6:
-		precompiles = PrecompiledContractsApricotPhasePre6
-	case evm.chainRules.IsApricotPhase2:
-		precompiles = PrecompiledContractsApricotPhase2
-	case evm.chainRules.IsIstanbul:
-		precompiles = PrecompiledContractsIstanbul
-	case evm.chainRules.IsByzantium:
-		precompiles = PrecompiledContractsByzantium
-	default:
-		precompiles = PrecompiledContractsHomestead
+	// Overridden precompiles replace both the native and the configured ones.
+	if evm.precompiles != nil {
+		p, ok := evm.precompiles[addr]
+		return p, ok
 	}
 
 	// Check the existing precompiles first
-	p, ok := precompiles[addr]
+	p, ok := nativePrecompiledContracts(evm.chainRules)[addr]
 	if ok {
 		return p, true
 	}
@@ -196,6 +182,8 @@ type EVM struct {
 	// available gas is calculated in gasCall* according to the 63/64 rule and later
 	// applied in opCall*.
 	callGasTemp uint64
+	// precompiles overrides the precompiles of the chain rules, if set
+	precompiles PrecompiledContracts
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
@@ -231,6 +219,13 @@ func (evm *EVM) Reset(txCtx TxContext, statedb StateDB) {
 	evm.StateDB = statedb
 }
 
+// SetPrecompiles replaces the precompiles of the chain rules with [precompiles]
+// for the calls made by the EVM. It is used to simulate calls with moved or
+// overridden precompiles.
+func (evm *EVM) SetPrecompiles(precompiles PrecompiledContracts) {
+	evm.precompiles = precompiles
+}
+
 // Cancel cancels any running EVM operation. This may be called concurrently and
 // it's safe to be called multiple times.
 func (evm *EVM) Cancel() {
@@ -262,16 +257,31 @@ func (evm *EVM) Interpreter() *EVMInterpreter {
 	return evm.interpreter
 }
 
+// SystemCallTracer returns the tracer to be notified of system calls, or nil if
+// system call tracing is disabled or unsupported by the configured tracer.
+func (evm *EVM) SystemCallTracer() SystemCallLogger {
+	if !evm.Config.TraceSystemCalls {
+		return nil
+	}
+	tracer, _ := evm.Config.Tracer.(SystemCallLogger)
+	return tracer
+}
+
 // DaemonCall separates a regular call from taking a snapshot and reverting to it in case of error.
 // The function returns the snapshot in order to permit another opportunity for reverting to the
 // snapshot in the event that the subsequent call to mint() in coreth/core/daemon.go fails.
 func (evm *EVM) DaemonCall(caller ContractRef, addr common.Address, input []byte, gas uint64) (snapshot int, ret []byte, leftOverGas uint64, err error) {
-	// Temporarily disable EVM debugging
+	// Temporarily disable EVM debugging, unless the tracer asked to see system
+	// calls, in which case the top call frame is reported as a system call.
 	oldTracer := evm.Config.Tracer
 	defer func() {
 		evm.Config.Tracer = oldTracer
 	}()
-	evm.Config.Tracer = nil
+	if tracer := evm.SystemCallTracer(); tracer != nil {
+		evm.Config.Tracer = &systemCallLogger{EVMLogger: oldTracer, system: tracer}
+	} else {
+		evm.Config.Tracer = nil
+	}
 
 	value := uint256.NewInt(0)
 	// Fail if we're trying to execute above the call depth limit
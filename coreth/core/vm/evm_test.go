@@ -4,10 +4,16 @@
 package vm
 
 import (
+	"math/big"
 	"testing"
 
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/params"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsProhibited(t *testing.T) {
@@ -33,3 +39,83 @@ func TestIsProhibited(t *testing.T) {
 	assert.False(t, IsProhibited(common.HexToAddress("0x0200000000000000000000000000000000000100")))
 	assert.False(t, IsProhibited(common.HexToAddress("0x0300000000000000000000000000000000000100")))
 }
+
+// systemCallSpy records the events it receives while tracing a system call.
+type systemCallSpy struct {
+	starts, ends, enters, sysStarts, sysEnds int
+	sysOutput                                []byte
+}
+
+func (s *systemCallSpy) CaptureStart(env *EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	s.starts++
+}
+
+func (s *systemCallSpy) CaptureEnd(output []byte, gasUsed uint64, err error) { s.ends++ }
+
+func (s *systemCallSpy) CaptureEnter(typ OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	s.enters++
+}
+
+func (s *systemCallSpy) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (s *systemCallSpy) CaptureTxStart(gasLimit uint64) {}
+
+func (s *systemCallSpy) CaptureTxEnd(restGas uint64) {}
+
+func (s *systemCallSpy) CaptureState(pc uint64, op OpCode, gas, cost uint64, scope *ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (s *systemCallSpy) CaptureFault(pc uint64, op OpCode, gas, cost uint64, scope *ScopeContext, depth int, err error) {
+}
+
+func (s *systemCallSpy) CaptureSystemCallStart(env *EVM, from common.Address, to common.Address, input []byte, gas uint64) {
+	s.sysStarts++
+}
+
+func (s *systemCallSpy) CaptureSystemCallEnd(output []byte, gasUsed uint64, err error) {
+	s.sysEnds++
+	s.sysOutput = common.CopyBytes(output)
+}
+
+func (s *systemCallSpy) CaptureSystemMint(to common.Address, amount *big.Int) {}
+
+func (s *systemCallSpy) CaptureSystemRevert(err error) {}
+
+func TestDaemonCallTracing(t *testing.T) {
+	daemon := common.HexToAddress("0x1000000000000000000000000000000000000002")
+	// PUSH1 0x2a PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	code := []byte{0x60, 0x2a, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+
+	for _, traceSystemCalls := range []bool{false, true} {
+		statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		require.NoError(t, err)
+		statedb.SetCode(daemon, code)
+		statedb.Finalise(true)
+
+		vmctx := BlockContext{
+			BlockNumber: big.NewInt(0),
+			CanTransfer: func(StateDB, common.Address, *uint256.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *uint256.Int) {},
+		}
+		spy := &systemCallSpy{}
+		evm := NewEVM(vmctx, TxContext{}, statedb, params.TestFlareChainConfig, Config{Tracer: spy, TraceSystemCalls: traceSystemCalls})
+
+		_, ret, _, err := evm.DaemonCall(AccountRef(daemon), daemon, nil, 100000)
+		require.NoError(t, err)
+		require.Len(t, ret, 32)
+
+		// The top frame of a daemon call is never reported as a regular call frame
+		assert.Zero(t, spy.starts)
+		assert.Zero(t, spy.ends)
+		if traceSystemCalls {
+			assert.Equal(t, 1, spy.sysStarts)
+			assert.Equal(t, 1, spy.sysEnds)
+			assert.Equal(t, ret, spy.sysOutput)
+		} else {
+			assert.Zero(t, spy.sysStarts)
+			assert.Zero(t, spy.sysEnds)
+		}
+		// The original tracer is restored after the daemon call
+		assert.Equal(t, spy, evm.Config.Tracer)
+	}
+}
@@ -51,3 +51,31 @@ type EVMLogger interface {
 	CaptureState(pc uint64, op OpCode, gas, cost uint64, scope *ScopeContext, rData []byte, depth int, err error)
 	CaptureFault(pc uint64, op OpCode, gas, cost uint64, scope *ScopeContext, depth int, err error)
 }
+
+// SystemCallLogger is an optional extension of EVMLogger for tracers that want
+// to observe the Flare system calls (the daemon call and the mint following
+// it) that are executed after the top call frame of a transaction has ended.
+// It is only used when Config.TraceSystemCalls is set.
+type SystemCallLogger interface {
+	// System call frame, reported instead of CaptureStart/CaptureEnd
+	CaptureSystemCallStart(env *EVM, from common.Address, to common.Address, input []byte, gas uint64)
+	CaptureSystemCallEnd(output []byte, gasUsed uint64, err error)
+	// Outcome of the last system call
+	CaptureSystemMint(to common.Address, amount *big.Int)
+	CaptureSystemRevert(err error)
+}
+
+// systemCallLogger routes the top call frame of a system call to the
+// SystemCallLogger hooks and forwards every other event unchanged.
+type systemCallLogger struct {
+	EVMLogger
+	system SystemCallLogger
+}
+
+func (l *systemCallLogger) CaptureStart(env *EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	l.system.CaptureSystemCallStart(env, from, to, input, gas)
+}
+
+func (l *systemCallLogger) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	l.system.CaptureSystemCallEnd(output, gasUsed, err)
+}
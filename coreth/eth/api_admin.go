@@ -36,6 +36,7 @@ import (
 
 	"github.com/ava-labs/coreth/core"
 	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/internal/chainarchive"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -151,3 +152,36 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	}
 	return true, nil
 }
+
+// ExportChainArchive exports the accepted blocks, with their receipts and
+// atomic transactions, into a new chain archive directory, either the whole
+// chain or a range of blocks if first and last are non-nil.
+func (api *AdminAPI) ExportChainArchive(dir string, first *uint64, last *uint64) (bool, error) {
+	if first == nil && last != nil {
+		return false, errors.New("last cannot be specified without first")
+	}
+	var (
+		from uint64
+		to   = api.eth.BlockChain().LastAcceptedBlock().NumberU64()
+	)
+	if first != nil {
+		from = *first
+	}
+	if last != nil {
+		to = *last
+	}
+	if err := chainarchive.Export(api.eth.BlockChain(), api.eth.AtomicTxs(), dir, from, to); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ImportChainArchive imports and accepts a blockchain from a chain archive
+// directory written by ExportChainArchive. The import is resumed if it was
+// interrupted.
+func (api *AdminAPI) ImportChainArchive(dir string) (bool, error) {
+	if err := chainarchive.Import(api.eth.BlockChain(), api.eth.ChainDb(), api.eth.AtomicTxs(), dir); err != nil {
+		return false, err
+	}
+	return true, nil
+}
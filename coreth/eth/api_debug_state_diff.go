@@ -0,0 +1,337 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/eth/tracers"
+	_ "github.com/ava-labs/coreth/eth/tracers/native"
+	"github.com/ava-labs/coreth/rpc"
+	"github.com/ava-labs/coreth/trie"
+	"github.com/ava-labs/coreth/triedb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// stateDiffReexec is the number of blocks re-executed to regenerate the state
+// a transaction is replayed on, the same as for tracing it.
+const stateDiffReexec = 128
+
+// AccountState is the state of an account reported by debug_getStateDiff.
+type AccountState struct {
+	Balance  *hexutil.Big   `json:"balance"`
+	Nonce    hexutil.Uint64 `json:"nonce"`
+	CodeHash common.Hash    `json:"codeHash"`
+}
+
+// StorageDiff is the value of a storage slot before and after a block or a
+// transaction.
+type StorageDiff struct {
+	Before common.Hash `json:"before"`
+	After  common.Hash `json:"after"`
+}
+
+// AccountStateDiff is the change of an account by a block or a transaction.
+// Before is null if the account did not exist, After is null if the account
+// was deleted.
+type AccountStateDiff struct {
+	Before  *AccountState                `json:"before"`
+	After   *AccountState                `json:"after"`
+	Storage map[common.Hash]*StorageDiff `json:"storage"`
+}
+
+// GetStateDiff returns the accounts modified by the given block, or by the
+// transaction at [txIndex] of the block if set, along with their values
+// before and after. The changes of a block are computed from the difference
+// between the state tries of its parent and its own, so they include the
+// upgrades and Flare system calls that are part of the committed root. The
+// changes of a transaction are computed by replaying it, including the daemon
+// call and mint executed after it.
+func (api *DebugAPI) GetStateDiff(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, txIndex *hexutil.Uint) (map[common.Address]*AccountStateDiff, error) {
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis has no state diff")
+	}
+	if txIndex != nil {
+		return api.replayStateDiff(ctx, block, int(*txIndex))
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	return trieStateDiff(api.eth.BlockChain().TrieDB(), parent.Root(), block.Root())
+}
+
+// trieStateDiff returns the accounts that differ between the state tries with
+// the given roots.
+func trieStateDiff(db *triedb.Database, beforeRoot, afterRoot common.Hash) (map[common.Address]*AccountStateDiff, error) {
+	beforeTrie, err := trie.NewStateTrie(trie.StateTrieID(beforeRoot), db)
+	if err != nil {
+		return nil, err
+	}
+	afterTrie, err := trie.NewStateTrie(trie.StateTrieID(afterRoot), db)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := diffTrieLeaves(beforeTrie, afterTrie)
+	if err != nil {
+		return nil, err
+	}
+	diff := make(map[common.Address]*AccountStateDiff, len(accounts))
+	for hash, leaf := range accounts {
+		preimage := afterTrie.GetKey(hash.Bytes())
+		if preimage == nil {
+			return nil, fmt.Errorf("no preimage found for hash %x", hash)
+		}
+		before, err := decodeAccount(leaf.before)
+		if err != nil {
+			return nil, err
+		}
+		after, err := decodeAccount(leaf.after)
+		if err != nil {
+			return nil, err
+		}
+		account := &AccountStateDiff{
+			Before:  trieAccountState(before),
+			After:   trieAccountState(after),
+			Storage: make(map[common.Hash]*StorageDiff),
+		}
+		beforeStorage, afterStorage := types.EmptyRootHash, types.EmptyRootHash
+		if before != nil {
+			beforeStorage = before.Root
+		}
+		if after != nil {
+			afterStorage = after.Root
+		}
+		if beforeStorage != afterStorage {
+			if err := trieStorageDiff(db, account, hash, beforeRoot, beforeStorage, afterRoot, afterStorage); err != nil {
+				return nil, err
+			}
+		}
+		diff[common.BytesToAddress(preimage)] = account
+	}
+	return diff, nil
+}
+
+// trieStorageDiff sets the slots that differ between the storage tries of the
+// account with the given hash.
+func trieStorageDiff(db *triedb.Database, account *AccountStateDiff, hash, beforeRoot, beforeStorage, afterRoot, afterStorage common.Hash) error {
+	beforeTrie, err := trie.NewStateTrie(trie.StorageTrieID(beforeRoot, hash, beforeStorage), db)
+	if err != nil {
+		return err
+	}
+	afterTrie, err := trie.NewStateTrie(trie.StorageTrieID(afterRoot, hash, afterStorage), db)
+	if err != nil {
+		return err
+	}
+	slots, err := diffTrieLeaves(beforeTrie, afterTrie)
+	if err != nil {
+		return err
+	}
+	for key, leaf := range slots {
+		preimage := afterTrie.GetKey(key.Bytes())
+		if preimage == nil {
+			return fmt.Errorf("no preimage found for hash %x", key)
+		}
+		before, err := decodeSlot(leaf.before)
+		if err != nil {
+			return err
+		}
+		after, err := decodeSlot(leaf.after)
+		if err != nil {
+			return err
+		}
+		account.Storage[common.BytesToHash(preimage)] = &StorageDiff{Before: before, After: after}
+	}
+	return nil
+}
+
+// replayStateDiff returns the accounts modified by the transaction at
+// [txIndex] of [block]. The accounts and slots touched by the transaction are
+// found by replaying it with the prestate tracer, then read from the states
+// before and after it.
+func (api *DebugAPI) replayStateDiff(ctx context.Context, block *types.Block, txIndex int) (map[common.Address]*AccountStateDiff, error) {
+	txs := block.Transactions()
+	if txIndex >= len(txs) {
+		return nil, fmt.Errorf("transaction index %d out of range for block %#x", txIndex, block.Hash())
+	}
+	msg, vmctx, statedb, release, err := api.eth.stateAtTransaction(ctx, block, txIndex, stateDiffReexec)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	txctx := &tracers.Context{
+		BlockHash:   block.Hash(),
+		BlockNumber: block.Number(),
+		TxIndex:     txIndex,
+		TxHash:      txs[txIndex].Hash(),
+	}
+	tracer, err := tracers.DefaultDirectory.New("prestateTracer", txctx, json.RawMessage(`{"diffMode":true}`))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		chainConfig = api.eth.blockchain.Config()
+		before      = statedb.Copy()
+		vmenv       = vm.NewEVM(vmctx, core.NewEVMTxContext(msg), statedb, chainConfig, vm.Config{Tracer: tracer, TraceSystemCalls: true})
+	)
+	statedb.SetTxContext(txctx.TxHash, txIndex)
+	if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit)); err != nil {
+		return nil, fmt.Errorf("transaction %#x failed: %w", txctx.TxHash, err)
+	}
+	statedb.Finalise(chainConfig.IsEIP158(block.Number()))
+
+	result, err := tracer.GetResult()
+	if err != nil {
+		return nil, err
+	}
+	type touchedAccount struct {
+		Storage map[common.Hash]common.Hash
+	}
+	var touched struct {
+		Pre  map[common.Address]touchedAccount
+		Post map[common.Address]touchedAccount
+	}
+	if err := json.Unmarshal(result, &touched); err != nil {
+		return nil, err
+	}
+	slots := make(map[common.Address]map[common.Hash]struct{})
+	for _, accounts := range []map[common.Address]touchedAccount{touched.Pre, touched.Post} {
+		for addr, account := range accounts {
+			if slots[addr] == nil {
+				slots[addr] = make(map[common.Hash]struct{})
+			}
+			for key := range account.Storage {
+				slots[addr][key] = struct{}{}
+			}
+		}
+	}
+	diff := make(map[common.Address]*AccountStateDiff, len(slots))
+	for addr, keys := range slots {
+		account := &AccountStateDiff{
+			Before:  stateAccountState(before, addr),
+			After:   stateAccountState(statedb, addr),
+			Storage: make(map[common.Hash]*StorageDiff),
+		}
+		if account.Before == nil && account.After == nil {
+			continue
+		}
+		for key := range keys {
+			slot := &StorageDiff{Before: before.GetState(addr, key), After: statedb.GetState(addr, key)}
+			if slot.Before != slot.After {
+				account.Storage[key] = slot
+			}
+		}
+		diff[addr] = account
+	}
+	return diff, nil
+}
+
+// leafDiff is the value of a trie leaf in two tries, nil where it is absent.
+type leafDiff struct {
+	before, after []byte
+}
+
+// diffTrieLeaves returns the leaves whose value differs between the tries, by
+// their hashed key.
+func diffTrieLeaves(before, after *trie.StateTrie) (map[common.Hash]*leafDiff, error) {
+	leaves := make(map[common.Hash]*leafDiff)
+	collect := func(a, b *trie.StateTrie, set func(leaf *leafDiff, value []byte)) error {
+		aIt, err := a.NodeIterator(nil)
+		if err != nil {
+			return err
+		}
+		bIt, err := b.NodeIterator(nil)
+		if err != nil {
+			return err
+		}
+		diff, _ := trie.NewDifferenceIterator(aIt, bIt)
+		it := trie.NewIterator(diff)
+		for it.Next() {
+			key := common.BytesToHash(it.Key)
+			leaf, ok := leaves[key]
+			if !ok {
+				leaf = new(leafDiff)
+				leaves[key] = leaf
+			}
+			set(leaf, it.Value)
+		}
+		return it.Err
+	}
+	// Leaves only in [after] were created, leaves only in [before] deleted.
+	if err := collect(before, after, func(leaf *leafDiff, value []byte) { leaf.after = value }); err != nil {
+		return nil, err
+	}
+	if err := collect(after, before, func(leaf *leafDiff, value []byte) { leaf.before = value }); err != nil {
+		return nil, err
+	}
+	// Leaves moved by the restructuring of the trie are reported unchanged.
+	for key, leaf := range leaves {
+		if bytes.Equal(leaf.before, leaf.after) {
+			delete(leaves, key)
+		}
+	}
+	return leaves, nil
+}
+
+func decodeAccount(blob []byte) (*types.StateAccount, error) {
+	if blob == nil {
+		return nil, nil
+	}
+	account := new(types.StateAccount)
+	if err := rlp.DecodeBytes(blob, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func decodeSlot(blob []byte) (common.Hash, error) {
+	if blob == nil {
+		return common.Hash{}, nil
+	}
+	_, content, _, err := rlp.Split(blob)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(content), nil
+}
+
+func trieAccountState(account *types.StateAccount) *AccountState {
+	if account == nil {
+		return nil
+	}
+	return &AccountState{
+		Balance:  (*hexutil.Big)(account.Balance.ToBig()),
+		Nonce:    hexutil.Uint64(account.Nonce),
+		CodeHash: common.BytesToHash(account.CodeHash),
+	}
+}
+
+func stateAccountState(statedb *state.StateDB, addr common.Address) *AccountState {
+	if !statedb.Exist(addr) {
+		return nil
+	}
+	return &AccountState{
+		Balance:  (*hexutil.Big)(statedb.GetBalance(addr).ToBig()),
+		Nonce:    hexutil.Uint64(statedb.GetNonce(addr)),
+		CodeHash: statedb.GetCodeHash(addr),
+	}
+}
@@ -29,6 +29,7 @@ package eth
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"reflect"
 	"strings"
 	"testing"
@@ -42,6 +43,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 
 	"golang.org/x/exp/slices"
@@ -238,3 +240,59 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+func TestTrieStateDiff(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db       = state.NewDatabaseWithConfig(rawdb.NewMemoryDatabase(), &triedb.Config{Preimages: true})
+		sdb, _   = state.New(types.EmptyRootHash, db, nil)
+		kept     = common.Address{0x01}
+		modified = common.Address{0x02}
+		deleted  = common.Address{0x03}
+		created  = common.Address{0x04}
+	)
+	for _, addr := range []common.Address{kept, modified, deleted} {
+		sdb.SetBalance(addr, uint256.NewInt(1))
+	}
+	sdb.SetState(modified, common.Hash{0x02}, common.Hash{0x01})
+	sdb.SetState(modified, common.Hash{0x04}, common.Hash{0x02})
+	before, _ := sdb.Commit(0, true)
+
+	sdb, _ = state.New(before, db, nil)
+	sdb.SetNonce(modified, 1)
+	sdb.SetState(modified, common.Hash{0x02}, common.Hash{0x03})
+	sdb.SetState(modified, common.Hash{0x04}, common.Hash{})
+	sdb.SetState(modified, common.Hash{0x06}, common.Hash{0x04})
+	sdb.SelfDestruct(deleted)
+	sdb.SetBalance(created, uint256.NewInt(2))
+	after, _ := sdb.Commit(1, true)
+
+	diff, err := trieStateDiff(db.TrieDB(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emptyCode := types.EmptyCodeHash
+	want := map[common.Address]*AccountStateDiff{
+		modified: {
+			Before: &AccountState{Balance: (*hexutil.Big)(big.NewInt(1)), CodeHash: emptyCode},
+			After:  &AccountState{Balance: (*hexutil.Big)(big.NewInt(1)), Nonce: 1, CodeHash: emptyCode},
+			Storage: map[common.Hash]*StorageDiff{
+				{0x02}: {Before: common.Hash{0x01}, After: common.Hash{0x03}},
+				{0x04}: {Before: common.Hash{0x02}},
+				{0x06}: {After: common.Hash{0x04}},
+			},
+		},
+		deleted: {
+			Before:  &AccountState{Balance: (*hexutil.Big)(big.NewInt(1)), CodeHash: emptyCode},
+			Storage: map[common.Hash]*StorageDiff{},
+		},
+		created: {
+			After:   &AccountState{Balance: (*hexutil.Big)(big.NewInt(2)), CodeHash: emptyCode},
+			Storage: map[common.Hash]*StorageDiff{},
+		},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("wrong state diff:\ngot %s\nwant %s", spew.Sdump(diff), spew.Sdump(want))
+	}
+}
@@ -0,0 +1,265 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/internal/ethapi"
+	"github.com/ava-labs/coreth/rpc"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// FlareAPI provides an API to access Flare specific chain data.
+type FlareAPI struct {
+	eth *Ethereum
+}
+
+// NewFlareAPI creates a new FlareAPI instance.
+func NewFlareAPI(eth *Ethereum) *FlareAPI {
+	return &FlareAPI{eth: eth}
+}
+
+// GetDaemonResults returns the outcome of the daemon calls and mints executed
+// in the given block, one entry per transaction after which the daemon was
+// called. It returns an error if the node did not record the results of the
+// block, e.g. because it was processed before results were recorded.
+func (api *FlareAPI) GetDaemonResults(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (types.DaemonResults, error) {
+	header, err := api.eth.APIBackend.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block %s not found", blockNrOrHash.String())
+	}
+	results := rawdb.ReadDaemonResults(api.eth.ChainDb(), header.Hash(), header.Number.Uint64())
+	if results == nil {
+		return nil, fmt.Errorf("daemon results of block #%d not recorded", header.Number.Uint64())
+	}
+	return results, nil
+}
+
+// GetFeeTreatment returns how the fee of the transaction with the given hash
+// was charged: whether it was a prioritised contract call charged the nominal
+// fee, the rule of the prioritised contract check that matched or failed, the
+// nominal fee and the amount refunded to the sender. It returns an error if
+// the node did not record the fee treatments of the block of the transaction.
+func (api *FlareAPI) GetFeeTreatment(ctx context.Context, hash common.Hash) (*types.FeeTreatment, error) {
+	found, _, blockHash, blockNumber, index, err := api.eth.APIBackend.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, ethapi.NewTxIndexingError() // transaction is not fully indexed
+	}
+	if !found {
+		return nil, nil // transaction is not existent or reachable
+	}
+	treatments := api.eth.blockchain.GetFeeTreatmentsByHash(blockHash)
+	if treatments == nil {
+		return nil, fmt.Errorf("fee treatments of block #%d not recorded", blockNumber)
+	}
+	treatment := treatments.ByTxIndex(uint(index))
+	if treatment == nil {
+		return nil, fmt.Errorf("fee treatment of transaction %s not recorded", hash.Hex())
+	}
+	return treatment, nil
+}
+
+// AttestationRoundResult is the vote breakdown of a state connector round at a
+// block, together with the submission that triggered its finalisation.
+type AttestationRoundResult struct {
+	*core.AttestationRound
+	BlockHash        common.Hash     `json:"blockHash"`
+	BlockNumber      hexutil.Uint64  `json:"blockNumber"`
+	TransactionHash  *common.Hash    `json:"transactionHash"`  // nil if the round was not finalised in the block
+	TransactionIndex *hexutil.Uint64 `json:"transactionIndex"` // nil if the round was not finalised in the block
+}
+
+// GetAttestationRound replays the given block and returns the votes of the
+// default and the local attestors on the given state connector round, as
+// counted when a submission in the block triggered the finalisation of the
+// round. If no submission in the block did, the votes are counted on the state
+// at the end of the block and the round is reported as not finalised.
+func (api *FlareAPI) GetAttestationRound(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, roundNumber hexutil.Uint64) (*AttestationRoundResult, error) {
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %s not found", blockNrOrHash.String())
+	}
+	if block.NumberU64() == 0 {
+		return nil, fmt.Errorf("state connector not active in genesis")
+	}
+	var (
+		config    = api.eth.blockchain.Config()
+		contracts = config.SystemContracts()
+		round     = new(big.Int).SetUint64(uint64(roundNumber))
+		result    = &AttestationRoundResult{BlockHash: block.Hash(), BlockNumber: hexutil.Uint64(block.NumberU64())}
+	)
+	if _, activated := contracts.StateConnector.Address.At(block.Time()); !activated || config.IsDurango(block.Time()) {
+		return nil, fmt.Errorf("state connector not active in block #%d", block.NumberU64())
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, release, err := api.eth.StateAtNextBlock(ctx, parent, block, 0, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Replay the block until a submission finalises the round
+	signer := types.MakeSigner(config, block.Number(), block.Time())
+	blockCtx := core.NewEVMBlockContext(block.Header(), api.eth.blockchain, nil)
+	for idx, tx := range block.Transactions() {
+		msg, err := core.TransactionToMessage(tx, signer, block.BaseFee())
+		if err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		vmenv := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, config, vm.Config{})
+		statedb.SetTxContext(tx.Hash(), idx)
+		res, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas()))
+		if err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		statedb.Finalise(vmenv.ChainConfig().IsEIP158(block.Number()))
+
+		if res.AttestationRound != nil && res.AttestationRound.Round.ToInt().Cmp(round) == 0 {
+			hash, index := tx.Hash(), hexutil.Uint64(idx)
+			result.AttestationRound, result.TransactionHash, result.TransactionIndex = res.AttestationRound, &hash, &index
+			return result, nil
+		}
+	}
+	// The round was not finalised in the block, count the votes as they stand
+	vmenv := vm.NewEVM(blockCtx, vm.TxContext{}, statedb, config, vm.Config{})
+	result.AttestationRound = core.TallyAttestationRound(vmenv, contracts, block.Time(), common.BigToHash(round).Bytes())
+	return result, nil
+}
+
+// GetAttestationDivergences returns the state connector rounds finalised in the
+// canonical blocks of the given range on which the local attestors of the node
+// reached a different decision than the default attestors. The range defaults
+// to the whole chain, both limits are inclusive. Divergences are only journaled
+// by nodes with local attestors configured.
+func (api *FlareAPI) GetAttestationDivergences(ctx context.Context, fromBlock, toBlock *rpc.BlockNumber) (types.AttestationDivergences, error) {
+	from, to := rpc.EarliestBlockNumber, rpc.LatestBlockNumber
+	if fromBlock != nil {
+		from = *fromBlock
+	}
+	if toBlock != nil {
+		to = *toBlock
+	}
+	first, err := api.eth.APIBackend.HeaderByNumber(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	if first == nil {
+		return nil, fmt.Errorf("block %s not found", from.String())
+	}
+	last, err := api.eth.APIBackend.HeaderByNumber(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, fmt.Errorf("block %s not found", to.String())
+	}
+	if first.Number.Uint64() > last.Number.Uint64() {
+		return nil, fmt.Errorf("invalid block range %d-%d", first.Number.Uint64(), last.Number.Uint64())
+	}
+	divergences := types.AttestationDivergences{}
+	for _, divergence := range rawdb.ReadAttestationDivergencesInRange(api.eth.ChainDb(), first.Number.Uint64(), last.Number.Uint64()) {
+		// Skip the divergences journaled for blocks that were not accepted
+		if api.eth.blockchain.GetCanonicalHash(divergence.BlockNumber) != divergence.BlockHash {
+			continue
+		}
+		divergences = append(divergences, divergence)
+	}
+	return divergences, nil
+}
+
+// maxGovernanceScanRange is the maximum number of blocks scanned for governance
+// changes by a single request not served by the governance change index.
+const maxGovernanceScanRange = 10_000
+
+// governanceReplayReexec is the number of blocks re-executed to regenerate the
+// state of a block replayed for its governance changes.
+const governanceReplayReexec = 128
+
+// GetGovernanceChanges returns the governance address, timelock, initial
+// airdrop and distribution changes signalled in the canonical blocks of the
+// given range, both limits inclusive. The range is served from the governance
+// change index if it covers it, otherwise the blocks of the range in which a
+// governance hook was called are replayed.
+func (api *FlareAPI) GetGovernanceChanges(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) (types.GovernanceChanges, error) {
+	first, err := api.eth.APIBackend.HeaderByNumber(ctx, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	if first == nil {
+		return nil, fmt.Errorf("block %s not found", fromBlock.String())
+	}
+	last, err := api.eth.APIBackend.HeaderByNumber(ctx, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, fmt.Errorf("block %s not found", toBlock.String())
+	}
+	from, to := first.Number.Uint64(), last.Number.Uint64()
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	db := api.eth.ChainDb()
+	if tail, head := rawdb.ReadGovernanceIndexTail(db), rawdb.ReadGovernanceIndexHead(db); tail != nil && head != nil && *tail <= from && to <= *head {
+		return rawdb.ReadGovernanceChangesInRange(db, from, to), nil
+	}
+	if to-from >= maxGovernanceScanRange {
+		return nil, fmt.Errorf("block range %d-%d not indexed and exceeds the scan limit of %d blocks", from, to, maxGovernanceScanRange)
+	}
+	changes := types.GovernanceChanges{}
+	for number := max(from, 1); number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block := api.eth.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		if !api.eth.blockchain.HasGovernanceCalls(block) {
+			continue
+		}
+		blockChanges, err := api.eth.replayGovernanceChanges(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, blockChanges...)
+	}
+	return changes, nil
+}
+
+// replayGovernanceChanges replays [block] on top of the state of its parent,
+// regenerated if needed, and returns the governance changes it signalled.
+func (s *Ethereum) replayGovernanceChanges(ctx context.Context, block *types.Block) (types.GovernanceChanges, error) {
+	parent := s.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, release, err := s.stateAtBlock(ctx, parent, governanceReplayReexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	changes, err := s.blockchain.ReplayGovernanceChanges(block, statedb)
+	if err != nil {
+		return nil, fmt.Errorf("block #%d replay failed: %w", block.NumberU64(), err)
+	}
+	return changes, nil
+}
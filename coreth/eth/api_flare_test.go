@@ -0,0 +1,115 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ava-labs/coreth/rpc"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that the governance changes are replayed from the blocks that called a
+// governance hook, or served from the index if it covers the range.
+func TestGetGovernanceChanges(t *testing.T) {
+	require := require.New(t)
+	var (
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr      = crypto.PubkeyToAddress(key.PublicKey)
+		config    = params.TestFlareChainConfig
+		blockTime = uint64(time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC).Unix())
+		contracts = config.SystemContracts()
+		timelock  = common.LeftPadBytes([]byte{0x0e, 0x10}, 8) // 3600, the permitted timelock
+	)
+	settings, ok := contracts.GovernanceSettings.Address.At(blockTime)
+	require.True(ok)
+	gspec := &core.Genesis{
+		Config:    config,
+		Timestamp: blockTime,
+		GasLimit:  params.CortinaGasLimit,
+		Alloc: types.GenesisAlloc{
+			addr: {Balance: new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(100))},
+			// Accepts any call and returns 3600: PUSH2 0x0e10 PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+			settings: {Balance: common.Big0, Code: []byte{0x61, 0x0e, 0x10, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}},
+		},
+	}
+	signer := types.LatestSigner(config)
+	setTimelock := append(contracts.GovernanceSettings.SetTimelockSelector[:], common.LeftPadBytes(timelock, 32)...)
+	_, blocks, _, err := core.GenerateChainWithGenesis(gspec, dummy.NewFakerWithCallbacks(core.TestCallbacks), 4, 10, func(i int, block *core.BlockGen) {
+		block.SetCoinbase(common.HexToAddress("0x0100000000000000000000000000000000000000"))
+		to, data := common.Address{0xaa}, []byte(nil)
+		if i == 1 {
+			to, data = settings, setTimelock
+		}
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), to, common.Big0, 100_000, big.NewInt(225*params.GWei), data), signer, key)
+		require.NoError(err)
+		block.AddTx(tx)
+	})
+	require.NoError(err)
+
+	db := rawdb.NewMemoryDatabase()
+	chain, err := core.NewBlockChain(db, core.DefaultCacheConfig, gspec, dummy.NewFakerWithCallbacks(core.TestCallbacks), vm.Config{}, common.Hash{}, false)
+	require.NoError(err)
+	defer chain.Stop()
+	_, err = chain.InsertChain(blocks)
+	require.NoError(err)
+	for _, block := range blocks {
+		require.NoError(chain.Accept(block))
+	}
+	chain.DrainAcceptorQueue()
+
+	eth := &Ethereum{blockchain: chain, chainDb: db}
+	eth.APIBackend = &EthAPIBackend{eth: eth}
+	api := NewFlareAPI(eth)
+
+	// The block calling setTimelock is replayed, the replaced timelock is
+	// read from the state of its parent.
+	changes, err := api.GetGovernanceChanges(context.Background(), 0, rpc.LatestBlockNumber)
+	require.NoError(err)
+	require.Len(changes, 1)
+	change := changes[0]
+	require.Equal(types.GovernanceTimelockChange, change.Kind)
+	require.Equal(settings, change.Contract)
+	require.Equal(blocks[1].Transactions()[0].Hash(), change.TxHash)
+	require.Equal(blocks[1].Hash(), change.BlockHash)
+	require.Equal(uint64(2), change.BlockNumber)
+	require.Equal(timelock, change.OldValue)
+	require.Equal(timelock, change.NewValue)
+	require.Empty(change.Error)
+
+	changes, err = api.GetGovernanceChanges(context.Background(), 3, 4)
+	require.NoError(err)
+	require.Empty(changes)
+
+	_, err = api.GetGovernanceChanges(context.Background(), 3, 1)
+	require.ErrorContains(err, "invalid block range")
+
+	// The index is read instead of replaying the blocks once it covers the
+	// range.
+	indexed := &types.GovernanceChange{Kind: types.DistributionChange, Contract: common.Address{0xbb}, Amount: big.NewInt(1)}
+	rawdb.WriteGovernanceIndexTail(db, 1)
+	rawdb.WriteGovernanceIndexHead(db, 4)
+	rawdb.WriteGovernanceChanges(db, 3, types.GovernanceChanges{indexed})
+	changes, err = api.GetGovernanceChanges(context.Background(), 1, 4)
+	require.NoError(err)
+	require.Len(changes, 1)
+	require.Equal(types.DistributionChange, changes[0].Kind)
+	require.Equal(blocks[2].Hash(), changes[0].BlockHash)
+
+	changes, err = api.GetGovernanceChanges(context.Background(), 0, 4)
+	require.NoError(err)
+	require.Len(changes, 1)
+	require.Equal(types.GovernanceTimelockChange, changes[0].Kind)
+}
@@ -28,6 +28,7 @@
 package eth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
@@ -49,6 +50,7 @@ import (
 	"github.com/ava-labs/coreth/eth/filters"
 	"github.com/ava-labs/coreth/eth/gasprice"
 	"github.com/ava-labs/coreth/eth/tracers"
+	"github.com/ava-labs/coreth/internal/chainarchive"
 	"github.com/ava-labs/coreth/internal/ethapi"
 	"github.com/ava-labs/coreth/internal/shutdowncheck"
 	"github.com/ava-labs/coreth/miner"
@@ -102,6 +104,7 @@ type Ethereum struct {
 
 	miner     *miner.Miner
 	etherbase common.Address
+	atomicTxs chainarchive.AtomicTxs
 
 	networkID     uint64
 	netRPCService *ethapi.NetAPI
@@ -149,9 +152,19 @@ func New(
 		"snapshot clean", common.StorageSize(config.SnapshotCache)*1024*1024,
 	)
 
+	// Resume a migration to the path-based scheme interrupted in a previous run
+	// before the scheme of the stored state is determined.
+	if err := pruner.RecoverStateSchemeMigration(chainDb); err != nil {
+		return nil, fmt.Errorf("failed to recover state scheme migration: %w", err)
+	}
+	var migrateScheme bool
 	scheme, err := rawdb.ParseStateScheme(config.StateScheme, chainDb)
 	if err != nil {
-		return nil, err
+		// The hash-based state is migrated once the chain is loaded with it.
+		if !config.StateSchemeMigration || config.StateScheme != rawdb.PathScheme || rawdb.ReadStateScheme(chainDb) != rawdb.HashScheme {
+			return nil, err
+		}
+		scheme, migrateScheme = rawdb.HashScheme, true
 	}
 	// Try to recover offline state pruning only in hash-based.
 	if scheme == rawdb.HashScheme {
@@ -164,6 +177,13 @@ func New(
 		if err := pruner.RecoverPruning(config.OfflinePruningDataDirectory, chainDb); err != nil {
 			log.Error("Failed to recover state", "error", err)
 		}
+		// An online pruning session cannot be resumed once trie nodes were
+		// flushed without its write barrier, so it is dropped when disabled.
+		if !config.OnlinePruning {
+			if err := pruner.DropOnlinePruning(config.OnlinePruningDataDirectory, chainDb); err != nil {
+				return nil, fmt.Errorf("failed to drop online pruning session: %w", err)
+			}
+		}
 	}
 
 	networkID := config.NetworkId
@@ -225,7 +245,14 @@ func New(
 			TransactionHistory:              config.TransactionHistory,
 			SkipTxIndexing:                  config.SkipTxIndexing,
 			StateHistory:                    config.StateHistory,
+			StateHistoryDirectory:           config.StateHistoryDirectory,
 			StateScheme:                     scheme,
+			OnlinePruning:                   config.OnlinePruning,
+			OnlinePruningDataDirectory:      config.OnlinePruningDataDirectory,
+			OnlinePruningBloomFilterSize:    config.OnlinePruningBloomFilterSize,
+			OnlinePruningInterval:           config.OnlinePruningInterval,
+			StateArchive:                    config.StateArchive,
+			StateArchiveRebuild:             config.StateArchiveRebuild,
 		}
 	)
 
@@ -240,6 +267,11 @@ func New(
 	if err := eth.handleOfflinePruning(cacheConfig, config.Genesis, vmConfig, lastAcceptedHash); err != nil {
 		return nil, err
 	}
+	if migrateScheme {
+		if err := eth.handleStateSchemeMigration(cacheConfig, config.Genesis, vmConfig, lastAcceptedHash); err != nil {
+			return nil, err
+		}
+	}
 
 	eth.bloomIndexer.Start(eth.blockchain)
 
@@ -279,6 +311,21 @@ func New(
 		return nil, err
 	}
 
+	if config.TraceIndexEnabled {
+		eth.blockchain.StartTraceIndexer(config.TraceHistory, tracers.NewBlockTracer(eth.APIBackend))
+	}
+	if config.LogIndexEnabled {
+		eth.blockchain.StartLogIndexer(config.LogIndexRebuild)
+	}
+	if config.GovernanceIndexEnabled {
+		eth.blockchain.StartGovernanceIndexer(func(block *types.Block) (types.GovernanceChanges, error) {
+			if !eth.blockchain.HasGovernanceCalls(block) {
+				return nil, nil
+			}
+			return eth.replayGovernanceChanges(context.Background(), block)
+		})
+	}
+
 	// Start the RPC service
 	eth.netRPCService = ethapi.NewNetAPI(eth.NetVersion())
 
@@ -324,6 +371,10 @@ func (s *Ethereum) APIs() []rpc.API {
 			Namespace: "debug",
 			Service:   NewDebugAPI(s),
 			Name:      "debug",
+		}, {
+			Namespace: "flare",
+			Service:   NewFlareAPI(s),
+			Name:      "flare",
 		}, {
 			Namespace: "net",
 			Service:   s.netRPCService,
@@ -352,6 +403,22 @@ func (s *Ethereum) SetEtherbase(etherbase common.Address) {
 	s.miner.SetEtherbase(etherbase)
 }
 
+// SetAtomicTxs sets the atomic transactions bundled with the accepted blocks
+// in chain archives.
+func (s *Ethereum) SetAtomicTxs(atomicTxs chainarchive.AtomicTxs) {
+	s.lock.Lock()
+	s.atomicTxs = atomicTxs
+	s.lock.Unlock()
+}
+
+// AtomicTxs returns the atomic transactions bundled with the accepted blocks in
+// chain archives, nil if not set.
+func (s *Ethereum) AtomicTxs() chainarchive.AtomicTxs {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.atomicTxs
+}
+
 func (s *Ethereum) Miner() *miner.Miner { return s.miner }
 
 func (s *Ethereum) AccountManager() *accounts.Manager { return s.accountManager }
@@ -483,3 +550,29 @@ func (s *Ethereum) handleOfflinePruning(cacheConfig *core.CacheConfig, gspec *co
 
 	return nil
 }
+
+// handleStateSchemeMigration converts the hash-based state of the last accepted
+// block to the path-based scheme, and reloads the chain with it.
+func (s *Ethereum) handleStateSchemeMigration(cacheConfig *core.CacheConfig, gspec *core.Genesis, vmConfig vm.Config, lastAcceptedHash common.Hash) error {
+	// Clean up middle roots
+	if err := s.blockchain.CleanBlockRootsAboveLastAccepted(); err != nil {
+		return err
+	}
+	targetRoot := s.blockchain.LastAcceptedBlock().Root()
+
+	// Allow the blockchain to be garbage collected immediately, since we will shut down the chain after the migration completes.
+	s.blockchain.Stop()
+	s.blockchain = nil
+	log.Info("Starting state scheme migration", "root", targetRoot, "scheme", rawdb.PathScheme)
+	if err := pruner.MigrateToPathScheme(s.chainDb, targetRoot); err != nil {
+		return fmt.Errorf("failed to migrate state %s to the path-based scheme: %w", targetRoot, err)
+	}
+	cacheConfig.StateScheme = rawdb.PathScheme
+
+	var err error
+	s.blockchain, err = core.NewBlockChain(s.chainDb, cacheConfig, gspec, s.engine, vmConfig, lastAcceptedHash, s.config.SkipUpgradeCheck)
+	if err != nil {
+		return fmt.Errorf("failed to re-initialize blockchain after state scheme migration: %w", err)
+	}
+	return nil
+}
@@ -188,12 +188,24 @@ type Config struct {
 	// to be issued without replay protection over the API even if AllowUnprotectedTxs is false.
 	AllowUnprotectedTxHashes []common.Hash
 
+	// FeeTreatmentInReceipts adds the Flare fee treatment of a transaction to
+	// its receipt returned by eth_getTransactionReceipt.
+	FeeTreatmentInReceipts bool
+
 	// OfflinePruning enables offline pruning on startup of the node. If a node is started
 	// with this configuration option, it must finish pruning before resuming normal operation.
 	OfflinePruning                bool
 	OfflinePruningBloomFilterSize uint64
 	OfflinePruningDataDirectory   string
 
+	// OnlinePruning enables deleting the stale trie nodes in the background while
+	// the node keeps accepting blocks, in sessions [OnlinePruningInterval] blocks
+	// apart.
+	OnlinePruning                bool
+	OnlinePruningBloomFilterSize uint64
+	OnlinePruningDataDirectory   string
+	OnlinePruningInterval        uint64
+
 	// SkipUpgradeCheck disables checking that upgrades must take place before the last
 	// accepted block. Skipping this check is useful when a node operator does not update
 	// their node before the network upgrade and their node accepts blocks that have
@@ -207,13 +219,53 @@ type Config struct {
 	TransactionHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	StateHistory       uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state histories are reserved.
 
+	// StateHistoryDirectory is the directory of the state histories of the
+	// path-based scheme, the histories are not kept if it is empty.
+	StateHistoryDirectory string `toml:",omitempty"`
+
 	// State scheme represents the scheme used to store ethereum states and trie
 	// nodes on top. It can be 'hash', 'path', or none which means use the scheme
 	// consistent with persistent state.
 	StateScheme string `toml:",omitempty"`
 
+	// StateSchemeMigration converts the hash-based state of the last accepted
+	// block to the path-based scheme on startup if 'path' is requested.
+	StateSchemeMigration bool `toml:",omitempty"`
+
+	// StateArchive keeps the state diffs of the accepted blocks, from which the
+	// states whose tries are pruned are served.
+	StateArchive bool `toml:",omitempty"`
+
+	// StateArchiveRebuild discards and rebuilds a state archive that does not
+	// reach the last accepted block, instead of failing on startup.
+	StateArchiveRebuild bool `toml:",omitempty"`
+
 	// SkipTxIndexing skips indexing transactions.
 	// This is useful for validators that don't need to index transactions.
 	// TransactionHistory can be still used to control unindexing old transactions.
 	SkipTxIndexing bool
+
+	// GovernanceIndexEnabled indexes the Flare governance changes of every
+	// accepted block in the background, for flare_getGovernanceChanges.
+	GovernanceIndexEnabled bool
+
+	// TraceIndexEnabled traces every accepted block in the background and
+	// indexes the flat call traces by address for trace_filter.
+	TraceIndexEnabled bool
+	// TraceHistory is the maximum number of blocks from head whose traces
+	// are reserved:
+	//  * 0:   means no limit
+	//  * N:   means N block limit [HEAD-N+1, HEAD] and delete extra traces
+	TraceHistory uint64 `toml:",omitempty"`
+
+	// TraceFileDir is the directory the struct logs of transactions traced
+	// to a file are written to. Empty means the system temporary directory.
+	TraceFileDir string `toml:",omitempty"`
+
+	// LogIndexEnabled indexes the logs of every accepted block by address and
+	// topic in the background, to serve eth_getLogs over wide ranges.
+	LogIndexEnabled bool
+	// LogIndexRebuild drops the existing log index and indexes the accepted
+	// blocks again.
+	LogIndexRebuild bool `toml:",omitempty"`
 }
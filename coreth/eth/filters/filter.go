@@ -194,13 +194,30 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	}
 
 	// If the requested range of blocks exceeds the maximum number of blocks allowed by the backend
-	// return an error instead of searching for the logs.
-	if maxBlocks := f.sys.backend.GetMaxBlocksPerRequest(); f.end-f.begin >= maxBlocks && maxBlocks > 0 {
-		return nil, fmt.Errorf("requested too many blocks from %d to %d, maximum is set to %d", f.begin, f.end, maxBlocks)
+	// return an error instead of searching for the logs. The blocks covered by the exact log
+	// index are not scanned, so they are not limited.
+	indexEnd, exact := f.logIndexEnd()
+	if maxBlocks := f.sys.backend.GetMaxBlocksPerRequest(); maxBlocks > 0 {
+		scanBegin := f.begin
+		if exact {
+			scanBegin = int64(indexEnd) + 1
+		}
+		if f.end-scanBegin >= maxBlocks {
+			return nil, fmt.Errorf("requested too many blocks from %d to %d, maximum is set to %d", f.begin, f.end, maxBlocks)
+		}
+	}
+	var logs []*types.Log
+	if exact {
+		if logs, err = f.exactLogs(ctx, indexEnd); err != nil {
+			return logs, err
+		}
+		f.begin = int64(indexEnd) + 1
+		if f.begin > f.end {
+			return logs, nil
+		}
 	}
 	// Gather all indexed logs, and finish with non indexed ones
 	logChan, errChan := f.rangeLogsAsync(ctx)
-	var logs []*types.Log
 	for {
 		select {
 		case log := <-logChan:
@@ -0,0 +1,125 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package filters
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/rpc"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// logIndexEnd returns the last block of the range of the filter that can be
+// served from the exact log index. It reports false if the index does not
+// cover the beginning of the range, or if the filter has no address or topic
+// criteria to look up.
+func (f *Filter) logIndexEnd() (uint64, bool) {
+	if len(f.addresses) == 0 && !slices.ContainsFunc(f.topics, func(sub []common.Hash) bool { return len(sub) > 0 }) {
+		return 0, false
+	}
+	db := f.sys.backend.ChainDb()
+	tail, head := rawdb.ReadLogIndexTail(db), rawdb.ReadLogIndexHead(db)
+	if tail == nil || head == nil || f.begin < 0 || uint64(f.begin) < *tail || uint64(f.begin) > *head {
+		return 0, false
+	}
+	return min(*head, uint64(f.end)), true
+}
+
+// exactLogs returns the logs matching the filter criteria from the beginning
+// of the range to [end], looking up the blocks containing them in the exact
+// log index.
+func (f *Filter) exactLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
+	var (
+		db        = f.sys.backend.ChainDb()
+		begin     = uint64(f.begin)
+		positions []rawdb.LogPosition
+		filtered  bool
+	)
+	// restrict intersects the positions with the ones matching a criterion.
+	restrict := func(matching []rawdb.LogPosition) {
+		if !filtered {
+			positions, filtered = matching, true
+			return
+		}
+		positions = intersectLogPositions(positions, matching)
+	}
+	if len(f.addresses) > 0 {
+		var matching []rawdb.LogPosition
+		for _, addr := range f.addresses {
+			matching = append(matching, rawdb.ReadAddressLogPositions(db, addr, begin, end)...)
+		}
+		restrict(sortLogPositions(matching))
+	}
+	for i, sub := range f.topics {
+		if i >= rawdb.LogIndexTopics {
+			break
+		}
+		if len(sub) == 0 {
+			continue // empty rule set == wildcard
+		}
+		var matching []rawdb.LogPosition
+		for _, topic := range sub {
+			matching = append(matching, rawdb.ReadTopicLogPositions(db, i, topic, begin, end)...)
+		}
+		restrict(sortLogPositions(matching))
+	}
+
+	// The logs of the blocks are filtered again, which also derives their
+	// fields from the blocks.
+	var logs []*types.Log
+	for i, pos := range positions {
+		if i > 0 && positions[i-1].Number == pos.Number {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+		header, err := f.sys.backend.HeaderByNumber(ctx, rpc.BlockNumber(pos.Number))
+		if header == nil || err != nil {
+			return logs, err
+		}
+		found, err := f.checkMatches(ctx, header)
+		if err != nil {
+			return logs, err
+		}
+		logs = append(logs, found...)
+	}
+	return logs, nil
+}
+
+// sortLogPositions sorts [positions] in ascending order and removes the
+// duplicates.
+func sortLogPositions(positions []rawdb.LogPosition) []rawdb.LogPosition {
+	slices.SortFunc(positions, compareLogPositions)
+	return slices.Compact(positions)
+}
+
+// intersectLogPositions returns the positions found in both sorted [a] and
+// [b].
+func intersectLogPositions(a, b []rawdb.LogPosition) []rawdb.LogPosition {
+	var both []rawdb.LogPosition
+	for len(a) > 0 && len(b) > 0 {
+		switch c := compareLogPositions(a[0], b[0]); {
+		case c < 0:
+			a = a[1:]
+		case c > 0:
+			b = b[1:]
+		default:
+			both = append(both, a[0])
+			a, b = a[1:], b[1:]
+		}
+	}
+	return both
+}
+
+func compareLogPositions(a, b rawdb.LogPosition) int {
+	if c := cmp.Compare(a.Number, b.Number); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.LogIndex, b.LogIndex)
+}
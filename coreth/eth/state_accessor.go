@@ -158,7 +158,7 @@ func (eth *Ethereum) hashState(ctx context.Context, block *types.Block, reexec u
 		if current = eth.blockchain.GetBlockByNumber(next); current == nil {
 			return nil, nil, fmt.Errorf("block #%d not found", next)
 		}
-		_, _, _, err := eth.blockchain.Processor().Process(current, parentHeader, statedb, vm.Config{})
+		_, _, _, _, err := eth.blockchain.Processor().Process(current, parentHeader, statedb, vm.Config{})
 		if err != nil {
 			return nil, nil, fmt.Errorf("processing block %d failed: %v", current.NumberU64(), err)
 		}
@@ -222,6 +222,14 @@ func (eth *Ethereum) pathState(block *types.Block) (*state.StateDB, func(), erro
 //     provided, it would be preferable to start from a fresh state, if we have it
 //     on disk.
 func (eth *Ethereum) stateAtBlock(ctx context.Context, block *types.Block, reexec uint64, base *state.StateDB, readOnly bool, preferDisk bool) (statedb *state.StateDB, release tracers.StateReleaseFunc, err error) {
+	// The states of the accepted blocks whose tries are not kept are read from
+	// the state archive if enabled, even if a base is provided since it cannot
+	// be committed on top of.
+	if !eth.blockchain.HasState(block.Root()) {
+		if statedb, err := eth.blockchain.HistoricalStateAt(block.Header()); err == nil {
+			return statedb, noopReleaser, nil
+		}
+	}
 	if eth.blockchain.TrieDB().Scheme() == rawdb.HashScheme {
 		return eth.hashState(ctx, block, reexec, base, readOnly, preferDisk)
 	}
@@ -92,6 +92,7 @@ type Backend interface {
 	BadBlocks() ([]*types.Block, []*core.BadBlockReason)
 	GetTransaction(ctx context.Context, txHash common.Hash) (bool, *types.Transaction, common.Hash, uint64, uint64, error)
 	RPCGasCap() uint64
+	TraceFileDir() string
 	ChainConfig() *params.ChainConfig
 	Engine() consensus.Engine
 	ChainDb() ethdb.Database
@@ -181,6 +182,9 @@ type TraceConfig struct {
 	Tracer  *string
 	Timeout *string
 	Reexec  *uint64
+	// IncludeSystemCalls reports the Flare daemon call and mint executed after
+	// each transaction to tracers that support it (call, flatCall, prestate).
+	IncludeSystemCalls bool
 	// Config specific to given tracer. Note struct logger
 	// config are historically embedded in main object.
 	TracerConfig json.RawMessage
@@ -309,7 +313,7 @@ func (api *API) traceChain(start, end *types.Block, config *TraceConfig, closed
 						TxIndex:     i,
 						TxHash:      tx.Hash(),
 					}
-					res, err := api.traceTx(ctx, msg, txctx, blockCtx, task.statedb, config)
+					res, err := api.traceTx(ctx, msg, txctx, blockCtx, task.statedb, config, nil)
 					if err != nil {
 						task.results[i] = &txTraceResult{TxHash: tx.Hash(), Error: err.Error()}
 						log.Warn("Tracing failed", "hash", tx.Hash(), "block", task.block.NumberU64(), "err", err)
@@ -660,7 +664,7 @@ func (api *baseAPI) traceBlock(ctx context.Context, block *types.Block, config *
 			TxIndex:     i,
 			TxHash:      tx.Hash(),
 		}
-		res, err := api.traceTx(ctx, msg, txctx, blockCtx, statedb, config)
+		res, err := api.traceTx(ctx, msg, txctx, blockCtx, statedb, config, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -703,7 +707,7 @@ func (api *baseAPI) traceBlockParallel(ctx context.Context, block *types.Block,
 					TxIndex:     task.index,
 					TxHash:      txs[task.index].Hash(),
 				}
-				res, err := api.traceTx(ctx, msg, txctx, blockCtx, task.statedb, config)
+				res, err := api.traceTx(ctx, msg, txctx, blockCtx, task.statedb, config, nil)
 				if err != nil {
 					results[task.index] = &txTraceResult{TxHash: txs[task.index].Hash(), Error: err.Error()}
 					continue
@@ -906,7 +910,7 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 		TxIndex:     int(index),
 		TxHash:      hash,
 	}
-	return api.traceTx(ctx, msg, txctx, vmctx, statedb, config)
+	return api.traceTx(ctx, msg, txctx, vmctx, statedb, config, nil)
 }
 
 // TraceCall lets you trace a given eth_call. It collects the structured logs
@@ -917,12 +921,135 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 // the trace will be conducted on the state after executing the specified transaction
 // within the specified block.
 func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
+	block, statedb, release, err := api.callState(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		vmctx       = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		precompiles vm.PrecompiledContracts
+	)
+	// Apply the customization rules if required.
+	if config != nil {
+		originalTime := block.Time()
+		config.BlockOverrides.Apply(&vmctx)
+		// Apply all relevant upgrades from [originalTime] to the block time set in the override.
+		// Should be applied before the state overrides.
+		err = core.ApplyUpgrades(api.backend.ChainConfig(), &originalTime, &vmctx, statedb)
+		if err != nil {
+			return nil, err
+		}
+
+		precompiles = vm.ActivePrecompiledContracts(api.backend.ChainConfig().Rules(vmctx.BlockNumber, vmctx.Time))
+		if err := config.StateOverrides.Apply(statedb, precompiles); err != nil {
+			return nil, err
+		}
+	}
+	// Execute the trace
+	msg, err := args.ToMessage(api.backend.RPCGasCap(), vmctx.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+
+	var traceConfig *TraceConfig
+	if config != nil {
+		config.BlockOverrides.ApplyFlare(msg)
+		traceConfig = &config.TraceConfig
+	}
+	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig, precompiles)
+}
+
+// Bundle is a list of calls traced one after the other by TraceCallMany. Its
+// block overrides apply to all of its calls.
+type Bundle struct {
+	Transactions   []ethapi.TransactionArgs `json:"transactions"`
+	BlockOverrides *ethapi.BlockOverrides   `json:"blockOverride"`
+}
+
+// TraceCallMany lets you trace bundles of calls executed one after the other
+// on top of the provided block, in the same way as TraceCall. Every call sees
+// the state changes of the calls before it. The state overrides of the config
+// are applied once before the first call, its block overrides apply to all
+// bundles, below the ones of each bundle. The traces of the calls are returned
+// per bundle.
+func (api *API) TraceCallMany(ctx context.Context, bundles []Bundle, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) ([][]interface{}, error) {
+	if len(bundles) == 0 {
+		return nil, errors.New("empty bundles")
+	}
+	block, statedb, release, err := api.callState(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		chainConfig = api.backend.ChainConfig()
+		is158       = chainConfig.IsEIP158(block.Number())
+		parentTime  = block.Time()
+		traceConfig *TraceConfig
+		results     = make([][]interface{}, len(bundles))
+		txIndex     int
+	)
+	if config != nil {
+		traceConfig = &config.TraceConfig
+	}
+	for i, bundle := range bundles {
+		vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		if config != nil {
+			config.BlockOverrides.Apply(&vmctx)
+		}
+		bundle.BlockOverrides.Apply(&vmctx)
+		// Apply all relevant upgrades from the time of the previous bundle to the
+		// time of this one. Upgrades of the first bundle are applied before the
+		// state overrides.
+		if err := core.ApplyUpgrades(chainConfig, &parentTime, &vmctx, statedb); err != nil {
+			return nil, err
+		}
+		parentTime = max(parentTime, vmctx.Time)
+		// The precompiles of each bundle are the ones active at its block
+		// context, moved by the state overrides, which are applied to the
+		// state only once.
+		precompiles := vm.ActivePrecompiledContracts(chainConfig.Rules(vmctx.BlockNumber, vmctx.Time))
+		if config != nil {
+			var err error
+			if i == 0 {
+				err = config.StateOverrides.Apply(statedb, precompiles)
+			} else {
+				err = config.StateOverrides.ApplyPrecompiles(precompiles)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		results[i] = make([]interface{}, len(bundle.Transactions))
+		for j, args := range bundle.Transactions {
+			msg, err := args.ToMessage(api.backend.RPCGasCap(), vmctx.BaseFee)
+			if err != nil {
+				return nil, fmt.Errorf("bundle %d call %d: %w", i, j, err)
+			}
+			if config != nil {
+				config.BlockOverrides.ApplyFlare(msg)
+			}
+			bundle.BlockOverrides.ApplyFlare(msg)
+			if results[i][j], err = api.traceTx(ctx, msg, &Context{TxIndex: txIndex}, vmctx, statedb, traceConfig, precompiles); err != nil {
+				return nil, fmt.Errorf("bundle %d call %d: %w", i, j, err)
+			}
+			statedb.Finalise(is158)
+			txIndex++
+		}
+	}
+	return results, nil
+}
+
+// callState returns the block to trace calls on top of and its state, after
+// the transaction selected by the config, if any.
+func (api *API) callState(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (*types.Block, *state.StateDB, StateReleaseFunc, error) {
 	// Try to retrieve the specified block
 	var (
-		err     error
-		block   *types.Block
-		statedb *state.StateDB
-		release StateReleaseFunc
+		err   error
+		block *types.Block
 	)
 	if hash, ok := blockNrOrHash.Hash(); ok {
 		block, err = api.blockByHash(ctx, hash)
@@ -933,64 +1060,40 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 			// more flexibility and stability than trying to trace on 'pending', since
 			// the contents of 'pending' is unstable and probably not a true representation
 			// of what the next actual block is likely to contain.
-			return nil, errors.New("tracing on top of pending is not supported")
+			return nil, nil, nil, errors.New("tracing on top of pending is not supported")
 		}
 		block, err = api.blockByNumber(ctx, number)
 	} else {
-		return nil, errors.New("invalid arguments; neither block nor hash specified")
+		return nil, nil, nil, errors.New("invalid arguments; neither block nor hash specified")
 	}
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	// try to recompute the state
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
 	}
-
+	var (
+		statedb *state.StateDB
+		release StateReleaseFunc
+	)
 	if config != nil && config.TxIndex != nil {
 		_, _, statedb, release, err = api.backend.StateAtTransaction(ctx, block, int(*config.TxIndex), reexec)
 	} else {
 		statedb, release, err = api.backend.StateAtBlock(ctx, block, reexec, nil, true, false)
 	}
 	if err != nil {
-		return nil, err
-	}
-	defer release()
-
-	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
-	// Apply the customization rules if required.
-	if config != nil {
-		originalTime := block.Time()
-		config.BlockOverrides.Apply(&vmctx)
-		// Apply all relevant upgrades from [originalTime] to the block time set in the override.
-		// Should be applied before the state overrides.
-		err = core.ApplyUpgrades(api.backend.ChainConfig(), &originalTime, &vmctx, statedb)
-		if err != nil {
-			return nil, err
-		}
-
-		if err := config.StateOverrides.Apply(statedb); err != nil {
-			return nil, err
-		}
-	}
-	// Execute the trace
-	msg, err := args.ToMessage(api.backend.RPCGasCap(), vmctx.BaseFee)
-	if err != nil {
-		return nil, err
-	}
-
-	var traceConfig *TraceConfig
-	if config != nil {
-		traceConfig = &config.TraceConfig
+		return nil, nil, nil, err
 	}
-	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
+	return block, statedb, release, nil
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
-// be tracer dependent.
-func (api *baseAPI) traceTx(ctx context.Context, message *core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
+// be tracer dependent. If [precompiles] is not nil, it replaces the precompiles
+// active in the environment.
+func (api *baseAPI) traceTx(ctx context.Context, message *core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig, precompiles vm.PrecompiledContracts) (interface{}, error) {
 	var (
 		tracer    Tracer
 		err       error
@@ -1008,7 +1111,10 @@ func (api *baseAPI) traceTx(ctx context.Context, message *core.Message, txctx *C
 			return nil, err
 		}
 	}
-	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.backend.ChainConfig(), vm.Config{Tracer: tracer, NoBaseFee: true})
+	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.backend.ChainConfig(), vm.Config{Tracer: tracer, NoBaseFee: true, TraceSystemCalls: config.IncludeSystemCalls})
+	if precompiles != nil {
+		vmenv.SetPrecompiles(precompiles)
+	}
 
 	// Define a meaningful timeout of a single transaction trace
 	if config.Timeout != nil {
@@ -1038,7 +1144,7 @@ func (api *baseAPI) traceTx(ctx context.Context, message *core.Message, txctx *C
 // APIs return the collection of RPC services the tracer package offers.
 func APIs(backend Backend) []rpc.API {
 	// Append all the local APIs and return
-	return []rpc.API{
+	apis := []rpc.API{
 		{
 			Namespace: "debug",
 			Service:   NewAPI(backend),
@@ -1050,6 +1156,14 @@ func APIs(backend Backend) []rpc.API {
 			Name:      "debug-file-tracer",
 		},
 	}
+	if backend, ok := backend.(SubscriptionBackend); ok {
+		apis = append(apis, rpc.API{
+			Namespace: "eth",
+			Service:   NewSubscriptionAPI(backend),
+			Name:      "trace-subscription",
+		})
+	}
+	return apis
 }
 
 // overrideConfig returns a copy of [original] with network upgrades enabled by [override] enabled,
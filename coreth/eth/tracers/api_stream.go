@@ -0,0 +1,277 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package tracers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/eth/tracers/logger"
+	"github.com/ava-labs/coreth/internal/ethapi"
+	"github.com/ava-labs/coreth/rpc"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// maxStreamRequestSize is the maximum size of a trace stream request body.
+const maxStreamRequestSize = 1024 * 1024
+
+// txStream re-executes a transaction with a logger.StreamLogger, so that its
+// struct logs are written out while it executes instead of being collected
+// in memory.
+type txStream struct {
+	api     *baseAPI
+	hash    common.Hash
+	index   int
+	msg     *core.Message
+	vmctx   vm.BlockContext
+	statedb *state.StateDB
+	release StateReleaseFunc
+	config  *TraceConfig
+	timeout time.Duration
+}
+
+// newTxStream looks up the transaction [hash] and the state it executed on.
+// The state must be released by running the stream.
+func (api *baseAPI) newTxStream(ctx context.Context, hash common.Hash, config *TraceConfig) (*txStream, error) {
+	if config == nil {
+		config = &TraceConfig{}
+	}
+	if config.Tracer != nil {
+		return nil, errors.New("only the struct logger can be streamed")
+	}
+	timeout := defaultTraceTimeout
+	if config.Timeout != nil {
+		var err error
+		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	found, _, blockHash, blockNumber, index, err := api.backend.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, ethapi.NewTxIndexingError()
+	}
+	// Only mined txes are supported
+	if !found {
+		return nil, errTxNotFound
+	}
+	// It shouldn't happen in practice.
+	if blockNumber == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	reexec := defaultTraceReexec
+	if config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	block, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(blockNumber), blockHash)
+	if err != nil {
+		return nil, err
+	}
+	msg, vmctx, statedb, release, err := api.backend.StateAtTransaction(ctx, block, int(index), reexec)
+	if err != nil {
+		return nil, err
+	}
+	return &txStream{
+		api:     api,
+		hash:    hash,
+		index:   int(index),
+		msg:     msg,
+		vmctx:   vmctx,
+		statedb: statedb,
+		release: release,
+		config:  config,
+		timeout: timeout,
+	}, nil
+}
+
+// run executes the transaction, writing a JSON line per step and a final line
+// with the output and gas used to [out]. If the execution fails or is stopped,
+// a last line holding the error is written instead.
+//
+// The execution is bounded by the configured timeout, or defaultTraceTimeout,
+// and is stopped when [ctx] is cancelled.
+func (s *txStream) run(ctx context.Context, out io.Writer) error {
+	defer s.release()
+
+	err := s.execute(ctx, out)
+	if err != nil {
+		line, _ := json.Marshal(struct {
+			Err string `json:"error"`
+		}{err.Error()})
+		out.Write(append(line, '\n'))
+	}
+	return err
+}
+
+func (s *txStream) execute(ctx context.Context, out io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	var (
+		streamer = logger.NewStreamLogger(s.config.Config, out)
+		vmenv    = vm.NewEVM(s.vmctx, core.NewEVMTxContext(s.msg), s.statedb, s.api.backend.ChainConfig(), vm.Config{Tracer: streamer, NoBaseFee: true})
+	)
+	stop := context.AfterFunc(ctx, func() {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			streamer.Stop(errors.New("execution timeout"))
+		} else {
+			streamer.Stop(ctx.Err())
+		}
+		// Stop evm execution. Note cancellation is not necessarily immediate.
+		vmenv.Cancel()
+	})
+	defer stop()
+
+	// Call Prepare to clear out the statedb access list
+	s.statedb.SetTxContext(s.hash, s.index)
+	if _, err := core.ApplyMessage(vmenv, s.msg, new(core.GasPool).AddGas(s.msg.GasLimit)); err != nil {
+		return fmt.Errorf("tracing failed: %w", err)
+	}
+	return streamer.Error()
+}
+
+// StreamTransaction re-executes the transaction [hash] and sends its struct
+// logs, in the format of the JSON logger, as notifications of a subscription
+// while it executes. The last notification holds the output and gas used, or
+// the error that stopped the execution.
+func (api *API) StreamTransaction(ctx context.Context, hash common.Hash, config *TraceConfig) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	stream, err := api.newTxStream(ctx, hash, config)
+	if err != nil {
+		return nil, err
+	}
+	sub := notifier.CreateSubscription()
+
+	// The execution outlives the subscribe call, it is stopped when the
+	// client unsubscribes or disconnects instead.
+	streamCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-sub.Err():
+			cancel()
+		case <-streamCtx.Done():
+		}
+	}()
+	go func() {
+		defer cancel()
+		stream.run(streamCtx, &notifyWriter{notifier: notifier, id: sub.ID})
+	}()
+	return sub, nil
+}
+
+// notifyWriter sends every line written to it as a notification.
+type notifyWriter struct {
+	notifier *rpc.Notifier
+	id       rpc.ID
+}
+
+func (w *notifyWriter) Write(line []byte) (int, error) {
+	msg := json.RawMessage(bytes.Clone(bytes.TrimSuffix(line, []byte("\n"))))
+	if err := w.notifier.Notify(w.id, msg); err != nil {
+		return 0, err
+	}
+	return len(line), nil
+}
+
+// TraceTransactionToFile re-executes the transaction [hash] and writes its
+// struct logs as JSON lines to a file in the configured trace directory,
+// returning the name of the file. The file is written in the background and
+// only appears under the returned name once it is complete.
+func (api *FileTracerAPI) TraceTransactionToFile(ctx context.Context, hash common.Hash, config *TraceConfig) (string, error) {
+	dir := api.backend.TraceFileDir()
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	stream, err := api.newTxStream(ctx, hash, config)
+	if err != nil {
+		return "", err
+	}
+	dump, err := os.CreateTemp(dir, fmt.Sprintf("tx_%#x-*.jsonl.part", hash.Bytes()[:4]))
+	if err != nil {
+		stream.release()
+		return "", err
+	}
+	name := strings.TrimSuffix(dump.Name(), ".part")
+	go func() {
+		out := bufio.NewWriter(dump)
+		if err := stream.run(context.Background(), out); err != nil {
+			log.Warn("Failed to trace transaction to file", "hash", hash, "file", name, "err", err)
+		}
+		if err := errors.Join(out.Flush(), dump.Close()); err != nil {
+			log.Warn("Failed to write transaction trace", "hash", hash, "file", name, "err", err)
+			os.Remove(dump.Name())
+			return
+		}
+		if err := os.Rename(dump.Name(), name); err != nil {
+			log.Warn("Failed to move transaction trace", "hash", hash, "file", name, "err", err)
+		}
+	}()
+	return name, nil
+}
+
+// streamRequest is the body of a request to the stream handler.
+type streamRequest struct {
+	TxHash common.Hash  `json:"txHash"`
+	Config *TraceConfig `json:"config"`
+}
+
+// streamHandler serves the struct logs of a transaction over HTTP.
+type streamHandler struct {
+	api *baseAPI
+}
+
+// NewStreamHandler returns an HTTP handler that re-executes the transaction
+// posted as {"txHash": ..., "config": ...} and streams its struct logs as JSON
+// lines in the response while it executes.
+func NewStreamHandler(backend Backend) http.Handler {
+	return &streamHandler{api: &baseAPI{backend: backend}}
+}
+
+func (h *streamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req streamRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxStreamRequestSize)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	stream, err := h.api.newTxStream(r.Context(), req.TxHash, req.Config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	stream.run(r.Context(), &flushWriter{w: w})
+}
+
+// flushWriter flushes every line written to an HTTP response, so that it is
+// sent to the client right away.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f *flushWriter) Write(line []byte) (int, error) {
+	n, err := f.w.Write(line)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,369 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/rpc"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// feedQueueSize is the number of accepted blocks queued for a feed, and the
+// number of computed results queued for each of its subscribers. Blocks that
+// do not fit are skipped, and reported in the next notification.
+const feedQueueSize = 16
+
+// stateDiffFeed is the key of the feed of the state diffs.
+const stateDiffFeed = "stateDiffs"
+
+var errTracerRequired = errors.New("a tracer is required")
+
+// SubscriptionBackend is the Backend of the subscriptions to the execution of
+// the accepted blocks.
+type SubscriptionBackend interface {
+	Backend
+	SubscribeChainAcceptedEvent(ch chan<- core.ChainEvent) event.Subscription
+}
+
+// SubscriptionAPI offers subscriptions to the traces and state diffs of the
+// accepted blocks over the eth namespace. Each block is traced once for all
+// the subscribers with the same parameters, which are charged the tracing
+// time against the CPU limit of their connection.
+type SubscriptionAPI struct {
+	baseAPI
+	events SubscriptionBackend
+
+	mu    sync.Mutex
+	feeds map[string]*blockFeed
+}
+
+// NewSubscriptionAPI creates a new API definition for the subscriptions to
+// the execution of the accepted blocks.
+func NewSubscriptionAPI(backend SubscriptionBackend) *SubscriptionAPI {
+	return &SubscriptionAPI{
+		baseAPI: baseAPI{backend: backend},
+		events:  backend,
+		feeds:   make(map[string]*blockFeed),
+	}
+}
+
+// AcceptedTraces is the notification of the acceptedTraces subscription.
+type AcceptedTraces struct {
+	BlockNumber hexutil.Uint64   `json:"blockNumber"`
+	BlockHash   common.Hash      `json:"blockHash"`
+	Traces      []*txTraceResult `json:"traces"`
+	Skipped     uint64           `json:"skipped,omitempty"` // blocks skipped since the previous notification
+}
+
+// AcceptedStateDiff is the notification of the stateDiffs subscription.
+type AcceptedStateDiff struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	StateDiff   StateDiff      `json:"stateDiff"`
+	Skipped     uint64         `json:"skipped,omitempty"` // blocks skipped since the previous notification
+}
+
+// StateDiffsArgs selects the accounts whose changes are reported by the
+// stateDiffs subscription. All the accounts are reported if empty.
+type StateDiffsArgs struct {
+	Addresses []common.Address `json:"addresses"`
+}
+
+// AcceptedTraces sends the results of tracing every accepted block with the
+// given tracer.
+func (api *SubscriptionAPI) AcceptedTraces(ctx context.Context, config *TraceConfig) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	// The struct logs of whole blocks are too large to be pushed.
+	if config == nil || config.Tracer == nil || *config.Tracer == "" {
+		return nil, errTracerRequired
+	}
+	if _, err := DefaultDirectory.New(*config.Tracer, new(Context), config.TracerConfig); err != nil {
+		return nil, err
+	}
+	key, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	feed, sub := api.subscribe("traces:"+string(key), func(ctx context.Context, block *types.Block) (any, error) {
+		return api.traceBlock(ctx, block, config)
+	})
+	rpcSub := notifier.CreateSubscription()
+	go api.serve(notifier, rpcSub, feed, sub, func(res *blockResult, skipped uint64) any {
+		return &AcceptedTraces{
+			BlockNumber: hexutil.Uint64(res.block.NumberU64()),
+			BlockHash:   res.block.Hash(),
+			Traces:      res.value.([]*txTraceResult),
+			Skipped:     skipped,
+		}
+	})
+	return rpcSub, nil
+}
+
+// StateDiffs sends the changes of the balance, nonce, code and storage of the
+// accounts modified by every accepted block. Blocks that do not modify any of
+// the selected accounts are not notified.
+func (api *SubscriptionAPI) StateDiffs(ctx context.Context, args *StateDiffsArgs) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	var addresses []common.Address
+	if args != nil {
+		addresses = args.Addresses
+	}
+	feed, sub := api.subscribe(stateDiffFeed, func(ctx context.Context, block *types.Block) (any, error) {
+		return api.blockStateDiff(ctx, block)
+	})
+	rpcSub := notifier.CreateSubscription()
+	go api.serve(notifier, rpcSub, feed, sub, func(res *blockResult, skipped uint64) any {
+		diff := res.value.(StateDiff)
+		if len(addresses) > 0 {
+			selected := make(StateDiff)
+			for addr, account := range diff {
+				if slices.Contains(addresses, addr) {
+					selected[addr] = account
+				}
+			}
+			if len(selected) == 0 {
+				return nil
+			}
+			diff = selected
+		}
+		return &AcceptedStateDiff{
+			BlockNumber: hexutil.Uint64(res.block.NumberU64()),
+			BlockHash:   res.block.Hash(),
+			StateDiff:   diff,
+			Skipped:     skipped,
+		}
+	})
+	return rpcSub, nil
+}
+
+// blockStateDiff returns the change of the state by all the transactions of
+// [block], including the Flare system calls executed after them.
+func (api *baseAPI) blockStateDiff(ctx context.Context, block *types.Block) (StateDiff, error) {
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return nil, err
+	}
+	statedb, release, err := api.backend.StateAtNextBlock(ctx, parent, block, defaultTraceReexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		txs      = block.Transactions()
+		is158    = api.backend.ChainConfig().IsEIP158(block.Number())
+		blockCtx = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		signer   = types.MakeSigner(api.backend.ChainConfig(), block.Number(), block.Time())
+		mode     = traceModeTracers[TraceModeStateDiff]
+		config   = &TraceConfig{Tracer: &mode.name, TracerConfig: mode.config, IncludeSystemCalls: true}
+		diff     = make(blockStateDiff)
+	)
+	for i, tx := range txs {
+		msg, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
+		txctx := &Context{
+			BlockHash:   block.Hash(),
+			BlockNumber: block.Number(),
+			TxIndex:     i,
+			TxHash:      tx.Hash(),
+		}
+		res, err := api.traceTx(ctx, msg, txctx, blockCtx, statedb, config, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := diff.add(res.(json.RawMessage)); err != nil {
+			return nil, err
+		}
+		statedb.Finalise(is158)
+	}
+	return diff.stateDiff(statedb), nil
+}
+
+// blockFeed computes a result for every accepted block and fans it out to its
+// subscribers.
+type blockFeed struct {
+	compute func(ctx context.Context, block *types.Block) (any, error)
+	subs    map[*feedSubscriber]struct{} // guarded by the mutex of the API
+	quit    chan struct{}
+}
+
+// blockResult is the result computed by a feed for an accepted block.
+type blockResult struct {
+	block *types.Block
+	value any
+	cost  time.Duration // processing time of computing the value
+}
+
+// feedSubscriber is a subscriber of a feed, receiving its results.
+type feedSubscriber struct {
+	ch      chan *blockResult
+	skipped atomic.Uint64 // results dropped because the subscriber lagged
+}
+
+// subscribe adds a subscriber to the feed with the given key, starting the
+// feed with [compute] if it has no other subscribers.
+func (api *SubscriptionAPI) subscribe(key string, compute func(context.Context, *types.Block) (any, error)) (*blockFeed, *feedSubscriber) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	feed, ok := api.feeds[key]
+	if !ok {
+		feed = &blockFeed{
+			compute: compute,
+			subs:    make(map[*feedSubscriber]struct{}),
+			quit:    make(chan struct{}),
+		}
+		api.feeds[key] = feed
+		go api.loop(feed)
+	}
+	sub := &feedSubscriber{ch: make(chan *blockResult, feedQueueSize)}
+	feed.subs[sub] = struct{}{}
+	return feed, sub
+}
+
+// unsubscribe removes a subscriber of the feed, stopping the feed if it was
+// the last one.
+func (api *SubscriptionAPI) unsubscribe(feed *blockFeed, sub *feedSubscriber) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	delete(feed.subs, sub)
+	if len(feed.subs) > 0 {
+		return
+	}
+	for key, f := range api.feeds {
+		if f == feed {
+			delete(api.feeds, key)
+		}
+	}
+	close(feed.quit)
+}
+
+// dispatch sends the result of a block to the subscribers of the feed. The
+// block is skipped for the subscribers whose queue is full, and for all of
+// them if its result could not be computed.
+func (api *SubscriptionAPI) dispatch(feed *blockFeed, res *blockResult) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	for sub := range feed.subs {
+		if res == nil {
+			sub.skipped.Add(1)
+			continue
+		}
+		select {
+		case sub.ch <- res:
+		default:
+			sub.skipped.Add(1)
+		}
+	}
+}
+
+// loop computes the results of the accepted blocks for the feed one at a
+// time, until the feed has no subscribers.
+func (api *SubscriptionAPI) loop(feed *blockFeed) {
+	var (
+		pending []*types.Block
+		done    chan *blockResult // Non-nil if a block is being computed.
+
+		headCh      = make(chan core.ChainEvent)
+		sub         = api.events.SubscribeChainAcceptedEvent(headCh)
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+	defer cancel()
+	if sub == nil {
+		log.Warn("could not create chain accepted subscription to feed subscriptions")
+		return
+	}
+	defer sub.Unsubscribe()
+
+	launch := func() {
+		block := pending[0]
+		pending = pending[1:]
+		done = make(chan *blockResult, 1)
+		go func(done chan *blockResult) {
+			start := time.Now()
+			value, err := feed.compute(ctx, block)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Warn("Failed to compute subscription result", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+				}
+				done <- nil
+				return
+			}
+			done <- &blockResult{block: block, value: value, cost: time.Since(start)}
+		}(done)
+	}
+	for {
+		select {
+		case head := <-headCh:
+			pending = append(pending, head.Block)
+			if len(pending) > feedQueueSize {
+				pending = pending[1:]
+				api.dispatch(feed, nil)
+			}
+			if done == nil {
+				launch()
+			}
+		case res := <-done:
+			done = nil
+			api.dispatch(feed, res)
+			if len(pending) > 0 {
+				launch()
+			}
+		case <-sub.Err():
+			return
+		case <-feed.quit:
+			return
+		}
+	}
+}
+
+// serve sends the results of the feed to the client of a subscription,
+// converted to notifications by [notification], which returns nil for the
+// results the client is not interested in.
+func (api *SubscriptionAPI) serve(notifier *rpc.Notifier, rpcSub *rpc.Subscription, feed *blockFeed, sub *feedSubscriber, notification func(res *blockResult, skipped uint64) any) {
+	defer api.unsubscribe(feed, sub)
+
+	var skipped uint64
+	for {
+		select {
+		case res := <-sub.ch:
+			// Lagging clients are slowed down the same way as their calls,
+			// until their results no longer fit their queue.
+			if err := notifier.Throttle(context.Background(), res.cost); err != nil {
+				return
+			}
+			skipped += sub.skipped.Swap(0)
+			if n := notification(res, skipped); n != nil {
+				notifier.Notify(rpcSub.ID, n)
+				skipped = 0
+			}
+		case <-rpcSub.Err():
+			return
+		case <-notifier.Closed():
+			return
+		}
+	}
+}
@@ -48,6 +48,7 @@ import (
 	"github.com/ava-labs/coreth/internal/ethapi"
 	"github.com/ava-labs/coreth/params"
 	"github.com/ava-labs/coreth/rpc"
+	"github.com/ava-labs/coreth/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -142,6 +143,10 @@ func (b *testBackend) RPCGasCap() uint64 {
 	return 25000000
 }
 
+func (b *testBackend) TraceFileDir() string {
+	return ""
+}
+
 func (b *testBackend) ChainConfig() *params.ChainConfig {
 	return b.chainConfig
 }
@@ -441,6 +446,135 @@ func TestTraceCall(t *testing.T) {
 	}
 }
 
+func TestTraceCallMany(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{
+		Config: params.TestFlareBanffChainConfig,
+		Alloc: types.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			accounts[1].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {})
+	defer backend.teardown()
+	api := NewAPI(backend)
+
+	var (
+		latest   = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		transfer = ethapi.TransactionArgs{
+			From:  &accounts[1].addr,
+			To:    &accounts[0].addr,
+			Value: (*hexutil.Big)(big.NewInt(params.Ether)),
+		}
+		number = ethapi.TransactionArgs{
+			From:  &accounts[0].addr,
+			Input: &hexutil.Bytes{0x43}, // blocknumber
+		}
+	)
+	results, err := api.TraceCallMany(context.Background(), []Bundle{
+		{Transactions: []ethapi.TransactionArgs{transfer}},
+		{
+			Transactions:   []ethapi.TransactionArgs{number},
+			BlockOverrides: &ethapi.BlockOverrides{Number: (*hexutil.Big)(big.NewInt(0x1337))},
+		},
+	}, latest, nil)
+	if err != nil {
+		t.Fatalf("failed to trace bundles: %v", err)
+	}
+	if len(results) != 2 || len(results[0]) != 1 || len(results[1]) != 1 {
+		t.Fatalf("unexpected number of traces: %v", results)
+	}
+	var have *logger.ExecutionResult
+	if err := json.Unmarshal(results[1][0].(json.RawMessage), &have); err != nil {
+		t.Fatalf("failed to unmarshal result: %v", err)
+	}
+	if len(have.StructLogs) != 2 || (*have.StructLogs[1].Stack)[0] != "0x1337" {
+		t.Errorf("block overrides of the bundle not applied: %v", string(results[1][0].(json.RawMessage)))
+	}
+
+	// The second transfer sees the balance spent by the first one.
+	_, err = api.TraceCallMany(context.Background(), []Bundle{
+		{Transactions: []ethapi.TransactionArgs{transfer}},
+		{Transactions: []ethapi.TransactionArgs{number, transfer}},
+	}, latest, nil)
+	want := fmt.Sprintf("bundle 1 call 1: tracing failed: insufficient funds for gas * price + value: address %s have 0 want 1000000000000000000", accounts[1].addr)
+	if err == nil || err.Error() != want {
+		t.Errorf("error mismatch, want '%v', got '%v'", want, err)
+	}
+}
+
+func TestTraceCallManyPrecompiles(t *testing.T) {
+	t.Parallel()
+
+	// Etna, and with it the point evaluation precompile, activates after the
+	// chain head, so only bundles overriding the time past it can reach it.
+	config := *params.TestFlareCortinaChainConfig
+	config.DurangoBlockTimestamp = utils.NewUint64(1000)
+	config.EtnaTimestamp = utils.NewUint64(1000)
+	config.SetEthUpgrades()
+
+	accounts := newAccounts(1)
+	genesis := &core.Genesis{
+		Config: &config,
+		Alloc: types.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {})
+	defer backend.teardown()
+	api := NewAPI(backend)
+
+	var (
+		latest     = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		identity   = common.BytesToAddress([]byte{0x04})
+		moved      = common.HexToAddress("0x1234")
+		pointEval  = common.BytesToAddress([]byte{0x0a})
+		callMoved  = ethapi.TransactionArgs{From: &accounts[0].addr, To: &moved, Input: &hexutil.Bytes{0x12, 0x34}}
+		callPoint  = ethapi.TransactionArgs{From: &accounts[0].addr, To: &pointEval}
+		afterEtna  = hexutil.Uint64(2000)
+		overrides  = &ethapi.StateOverride{identity: {MovePrecompileTo: &moved}}
+		traceCalls = func(config *TraceCallConfig) [][]interface{} {
+			results, err := api.TraceCallMany(context.Background(), []Bundle{
+				{Transactions: []ethapi.TransactionArgs{callPoint}},
+				{
+					Transactions:   []ethapi.TransactionArgs{callPoint, callMoved},
+					BlockOverrides: &ethapi.BlockOverrides{Time: &afterEtna},
+				},
+			}, latest, config)
+			if err != nil {
+				t.Fatalf("failed to trace bundles: %v", err)
+			}
+			return results
+		}
+		result = func(raw interface{}) *logger.ExecutionResult {
+			var have *logger.ExecutionResult
+			if err := json.Unmarshal(raw.(json.RawMessage), &have); err != nil {
+				t.Fatalf("failed to unmarshal result: %v", err)
+			}
+			return have
+		}
+	)
+	for _, config := range []*TraceCallConfig{nil, {StateOverrides: overrides}} {
+		results := traceCalls(config)
+		// Before Etna the point evaluation address is a plain empty account,
+		// after it the precompile rejects the empty input.
+		if have := result(results[0][0]); have.Failed {
+			t.Errorf("point evaluation called before Etna: %v", string(results[0][0].(json.RawMessage)))
+		}
+		if have := result(results[1][0]); !have.Failed {
+			t.Errorf("point evaluation not called after Etna: %v", string(results[1][0].(json.RawMessage)))
+		}
+		if config == nil {
+			continue
+		}
+		if have := result(results[1][1]); have.ReturnValue != "1234" {
+			t.Errorf("moved precompile not called: %v", string(results[1][1].(json.RawMessage)))
+		}
+	}
+}
+
 func TestTraceTransaction(t *testing.T) {
 	t.Parallel()
 
@@ -893,6 +1027,24 @@ func TestTracingWithOverrides(t *testing.T) {
 			},
 			want: `{"gas":25288,"failed":false,"returnValue":"0000000000000000000000000000000000000000000000000000000000000055"}`,
 		},
+		{ // Moved precompile
+			// The identity precompile is moved to another account, which
+			// echoes the call data.
+			blockNumber: rpc.LatestBlockNumber,
+			call: ethapi.TransactionArgs{
+				From: &randomAccounts[0].addr,
+				To:   &randomAccounts[2].addr,
+				Data: newRPCBytes([]byte{0x12, 0x34}),
+			},
+			config: &TraceCallConfig{
+				StateOverrides: &ethapi.StateOverride{
+					common.BytesToAddress([]byte{0x04}): ethapi.OverrideAccount{
+						MovePrecompileTo: &randomAccounts[2].addr,
+					},
+				},
+			},
+			want: `{"gas":21050,"failed":false,"returnValue":"1234"}`,
+		},
 	}
 	for i, tc := range testSuite {
 		result, err := api.TraceCall(context.Background(), tc.call, rpc.BlockNumberOrHash{BlockNumber: &tc.blockNumber}, tc.config)
@@ -0,0 +1,688 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package tracers
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/internal/ethapi"
+	"github.com/ava-labs/coreth/rpc"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Modes of the Parity trace replay methods.
+const (
+	TraceModeTrace     = "trace"     // flat call traces
+	TraceModeStateDiff = "stateDiff" // state changes
+	TraceModeVMTrace   = "vmTrace"   // executed instructions
+)
+
+// maxTraceFilterRange is the maximum number of blocks traced by trace_filter,
+// unless they are indexed and the traces are filtered by address.
+const maxTraceFilterRange = 1000
+
+var (
+	errUnknownTraceMode = errors.New("unknown trace mode")
+
+	// flatTraceConfig configures the flat call tracer to report Parity errors.
+	flatTraceConfig = json.RawMessage(`{"convertParityErrors":true}`)
+
+	// traceModeTracers are the tracers and configs that produce each mode.
+	traceModeTracers = map[string]struct {
+		name   string
+		config json.RawMessage
+	}{
+		TraceModeTrace:     {"flatCallTracer", flatTraceConfig},
+		TraceModeStateDiff: {"prestateTracer", json.RawMessage(`{"diffMode":true}`)},
+		TraceModeVMTrace:   {"vmTracer", nil},
+	}
+)
+
+// TraceAPI is the collection of Parity/OpenEthereum compatible tracing APIs
+// exposed over the trace namespace. Flat traces include the Flare system
+// calls executed after each transaction, marked as system frames, with the
+// daemon mint reported as a reward.
+type TraceAPI struct {
+	baseAPI
+}
+
+// NewTraceAPI creates a new API definition for the Parity tracing methods of
+// the Ethereum service.
+func NewTraceAPI(backend Backend) *TraceAPI {
+	return &TraceAPI{baseAPI{backend: backend}}
+}
+
+// TraceResults is the outcome of replaying a transaction or a call in the
+// requested modes. The modes that were not requested are null.
+type TraceResults struct {
+	Output          hexutil.Bytes     `json:"output"`
+	StateDiff       StateDiff         `json:"stateDiff"`
+	Trace           []json.RawMessage `json:"trace"`
+	VMTrace         json.RawMessage   `json:"vmTrace"`
+	TransactionHash *common.Hash      `json:"transactionHash,omitempty"`
+}
+
+// TraceCallRequest is a call of trace_callMany and the modes to trace it in,
+// encoded as a [call, modes] pair.
+type TraceCallRequest struct {
+	Args       ethapi.TransactionArgs
+	TraceTypes []string
+}
+
+// UnmarshalJSON decodes a [call, modes] pair.
+func (r *TraceCallRequest) UnmarshalJSON(input []byte) error {
+	var pair []json.RawMessage
+	if err := json.Unmarshal(input, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("expected [call, traceTypes], got %d elements", len(pair))
+	}
+	if err := json.Unmarshal(pair[0], &r.Args); err != nil {
+		return err
+	}
+	return json.Unmarshal(pair[1], &r.TraceTypes)
+}
+
+// TraceFilterArgs selects the flat traces returned by trace_filter.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// Block returns the flat traces of all the transactions of a block.
+func (api *TraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]json.RawMessage, error) {
+	block, err := api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return api.blockTraces(ctx, block)
+}
+
+// Transaction returns the flat traces of a transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]json.RawMessage, error) {
+	msg, txctx, vmctx, statedb, release, err := api.transactionState(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	res, err := api.traceTx(ctx, msg, txctx, vmctx, statedb, flatTraceTxConfig(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var traces []json.RawMessage
+	if err := json.Unmarshal(res.(json.RawMessage), &traces); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// ReplayTransaction replays a transaction in the given modes.
+func (api *TraceAPI) ReplayTransaction(ctx context.Context, hash common.Hash, traceTypes []string) (*TraceResults, error) {
+	msg, txctx, vmctx, statedb, release, err := api.transactionState(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return api.replayTx(ctx, msg, txctx, vmctx, statedb, traceTypes)
+}
+
+// ReplayBlockTransactions replays all the transactions of a block in the
+// given modes.
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, traceTypes []string) ([]*TraceResults, error) {
+	block, err := api.blockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return nil, err
+	}
+	statedb, release, err := api.backend.StateAtNextBlock(ctx, parent, block, defaultTraceReexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		txs      = block.Transactions()
+		is158    = api.backend.ChainConfig().IsEIP158(block.Number())
+		blockCtx = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		signer   = types.MakeSigner(api.backend.ChainConfig(), block.Number(), block.Time())
+		results  = make([]*TraceResults, len(txs))
+	)
+	for i, tx := range txs {
+		msg, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
+		txctx := &Context{
+			BlockHash:   block.Hash(),
+			BlockNumber: block.Number(),
+			TxIndex:     i,
+			TxHash:      tx.Hash(),
+		}
+		res, err := api.replayTx(ctx, msg, txctx, blockCtx, statedb, traceTypes)
+		if err != nil {
+			return nil, err
+		}
+		hash := tx.Hash()
+		res.TransactionHash = &hash
+		results[i] = res
+		statedb.Finalise(is158)
+	}
+	return results, nil
+}
+
+// Call replays a call on top of the given block, or the latest one, in the
+// given modes.
+func (api *TraceAPI) Call(ctx context.Context, args ethapi.TransactionArgs, traceTypes []string, blockNrOrHash *rpc.BlockNumberOrHash) (*TraceResults, error) {
+	results, err := api.CallMany(ctx, []TraceCallRequest{{Args: args, TraceTypes: traceTypes}}, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// CallMany replays calls one after the other on top of the given block, or
+// the latest one, each in its own modes. Every call sees the state changes
+// of the calls before it.
+func (api *TraceAPI) CallMany(ctx context.Context, calls []TraceCallRequest, blockNrOrHash *rpc.BlockNumberOrHash) ([]*TraceResults, error) {
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	block, err := api.blockByNumberOrHash(ctx, *blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	statedb, release, err := api.backend.StateAtBlock(ctx, block, defaultTraceReexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		is158   = api.backend.ChainConfig().IsEIP158(block.Number())
+		vmctx   = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		results = make([]*TraceResults, len(calls))
+	)
+	for i, call := range calls {
+		msg, err := call.Args.ToMessage(api.backend.RPCGasCap(), vmctx.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		txctx := &Context{TxIndex: i}
+		if results[i], err = api.replayTx(ctx, msg, txctx, vmctx, statedb, call.TraceTypes); err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		statedb.Finalise(is158)
+	}
+	return results, nil
+}
+
+// Filter returns the flat traces of a range of blocks that match the given
+// addresses. A trace matches if its sender is one of the from addresses and
+// its recipient one of the to addresses, an empty list matching any address.
+// If the range is covered by the trace index, the traces are read from it
+// instead of re-executing the blocks.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]json.RawMessage, error) {
+	from, to := rpc.LatestBlockNumber, rpc.LatestBlockNumber
+	if args.FromBlock != nil {
+		from = *args.FromBlock
+	}
+	if args.ToBlock != nil {
+		to = *args.ToBlock
+	}
+	first, err := api.blockByNumber(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	last, err := api.blockByNumber(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	if first.NumberU64() > last.NumberU64() {
+		return nil, fmt.Errorf("fromBlock %d is after toBlock %d", first.NumberU64(), last.NumberU64())
+	}
+	var (
+		indexed  = api.traceIndexCovers(first.NumberU64(), last.NumberU64())
+		filtered = len(args.FromAddress) > 0 || len(args.ToAddress) > 0
+	)
+	if (!indexed || !filtered) && last.NumberU64()-first.NumberU64() >= maxTraceFilterRange {
+		return nil, fmt.Errorf("block range is greater than %d", maxTraceFilterRange)
+	}
+	if indexed {
+		return api.filterIndexed(ctx, first.NumberU64(), last.NumberU64(), args)
+	}
+	var (
+		after   uint64
+		matches = []json.RawMessage{}
+	)
+	if args.After != nil {
+		after = *args.After
+	}
+	for number := first.NumberU64(); number <= last.NumberU64(); number++ {
+		block := first
+		if number != first.NumberU64() {
+			if block, err = api.blockByNumber(ctx, rpc.BlockNumber(number)); err != nil {
+				return nil, err
+			}
+		}
+		if number == 0 {
+			continue
+		}
+		traces, err := api.blockTraces(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range traces {
+			ok, err := matchTraceAddresses(trace, args.FromAddress, args.ToAddress)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if after > 0 {
+				after--
+				continue
+			}
+			matches = append(matches, trace)
+			if args.Count != nil && uint64(len(matches)) >= *args.Count {
+				return matches, nil
+			}
+		}
+	}
+	return matches, nil
+}
+
+// traceIndexCovers reports whether the traces of all the blocks in
+// [first, last] are in the trace index.
+func (api *TraceAPI) traceIndexCovers(first, last uint64) bool {
+	db := api.backend.ChainDb()
+	tail, head := rawdb.ReadTraceIndexTail(db), rawdb.ReadTraceIndexHead(db)
+	return tail != nil && head != nil && *tail <= first && last <= *head
+}
+
+// filterIndexed serves trace_filter from the trace index. If addresses are
+// given, only the traces they are indexed by are read.
+func (api *TraceAPI) filterIndexed(ctx context.Context, first, last uint64, args TraceFilterArgs) ([]json.RawMessage, error) {
+	var (
+		db      = api.backend.ChainDb()
+		after   uint64
+		matches = []json.RawMessage{}
+	)
+	if args.After != nil {
+		after = *args.After
+	}
+	// match adds [trace] to the results and reports whether there are enough.
+	match := func(trace json.RawMessage) bool {
+		if after > 0 {
+			after--
+			return false
+		}
+		matches = append(matches, trace)
+		return args.Count != nil && uint64(len(matches)) >= *args.Count
+	}
+	if len(args.FromAddress) == 0 && len(args.ToAddress) == 0 {
+		for number := first; number <= last; number++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			for _, trace := range rawdb.ReadBlockTraces(db, number) {
+				if match(trace.Trace) {
+					return matches, nil
+				}
+			}
+		}
+		return matches, nil
+	}
+	var (
+		number uint64
+		traces []rawdb.IndexedTrace
+	)
+	for i, pos := range tracePositions(db, first, last, args.FromAddress, args.ToAddress) {
+		if i == 0 || pos.Number != number {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			number, traces = pos.Number, rawdb.ReadBlockTraces(db, pos.Number)
+		}
+		if int(pos.TraceIndex) >= len(traces) {
+			return nil, fmt.Errorf("missing indexed trace %d of block %d", pos.TraceIndex, pos.Number)
+		}
+		if match(traces[pos.TraceIndex].Trace) {
+			break
+		}
+	}
+	return matches, nil
+}
+
+// tracePositions returns the positions of the indexed traces in [first, last]
+// whose sender is one of [from] and whose recipient one of [to], in ascending
+// order. An empty list matches any address, but not both.
+func tracePositions(db ethdb.Iteratee, first, last uint64, from, to []common.Address) []rawdb.TracePosition {
+	read := func(addrs []common.Address, role rawdb.TraceRole) map[rawdb.TracePosition]struct{} {
+		positions := make(map[rawdb.TracePosition]struct{})
+		for _, addr := range addrs {
+			for _, pos := range rawdb.ReadTracePositions(db, addr, role, first, last) {
+				positions[pos] = struct{}{}
+			}
+		}
+		return positions
+	}
+	var positions map[rawdb.TracePosition]struct{}
+	switch {
+	case len(from) == 0:
+		positions = read(to, rawdb.TraceRoleTo)
+	case len(to) == 0:
+		positions = read(from, rawdb.TraceRoleFrom)
+	default:
+		positions = read(from, rawdb.TraceRoleFrom)
+		recipients := read(to, rawdb.TraceRoleTo)
+		for pos := range positions {
+			if _, ok := recipients[pos]; !ok {
+				delete(positions, pos)
+			}
+		}
+	}
+	sorted := make([]rawdb.TracePosition, 0, len(positions))
+	for pos := range positions {
+		sorted = append(sorted, pos)
+	}
+	slices.SortFunc(sorted, func(a, b rawdb.TracePosition) int {
+		if a.Number != b.Number {
+			return cmp.Compare(a.Number, b.Number)
+		}
+		return cmp.Compare(a.TraceIndex, b.TraceIndex)
+	})
+	return sorted
+}
+
+// blockByNumberOrHash returns the block with the given number or hash.
+func (api *TraceAPI) blockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return api.blockByHash(ctx, hash)
+	}
+	if number, ok := blockNrOrHash.Number(); ok {
+		if number == rpc.PendingBlockNumber {
+			return nil, errors.New("tracing on top of pending is not supported")
+		}
+		return api.blockByNumber(ctx, number)
+	}
+	return nil, errors.New("invalid arguments; neither block nor hash specified")
+}
+
+// blockTraces returns the flat traces of all the transactions of [block].
+func (api *TraceAPI) blockTraces(ctx context.Context, block *types.Block) ([]json.RawMessage, error) {
+	results, err := api.traceBlock(ctx, block, flatTraceTxConfig())
+	if err != nil {
+		return nil, err
+	}
+	traces := []json.RawMessage{}
+	for _, result := range results {
+		var txTraces []json.RawMessage
+		if err := json.Unmarshal(result.Result.(json.RawMessage), &txTraces); err != nil {
+			return nil, err
+		}
+		traces = append(traces, txTraces...)
+	}
+	return traces, nil
+}
+
+// NewBlockTracer returns the tracer of the trace indexer of the blockchain,
+// which traces the accepted blocks with the flat call tracer.
+func NewBlockTracer(backend Backend) core.BlockTracer {
+	api := NewTraceAPI(backend)
+	return func(block *types.Block) ([]rawdb.IndexedTrace, error) {
+		return api.indexedTraces(context.Background(), block)
+	}
+}
+
+// indexedTraces returns the flat traces of all the transactions of [block]
+// along with their senders and recipients.
+func (api *TraceAPI) indexedTraces(ctx context.Context, block *types.Block) ([]rawdb.IndexedTrace, error) {
+	results, err := api.traceBlock(ctx, block, flatTraceTxConfig())
+	if err != nil {
+		return nil, err
+	}
+	traces := []rawdb.IndexedTrace{}
+	for i, result := range results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("tx %d: %s", i, result.Error)
+		}
+		var txTraces []json.RawMessage
+		if err := json.Unmarshal(result.Result.(json.RawMessage), &txTraces); err != nil {
+			return nil, err
+		}
+		for _, trace := range txTraces {
+			sender, recipient, err := traceParties(trace)
+			if err != nil {
+				return nil, err
+			}
+			traces = append(traces, rawdb.IndexedTrace{
+				TxIndex: uint32(i),
+				From:    sender,
+				To:      recipient,
+				Trace:   trace,
+			})
+		}
+	}
+	return traces, nil
+}
+
+// transactionState returns the message of a mined transaction and the state
+// it was executed on.
+func (api *TraceAPI) transactionState(ctx context.Context, hash common.Hash) (*core.Message, *Context, vm.BlockContext, *state.StateDB, StateReleaseFunc, error) {
+	found, _, blockHash, blockNumber, index, err := api.backend.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, nil, vm.BlockContext{}, nil, nil, ethapi.NewTxIndexingError()
+	}
+	if !found {
+		return nil, nil, vm.BlockContext{}, nil, nil, errTxNotFound
+	}
+	if blockNumber == 0 {
+		return nil, nil, vm.BlockContext{}, nil, nil, errors.New("genesis is not traceable")
+	}
+	block, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(blockNumber), blockHash)
+	if err != nil {
+		return nil, nil, vm.BlockContext{}, nil, nil, err
+	}
+	msg, vmctx, statedb, release, err := api.backend.StateAtTransaction(ctx, block, int(index), defaultTraceReexec)
+	if err != nil {
+		return nil, nil, vm.BlockContext{}, nil, nil, err
+	}
+	txctx := &Context{
+		BlockHash:   blockHash,
+		BlockNumber: block.Number(),
+		TxIndex:     int(index),
+		TxHash:      hash,
+	}
+	return msg, txctx, vmctx, statedb, release, nil
+}
+
+// replayTx executes [message] in the provided environment and traces it in
+// the given modes.
+func (api *TraceAPI) replayTx(ctx context.Context, message *core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, traceTypes []string) (*TraceResults, error) {
+	config := make(map[string]json.RawMessage)
+	for _, mode := range traceTypes {
+		tracer, ok := traceModeTracers[mode]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", errUnknownTraceMode, mode)
+		}
+		config[tracer.name] = tracer.config
+	}
+	muxConfig, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	tracer, err := DefaultDirectory.New("muxTracer", txctx, muxConfig)
+	if err != nil {
+		return nil, err
+	}
+	vmenv := vm.NewEVM(vmctx, core.NewEVMTxContext(message), statedb, api.backend.ChainConfig(), vm.Config{Tracer: tracer, NoBaseFee: true, TraceSystemCalls: true})
+
+	deadlineCtx, cancel := context.WithTimeout(ctx, defaultTraceTimeout)
+	go func() {
+		<-deadlineCtx.Done()
+		if errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
+			tracer.Stop(errors.New("execution timeout"))
+			// Stop evm execution. Note cancellation is not necessarily immediate.
+			vmenv.Cancel()
+		}
+	}()
+	defer cancel()
+
+	statedb.SetTxContext(txctx.TxHash, txctx.TxIndex)
+	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.GasLimit))
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %w", err)
+	}
+	raw, err := tracer.GetResult()
+	if err != nil {
+		return nil, err
+	}
+	var outputs map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &outputs); err != nil {
+		return nil, err
+	}
+	res := &TraceResults{Output: result.ReturnData}
+	if res.Output == nil {
+		res.Output = hexutil.Bytes{}
+	}
+	if out, ok := outputs[traceModeTracers[TraceModeTrace].name]; ok {
+		if res.Trace, err = unlocalizeTraces(out); err != nil {
+			return nil, err
+		}
+	}
+	if out, ok := outputs[traceModeTracers[TraceModeStateDiff].name]; ok {
+		if res.StateDiff, err = newStateDiff(out); err != nil {
+			return nil, err
+		}
+	}
+	if out, ok := outputs[traceModeTracers[TraceModeVMTrace].name]; ok {
+		res.VMTrace = out
+	}
+	return res, nil
+}
+
+// localizedTraceFields are the fields of a flat trace locating it in the
+// chain, which Parity leaves out of the traces of replayed transactions.
+var localizedTraceFields = []string{"blockHash", "blockNumber", "transactionHash", "transactionPosition"}
+
+// unlocalizeTraces decodes the flat traces in [out] without the fields
+// locating them in the chain.
+func unlocalizeTraces(out json.RawMessage) ([]json.RawMessage, error) {
+	var traces []map[string]json.RawMessage
+	if err := json.Unmarshal(out, &traces); err != nil {
+		return nil, err
+	}
+	res := make([]json.RawMessage, 0, len(traces))
+	for _, trace := range traces {
+		for _, field := range localizedTraceFields {
+			delete(trace, field)
+		}
+		enc, err := json.Marshal(trace)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, enc)
+	}
+	return res, nil
+}
+
+// flatTraceTxConfig returns the config to trace transactions with the flat
+// call tracer, including the Flare system calls.
+func flatTraceTxConfig() *TraceConfig {
+	tracer := traceModeTracers[TraceModeTrace].name
+	return &TraceConfig{
+		Tracer:             &tracer,
+		IncludeSystemCalls: true,
+		TracerConfig:       flatTraceConfig,
+	}
+}
+
+// traceAddresses holds the fields of a flat trace that trace_filter matches.
+type traceAddresses struct {
+	Action struct {
+		From           *common.Address `json:"from"`
+		To             *common.Address `json:"to"`
+		SelfDestructed *common.Address `json:"address"`
+		RefundAddress  *common.Address `json:"refundAddress"`
+		Author         *common.Address `json:"author"`
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"`
+	} `json:"result"`
+}
+
+// matchTraceAddresses reports whether the sender of [trace] is in [from] and
+// its recipient in [to]. Empty lists match any address.
+func matchTraceAddresses(trace json.RawMessage, from, to []common.Address) (bool, error) {
+	if len(from) == 0 && len(to) == 0 {
+		return true, nil
+	}
+	sender, recipient, err := traceParties(trace)
+	if err != nil {
+		return false, err
+	}
+	return containsAddress(from, sender) && containsAddress(to, recipient), nil
+}
+
+// traceParties returns the addresses trace_filter matches as the sender and
+// the recipient of [trace].
+func traceParties(trace json.RawMessage) (*common.Address, *common.Address, error) {
+	var addrs traceAddresses
+	if err := json.Unmarshal(trace, &addrs); err != nil {
+		return nil, nil, err
+	}
+	sender := addrs.Action.From
+	if addrs.Action.SelfDestructed != nil {
+		sender = addrs.Action.SelfDestructed
+	}
+	recipient := addrs.Action.To
+	switch {
+	case addrs.Action.RefundAddress != nil:
+		recipient = addrs.Action.RefundAddress
+	case addrs.Action.Author != nil:
+		recipient = addrs.Action.Author
+	case recipient == nil && addrs.Result != nil:
+		recipient = addrs.Result.Address
+	}
+	return sender, recipient, nil
+}
+
+func containsAddress(addrs []common.Address, addr *common.Address) bool {
+	if len(addrs) == 0 {
+		return true
+	}
+	if addr == nil {
+		return false
+	}
+	for _, a := range addrs {
+		if a == *addr {
+			return true
+		}
+	}
+	return false
+}
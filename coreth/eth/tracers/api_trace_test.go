@@ -0,0 +1,136 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package tracers_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/eth/tracers"
+	"github.com/ava-labs/coreth/internal/ethapi"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ava-labs/coreth/rpc"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	// Force-load the native tracers, to trigger registration
+	_ "github.com/ava-labs/coreth/eth/tracers/native"
+)
+
+// requireTraceFixture checks that [have] encodes to the Parity formatted
+// result recorded in testdata/trace_api/[name].json.
+func requireTraceFixture(t *testing.T, name string, have interface{}) {
+	t.Helper()
+	want, err := os.ReadFile(filepath.Join("testdata", "trace_api", name+".json"))
+	require.NoError(t, err)
+	enc, err := json.Marshal(have)
+	require.NoError(t, err)
+	require.JSONEq(t, string(want), string(enc), name)
+}
+
+// Tests the results of the trace namespace against Parity formatted fixtures,
+// on a chain with a plain transfer and calls to a contract storing a value and
+// calling another contract.
+func TestTraceAPI(t *testing.T) {
+	var (
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		key2, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+		caller  = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+		callee  = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+		genesis = &core.Genesis{
+			Config: params.TestFlareChainConfig,
+			Alloc: types.GenesisAlloc{
+				addr1: {Balance: big.NewInt(params.Ether)},
+				addr2: {Balance: big.NewInt(params.Ether)},
+				// Stores 1 at slot 0, calls the callee and returns its output
+				caller: {Balance: common.Big0, Code: common.FromHex("0x602a60005260016000556020600060006000600060bb5af15060206000f3")},
+				// Returns 7
+				callee: {Balance: common.Big0, Code: common.FromHex("0x600760005260206000f3")},
+			},
+		}
+		signer = types.HomesteadSigner{}
+		hashes []common.Hash
+	)
+	backend := tracers.NewTestBackend(t, 2, genesis, func(i int, b *core.BlockGen) {
+		send := func(key *ecdsa.PrivateKey, to common.Address, value int64) {
+			tx, err := types.SignTx(types.NewTx(&types.LegacyTx{
+				Nonce:    b.TxNonce(crypto.PubkeyToAddress(key.PublicKey)),
+				To:       &to,
+				Value:    big.NewInt(value),
+				Gas:      100_000,
+				GasPrice: new(big.Int).Add(b.BaseFee(), big.NewInt(int64(500*params.GWei))),
+			}), signer, key)
+			require.NoError(t, err)
+			b.AddTx(tx)
+			hashes = append(hashes, tx.Hash())
+		}
+		switch i {
+		case 0:
+			send(key1, addr2, 1000)
+			send(key1, caller, 0)
+		case 1:
+			send(key2, caller, 0)
+		}
+	})
+	var (
+		ctx    = context.Background()
+		api    = tracers.NewTraceAPI(backend)
+		latest = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		all    = []string{tracers.TraceModeTrace, tracers.TraceModeStateDiff, tracers.TraceModeVMTrace}
+	)
+
+	block, err := api.Block(ctx, 1)
+	require.NoError(t, err)
+	requireTraceFixture(t, "block", block)
+
+	transaction, err := api.Transaction(ctx, hashes[1])
+	require.NoError(t, err)
+	requireTraceFixture(t, "transaction", transaction)
+
+	replayed, err := api.ReplayTransaction(ctx, hashes[1], all)
+	require.NoError(t, err)
+	requireTraceFixture(t, "replay_transaction", replayed)
+
+	replayedBlock, err := api.ReplayBlockTransactions(ctx, rpc.BlockNumberOrHashWithNumber(2), []string{tracers.TraceModeTrace})
+	require.NoError(t, err)
+	requireTraceFixture(t, "replay_block_transactions", replayedBlock)
+
+	_, err = api.ReplayTransaction(ctx, hashes[1], []string{"unknown"})
+	require.ErrorContains(t, err, "unknown trace mode")
+
+	call := ethapi.TransactionArgs{From: &addr1, To: &caller}
+	called, err := api.Call(ctx, call, []string{tracers.TraceModeTrace, tracers.TraceModeVMTrace}, &latest)
+	require.NoError(t, err)
+	requireTraceFixture(t, "call", called)
+
+	// The second call sees the balance transferred by the first one.
+	value := (*hexutil.Big)(big.NewInt(5))
+	calledMany, err := api.CallMany(ctx, []tracers.TraceCallRequest{
+		{Args: ethapi.TransactionArgs{From: &addr2, To: &addr1, Value: value}, TraceTypes: []string{tracers.TraceModeTrace}},
+		{Args: call, TraceTypes: []string{tracers.TraceModeStateDiff}},
+	}, &latest)
+	require.NoError(t, err)
+	requireTraceFixture(t, "call_many", calledMany)
+
+	from, to := rpc.BlockNumber(1), rpc.BlockNumber(2)
+	filtered, err := api.Filter(ctx, tracers.TraceFilterArgs{FromBlock: &from, ToBlock: &to, ToAddress: []common.Address{callee}})
+	require.NoError(t, err)
+	requireTraceFixture(t, "filter", filtered)
+
+	count := uint64(1)
+	filtered, err = api.Filter(ctx, tracers.TraceFilterArgs{FromBlock: &from, ToBlock: &to, FromAddress: []common.Address{addr1}, After: &count, Count: &count})
+	require.NoError(t, err)
+	requireTraceFixture(t, "filter_paged", filtered)
+}
@@ -0,0 +1,19 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package tracers
+
+import (
+	"testing"
+
+	"github.com/ava-labs/coreth/core"
+)
+
+// NewTestBackend exposes the test backend to the external tests of the
+// package, which can load the native tracers. The backend is torn down when
+// the test finishes.
+func NewTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i int, b *core.BlockGen)) Backend {
+	backend := newTestBackend(t, n, gspec, generator)
+	t.Cleanup(backend.teardown)
+	return backend
+}
@@ -55,6 +55,10 @@ type Config struct {
 	EnableReturnData bool // enable return data capture
 	Debug            bool // print output during capture end
 	Limit            int  // maximum length of output, but zero means unlimited
+	MemoryLimit      int  // maximum bytes of memory per streamed log, but zero means unlimited
+	StackLimit       int  // maximum top stack items per streamed log, but zero means unlimited
+	StorageLimit     int  // maximum storage slots per streamed log, but zero means unlimited
+	ReturnDataLimit  int  // maximum bytes of return data per streamed log, but zero means unlimited
 	// Chain overrides, can be used to execute a trace using future fork rules
 	Overrides *params.ChainConfig `json:"overrides,omitempty"`
 }
@@ -0,0 +1,217 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"sort"
+	"sync/atomic"
+
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+// StreamLogger is an EVM state logger that writes the structured logs as JSON
+// lines, in the format of the JSONLogger, while the EVM executes instead of
+// keeping them in memory. The captured memory, stack, storage and return data
+// are truncated to the limits of the configuration.
+//
+// A failed write cancels the execution, so that the tracing of a transaction
+// stops as soon as the client it is streamed to goes away.
+type StreamLogger struct {
+	cfg Config
+	out io.Writer
+	env *vm.EVM
+
+	storage map[common.Address]Storage
+	count   int
+	err     error // first write failure, which stops the logging
+
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// NewStreamLogger returns a new logger streaming into [out].
+func NewStreamLogger(cfg *Config, out io.Writer) *StreamLogger {
+	l := &StreamLogger{
+		out:     out,
+		storage: make(map[common.Address]Storage),
+	}
+	if cfg != nil {
+		l.cfg = *cfg
+	}
+	return l
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (l *StreamLogger) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	l.env = env
+}
+
+// CaptureState writes a new structured log message, limited to the configured
+// sizes, to the output.
+func (l *StreamLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if l.interrupt.Load() || l.err != nil {
+		return
+	}
+	// check if already written the specified number of logs
+	if l.cfg.Limit != 0 && l.cfg.Limit <= l.count {
+		return
+	}
+	memory := scope.Memory
+	stackData := scope.Stack.Data()
+	stackLen := len(stackData)
+
+	// The log is encoded before returning, so the EVM buffers can be
+	// referenced without copying them.
+	log := StructLog{
+		Pc:            pc,
+		Op:            op,
+		Gas:           gas,
+		GasCost:       cost,
+		MemorySize:    memory.Len(),
+		Depth:         depth,
+		RefundCounter: l.env.StateDB.GetRefund(),
+		Err:           err,
+	}
+	if l.cfg.EnableMemory {
+		log.Memory = truncate(memory.Data(), l.cfg.MemoryLimit)
+	}
+	if !l.cfg.DisableStack {
+		log.Stack = stackData
+		if l.cfg.StackLimit > 0 && stackLen > l.cfg.StackLimit {
+			log.Stack = stackData[stackLen-l.cfg.StackLimit:]
+		}
+	}
+	if l.cfg.EnableReturnData {
+		log.ReturnData = truncate(rData, l.cfg.ReturnDataLimit)
+	}
+	if !l.cfg.DisableStorage && (op == vm.SLOAD || op == vm.SSTORE) {
+		contract := scope.Contract.Address()
+		if l.storage[contract] == nil {
+			l.storage[contract] = make(Storage)
+		}
+		if op == vm.SLOAD && stackLen >= 1 {
+			slot := common.Hash(stackData[stackLen-1].Bytes32())
+			l.storage[contract][slot] = l.env.StateDB.GetState(contract, slot)
+			log.Storage = limitStorage(l.storage[contract], slot, l.cfg.StorageLimit)
+		} else if op == vm.SSTORE && stackLen >= 2 {
+			slot := common.Hash(stackData[stackLen-1].Bytes32())
+			l.storage[contract][slot] = common.Hash(stackData[stackLen-2].Bytes32())
+			log.Storage = limitStorage(l.storage[contract], slot, l.cfg.StorageLimit)
+		}
+	}
+	l.write(&log)
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault
+// while running an opcode.
+func (l *StreamLogger) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnd writes the output and the gas used by the execution.
+func (l *StreamLogger) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	type endLog struct {
+		Output  string              `json:"output"`
+		GasUsed math.HexOrDecimal64 `json:"gasUsed"`
+		Err     string              `json:"error,omitempty"`
+	}
+	if l.err != nil {
+		return
+	}
+	var errMsg string
+	if err != nil {
+		errMsg = err.Error()
+	}
+	l.write(endLog{common.Bytes2Hex(output), math.HexOrDecimal64(gasUsed), errMsg})
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (l *StreamLogger) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (l *StreamLogger) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (l *StreamLogger) CaptureTxStart(gasLimit uint64) {}
+
+func (l *StreamLogger) CaptureTxEnd(restGas uint64) {}
+
+// Stop terminates execution of the logger at the first opportune moment.
+func (l *StreamLogger) Stop(err error) {
+	l.reason = err
+	l.interrupt.Store(true)
+}
+
+// Error returns the reason the logging was stopped or the write failure that
+// aborted it, if any.
+func (l *StreamLogger) Error() error {
+	if l.interrupt.Load() {
+		return l.reason
+	}
+	return l.err
+}
+
+// write encodes [v] as a single JSON line. Struct logs carrying storage get
+// it appended as a "storage" field, which the StructLog encoding omits.
+func (l *StreamLogger) write(v interface{}) {
+	line, err := json.Marshal(v)
+	if err == nil {
+		if log, ok := v.(*StructLog); ok && len(log.Storage) > 0 {
+			var storage []byte
+			if storage, err = json.Marshal(log.Storage); err == nil {
+				line = append(bytes.TrimSuffix(line, []byte("}")), `,"storage":`...)
+				line = append(append(line, storage...), '}')
+			}
+		}
+	}
+	if err == nil {
+		_, err = l.out.Write(append(line, '\n'))
+	}
+	if err != nil {
+		l.err = errors.Join(errors.New("trace stream failed"), err)
+		if l.env != nil {
+			l.env.Cancel()
+		}
+		return
+	}
+	l.count++
+}
+
+// truncate returns the first [limit] bytes of [data], or all of it if the
+// limit is zero.
+func truncate(data []byte, limit int) []byte {
+	if limit > 0 && len(data) > limit {
+		return data[:limit]
+	}
+	return data
+}
+
+// limitStorage returns the [storage] of a contract if it holds at most [limit]
+// slots. Otherwise it returns the accessed [slot] and the lowest other slots
+// up to the limit.
+func limitStorage(storage Storage, slot common.Hash, limit int) Storage {
+	if limit <= 0 || len(storage) <= limit {
+		return storage
+	}
+	slots := make([]common.Hash, 0, len(storage))
+	for s := range storage {
+		if s != slot {
+			slots = append(slots, s)
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return bytes.Compare(slots[i][:], slots[j][:]) < 0 })
+
+	limited := Storage{slot: storage[slot]}
+	for _, s := range slots[:limit-1] {
+		limited[s] = storage[s]
+	}
+	return limited
+}
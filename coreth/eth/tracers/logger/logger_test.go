@@ -17,9 +17,11 @@
 package logger
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/ava-labs/coreth/core/rawdb"
@@ -71,6 +73,58 @@ func TestStoreCapture(t *testing.T) {
 	}
 }
 
+// Tests that the stream logger writes a JSON line per step, truncated to the
+// configured limits.
+func TestStreamLoggerLimits(t *testing.T) {
+	var (
+		out        bytes.Buffer
+		logger     = NewStreamLogger(&Config{EnableMemory: true, MemoryLimit: 8, StackLimit: 1, StorageLimit: 2}, &out)
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		env        = vm.NewEVM(vm.BlockContext{}, vm.TxContext{}, statedb, params.TestFlareChainConfig, vm.Config{Tracer: logger})
+		contract   = vm.NewContract(&dummyContractRef{}, &dummyContractRef{}, new(uint256.Int), 100000)
+	)
+	contract.Code = []byte{
+		byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x0, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x2, byte(vm.PUSH1), 0x1, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0xff, byte(vm.PUSH1), 0x0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x3, byte(vm.PUSH1), 0x2, byte(vm.SSTORE),
+	}
+	logger.CaptureStart(env, common.Address{}, contract.Address(), false, nil, 0, nil)
+	statedb.Prepare(params.TestRules, common.Address{}, common.Address{}, nil, nil, nil)
+	if _, err := env.Interpreter().Run(contract, []byte{}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Error(); err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if len(lines) != 13 { // including the implicit STOP
+		t.Fatalf("expected 13 logs, got %d", len(lines))
+	}
+	var last struct {
+		Memory  string
+		MemSize int
+		Stack   []string
+		Storage map[common.Hash]common.Hash
+	}
+	if err := json.Unmarshal(lines[11], &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.Memory != "0x0000000000000000" || last.MemSize != 32 {
+		t.Errorf("unexpected memory %s of size %d", last.Memory, last.MemSize)
+	}
+	if len(last.Stack) != 1 || last.Stack[0] != "0x2" {
+		t.Errorf("unexpected stack %v", last.Stack)
+	}
+	exp := map[common.Hash]common.Hash{
+		common.BigToHash(big.NewInt(0)): common.BigToHash(big.NewInt(1)),
+		common.BigToHash(big.NewInt(2)): common.BigToHash(big.NewInt(3)),
+	}
+	if !reflect.DeepEqual(last.Storage, exp) {
+		t.Errorf("unexpected storage %v", last.Storage)
+	}
+}
+
 // Tests that blank fields don't appear in logs when JSON marshalled, to reduce
 // logs bloat and confusion. See https://github.com/ethereum/go-ethereum/issues/24487
 func TestStructLogMarshalingOmitEmpty(t *testing.T) {
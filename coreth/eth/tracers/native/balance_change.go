@@ -0,0 +1,400 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/eth/tracers"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("balanceChangeTracer", newBalanceChangeTracer, false)
+}
+
+// Token standards of the decoded transfers.
+const (
+	standardERC20   = "ERC20"
+	standardERC721  = "ERC721"
+	standardERC1155 = "ERC1155"
+)
+
+var (
+	transferEventTopic       = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	transferSingleEventTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	transferBatchEventTopic  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+// balanceChange is the change of the native balance of an account by a
+// transaction.
+type balanceChange struct {
+	Before *hexutil.Big `json:"before"`
+	After  *hexutil.Big `json:"after"`
+	Delta  *hexutil.Big `json:"delta"`
+}
+
+// feeCharge is how the fee of a transaction was charged. The sender pays the
+// fee, of which the refund of a prioritised call is given back and the rest
+// is burnt to 0x…dEaD, or to the coinbase on Songbird networks.
+type feeCharge struct {
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	GasPrice    *hexutil.Big   `json:"gasPrice"`
+	Fee         *hexutil.Big   `json:"fee"`
+	BurnAddress common.Address `json:"burnAddress"`
+	Burnt       *hexutil.Big   `json:"burnt"`
+	Prioritised bool           `json:"prioritised"`
+	Refund      *hexutil.Big   `json:"refund"`
+}
+
+// tokenTransfer is a transfer decoded from an ERC-20, ERC-721 or ERC-1155
+// transfer event.
+type tokenTransfer struct {
+	Standard string          `json:"standard"`
+	Token    common.Address  `json:"token"`
+	Operator *common.Address `json:"operator,omitempty"`
+	From     common.Address  `json:"from"`
+	To       common.Address  `json:"to"`
+	TokenID  *hexutil.Big    `json:"tokenId,omitempty"`
+	Value    *hexutil.Big    `json:"value,omitempty"`
+}
+
+type balanceChangeResult struct {
+	BalanceChanges map[common.Address]*balanceChange `json:"balanceChanges"`
+	Fee            *feeCharge                        `json:"fee"`
+	Transfers      []tokenTransfer                   `json:"transfers"`
+}
+
+// balanceChangeTracer reports the asset flows of a transaction: the native
+// balance changes of the accounts it touched, including the fee payment and
+// the inflation minted by the Flare daemon, and the token transfers emitted
+// by the calls that did not revert.
+type balanceChangeTracer struct {
+	noopTracer
+	env       *vm.EVM
+	pre       map[common.Address]*big.Int // balances before the transaction
+	transfers [][]tokenTransfer           // transfers of the open call frames
+	system    int                         // number of transfers of the transaction before the last system call
+	gasLimit  uint64
+	to        *common.Address // recipient of the transaction, nil for contract creations
+	input     []byte
+	output    []byte
+	failed    bool
+	result    *balanceChangeResult
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+func newBalanceChangeTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &balanceChangeTracer{pre: make(map[common.Address]*big.Int)}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *balanceChangeTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	if !create {
+		t.to = &to
+		t.input = common.CopyBytes(input)
+	}
+	t.touch(from)
+	t.touch(to)
+	t.touch(env.Context.Coinbase)
+	if burnAddress, _, err := core.FeeParams(env); err == nil {
+		t.touch(burnAddress)
+	}
+	if contracts := env.ChainConfig().SystemContracts(); contracts != nil {
+		t.touch(contracts.Daemon.Address)
+	}
+	// The value has been transferred and the gas limit bought already.
+	gasCost := new(big.Int).Mul(env.TxContext.GasPrice, new(big.Int).SetUint64(t.gasLimit))
+	t.pre[to].Sub(t.pre[to], value)
+	t.pre[from].Add(t.pre[from], new(big.Int).Add(value, gasCost))
+
+	t.transfers = append(t.transfers, nil)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *balanceChangeTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.output = common.CopyBytes(output)
+	if err != nil {
+		t.failed = true
+		if len(t.transfers) > 0 {
+			t.transfers[0] = nil
+		}
+	}
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *balanceChangeTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	// skip if the previous op caused an error
+	if err != nil || t.interrupt.Load() || len(t.transfers) == 0 {
+		return
+	}
+	// Transfer events have three or four topics.
+	if op < vm.LOG3 || op > vm.LOG4 {
+		return
+	}
+	var (
+		stackData = scope.Stack.Data()
+		mStart    = stackData[len(stackData)-1]
+		mSize     = stackData[len(stackData)-2]
+		topics    = make([]common.Hash, int(op-vm.LOG0))
+	)
+	for i := range topics {
+		topics[i] = common.Hash(stackData[len(stackData)-3-i].Bytes32())
+	}
+	data, err := tracers.GetMemoryCopyPadded(scope.Memory, int64(mStart.Uint64()), int64(mSize.Uint64()))
+	if err != nil {
+		return
+	}
+	frame := len(t.transfers) - 1
+	t.transfers[frame] = append(t.transfers[frame], decodeTransfers(scope.Contract.Address(), topics, data)...)
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *balanceChangeTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.transfers = append(t.transfers, nil)
+	switch typ {
+	case vm.CALL, vm.CREATE, vm.CREATE2, vm.SELFDESTRUCT:
+		// The value has been transferred already.
+		newFrom, newTo := t.touch(from), t.touch(to)
+		if value == nil || from == to {
+			return
+		}
+		if newFrom {
+			t.pre[from].Add(t.pre[from], value)
+		}
+		if newTo {
+			t.pre[to].Sub(t.pre[to], value)
+		}
+	}
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *balanceChangeTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	size := len(t.transfers)
+	if size <= 1 {
+		return
+	}
+	transfers := t.transfers[size-1]
+	t.transfers = t.transfers[:size-1]
+	if err == nil {
+		t.transfers[size-2] = append(t.transfers[size-2], transfers...)
+	}
+}
+
+// CaptureSystemCallStart is called when the EVM starts a Flare system call
+// after the top call frame has ended. Its transfers are traced as the ones
+// of a nested call.
+func (t *balanceChangeTracer) CaptureSystemCallStart(env *vm.EVM, from common.Address, to common.Address, input []byte, gas uint64) {
+	if len(t.transfers) == 0 {
+		t.transfers = append(t.transfers, nil)
+	}
+	t.system = len(t.transfers[0])
+	t.touch(from)
+	t.touch(to)
+	t.transfers = append(t.transfers, nil)
+}
+
+// CaptureSystemCallEnd is called when a Flare system call finishes.
+func (t *balanceChangeTracer) CaptureSystemCallEnd(output []byte, gasUsed uint64, err error) {
+	t.CaptureExit(output, gasUsed, err)
+}
+
+// CaptureSystemMint is called when the last system call minted [amount] on to [to].
+func (t *balanceChangeTracer) CaptureSystemMint(to common.Address, amount *big.Int) {
+	if t.touch(to) {
+		t.pre[to].Sub(t.pre[to], amount)
+	}
+}
+
+// CaptureSystemRevert is called when the state changes of the last system
+// call were reverted after it had finished, which drops its transfers.
+func (t *balanceChangeTracer) CaptureSystemRevert(err error) {
+	if len(t.transfers) > 0 && t.system <= len(t.transfers[0]) {
+		t.transfers[0] = t.transfers[0][:t.system]
+	}
+}
+
+func (t *balanceChangeTracer) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+// CaptureTxEnd is called after the fee has been charged and the Flare system
+// calls have run, once the balances are final.
+func (t *balanceChangeTracer) CaptureTxEnd(restGas uint64) {
+	if t.env == nil {
+		return
+	}
+	result := &balanceChangeResult{
+		BalanceChanges: make(map[common.Address]*balanceChange),
+		Fee:            t.feeCharge(t.gasLimit - restGas),
+		Transfers:      []tokenTransfer{},
+	}
+	for addr, before := range t.pre {
+		after := t.env.StateDB.GetBalance(addr).ToBig()
+		if after.Cmp(before) == 0 {
+			continue
+		}
+		result.BalanceChanges[addr] = &balanceChange{
+			Before: (*hexutil.Big)(before),
+			After:  (*hexutil.Big)(after),
+			Delta:  (*hexutil.Big)(new(big.Int).Sub(after, before)),
+		}
+	}
+	if len(t.transfers) > 0 && t.transfers[0] != nil {
+		result.Transfers = t.transfers[0]
+	}
+	t.result = result
+}
+
+// feeCharge returns how the fee of [gasUsed] was charged, following the
+// state transition.
+func (t *balanceChangeTracer) feeCharge(gasUsed uint64) *feeCharge {
+	var (
+		contracts = t.env.ChainConfig().SystemContracts()
+		price     = t.env.TxContext.GasPrice
+		fee       = new(big.Int).Mul(price, new(big.Int).SetUint64(gasUsed))
+		refund    = new(big.Int)
+	)
+	burnAddress, nominalGasPrice, _ := core.FeeParams(t.env)
+	prioritised := false
+	if !t.failed {
+		prioritised, _ = core.ClassifyPrioritisedContractCall(contracts, t.env.Context.Time, t.to, t.input, t.output, t.gasLimit)
+	}
+	nominalFee := new(big.Int).Mul(new(big.Int).SetUint64(params.TxGas), new(big.Int).SetUint64(nominalGasPrice))
+	if prioritised && fee.Cmp(nominalFee) > 0 {
+		refund.Sub(fee, nominalFee)
+	}
+	return &feeCharge{
+		GasUsed:     hexutil.Uint64(gasUsed),
+		GasPrice:    (*hexutil.Big)(new(big.Int).Set(price)),
+		Fee:         (*hexutil.Big)(fee),
+		BurnAddress: burnAddress,
+		Burnt:       (*hexutil.Big)(new(big.Int).Sub(fee, refund)),
+		Prioritised: prioritised,
+		Refund:      (*hexutil.Big)(refund),
+	}
+}
+
+// GetResult returns the json-encoded balance changes, fee and token transfers
+// of the transaction, and any error arising from the encoding or forceful
+// termination (via `Stop`).
+func (t *balanceChangeTracer) GetResult() (json.RawMessage, error) {
+	if t.result == nil {
+		return nil, errors.New("incorrect number of top-level calls")
+	}
+	res, err := json.Marshal(t.result)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *balanceChangeTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// touch records the balance of [addr] before the transaction if it was not
+// touched yet, which is reported by the return value. The balance is read
+// from the current state, the caller adjusts it for the changes made since
+// the transaction started.
+func (t *balanceChangeTracer) touch(addr common.Address) bool {
+	if _, ok := t.pre[addr]; ok {
+		return false
+	}
+	t.pre[addr] = t.env.StateDB.GetBalance(addr).ToBig()
+	return true
+}
+
+// decodeTransfers decodes the token transfers of a log emitted by [token].
+// Logs that are not ERC-20, ERC-721 or ERC-1155 transfer events, or that are
+// malformed, are ignored.
+func decodeTransfers(token common.Address, topics []common.Hash, data []byte) []tokenTransfer {
+	switch {
+	case topics[0] == transferEventTopic && len(topics) == 3 && len(data) == 32:
+		return []tokenTransfer{{
+			Standard: standardERC20,
+			Token:    token,
+			From:     common.BytesToAddress(topics[1].Bytes()),
+			To:       common.BytesToAddress(topics[2].Bytes()),
+			Value:    (*hexutil.Big)(new(big.Int).SetBytes(data)),
+		}}
+	case topics[0] == transferEventTopic && len(topics) == 4 && len(data) == 0:
+		return []tokenTransfer{{
+			Standard: standardERC721,
+			Token:    token,
+			From:     common.BytesToAddress(topics[1].Bytes()),
+			To:       common.BytesToAddress(topics[2].Bytes()),
+			TokenID:  (*hexutil.Big)(topics[3].Big()),
+		}}
+	case topics[0] == transferSingleEventTopic && len(topics) == 4 && len(data) == 64:
+		return []tokenTransfer{erc1155Transfer(token, topics, data[:32], data[32:])}
+	case topics[0] == transferBatchEventTopic && len(topics) == 4:
+		ids, ok := decodeWordArray(data, 0)
+		if !ok {
+			return nil
+		}
+		values, ok := decodeWordArray(data, 1)
+		if !ok || len(values) != len(ids) {
+			return nil
+		}
+		transfers := make([]tokenTransfer, len(ids))
+		for i := range ids {
+			transfers[i] = erc1155Transfer(token, topics, ids[i], values[i])
+		}
+		return transfers
+	}
+	return nil
+}
+
+func erc1155Transfer(token common.Address, topics []common.Hash, id, value []byte) tokenTransfer {
+	operator := common.BytesToAddress(topics[1].Bytes())
+	return tokenTransfer{
+		Standard: standardERC1155,
+		Token:    token,
+		Operator: &operator,
+		From:     common.BytesToAddress(topics[2].Bytes()),
+		To:       common.BytesToAddress(topics[3].Bytes()),
+		TokenID:  (*hexutil.Big)(new(big.Int).SetBytes(id)),
+		Value:    (*hexutil.Big)(new(big.Int).SetBytes(value)),
+	}
+}
+
+// decodeWordArray decodes the ABI encoded uint256[] that is the [arg]th
+// argument of [data].
+func decodeWordArray(data []byte, arg int) ([][]byte, bool) {
+	head := (arg + 1) * 32
+	if len(data) < head {
+		return nil, false
+	}
+	offset := new(big.Int).SetBytes(data[head-32 : head])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return nil, false
+	}
+	start := offset.Uint64() + 32
+	length := new(big.Int).SetBytes(data[start-32 : start])
+	if !length.IsUint64() || length.Uint64() > (uint64(len(data))-start)/32 {
+		return nil, false
+	}
+	words := make([][]byte, length.Uint64())
+	for i := range words {
+		words[i] = data[start+uint64(i)*32 : start+uint64(i+1)*32]
+	}
+	return words, true
+}
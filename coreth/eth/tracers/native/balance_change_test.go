@@ -0,0 +1,91 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests the balance changes and the fee reported for the transfers traced by
+// traceWithSystemCalls, along the fee, refund and daemon mint paths of the
+// Flare state transition.
+func TestBalanceChangeTracer(t *testing.T) {
+	var (
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender    = crypto.PubkeyToAddress(key.PublicKey)
+		burn      = common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+		contracts = params.TestFlareChainConfig.SystemContracts()
+		daemon    = contracts.Daemon.Address
+		ftso      = contracts.Prioritised.FTSOAddress
+		// The legacy transactions pay their gas price on top of the base fee
+		price   = big.NewInt(225*params.GWei + 25*params.GWei)
+		fee     = new(big.Int).Mul(big.NewInt(int64(params.TxGas)), price)
+		nominal = new(big.Int).Mul(big.NewInt(int64(params.TxGas)), big.NewInt(params.ApricotPhase4MinBaseFee))
+	)
+	// The deltas are decoded as strings, hexutil.Big only decoding positive
+	// numbers.
+	type result struct {
+		BalanceChanges map[common.Address]struct {
+			Before *hexutil.Big `json:"before"`
+			After  *hexutil.Big `json:"after"`
+			Delta  string       `json:"delta"`
+		} `json:"balanceChanges"`
+		Fee       *feeCharge      `json:"fee"`
+		Transfers []tokenTransfer `json:"transfers"`
+	}
+	trace := func(to common.Address, daemonCode []byte) *result {
+		var res result
+		require.NoError(t, json.Unmarshal(traceWithSystemCalls(t, "balanceChangeTracer", "null", to, daemonCode), &res))
+		return &res
+	}
+	requireDelta := func(res *result, addr common.Address, delta *big.Int) {
+		t.Helper()
+		require.Contains(t, res.BalanceChanges, addr)
+		change := res.BalanceChanges[addr]
+		require.Equal(t, (*hexutil.Big)(delta).String(), change.Delta)
+		require.Equal(t, new(big.Int).Add(change.Before.ToInt(), delta), change.After.ToInt())
+	}
+
+	// The sender pays the value and the fee, which is burnt, and the daemon
+	// receives the inflation it minted.
+	res := trace(systemCallRecipient, daemonMintCode)
+	require.Len(t, res.BalanceChanges, 4)
+	requireDelta(res, sender, new(big.Int).Neg(new(big.Int).Add(fee, common.Big1)))
+	requireDelta(res, systemCallRecipient, common.Big1)
+	requireDelta(res, burn, fee)
+	requireDelta(res, daemon, big.NewInt(1000))
+	require.Equal(t, uint64(params.TxGas), uint64(res.Fee.GasUsed))
+	require.Equal(t, price, res.Fee.GasPrice.ToInt())
+	require.Equal(t, fee, res.Fee.Fee.ToInt())
+	require.Equal(t, burn, res.Fee.BurnAddress)
+	require.Equal(t, fee, res.Fee.Burnt.ToInt())
+	require.False(t, res.Fee.Prioritised)
+	require.Zero(t, res.Fee.Refund.ToInt().Sign())
+	require.Empty(t, res.Transfers)
+
+	// Nothing is minted when the mint request is reverted.
+	res = trace(systemCallRecipient, daemonExcessCode)
+	require.Len(t, res.BalanceChanges, 3)
+	require.NotContains(t, res.BalanceChanges, daemon)
+
+	// A prioritised call is charged the nominal fee, the rest of the fee is
+	// refunded.
+	res = trace(ftso, daemonMintCode)
+	requireDelta(res, sender, new(big.Int).Neg(new(big.Int).Add(nominal, common.Big1)))
+	requireDelta(res, ftso, common.Big1)
+	requireDelta(res, burn, nominal)
+	requireDelta(res, daemon, big.NewInt(1000))
+	require.True(t, res.Fee.Prioritised)
+	require.Equal(t, fee, res.Fee.Fee.ToInt())
+	require.Equal(t, nominal, res.Fee.Burnt.ToInt())
+	require.Equal(t, new(big.Int).Sub(fee, nominal), res.Fee.Refund.ToInt())
+}
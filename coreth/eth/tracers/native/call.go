@@ -71,6 +71,11 @@ type callFrame struct {
 	// Placed at end on purpose. The RLP will be decoded to 0 instead of
 	// nil if there are non-empty elements after in the struct.
 	Value *big.Int `json:"value,omitempty" rlp:"optional"`
+	// Flare system calls executed after the top call, only populated on the
+	// top frame when system call tracing is enabled.
+	SystemCalls []callFrame `json:"systemCalls,omitempty" rlp:"-"`
+	// Amount minted by a system call.
+	Minted *big.Int `json:"minted,omitempty" rlp:"-"`
 }
 
 func (f callFrame) TypeString() string {
@@ -110,6 +115,7 @@ type callFrameMarshaling struct {
 	Value      *hexutil.Big
 	Input      hexutil.Bytes
 	Output     hexutil.Bytes
+	Minted     *hexutil.Big
 }
 
 type callTracer struct {
@@ -254,6 +260,67 @@ func (t *callTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
 	t.callstack[size-1].Calls = append(t.callstack[size-1].Calls, call)
 }
 
+// CaptureSystemCallStart is called when the EVM starts a Flare system call
+// after the top call frame has ended.
+func (t *callTracer) CaptureSystemCallStart(env *vm.EVM, from common.Address, to common.Address, input []byte, gas uint64) {
+	// Skip if tracing was interrupted, or if there is no top call frame to
+	// report the system call on
+	if t.interrupt.Load() || len(t.callstack) == 0 {
+		return
+	}
+	toCopy := to
+	call := callFrame{
+		Type:  vm.CALL,
+		From:  from,
+		To:    &toCopy,
+		Input: common.CopyBytes(input),
+		Gas:   gas,
+		Value: new(big.Int),
+	}
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureSystemCallEnd is called when a Flare system call finishes.
+func (t *callTracer) CaptureSystemCallEnd(output []byte, gasUsed uint64, err error) {
+	size := len(t.callstack)
+	if size <= 1 {
+		return
+	}
+	// pop the system call
+	call := t.callstack[size-1]
+	t.callstack = t.callstack[:size-1]
+
+	call.GasUsed = gasUsed
+	call.processOutput(output, err)
+	if t.config.WithLog {
+		clearFailedLogs(&call, false)
+	}
+	t.callstack[0].SystemCalls = append(t.callstack[0].SystemCalls, call)
+}
+
+// CaptureSystemMint is called when the last system call minted [amount] on to [to].
+func (t *callTracer) CaptureSystemMint(to common.Address, amount *big.Int) {
+	if len(t.callstack) == 0 {
+		return
+	}
+	if n := len(t.callstack[0].SystemCalls); n > 0 {
+		t.callstack[0].SystemCalls[n-1].Minted = new(big.Int).Set(amount)
+	}
+}
+
+// CaptureSystemRevert is called when the state changes of the last system
+// call were reverted after it had finished.
+func (t *callTracer) CaptureSystemRevert(err error) {
+	if len(t.callstack) == 0 {
+		return
+	}
+	if n := len(t.callstack[0].SystemCalls); n > 0 {
+		call := &t.callstack[0].SystemCalls[n-1]
+		call.Error = err.Error()
+		clearFailedLogs(call, false)
+	}
+}
+
 func (t *callTracer) CaptureTxStart(gasLimit uint64) {
 	t.gasLimit = gasLimit
 }
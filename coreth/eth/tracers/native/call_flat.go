@@ -77,6 +77,7 @@ type flatCallFrame struct {
 	TransactionHash     *common.Hash    `json:"transactionHash"`
 	TransactionPosition uint64          `json:"transactionPosition"`
 	Type                string          `json:"type"`
+	System              bool            `json:"system,omitempty"`
 }
 
 type flatCallAction struct {
@@ -212,6 +213,28 @@ func (t *flatCallTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
 	}
 }
 
+// CaptureSystemCallStart is called when the EVM starts a Flare system call
+// after the top call frame has ended.
+func (t *flatCallTracer) CaptureSystemCallStart(env *vm.EVM, from common.Address, to common.Address, input []byte, gas uint64) {
+	t.tracer.CaptureSystemCallStart(env, from, to, input, gas)
+}
+
+// CaptureSystemCallEnd is called when a Flare system call finishes.
+func (t *flatCallTracer) CaptureSystemCallEnd(output []byte, gasUsed uint64, err error) {
+	t.tracer.CaptureSystemCallEnd(output, gasUsed, err)
+}
+
+// CaptureSystemMint is called when the last system call minted [amount] on to [to].
+func (t *flatCallTracer) CaptureSystemMint(to common.Address, amount *big.Int) {
+	t.tracer.CaptureSystemMint(to, amount)
+}
+
+// CaptureSystemRevert is called when the state changes of the last system
+// call were reverted after it had finished.
+func (t *flatCallTracer) CaptureSystemRevert(err error) {
+	t.tracer.CaptureSystemRevert(err)
+}
+
 func (t *flatCallTracer) CaptureTxStart(gasLimit uint64) {
 	t.tracer.CaptureTxStart(gasLimit)
 }
@@ -230,6 +253,13 @@ func (t *flatCallTracer) GetResult() (json.RawMessage, error) {
 	if err != nil {
 		return nil, err
 	}
+	for i := range t.tracer.callstack[0].SystemCalls {
+		system, err := flatFromSystemCall(&t.tracer.callstack[0].SystemCalls[i], t.config.ConvertParityErrors, t.ctx)
+		if err != nil {
+			return nil, err
+		}
+		flat = append(flat, system...)
+	}
 
 	res, err := json.Marshal(flat)
 	if err != nil {
@@ -296,6 +326,32 @@ func flatFromNested(input *callFrame, traceAddress []int, convertErrs bool, ctx
 	return output, nil
 }
 
+// flatFromSystemCall flattens a Flare system call. All resulting frames are
+// marked as system frames and a mint is reported as a reward frame.
+func flatFromSystemCall(input *callFrame, convertErrs bool, ctx *tracers.Context) ([]flatCallFrame, error) {
+	output, err := flatFromNested(input, []int{}, convertErrs, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if input.Minted != nil {
+		frame := flatCallFrame{
+			Type: "reward",
+			Action: flatCallAction{
+				Author:     input.To,
+				RewardType: "mint",
+				Value:      input.Minted,
+			},
+			TraceAddress: []int{},
+		}
+		fillCallFrameFromContext(&frame, ctx)
+		output = append(output, frame)
+	}
+	for i := range output {
+		output[i].System = true
+	}
+	return output, nil
+}
+
 func newFlatCreate(input *callFrame) *flatCallFrame {
 	var (
 		actionInit = input.Input[:]
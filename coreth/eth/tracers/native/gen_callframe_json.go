@@ -28,6 +28,8 @@ func (c callFrame) MarshalJSON() ([]byte, error) {
 		Calls        []callFrame     `json:"calls,omitempty" rlp:"optional"`
 		Logs         []callLog       `json:"logs,omitempty" rlp:"optional"`
 		Value        *hexutil.Big    `json:"value,omitempty" rlp:"optional"`
+		SystemCalls  []callFrame     `json:"systemCalls,omitempty" rlp:"-"`
+		Minted       *hexutil.Big    `json:"minted,omitempty" rlp:"-"`
 		TypeString   string          `json:"type"`
 	}
 	var enc callFrame0
@@ -43,6 +45,8 @@ func (c callFrame) MarshalJSON() ([]byte, error) {
 	enc.Calls = c.Calls
 	enc.Logs = c.Logs
 	enc.Value = (*hexutil.Big)(c.Value)
+	enc.SystemCalls = c.SystemCalls
+	enc.Minted = (*hexutil.Big)(c.Minted)
 	enc.TypeString = c.TypeString()
 	return json.Marshal(&enc)
 }
@@ -62,6 +66,8 @@ func (c *callFrame) UnmarshalJSON(input []byte) error {
 		Calls        []callFrame     `json:"calls,omitempty" rlp:"optional"`
 		Logs         []callLog       `json:"logs,omitempty" rlp:"optional"`
 		Value        *hexutil.Big    `json:"value,omitempty" rlp:"optional"`
+		SystemCalls  []callFrame     `json:"systemCalls,omitempty" rlp:"-"`
+		Minted       *hexutil.Big    `json:"minted,omitempty" rlp:"-"`
 	}
 	var dec callFrame0
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -103,5 +109,11 @@ func (c *callFrame) UnmarshalJSON(input []byte) error {
 	if dec.Value != nil {
 		c.Value = (*big.Int)(dec.Value)
 	}
+	if dec.SystemCalls != nil {
+		c.SystemCalls = dec.SystemCalls
+	}
+	if dec.Minted != nil {
+		c.Minted = (*big.Int)(dec.Minted)
+	}
 	return nil
 }
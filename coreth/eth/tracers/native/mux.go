@@ -44,6 +44,7 @@ func init() {
 type muxTracer struct {
 	names   []string
 	tracers []tracers.Tracer
+	system  bool // Whether a system call is being traced
 }
 
 // newMuxTracer returns a new mux tracer.
@@ -84,21 +85,21 @@ func (t *muxTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
 
 // CaptureState implements the EVMLogger interface to trace a single step of VM execution.
 func (t *muxTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
-	for _, t := range t.tracers {
+	for _, t := range t.activeTracers() {
 		t.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
 	}
 }
 
 // CaptureFault implements the EVMLogger interface to trace an execution fault.
 func (t *muxTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
-	for _, t := range t.tracers {
+	for _, t := range t.activeTracers() {
 		t.CaptureFault(pc, op, gas, cost, scope, depth, err)
 	}
 }
 
 // CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
 func (t *muxTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
-	for _, t := range t.tracers {
+	for _, t := range t.activeTracers() {
 		t.CaptureEnter(typ, from, to, input, gas, value)
 	}
 }
@@ -106,11 +107,70 @@ func (t *muxTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.A
 // CaptureExit is called when EVM exits a scope, even if the scope didn't
 // execute any code.
 func (t *muxTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
-	for _, t := range t.tracers {
+	for _, t := range t.activeTracers() {
 		t.CaptureExit(output, gasUsed, err)
 	}
 }
 
+// CaptureSystemCallStart is called when the EVM starts a Flare system call
+// after the top call frame has ended.
+func (t *muxTracer) CaptureSystemCallStart(env *vm.EVM, from common.Address, to common.Address, input []byte, gas uint64) {
+	t.system = true
+	for _, t := range t.systemTracers() {
+		t.CaptureSystemCallStart(env, from, to, input, gas)
+	}
+}
+
+// CaptureSystemCallEnd is called when a Flare system call finishes.
+func (t *muxTracer) CaptureSystemCallEnd(output []byte, gasUsed uint64, err error) {
+	t.system = false
+	for _, t := range t.systemTracers() {
+		t.CaptureSystemCallEnd(output, gasUsed, err)
+	}
+}
+
+// CaptureSystemMint is called when the last system call minted [amount] on to [to].
+func (t *muxTracer) CaptureSystemMint(to common.Address, amount *big.Int) {
+	for _, t := range t.systemTracers() {
+		t.CaptureSystemMint(to, amount)
+	}
+}
+
+// CaptureSystemRevert is called when the state changes of the last system
+// call were reverted after it had finished.
+func (t *muxTracer) CaptureSystemRevert(err error) {
+	for _, t := range t.systemTracers() {
+		t.CaptureSystemRevert(err)
+	}
+}
+
+// systemTracers returns the wrapped tracers that observe system calls.
+func (t *muxTracer) systemTracers() []vm.SystemCallLogger {
+	var res []vm.SystemCallLogger
+	for _, t := range t.tracers {
+		if st, ok := t.(vm.SystemCallLogger); ok {
+			res = append(res, st)
+		}
+	}
+	return res
+}
+
+// activeTracers returns the wrapped tracers that should receive the current
+// execution events. During a system call only the tracers observing system
+// calls are notified.
+func (t *muxTracer) activeTracers() []tracers.Tracer {
+	if !t.system {
+		return t.tracers
+	}
+	var res []tracers.Tracer
+	for _, t := range t.tracers {
+		if _, ok := t.(vm.SystemCallLogger); ok {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
 func (t *muxTracer) CaptureTxStart(gasLimit uint64) {
 	for _, t := range t.tracers {
 		t.CaptureTxStart(gasLimit)
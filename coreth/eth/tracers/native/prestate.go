@@ -189,6 +189,25 @@ func (t *prestateTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64,
 	}
 }
 
+// CaptureSystemCallStart is called when the EVM starts a Flare system call
+// after the top call frame has ended.
+func (t *prestateTracer) CaptureSystemCallStart(env *vm.EVM, from common.Address, to common.Address, input []byte, gas uint64) {
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+}
+
+// CaptureSystemCallEnd is called when a Flare system call finishes.
+func (t *prestateTracer) CaptureSystemCallEnd(output []byte, gasUsed uint64, err error) {}
+
+// CaptureSystemMint is called when the last system call minted [amount] on to [to].
+func (t *prestateTracer) CaptureSystemMint(to common.Address, amount *big.Int) {
+	t.lookupAccount(to)
+}
+
+// CaptureSystemRevert is called when the state changes of the last system
+// call were reverted after it had finished.
+func (t *prestateTracer) CaptureSystemRevert(err error) {}
+
 func (t *prestateTracer) CaptureTxStart(gasLimit uint64) {
 	t.gasLimit = gasLimit
 }
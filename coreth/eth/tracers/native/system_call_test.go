@@ -0,0 +1,183 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/eth/tracers"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// Returns a mint request of 1000: PUSH2 0x03e8 PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	daemonMintCode = []byte{0x61, 0x03, 0xe8, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+	// Returns a mint request above the maximum: PUSH1 0 NOT PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	daemonExcessCode = []byte{0x60, 0x00, 0x19, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+)
+
+// systemCallRecipient is the recipient of the transfers traced by
+// traceWithSystemCalls.
+var systemCallRecipient = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+// traceWithSystemCalls traces a transfer to [to] on the Flare test network
+// with the Flare system calls traced, the daemon running [daemonCode], and
+// returns the result of [tracerName].
+func traceWithSystemCalls(t *testing.T, tracerName string, tracerConfig string, to common.Address, daemonCode []byte) json.RawMessage {
+	t.Helper()
+	var (
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		from      = crypto.PubkeyToAddress(key.PublicKey)
+		config    = params.TestFlareChainConfig
+		daemon    = config.SystemContracts().Daemon.Address
+		blockTime = uint64(time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC).Unix())
+		context   = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			Coinbase:    common.HexToAddress("0x0100000000000000000000000000000000000000"),
+			BlockNumber: big.NewInt(1),
+			Time:        blockTime,
+			Difficulty:  big.NewInt(1),
+			GasLimit:    params.CortinaGasLimit,
+			BaseFee:     big.NewInt(25 * params.GWei),
+		}
+		statedb = makePreState(t, types.GenesisAlloc{
+			from:   {Balance: big.NewInt(params.Ether)},
+			daemon: {Balance: common.Big0, Code: daemonCode},
+		})
+	)
+	signer := types.MakeSigner(config, context.BlockNumber, context.Time)
+	tx, err := types.SignTx(types.NewTransaction(0, to, big.NewInt(1), params.TxGas, big.NewInt(225*params.GWei), nil), signer, key)
+	require.NoError(t, err)
+
+	tracer, err := tracers.DefaultDirectory.New(tracerName, new(tracers.Context), json.RawMessage(tracerConfig))
+	require.NoError(t, err)
+	msg, err := core.TransactionToMessage(tx, signer, context.BaseFee)
+	require.NoError(t, err)
+	evm := vm.NewEVM(context, core.NewEVMTxContext(msg), statedb, config, vm.Config{Tracer: tracer, TraceSystemCalls: true})
+	_, err = core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas())).TransitionDb()
+	require.NoError(t, err)
+	res, err := tracer.GetResult()
+	require.NoError(t, err)
+	return res
+}
+
+func TestCallTracerSystemCalls(t *testing.T) {
+	daemon := params.TestFlareChainConfig.SystemContracts().Daemon.Address
+
+	var frame callFrame
+	require.NoError(t, json.Unmarshal(traceWithSystemCalls(t, "callTracer", "null", systemCallRecipient, daemonMintCode), &frame))
+	require.Empty(t, frame.Calls)
+	require.Len(t, frame.SystemCalls, 1)
+	system := frame.SystemCalls[0]
+	require.Equal(t, daemon, system.From)
+	require.Equal(t, &daemon, system.To)
+	require.Equal(t, []byte{0x7f, 0xec, 0x8d, 0x38}, system.Input)
+	require.Equal(t, common.LeftPadBytes([]byte{0x03, 0xe8}, 32), system.Output)
+	require.Equal(t, big.NewInt(1000), system.Minted)
+	require.Empty(t, system.Error)
+
+	// The daemon call is reported as failed when its mint request is reverted.
+	frame = callFrame{}
+	require.NoError(t, json.Unmarshal(traceWithSystemCalls(t, "callTracer", "null", systemCallRecipient, daemonExcessCode), &frame))
+	require.Len(t, frame.SystemCalls, 1)
+	require.Nil(t, frame.SystemCalls[0].Minted)
+	require.Contains(t, frame.SystemCalls[0].Error, "exceeded max of")
+}
+
+func TestFlatCallTracerSystemCalls(t *testing.T) {
+	daemon := params.TestFlareChainConfig.SystemContracts().Daemon.Address
+
+	var frames []flatCallFrame
+	require.NoError(t, json.Unmarshal(traceWithSystemCalls(t, "flatCallTracer", "null", systemCallRecipient, daemonMintCode), &frames))
+	require.Len(t, frames, 3)
+	require.False(t, frames[0].System)
+
+	call, reward := frames[1], frames[2]
+	require.True(t, call.System)
+	require.Equal(t, "call", call.Type)
+	require.Equal(t, &daemon, call.Action.From)
+	require.Equal(t, &daemon, call.Action.To)
+	require.Empty(t, call.TraceAddress)
+	require.True(t, reward.System)
+	require.Equal(t, "reward", reward.Type)
+	require.Equal(t, "mint", reward.Action.RewardType)
+	require.Equal(t, &daemon, reward.Action.Author)
+	require.Equal(t, big.NewInt(1000), reward.Action.Value)
+
+	// No reward is reported when the mint request is reverted.
+	frames = nil
+	require.NoError(t, json.Unmarshal(traceWithSystemCalls(t, "flatCallTracer", "null", systemCallRecipient, daemonExcessCode), &frames))
+	require.Len(t, frames, 2)
+	require.True(t, frames[1].System)
+	require.Contains(t, frames[1].Error, "exceeded max of")
+}
+
+func TestPrestateTracerSystemCalls(t *testing.T) {
+	daemon := params.TestFlareChainConfig.SystemContracts().Daemon.Address
+
+	var pre state
+	require.NoError(t, json.Unmarshal(traceWithSystemCalls(t, "prestateTracer", "null", systemCallRecipient, daemonMintCode), &pre))
+	require.Contains(t, pre, daemon)
+	require.Equal(t, daemonMintCode, []byte(pre[daemon].Code))
+
+	var diff struct {
+		Post state `json:"post"`
+		Pre  state `json:"pre"`
+	}
+	require.NoError(t, json.Unmarshal(traceWithSystemCalls(t, "prestateTracer", `{"diffMode": true}`, systemCallRecipient, daemonMintCode), &diff))
+	require.Contains(t, diff.Pre, daemon)
+	require.Zero(t, diff.Pre[daemon].Balance.Sign())
+	require.Contains(t, diff.Post, daemon)
+	require.Equal(t, big.NewInt(1000), diff.Post[daemon].Balance)
+
+	// The daemon is left unchanged when the mint request is reverted.
+	diff.Pre, diff.Post = nil, nil
+	require.NoError(t, json.Unmarshal(traceWithSystemCalls(t, "prestateTracer", `{"diffMode": true}`, systemCallRecipient, daemonExcessCode), &diff))
+	require.NotContains(t, diff.Post, daemon)
+}
+
+func TestMuxTracerSystemCalls(t *testing.T) {
+	daemon := params.TestFlareChainConfig.SystemContracts().Daemon.Address
+
+	var res map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(traceWithSystemCalls(t, "muxTracer", `{"callTracer": null, "prestateTracer": null, "4byteTracer": null}`, systemCallRecipient, daemonMintCode), &res))
+
+	var frame callFrame
+	require.NoError(t, json.Unmarshal(res["callTracer"], &frame))
+	require.Len(t, frame.SystemCalls, 1)
+	require.Equal(t, big.NewInt(1000), frame.SystemCalls[0].Minted)
+
+	var pre state
+	require.NoError(t, json.Unmarshal(res["prestateTracer"], &pre))
+	require.Contains(t, pre, daemon)
+
+	// Tracers without system call hooks do not observe the daemon call.
+	var ids map[string]int
+	require.NoError(t, json.Unmarshal(res["4byteTracer"], &ids))
+	require.Empty(t, ids)
+}
+
+// Tests that the outcome of a system call reported before any call frame was
+// captured is ignored.
+func TestCallTracerSystemCallWithoutFrame(t *testing.T) {
+	tracer, err := tracers.DefaultDirectory.New("callTracer", new(tracers.Context), nil)
+	require.NoError(t, err)
+	system := tracer.(vm.SystemCallLogger)
+	require.NotPanics(t, func() {
+		system.CaptureSystemCallStart(nil, common.Address{}, common.Address{}, nil, 0)
+		system.CaptureSystemCallEnd(nil, 0, nil)
+		system.CaptureSystemMint(common.Address{}, big.NewInt(1))
+		system.CaptureSystemRevert(core.ErrInsufficientFunds)
+	})
+}
@@ -0,0 +1,283 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/eth/tracers"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/holiman/uint256"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("vmTracer", newVMTracer, false)
+}
+
+// vmTraceFrame is the execution of the code of a call frame in the Parity
+// vmTrace format.
+type vmTraceFrame struct {
+	Code hexutil.Bytes `json:"code"`
+	Ops  []*vmTraceOp  `json:"ops"`
+}
+
+// vmTraceOp is a single executed instruction.
+type vmTraceOp struct {
+	Cost uint64        `json:"cost"`
+	Ex   *vmTraceEx    `json:"ex"` // nil if the instruction failed
+	PC   uint64        `json:"pc"`
+	Sub  *vmTraceFrame `json:"sub"` // frame of the call or create, if any
+}
+
+// vmTraceEx is the outcome of an executed instruction.
+type vmTraceEx struct {
+	Mem   *vmTraceMem     `json:"mem"`
+	Push  []*hexutil.U256 `json:"push"`
+	Store *vmTraceStore   `json:"store"`
+	Used  uint64          `json:"used"` // gas left after the instruction, including the gas returned by a call
+}
+
+// vmTraceMem is the memory written by an instruction.
+type vmTraceMem struct {
+	Off  uint64        `json:"off"`
+	Data hexutil.Bytes `json:"data"`
+}
+
+// vmTraceStore is the storage slot written by an instruction.
+type vmTraceStore struct {
+	Key *hexutil.U256 `json:"key"`
+	Val *hexutil.U256 `json:"val"`
+}
+
+// vmTracePending is the last instruction of a frame whose outcome is only
+// known once the next instruction of the frame starts.
+type vmTracePending struct {
+	op      *vmTraceOp
+	pushes  int
+	memOff  uint64
+	memSize uint64
+}
+
+// vmTracer reports the executed instructions of a transaction in the Parity
+// vmTrace format.
+type vmTracer struct {
+	noopTracer
+	env       *vm.EVM
+	root      *vmTraceFrame
+	frames    []*vmTraceFrame
+	pending   []*vmTracePending
+	system    int         // depth of the system call being traced, if any
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// newVMTracer returns a new vmTracer.
+func newVMTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &vmTracer{}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *vmTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.root = t.enter(create, to, input)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *vmTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.exit()
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *vmTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.interrupt.Load() || t.system > 0 || len(t.frames) == 0 {
+		return
+	}
+	t.settle(scope, gas)
+	// Like Parity, instructions failing before they are executed, on a stack
+	// underflow or on insufficient gas, are not traced.
+	if err != nil {
+		return
+	}
+
+	var (
+		stack   = scope.Stack.Data()
+		pending = &vmTracePending{
+			op: &vmTraceOp{
+				Cost: cost,
+				PC:   pc,
+				Ex:   &vmTraceEx{Push: []*hexutil.U256{}, Used: gas - min(gas, cost)},
+			},
+			pushes: vmTracePushes(op),
+		}
+	)
+	back := func(n int) *uint256.Int {
+		if n >= len(stack) {
+			return new(uint256.Int)
+		}
+		return &stack[len(stack)-1-n]
+	}
+	switch op {
+	case vm.SSTORE:
+		pending.op.Ex.Store = &vmTraceStore{
+			Key: (*hexutil.U256)(new(uint256.Int).Set(back(0))),
+			Val: (*hexutil.U256)(new(uint256.Int).Set(back(1))),
+		}
+	case vm.MSTORE:
+		pending.memOff, pending.memSize = back(0).Uint64(), 32
+	case vm.MSTORE8:
+		pending.memOff, pending.memSize = back(0).Uint64(), 1
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY, vm.MCOPY:
+		pending.memOff, pending.memSize = back(0).Uint64(), back(2).Uint64()
+	case vm.EXTCODECOPY:
+		pending.memOff, pending.memSize = back(1).Uint64(), back(3).Uint64()
+	case vm.CALL, vm.CALLCODE:
+		pending.memOff, pending.memSize = back(5).Uint64(), back(6).Uint64()
+	case vm.DELEGATECALL, vm.STATICCALL:
+		pending.memOff, pending.memSize = back(4).Uint64(), back(5).Uint64()
+	}
+	frame := t.frames[len(t.frames)-1]
+	frame.Ops = append(frame.Ops, pending.op)
+	t.pending[len(t.pending)-1] = pending
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *vmTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if t.system > 0 || len(t.pending) == 0 {
+		return
+	}
+	if pending := t.pending[len(t.pending)-1]; pending != nil {
+		pending.op.Ex = nil
+		t.pending[len(t.pending)-1] = nil
+	}
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *vmTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.system > 0 {
+		return
+	}
+	frame := t.enter(typ == vm.CREATE || typ == vm.CREATE2, to, input)
+	if typ == vm.SELFDESTRUCT {
+		return
+	}
+	if parent := t.pending[len(t.pending)-2]; parent != nil {
+		parent.op.Sub = frame
+	}
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *vmTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.system > 0 {
+		return
+	}
+	t.exit()
+}
+
+// CaptureSystemCallStart is called when the EVM starts a Flare system call
+// after the top call frame has ended. System calls are not part of the
+// vmTrace of the transaction.
+func (t *vmTracer) CaptureSystemCallStart(env *vm.EVM, from common.Address, to common.Address, input []byte, gas uint64) {
+	t.system++
+}
+
+// CaptureSystemCallEnd is called when a Flare system call finishes.
+func (t *vmTracer) CaptureSystemCallEnd(output []byte, gasUsed uint64, err error) {
+	t.system--
+}
+
+// CaptureSystemMint is called when the last system call minted [amount] on to [to].
+func (t *vmTracer) CaptureSystemMint(to common.Address, amount *big.Int) {}
+
+// CaptureSystemRevert is called when the state changes of the last system
+// call were reverted after it had finished.
+func (t *vmTracer) CaptureSystemRevert(err error) {}
+
+// GetResult returns the json-encoded vmTrace of the transaction.
+func (t *vmTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.root)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *vmTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// enter starts tracing the code of a new call frame.
+func (t *vmTracer) enter(create bool, to common.Address, input []byte) *vmTraceFrame {
+	frame := &vmTraceFrame{Ops: []*vmTraceOp{}}
+	if create {
+		frame.Code = common.CopyBytes(input)
+	} else {
+		frame.Code = t.env.StateDB.GetCode(to)
+	}
+	t.frames = append(t.frames, frame)
+	t.pending = append(t.pending, nil)
+	return frame
+}
+
+// exit stops tracing the current call frame. The outcome of its last
+// instruction can no longer be observed, so nothing is reported as pushed.
+func (t *vmTracer) exit() {
+	if len(t.frames) == 0 {
+		return
+	}
+	t.frames = t.frames[:len(t.frames)-1]
+	t.pending = t.pending[:len(t.pending)-1]
+}
+
+// settle fills the outcome of the last instruction of the current frame from
+// the stack, memory and gas of the frame before its next instruction.
+func (t *vmTracer) settle(scope *vm.ScopeContext, gas uint64) {
+	pending := t.pending[len(t.pending)-1]
+	if pending == nil {
+		return
+	}
+	stack := scope.Stack.Data()
+	pending.op.Ex.Used = gas
+	pending.op.Ex.Push = make([]*hexutil.U256, 0, pending.pushes)
+	for i := max(len(stack)-pending.pushes, 0); i < len(stack); i++ {
+		pending.op.Ex.Push = append(pending.op.Ex.Push, (*hexutil.U256)(new(uint256.Int).Set(&stack[i])))
+	}
+	if pending.memSize > 0 {
+		data, err := tracers.GetMemoryCopyPadded(scope.Memory, int64(pending.memOff), int64(pending.memSize))
+		if err == nil {
+			pending.op.Ex.Mem = &vmTraceMem{Off: pending.memOff, Data: data}
+		}
+	}
+	t.pending[len(t.pending)-1] = nil
+}
+
+// vmTracePushes returns the number of stack items reported as pushed by [op].
+// Like Parity, the duplicated and swapped items are reported in full.
+func vmTracePushes(op vm.OpCode) int {
+	switch {
+	case op.IsPush():
+		return 1
+	case vm.DUP1 <= op && op <= vm.DUP16:
+		return int(op-vm.DUP1) + 2
+	case vm.SWAP1 <= op && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	}
+	switch op {
+	case vm.ADD, vm.MUL, vm.SUB, vm.DIV, vm.SDIV, vm.MOD, vm.SMOD, vm.ADDMOD, vm.MULMOD, vm.EXP, vm.SIGNEXTEND,
+		vm.LT, vm.GT, vm.SLT, vm.SGT, vm.EQ, vm.ISZERO, vm.AND, vm.OR, vm.XOR, vm.NOT, vm.BYTE, vm.SHL, vm.SHR, vm.SAR,
+		vm.KECCAK256, vm.ADDRESS, vm.BALANCE, vm.ORIGIN, vm.CALLER, vm.CALLVALUE, vm.CALLDATALOAD, vm.CALLDATASIZE,
+		vm.CODESIZE, vm.GASPRICE, vm.EXTCODESIZE, vm.RETURNDATASIZE, vm.EXTCODEHASH, vm.BLOCKHASH, vm.COINBASE,
+		vm.TIMESTAMP, vm.NUMBER, vm.DIFFICULTY, vm.GASLIMIT, vm.CHAINID, vm.SELFBALANCE, vm.BASEFEE, vm.BLOBHASH,
+		vm.BLOBBASEFEE, vm.MLOAD, vm.SLOAD, vm.TLOAD, vm.PC, vm.MSIZE, vm.GAS,
+		vm.CREATE, vm.CREATE2, vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		return 1
+	}
+	return 0
+}
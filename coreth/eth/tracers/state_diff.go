@@ -0,0 +1,260 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// prestateAccount is an account as reported by the prestateTracer.
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Code    hexutil.Bytes               `json:"code"`
+	Nonce   uint64                      `json:"nonce"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// prestateDiff is the result of the prestateTracer in diff mode. Accounts
+// that were modified are in both pre and post, but post only holds the
+// fields that changed. Created accounts are only in post, deleted ones only
+// in pre.
+type prestateDiff struct {
+	Pre  map[common.Address]*prestateAccount `json:"pre"`
+	Post map[common.Address]*prestateAccount `json:"post"`
+}
+
+// diffValue is the change of a single value in the Parity stateDiff format:
+// "=" if it did not change, {"+": new} if it was created, {"-": old} if it was
+// deleted and {"*": {"from": old, "to": new}} if it was modified.
+type diffValue struct {
+	kind     string
+	from, to interface{}
+}
+
+func (v *diffValue) MarshalJSON() ([]byte, error) {
+	switch v.kind {
+	case "+":
+		return json.Marshal(map[string]interface{}{"+": v.to})
+	case "-":
+		return json.Marshal(map[string]interface{}{"-": v.from})
+	case "*":
+		return json.Marshal(map[string]interface{}{"*": map[string]interface{}{"from": v.from, "to": v.to}})
+	}
+	return json.Marshal("=")
+}
+
+// AccountDiff is the change of an account in the Parity stateDiff format.
+type AccountDiff struct {
+	Balance *diffValue                 `json:"balance"`
+	Code    *diffValue                 `json:"code"`
+	Nonce   *diffValue                 `json:"nonce"`
+	Storage map[common.Hash]*diffValue `json:"storage"`
+}
+
+// StateDiff is the change of the state by a transaction in the Parity
+// stateDiff format.
+type StateDiff map[common.Address]*AccountDiff
+
+// newStateDiff converts the result of the prestateTracer in diff mode to the
+// Parity stateDiff format.
+func newStateDiff(result json.RawMessage) (StateDiff, error) {
+	var diff prestateDiff
+	if err := json.Unmarshal(result, &diff); err != nil {
+		return nil, err
+	}
+	stateDiff := make(StateDiff)
+	for addr, pre := range diff.Pre {
+		post, ok := diff.Post[addr]
+		if !ok {
+			stateDiff[addr] = deletedAccountDiff(pre)
+			continue
+		}
+		stateDiff[addr] = modifiedAccountDiff(pre, post)
+	}
+	for addr, post := range diff.Post {
+		if _, ok := diff.Pre[addr]; !ok {
+			stateDiff[addr] = createdAccountDiff(post)
+		}
+	}
+	return stateDiff, nil
+}
+
+func createdAccountDiff(post *prestateAccount) *AccountDiff {
+	account := &AccountDiff{
+		Balance: &diffValue{kind: "+", to: balanceOf(post)},
+		Code:    &diffValue{kind: "+", to: codeOf(post)},
+		Nonce:   &diffValue{kind: "+", to: hexutil.Uint64(post.Nonce)},
+		Storage: make(map[common.Hash]*diffValue),
+	}
+	for key, val := range post.Storage {
+		account.Storage[key] = &diffValue{kind: "+", to: val}
+	}
+	return account
+}
+
+func deletedAccountDiff(pre *prestateAccount) *AccountDiff {
+	account := &AccountDiff{
+		Balance: &diffValue{kind: "-", from: balanceOf(pre)},
+		Code:    &diffValue{kind: "-", from: codeOf(pre)},
+		Nonce:   &diffValue{kind: "-", from: hexutil.Uint64(pre.Nonce)},
+		Storage: make(map[common.Hash]*diffValue),
+	}
+	for key, val := range pre.Storage {
+		account.Storage[key] = &diffValue{kind: "-", from: val}
+	}
+	return account
+}
+
+func modifiedAccountDiff(pre, post *prestateAccount) *AccountDiff {
+	account := &AccountDiff{
+		Balance: &diffValue{kind: "="},
+		Code:    &diffValue{kind: "="},
+		Nonce:   &diffValue{kind: "="},
+		Storage: make(map[common.Hash]*diffValue),
+	}
+	if post.Balance != nil && post.Balance.ToInt().Cmp(balanceOf(pre).ToInt()) != 0 {
+		account.Balance = &diffValue{kind: "*", from: balanceOf(pre), to: post.Balance}
+	}
+	if post.Code != nil && !bytes.Equal(post.Code, pre.Code) {
+		account.Code = &diffValue{kind: "*", from: codeOf(pre), to: post.Code}
+	}
+	if post.Nonce != 0 && post.Nonce != pre.Nonce {
+		account.Nonce = &diffValue{kind: "*", from: hexutil.Uint64(pre.Nonce), to: hexutil.Uint64(post.Nonce)}
+	}
+	// Slots that were empty before are omitted from pre, slots that are empty
+	// after are omitted from post.
+	for key, from := range pre.Storage {
+		account.Storage[key] = &diffValue{kind: "*", from: from, to: post.Storage[key]}
+	}
+	for key, to := range post.Storage {
+		if _, ok := pre.Storage[key]; !ok {
+			account.Storage[key] = &diffValue{kind: "*", from: common.Hash{}, to: to}
+		}
+	}
+	return account
+}
+
+func balanceOf(account *prestateAccount) *hexutil.Big {
+	if account.Balance == nil {
+		return (*hexutil.Big)(new(big.Int))
+	}
+	return account.Balance
+}
+
+func codeOf(account *prestateAccount) hexutil.Bytes {
+	if account.Code == nil {
+		return hexutil.Bytes{}
+	}
+	return account.Code
+}
+
+// blockAccount is an account touched by the transactions of a block.
+type blockAccount struct {
+	pre   *prestateAccount            // the account before the block, nil if it did not exist
+	slots map[common.Hash]common.Hash // the values before the block of the modified slots
+}
+
+// blockStateDiff accumulates the results of the prestateTracer in diff mode
+// of the transactions of a block into the change of the state by the whole
+// block.
+type blockStateDiff map[common.Address]*blockAccount
+
+// add records the accounts and slots modified by the next transaction. The
+// values before the block are the ones before the first transaction that
+// modified them.
+func (d blockStateDiff) add(result json.RawMessage) error {
+	var diff prestateDiff
+	if err := json.Unmarshal(result, &diff); err != nil {
+		return err
+	}
+	for addr, pre := range diff.Pre {
+		account, ok := d[addr]
+		if !ok {
+			account = &blockAccount{pre: pre, slots: make(map[common.Hash]common.Hash)}
+			d[addr] = account
+		}
+		for key, val := range pre.Storage {
+			if _, ok := account.slots[key]; !ok {
+				account.slots[key] = val
+			}
+		}
+	}
+	// Slots that were empty before are omitted from pre.
+	for addr, post := range diff.Post {
+		account, ok := d[addr]
+		if !ok {
+			account = &blockAccount{slots: make(map[common.Hash]common.Hash)}
+			d[addr] = account
+		}
+		for key := range post.Storage {
+			if _, ok := account.slots[key]; !ok {
+				account.slots[key] = common.Hash{}
+			}
+		}
+	}
+	return nil
+}
+
+// stateDiff returns the change of the state by the block in the Parity
+// stateDiff format, given the state after its transactions.
+func (d blockStateDiff) stateDiff(statedb vm.StateDB) StateDiff {
+	stateDiff := make(StateDiff)
+	for addr, account := range d {
+		exists := statedb.Exist(addr)
+		if account.pre == nil && !exists {
+			continue
+		}
+		pre, post := &prestateAccount{Storage: make(map[common.Hash]common.Hash)}, &prestateAccount{Storage: make(map[common.Hash]common.Hash)}
+		if account.pre != nil {
+			pre.Balance, pre.Code, pre.Nonce = account.pre.Balance, account.pre.Code, account.pre.Nonce
+		}
+		var (
+			balance  = (*hexutil.Big)(statedb.GetBalance(addr).ToBig())
+			code     = hexutil.Bytes(statedb.GetCode(addr))
+			nonce    = statedb.GetNonce(addr)
+			modified bool
+		)
+		for key, from := range account.slots {
+			to := statedb.GetState(addr, key)
+			if exists && account.pre != nil && from == to {
+				continue
+			}
+			if from != (common.Hash{}) {
+				pre.Storage[key] = from
+			}
+			if to != (common.Hash{}) {
+				post.Storage[key] = to
+			}
+			modified = true
+		}
+		switch {
+		case account.pre == nil:
+			post.Balance, post.Code, post.Nonce = balance, code, nonce
+			stateDiff[addr] = createdAccountDiff(post)
+		case !exists:
+			stateDiff[addr] = deletedAccountDiff(pre)
+		default:
+			// Only the fields that changed are set in post.
+			if balance.ToInt().Cmp(balanceOf(pre).ToInt()) != 0 {
+				post.Balance, modified = balance, true
+			}
+			if !bytes.Equal(code, pre.Code) {
+				post.Code, modified = code, true
+			}
+			if nonce != pre.Nonce {
+				post.Nonce, modified = nonce, true
+			}
+			if modified {
+				stateDiff[addr] = modifiedAccountDiff(pre, post)
+			}
+		}
+	}
+	return stateDiff
+}
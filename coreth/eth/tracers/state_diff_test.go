@@ -0,0 +1,136 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package tracers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStateDiff(t *testing.T) {
+	t.Parallel()
+	prestate := `{
+		"pre": {
+			"0x00000000000000000000000000000000000000aa": {"balance": "0x10", "nonce": 1, "storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000005"
+			}},
+			"0x00000000000000000000000000000000000000bb": {"balance": "0x7", "code": "0x6000"}
+		},
+		"post": {
+			"0x00000000000000000000000000000000000000aa": {"balance": "0x8", "nonce": 2, "storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000002": "0x0000000000000000000000000000000000000000000000000000000000000003"
+			}},
+			"0x00000000000000000000000000000000000000cc": {"balance": "0x1", "code": "0x60016000"}
+		}
+	}`
+	diff, err := newStateDiff(json.RawMessage(prestate))
+	require.NoError(t, err)
+	have, err := json.Marshal(diff)
+	require.NoError(t, err)
+
+	want := `{
+		"0x00000000000000000000000000000000000000aa": {
+			"balance": {"*": {"from": "0x10", "to": "0x8"}},
+			"code": "=",
+			"nonce": {"*": {"from": "0x1", "to": "0x2"}},
+			"storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": {"*": {
+					"from": "0x0000000000000000000000000000000000000000000000000000000000000005",
+					"to": "0x0000000000000000000000000000000000000000000000000000000000000000"
+				}},
+				"0x0000000000000000000000000000000000000000000000000000000000000002": {"*": {
+					"from": "0x0000000000000000000000000000000000000000000000000000000000000000",
+					"to": "0x0000000000000000000000000000000000000000000000000000000000000003"
+				}}
+			}
+		},
+		"0x00000000000000000000000000000000000000bb": {
+			"balance": {"-": "0x7"},
+			"code": {"-": "0x6000"},
+			"nonce": {"-": "0x0"},
+			"storage": {}
+		},
+		"0x00000000000000000000000000000000000000cc": {
+			"balance": {"+": "0x1"},
+			"code": {"+": "0x60016000"},
+			"nonce": {"+": "0x0"},
+			"storage": {}
+		}
+	}`
+	require.JSONEq(t, want, string(have))
+}
+
+func TestBlockStateDiff(t *testing.T) {
+	t.Parallel()
+	// The first transaction modifies 0xaa and creates 0xcc, the second one
+	// clears the slot set by the first one and deletes 0xbb.
+	txs := []string{`{
+		"pre": {
+			"0x00000000000000000000000000000000000000aa": {"balance": "0x10", "nonce": 1, "storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000005"
+			}}
+		},
+		"post": {
+			"0x00000000000000000000000000000000000000aa": {"balance": "0x8", "nonce": 2, "storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000002": "0x0000000000000000000000000000000000000000000000000000000000000003"
+			}},
+			"0x00000000000000000000000000000000000000cc": {"balance": "0x1"}
+		}
+	}`, `{
+		"pre": {
+			"0x00000000000000000000000000000000000000aa": {"balance": "0x8", "nonce": 2, "storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000002": "0x0000000000000000000000000000000000000000000000000000000000000003"
+			}},
+			"0x00000000000000000000000000000000000000bb": {"balance": "0x7"}
+		},
+		"post": {
+			"0x00000000000000000000000000000000000000aa": {}
+		}
+	}`}
+	diff := make(blockStateDiff)
+	for _, tx := range txs {
+		require.NoError(t, diff.add(json.RawMessage(tx)))
+	}
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	require.NoError(t, err)
+	statedb.SetBalance(common.Address{19: 0xaa}, uint256.NewInt(8))
+	statedb.SetNonce(common.Address{19: 0xaa}, 2)
+	statedb.SetBalance(common.Address{19: 0xcc}, uint256.NewInt(1))
+
+	have, err := json.Marshal(diff.stateDiff(statedb))
+	require.NoError(t, err)
+	want := `{
+		"0x00000000000000000000000000000000000000aa": {
+			"balance": {"*": {"from": "0x10", "to": "0x8"}},
+			"code": "=",
+			"nonce": {"*": {"from": "0x1", "to": "0x2"}},
+			"storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": {"*": {
+					"from": "0x0000000000000000000000000000000000000000000000000000000000000005",
+					"to": "0x0000000000000000000000000000000000000000000000000000000000000000"
+				}}
+			}
+		},
+		"0x00000000000000000000000000000000000000bb": {
+			"balance": {"-": "0x7"},
+			"code": {"-": "0x"},
+			"nonce": {"-": "0x0"},
+			"storage": {}
+		},
+		"0x00000000000000000000000000000000000000cc": {
+			"balance": {"+": "0x1"},
+			"code": {"+": "0x"},
+			"nonce": {"+": "0x0"},
+			"storage": {}
+		}
+	}`
+	require.JSONEq(t, want, string(have))
+}
@@ -0,0 +1,258 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+// Package chainarchive implements the versioned chain archive written by
+// admin_exportChainArchive and read by admin_importChainArchive.
+//
+// An archive is a directory holding a manifest and the chunks of the exported
+// range of accepted blocks. Each chunk is a zstd compressed stream of RLP
+// entries, one per block, bundling the block, its receipts and the atomic
+// transactions accepted with it. The manifest records the chain ID, the
+// genesis and the range of the archive, and the height range, size and hash
+// of every chunk, so that the archive can be verified before anything is
+// imported from it.
+package chainarchive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ava-labs/avalanchego/utils/compression"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/trie"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// Version is the version of the archive format written by Export.
+	Version = 1
+
+	// ManifestFile is the name of the manifest in the archive directory.
+	ManifestFile = "manifest.json"
+
+	maxChunkSize = 512 * units.MiB // Maximum uncompressed size of a chunk
+)
+
+// chunkSize is the uncompressed size from which a chunk is closed, it is only
+// changed by tests.
+var chunkSize = 32 * units.MiB
+
+// AtomicTxs gives access to the atomic transactions of the accepted blocks,
+// which are kept by the VM outside of the chain database.
+type AtomicTxs interface {
+	// Read returns the atomic transactions accepted at the height, encoded as
+	// a batch, or nil if there are none.
+	Read(height uint64) ([]byte, error)
+
+	// Verify checks that the encoded atomic transactions are the ones of the
+	// block.
+	Verify(block *types.Block, txs []byte) error
+
+	// Accept accepts the imported block, already inserted into the chain, the
+	// way the VM accepts the blocks decided by consensus, writing its atomic
+	// transactions to the atomic repository and shared memory.
+	Accept(block *types.Block) error
+}
+
+// Manifest describes the content of an archive.
+type Manifest struct {
+	Version   uint64      `json:"version"`
+	ChainID   *big.Int    `json:"chainId"`
+	Genesis   common.Hash `json:"genesis"`
+	First     uint64      `json:"first"`
+	Last      uint64      `json:"last"`
+	AtomicTxs bool        `json:"atomicTxs"` // Whether the atomic transactions are bundled
+	Chunks    []Chunk     `json:"chunks"`
+}
+
+// Chunk describes a chunk file of an archive.
+type Chunk struct {
+	File  string      `json:"file"`
+	First uint64      `json:"first"`
+	Last  uint64      `json:"last"`
+	Size  uint64      `json:"size"` // Size of the compressed file
+	Hash  common.Hash `json:"hash"` // Keccak256 hash of the compressed file
+}
+
+// Entry is a block of an archive.
+type Entry struct {
+	Block     *types.Block
+	Receipts  types.Receipts
+	AtomicTxs []byte
+}
+
+// storedEntry is the RLP encoding of an Entry, with the receipts in their
+// storage form.
+type storedEntry struct {
+	Block     *types.Block
+	Receipts  []*types.ReceiptForStorage
+	AtomicTxs []byte
+}
+
+// verify checks that the manifest is supported and that its chunks cover its
+// range in order.
+func (m *Manifest) verify() error {
+	if m.Version != Version {
+		return fmt.Errorf("unsupported archive version %d, want %d", m.Version, Version)
+	}
+	if m.ChainID == nil {
+		return errors.New("missing chain ID")
+	}
+	if m.First > m.Last {
+		return fmt.Errorf("invalid range [%d, %d]", m.First, m.Last)
+	}
+	if len(m.Chunks) == 0 {
+		return errors.New("no chunks")
+	}
+	next := m.First
+	for i, chunk := range m.Chunks {
+		if chunk.File == "" || filepath.Base(chunk.File) != chunk.File {
+			return fmt.Errorf("chunk %d: invalid file name %q", i, chunk.File)
+		}
+		if chunk.First != next || chunk.First > chunk.Last {
+			return fmt.Errorf("chunk %d: range [%d, %d] does not follow block %d", i, chunk.First, chunk.Last, next-1)
+		}
+		next = chunk.Last + 1
+	}
+	if next-1 != m.Last {
+		return fmt.Errorf("chunks end at block %d, want %d", next-1, m.Last)
+	}
+	return nil
+}
+
+// Reader reads the chunks of an archive, verifying them against its manifest.
+type Reader struct {
+	dir        string
+	manifest   *Manifest
+	hash       common.Hash
+	compressor compression.Compressor
+}
+
+// Open reads and verifies the manifest of the archive in [dir].
+func Open(dir string) (*Reader, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(Manifest)
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := manifest.verify(); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	compressor, err := compression.NewZstdCompressor(maxChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		dir:        dir,
+		manifest:   manifest,
+		hash:       crypto.Keccak256Hash(data),
+		compressor: compressor,
+	}, nil
+}
+
+// Manifest returns the manifest of the archive.
+func (r *Reader) Manifest() *Manifest {
+	return r.manifest
+}
+
+// Hash returns the hash of the manifest, which identifies the archive.
+func (r *Reader) Hash() common.Hash {
+	return r.hash
+}
+
+// ReadChunk reads the blocks of the chunk at [index]. The chunk is checked
+// against its hash, and the bodies and receipts of the blocks against their
+// headers.
+func (r *Reader) ReadChunk(index int) ([]*Entry, error) {
+	chunk := r.manifest.Chunks[index]
+	data, err := os.ReadFile(filepath.Join(r.dir, chunk.File))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(data)) != chunk.Size {
+		return nil, fmt.Errorf("chunk %s: size mismatch: have %d, want %d", chunk.File, len(data), chunk.Size)
+	}
+	if hash := crypto.Keccak256Hash(data); hash != chunk.Hash {
+		return nil, fmt.Errorf("chunk %s: hash mismatch: have %s, want %s", chunk.File, hash.Hex(), chunk.Hash.Hex())
+	}
+	if data, err = r.compressor.Decompress(data); err != nil {
+		return nil, fmt.Errorf("chunk %s: %w", chunk.File, err)
+	}
+	var (
+		stream  = rlp.NewStream(bytes.NewReader(data), 0)
+		entries []*Entry
+		number  = chunk.First
+	)
+	for {
+		var stored storedEntry
+		if err := stream.Decode(&stored); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("chunk %s: block %d: %w", chunk.File, number, err)
+		}
+		if stored.Block == nil || stored.Block.NumberU64() != number || number > chunk.Last {
+			return nil, fmt.Errorf("chunk %s: unexpected block, want %d", chunk.File, number)
+		}
+		if len(entries) > 0 && stored.Block.ParentHash() != entries[len(entries)-1].Block.Hash() {
+			return nil, fmt.Errorf("chunk %s: block %d does not extend block %d", chunk.File, number, number-1)
+		}
+		if len(stored.AtomicTxs) > 0 && !r.manifest.AtomicTxs {
+			return nil, fmt.Errorf("chunk %s: block %d: unexpected atomic transactions", chunk.File, number)
+		}
+		entry := &Entry{
+			Block:     stored.Block,
+			Receipts:  make(types.Receipts, len(stored.Receipts)),
+			AtomicTxs: stored.AtomicTxs,
+		}
+		for i, receipt := range stored.Receipts {
+			entry.Receipts[i] = (*types.Receipt)(receipt)
+		}
+		if err := verifyEntry(entry); err != nil {
+			return nil, fmt.Errorf("chunk %s: block %d: %w", chunk.File, number, err)
+		}
+		entries = append(entries, entry)
+		number++
+	}
+	if number != chunk.Last+1 {
+		return nil, fmt.Errorf("chunk %s: ends at block %d, want %d", chunk.File, number-1, chunk.Last)
+	}
+	return entries, nil
+}
+
+// verifyEntry checks the body and the receipts of the block against its header.
+func verifyEntry(entry *Entry) error {
+	var (
+		header = entry.Block.Header()
+		txs    = entry.Block.Transactions()
+	)
+	if hash := types.CalcUncleHash(entry.Block.Uncles()); hash != header.UncleHash {
+		return fmt.Errorf("uncle root hash mismatch: have %x, want %x", hash, header.UncleHash)
+	}
+	if hash := types.DeriveSha(txs, trie.NewStackTrie(nil)); hash != header.TxHash {
+		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
+	}
+	if len(entry.Receipts) != len(txs) {
+		return fmt.Errorf("receipt count mismatch: have %d, want %d", len(entry.Receipts), len(txs))
+	}
+	// The type of the receipts is part of their consensus encoding but not
+	// of the storage one.
+	for i, receipt := range entry.Receipts {
+		receipt.Type = txs[i].Type()
+	}
+	if hash := types.DeriveSha(entry.Receipts, trie.NewStackTrie(nil)); hash != header.ReceiptHash {
+		return fmt.Errorf("receipt root hash mismatch: have %x, want %x", hash, header.ReceiptHash)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package chainarchive
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/stretchr/testify/require"
+)
+
+// testAtomicTxs bundles the height of every third block as its atomic
+// transactions, and accepts the imported blocks into [chain].
+type testAtomicTxs struct {
+	chain *core.BlockChain
+}
+
+func (testAtomicTxs) Read(height uint64) ([]byte, error) {
+	if height%3 != 0 {
+		return nil, nil
+	}
+	return []byte{byte(height)}, nil
+}
+
+func (a testAtomicTxs) Verify(block *types.Block, txs []byte) error {
+	if want, _ := a.Read(block.NumberU64()); !bytes.Equal(txs, want) {
+		return fmt.Errorf("have %x, want %x", txs, want)
+	}
+	return nil
+}
+
+func (a testAtomicTxs) Accept(block *types.Block) error {
+	return a.chain.Accept(block)
+}
+
+func newTestChain(t *testing.T, db ethdb.Database, gspec *core.Genesis) *core.BlockChain {
+	chain, err := core.NewBlockChain(db, core.DefaultCacheConfig, gspec, dummy.NewFakerWithCallbacks(core.TestCallbacks), vm.Config{}, common.Hash{}, false)
+	require.NoError(t, err)
+	t.Cleanup(chain.Stop)
+	return chain
+}
+
+// Tests that an exported archive is verified and imported into a new chain,
+// and that an interrupted import is resumed.
+func TestExportImport(t *testing.T) {
+	require := require.New(t)
+	defer func(size int) { chunkSize = size }(chunkSize)
+	chunkSize = 1024
+
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		funds  = new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(100))
+		gspec  = &core.Genesis{
+			Config: &params.ChainConfig{ChainID: big.NewInt(14), HomesteadBlock: new(big.Int)},
+			Alloc:  types.GenesisAlloc{addr: {Balance: funds}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _, err := core.GenerateChainWithGenesis(gspec, dummy.NewFakerWithCallbacks(core.TestCallbacks), 40, 10, func(i int, block *core.BlockGen) {
+		to := common.BigToAddress(big.NewInt(int64(i + 1)))
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), to, big.NewInt(1), 21000, big.NewInt(225000000000), nil), signer, key)
+		require.NoError(err)
+		block.AddTx(tx)
+	})
+	require.NoError(err)
+
+	source := newTestChain(t, rawdb.NewMemoryDatabase(), gspec)
+	_, err = source.InsertChain(blocks)
+	require.NoError(err)
+	for _, block := range blocks {
+		require.NoError(source.Accept(block))
+	}
+	source.DrainAcceptorQueue()
+
+	dir := filepath.Join(t.TempDir(), "archive")
+	require.Error(Export(source, testAtomicTxs{}, dir, 0, 41))
+	require.NoError(Export(source, testAtomicTxs{}, dir, 0, 40))
+	require.Error(Export(source, testAtomicTxs{}, dir, 0, 40), "export overwrote an existing archive")
+
+	archive, err := Open(dir)
+	require.NoError(err)
+	manifest := archive.Manifest()
+	require.Equal(uint64(0), manifest.First)
+	require.Equal(uint64(40), manifest.Last)
+	require.Equal(big.NewInt(14), manifest.ChainID)
+	require.True(manifest.AtomicTxs)
+	require.Greater(len(manifest.Chunks), 2)
+
+	entries, err := archive.ReadChunk(1)
+	require.NoError(err)
+	entry := entries[0]
+	require.Equal(blocks[entry.Block.NumberU64()-1].Hash(), entry.Block.Hash())
+	require.Equal(source.GetReceiptsByHash(entry.Block.Hash())[0].TxHash, entry.Block.Transactions()[0].Hash())
+	require.Len(entry.Receipts, 1)
+
+	// A corrupted chunk fails the import once the previous ones are imported.
+	last := filepath.Join(dir, manifest.Chunks[len(manifest.Chunks)-1].File)
+	data, err := os.ReadFile(last)
+	require.NoError(err)
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)/2] ^= 0xff
+	require.NoError(os.WriteFile(last, corrupted, 0644))
+
+	db := rawdb.NewMemoryDatabase()
+	chain := newTestChain(t, db, gspec)
+	require.ErrorContains(Import(chain, db, testAtomicTxs{chain}, dir), "hash mismatch")
+	hash, next, ok := rawdb.ReadChainImportProgress(db)
+	require.True(ok)
+	require.Equal(archive.Hash(), hash)
+	require.Equal(uint64(len(manifest.Chunks)-1), next)
+
+	// The import resumes from the chunk that failed, the first chunk is not
+	// read again.
+	require.NoError(os.WriteFile(last, data, 0644))
+	require.NoError(os.Remove(filepath.Join(dir, manifest.Chunks[0].File)))
+	require.NoError(Import(chain, db, testAtomicTxs{chain}, dir))
+	for _, block := range blocks {
+		require.True(chain.HasBlock(block.Hash(), block.NumberU64()))
+		require.Len(rawdb.ReadRawReceipts(db, block.Hash(), block.NumberU64()), 1)
+	}
+	require.Equal(blocks[len(blocks)-1].Hash(), chain.LastAcceptedBlock().Hash())
+	_, _, ok = rawdb.ReadChainImportProgress(db)
+	require.False(ok)
+
+	// The imported blocks are accepted by the chain alone without atomic
+	// transactions, and the accepted head is kept across a restart.
+	plain := filepath.Join(t.TempDir(), "plain")
+	require.NoError(Export(source, nil, plain, 0, 40))
+	db = rawdb.NewMemoryDatabase()
+	chain = newTestChain(t, db, gspec)
+	require.NoError(Import(chain, db, nil, plain))
+	chain.Stop()
+	chain, err = core.NewBlockChain(db, core.DefaultCacheConfig, gspec, dummy.NewFakerWithCallbacks(core.TestCallbacks), vm.Config{}, blocks[len(blocks)-1].Hash(), false)
+	require.NoError(err)
+	defer chain.Stop()
+	require.Equal(blocks[len(blocks)-1].Hash(), chain.LastAcceptedBlock().Hash())
+
+	// The archive is rejected by another chain.
+	other := &core.Genesis{Config: &params.ChainConfig{ChainID: big.NewInt(16), HomesteadBlock: new(big.Int)}, Alloc: gspec.Alloc}
+	otherDB := rawdb.NewMemoryDatabase()
+	require.ErrorContains(Import(newTestChain(t, otherDB, other), otherDB, nil, dir), "archive of chain 14")
+}
@@ -0,0 +1,143 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package chainarchive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/compression"
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// exporter writes the chunks of an archive.
+type exporter struct {
+	dir        string
+	manifest   *Manifest
+	compressor compression.Compressor
+	buf        bytes.Buffer
+	first      uint64 // First block of the pending chunk
+}
+
+// Export writes the accepted blocks [first, last] of [chain] into a new
+// archive in [dir], which must not exist. The atomic transactions are bundled
+// if [atomic] is not nil.
+func Export(chain *core.BlockChain, atomic AtomicTxs, dir string, first, last uint64) error {
+	if first > last {
+		return fmt.Errorf("invalid range [%d, %d]", first, last)
+	}
+	if accepted := chain.LastAcceptedBlock().NumberU64(); last > accepted {
+		return fmt.Errorf("block %d is above the last accepted block %d", last, accepted)
+	}
+	if _, err := os.Stat(dir); err == nil {
+		// Allowing to overwrite could be a DoS vector, since the directory may
+		// point to arbitrary paths on the drive.
+		return errors.New("location would overwrite an existing file")
+	}
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return err
+	}
+	compressor, err := compression.NewZstdCompressor(maxChunkSize)
+	if err != nil {
+		return err
+	}
+	e := &exporter{
+		dir: dir,
+		manifest: &Manifest{
+			Version:   Version,
+			ChainID:   chain.Config().ChainID,
+			Genesis:   chain.Genesis().Hash(),
+			First:     first,
+			Last:      last,
+			AtomicTxs: atomic != nil,
+		},
+		compressor: compressor,
+		first:      first,
+	}
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	log.Info("Exporting chain", "first", first, "last", last, "dir", dir)
+	for number := first; number <= last; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return fmt.Errorf("export failed on #%d: not found", number)
+		}
+		receipts := chain.GetReceiptsByHash(block.Hash())
+		if receipts == nil && len(block.Transactions()) > 0 {
+			return fmt.Errorf("export failed on #%d: receipts not found", number)
+		}
+		entry := &storedEntry{
+			Block:    block,
+			Receipts: make([]*types.ReceiptForStorage, len(receipts)),
+		}
+		for i, receipt := range receipts {
+			entry.Receipts[i] = (*types.ReceiptForStorage)(receipt)
+		}
+		if atomic != nil {
+			if entry.AtomicTxs, err = atomic.Read(number); err != nil {
+				return fmt.Errorf("export failed on #%d: %w", number, err)
+			}
+		}
+		if err := rlp.Encode(&e.buf, entry); err != nil {
+			return fmt.Errorf("export failed on #%d: %w", number, err)
+		}
+		if e.buf.Len() >= chunkSize || number == last {
+			if err := e.writeChunk(number); err != nil {
+				return err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting chain", "exported", number-first+1, "remaining", last-number, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	// The manifest is written last, an interrupted export has none.
+	data, err := json.MarshalIndent(e.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, ManifestFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, ManifestFile)); err != nil {
+		return err
+	}
+	log.Info("Exported chain", "first", first, "last", last, "chunks", len(e.manifest.Chunks), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// writeChunk compresses the pending chunk ending at block [last] into its file.
+func (e *exporter) writeChunk(last uint64) error {
+	data, err := e.compressor.Compress(e.buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to compress blocks [%d, %d]: %w", e.first, last, err)
+	}
+	chunk := Chunk{
+		File:  fmt.Sprintf("%06d.rlp.zst", len(e.manifest.Chunks)),
+		First: e.first,
+		Last:  last,
+		Size:  uint64(len(data)),
+		Hash:  crypto.Keccak256Hash(data),
+	}
+	if err := os.WriteFile(filepath.Join(e.dir, chunk.File), data, 0644); err != nil {
+		return err
+	}
+	e.manifest.Chunks = append(e.manifest.Chunks, chunk)
+	e.buf.Reset()
+	e.first = last + 1
+	return nil
+}
@@ -0,0 +1,145 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package chainarchive
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/rawdb"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// importBatchSize is the number of blocks inserted at once.
+const importBatchSize = 2500
+
+// Import verifies the archive in [dir], inserts its blocks into [chain] and
+// accepts them, writing their archived receipts to [db]. If [atomic] is not
+// nil, the atomic transactions are verified against the blocks and the blocks
+// are accepted through it, otherwise they are accepted by the chain only.
+// Every chunk is verified before any of its blocks is inserted, and the
+// progress is kept in [db] after each of them, so that an interrupted import
+// of the same archive resumes from the first chunk not imported.
+func Import(chain *core.BlockChain, db ethdb.Database, atomic AtomicTxs, dir string) error {
+	r, err := Open(dir)
+	if err != nil {
+		return err
+	}
+	manifest := r.Manifest()
+	if chainID := chain.Config().ChainID; manifest.ChainID.Cmp(chainID) != 0 {
+		return fmt.Errorf("archive of chain %d, want %d", manifest.ChainID, chainID)
+	}
+	if genesis := chain.Genesis().Hash(); manifest.Genesis != genesis {
+		return fmt.Errorf("archive of genesis %s, want %s", manifest.Genesis.Hex(), genesis.Hex())
+	}
+	start := 0
+	if hash, next, ok := rawdb.ReadChainImportProgress(db); ok && hash == r.Hash() && next <= uint64(len(manifest.Chunks)) {
+		start = int(next)
+		log.Info("Resuming chain import", "dir", dir, "chunk", start, "chunks", len(manifest.Chunks))
+	} else {
+		rawdb.WriteChainImportProgress(db, r.Hash(), 0)
+		log.Info("Importing chain", "dir", dir, "first", manifest.First, "last", manifest.Last, "chunks", len(manifest.Chunks))
+	}
+	var (
+		begin  = time.Now()
+		logged = time.Now()
+		parent common.Hash
+	)
+	for i := start; i < len(manifest.Chunks); i++ {
+		entries, err := r.ReadChunk(i)
+		if err != nil {
+			return err
+		}
+		if i > start && entries[0].Block.ParentHash() != parent {
+			return fmt.Errorf("block %d does not extend block %d", entries[0].Block.NumberU64(), entries[0].Block.NumberU64()-1)
+		}
+		parent = entries[len(entries)-1].Block.Hash()
+
+		blocks := make(types.Blocks, 0, len(entries))
+		for _, entry := range entries {
+			block := entry.Block
+			if atomic != nil && manifest.AtomicTxs {
+				if err := atomic.Verify(block, entry.AtomicTxs); err != nil {
+					return fmt.Errorf("block %d: invalid atomic transactions: %w", block.NumberU64(), err)
+				}
+			}
+			// The genesis block is only checked against the one of the chain.
+			if block.NumberU64() == 0 {
+				if block.Hash() != manifest.Genesis {
+					return fmt.Errorf("archived genesis %s does not match %s", block.Hash().Hex(), manifest.Genesis.Hex())
+				}
+				continue
+			}
+			blocks = append(blocks, block)
+		}
+		for len(blocks) > 0 {
+			batch := blocks[:min(len(blocks), importBatchSize)]
+			blocks = blocks[len(batch):]
+			if !hasAllBlocks(chain, batch) {
+				if _, err := chain.InsertChain(batch); err != nil {
+					return fmt.Errorf("chunk %d: failed to insert: %w", i, err)
+				}
+			}
+			if err := acceptBlocks(chain, atomic, batch); err != nil {
+				return fmt.Errorf("chunk %d: failed to accept: %w", i, err)
+			}
+		}
+		chain.DrainAcceptorQueue()
+
+		// The archived receipts, verified against the blocks, are kept in
+		// place of the ones of the execution.
+		batch := db.NewBatch()
+		for _, entry := range entries {
+			if entry.Block.NumberU64() > 0 {
+				rawdb.WriteReceipts(batch, entry.Block.Hash(), entry.Block.NumberU64(), entry.Receipts)
+			}
+		}
+		rawdb.WriteChainImportProgress(batch, r.Hash(), uint64(i+1))
+		if err := batch.Write(); err != nil {
+			return fmt.Errorf("chunk %d: failed to write receipts: %w", i, err)
+		}
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Importing chain", "chunk", i+1, "chunks", len(manifest.Chunks), "number", entries[len(entries)-1].Block.NumberU64(), "elapsed", common.PrettyDuration(time.Since(begin)))
+			logged = time.Now()
+		}
+	}
+	rawdb.DeleteChainImportProgress(db)
+	log.Info("Imported chain", "first", manifest.First, "last", manifest.Last, "elapsed", common.PrettyDuration(time.Since(begin)))
+	return nil
+}
+
+// acceptBlocks accepts the blocks of [batch] that are not accepted yet,
+// through [atomic] if it is not nil.
+func acceptBlocks(chain *core.BlockChain, atomic AtomicTxs, batch []*types.Block) error {
+	lastAccepted := chain.LastAcceptedBlock().NumberU64()
+	for _, block := range batch {
+		if block.NumberU64() <= lastAccepted {
+			continue
+		}
+		var err error
+		if atomic != nil {
+			err = atomic.Accept(block)
+		} else {
+			err = chain.Accept(block)
+		}
+		if err != nil {
+			return fmt.Errorf("block %d: %w", block.NumberU64(), err)
+		}
+	}
+	return nil
+}
+
+func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
+	for _, b := range bs {
+		if !chain.HasBlock(b.Hash(), b.NumberU64()) {
+			return false
+		}
+	}
+	return true
+}
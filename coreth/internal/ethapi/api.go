@@ -955,21 +955,27 @@ func (s *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.
 // if statDiff is set, all diff will be applied first and then execute the call
 // message.
 type OverrideAccount struct {
-	Nonce     *hexutil.Uint64              `json:"nonce"`
-	Code      *hexutil.Bytes               `json:"code"`
-	Balance   **hexutil.Big                `json:"balance"`
-	State     *map[common.Hash]common.Hash `json:"state"`
-	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
+	Nonce            *hexutil.Uint64              `json:"nonce"`
+	Code             *hexutil.Bytes               `json:"code"`
+	Balance          **hexutil.Big                `json:"balance"`
+	State            *map[common.Hash]common.Hash `json:"state"`
+	StateDiff        *map[common.Hash]common.Hash `json:"stateDiff"`
+	MovePrecompileTo *common.Address              `json:"movePrecompileToAddress"`
 }
 
 // StateOverride is the collection of overridden accounts.
 type StateOverride map[common.Address]OverrideAccount
 
 // Apply overrides the fields of specified accounts into the given state.
-func (diff *StateOverride) Apply(state *state.StateDB) error {
+// Precompiles that are moved or whose account is overridden are updated in
+// [precompiles]. If it is nil, precompiles cannot be moved.
+func (diff *StateOverride) Apply(state *state.StateDB, precompiles vm.PrecompiledContracts) error {
 	if diff == nil {
 		return nil
 	}
+	if err := diff.ApplyPrecompiles(precompiles); err != nil {
+		return err
+	}
 	for addr, account := range *diff {
 		// Override account nonce.
 		if account.Nonce != nil {
@@ -1005,6 +1011,38 @@ func (diff *StateOverride) Apply(state *state.StateDB) error {
 	return nil
 }
 
+// ApplyPrecompiles updates the precompiles that are moved or whose account is
+// overridden in [precompiles], without overriding the state. If it is nil,
+// precompiles cannot be moved.
+func (diff *StateOverride) ApplyPrecompiles(precompiles vm.PrecompiledContracts) error {
+	if diff == nil {
+		return nil
+	}
+	for addr, account := range *diff {
+		// The code of a moved precompile runs at its new address, the account
+		// at its old address behaves like any other one. If the new address is
+		// another precompile, the latter is replaced.
+		p, isPrecompile := precompiles[addr]
+		if account.MovePrecompileTo != nil {
+			if precompiles == nil {
+				return errors.New("precompiles cannot be moved")
+			}
+			if !isPrecompile {
+				return fmt.Errorf("account %s is not a precompile", addr.Hex())
+			}
+			// Refuse to move a precompile to an account that is overridden too.
+			if _, ok := (*diff)[*account.MovePrecompileTo]; ok {
+				return fmt.Errorf("account %s is already overridden", account.MovePrecompileTo.Hex())
+			}
+			precompiles[*account.MovePrecompileTo] = p
+		}
+		if isPrecompile {
+			delete(precompiles, addr)
+		}
+	}
+	return nil
+}
+
 // BlockOverrides is a set of header fields to override.
 type BlockOverrides struct {
 	Number      *hexutil.Big
@@ -1014,6 +1052,11 @@ type BlockOverrides struct {
 	Coinbase    *common.Address
 	BaseFee     *hexutil.Big
 	BlobBaseFee *hexutil.Big
+
+	// Flare selects the Flare system contract side effects applied after the
+	// call. When set, the applied side effects are reported by the methods
+	// returning the full execution result.
+	Flare *core.FlareSideEffects
 }
 
 // Apply overrides the given header fields into the given block context.
@@ -1044,6 +1087,15 @@ func (diff *BlockOverrides) Apply(blockCtx *vm.BlockContext) {
 	}
 }
 
+// ApplyFlare selects the Flare side effects applied after the given message.
+func (diff *BlockOverrides) ApplyFlare(msg *core.Message) {
+	if diff == nil || diff.Flare == nil {
+		return
+	}
+	sideEffects := *diff.Flare
+	msg.FlareSideEffects = &sideEffects
+}
+
 // ChainContextBackend provides methods required to implement ChainContext.
 type ChainContextBackend interface {
 	Engine() consensus.Engine
@@ -1077,7 +1129,8 @@ func (context *ChainContext) GetHeader(hash common.Hash, number uint64) *types.H
 }
 
 func doCall(ctx context.Context, b Backend, args TransactionArgs, state *state.StateDB, header *types.Header, overrides *StateOverride, blockOverrides *BlockOverrides, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
-	if err := overrides.Apply(state); err != nil {
+	precompiles := vm.ActivePrecompiledContracts(b.ChainConfig().Rules(header.Number, header.Time))
+	if err := overrides.Apply(state, precompiles); err != nil {
 		return nil, err
 	}
 
@@ -1102,7 +1155,9 @@ func doCall(ctx context.Context, b Backend, args TransactionArgs, state *state.S
 	if err != nil {
 		return nil, err
 	}
+	blockOverrides.ApplyFlare(msg)
 	evm := b.GetEVM(ctx, msg, state, header, &vm.Config{NoBaseFee: true}, &blockCtx)
+	evm.SetPrecompiles(precompiles)
 
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -1183,22 +1238,29 @@ func (s *BlockChainAPI) Call(ctx context.Context, args TransactionArgs, blockNrO
 // successfully at block `blockNrOrHash`. It returns error if the transaction would revert, or if
 // there are unexpected failures. The gas limit is capped by both `args.Gas` (if non-nil &
 // non-zero) and `gasCap` (if non-zero).
-func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, gasCap uint64) (hexutil.Uint64, error) {
+func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides, gasCap uint64) (hexutil.Uint64, error) {
 	// Retrieve the base state and mutate it with any overrides
 	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return 0, err
 	}
-	if err = overrides.Apply(state); err != nil {
+	precompiles := vm.ActivePrecompiledContracts(b.ChainConfig().Rules(header.Number, header.Time))
+	if err = overrides.Apply(state, precompiles); err != nil {
 		return 0, err
 	}
 	// Construct the gas estimator option from the user input
 	opts := &gasestimator.Options{
-		Config:     b.ChainConfig(),
-		Chain:      NewChainContext(ctx, b),
-		Header:     header,
-		State:      state,
-		ErrorRatio: estimateGasErrorRatio,
+		Config:      b.ChainConfig(),
+		Chain:       NewChainContext(ctx, b),
+		Header:      header,
+		State:       state,
+		Precompiles: precompiles,
+		ErrorRatio:  estimateGasErrorRatio,
+	}
+	if blockOverrides != nil {
+		blockCtx := core.NewEVMBlockContext(header, opts.Chain, nil)
+		blockOverrides.Apply(&blockCtx)
+		opts.BlockContext = &blockCtx
 	}
 
 	// If the user has not specified a gas limit, use the block gas limit
@@ -1211,6 +1273,7 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 	if err != nil {
 		return 0, err
 	}
+	blockOverrides.ApplyFlare(call)
 	estimate, revert, err := gasestimator.Estimate(ctx, call, opts, gasCap)
 	if err != nil {
 		if len(revert) > 0 {
@@ -1227,12 +1290,12 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 // value is capped by both `args.Gas` (if non-nil & non-zero) and the backend's RPCGasCap
 // configuration (if non-zero).
 // Note: Required blob gas is not computed in this method.
-func (s *BlockChainAPI) EstimateGas(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride) (hexutil.Uint64, error) {
+func (s *BlockChainAPI) EstimateGas(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) (hexutil.Uint64, error) {
 	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
 	if blockNrOrHash != nil {
 		bNrOrHash = *blockNrOrHash
 	}
-	return DoEstimateGas(ctx, s.b, args, bNrOrHash, overrides, s.b.RPCGasCap())
+	return DoEstimateGas(ctx, s.b, args, bNrOrHash, overrides, blockOverrides, s.b.RPCGasCap())
 }
 
 // RPCMarshalHeader converts the given header to the RPC output .
@@ -1710,7 +1773,19 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 
 	// Derive the sender.
 	signer := types.MakeSigner(s.b.ChainConfig(), header.Number, header.Time)
-	return marshalReceipt(receipt, blockHash, blockNumber, signer, tx, int(index)), nil
+	fields := marshalReceipt(receipt, blockHash, blockNumber, signer, tx, int(index))
+
+	// Add the Flare fee treatment, if enabled and recorded.
+	if s.b.FeeTreatmentInReceipts() {
+		treatments, err := s.b.GetFeeTreatments(ctx, blockHash)
+		if err != nil {
+			return nil, err
+		}
+		if treatment := treatments.ByTxIndex(uint(index)); treatment != nil {
+			fields["feeTreatment"] = treatment
+		}
+	}
+	return fields, nil
 }
 
 // marshalReceipt marshals a transaction receipt into a JSON object.
@@ -25,11 +25,13 @@ type DetailedExecutionResult struct {
 	ErrCode    int           `json:"errCode"`    // EVM error code
 	Err        string        `json:"err"`        // Any error encountered during the execution(listed in core/vm/errors.go)
 	ReturnData hexutil.Bytes `json:"returnData"` // Data from evm(function result or data supplied with revert opcode)
+
+	Flare *core.FlareEffects `json:"flare,omitempty"` // Flare side effects applied after the call, if selected by the block overrides
 }
 
 // CallDetailed performs the same call as Call, but returns the full context
-func (s *BlockChainAPI) CallDetailed(ctx context.Context, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) (*DetailedExecutionResult, error) {
-	result, err := DoCall(ctx, s.b, args, blockNrOrHash, overrides, nil, s.b.RPCEVMTimeout(), s.b.RPCGasCap())
+func (s *BlockChainAPI) CallDetailed(ctx context.Context, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) (*DetailedExecutionResult, error) {
+	result, err := DoCall(ctx, s.b, args, blockNrOrHash, overrides, blockOverrides, s.b.RPCEVMTimeout(), s.b.RPCGasCap())
 	if err != nil {
 		return nil, err
 	}
@@ -37,6 +39,7 @@ func (s *BlockChainAPI) CallDetailed(ctx context.Context, args TransactionArgs,
 	reply := &DetailedExecutionResult{
 		UsedGas:    result.UsedGas,
 		ReturnData: result.ReturnData,
+		Flare:      result.FlareEffects,
 	}
 	if result.Err != nil {
 		if err, ok := result.Err.(rpc.Error); ok {
@@ -488,6 +488,7 @@ func (b testBackend) RPCGasCap() uint64                          { return 100000
 func (b testBackend) RPCEVMTimeout() time.Duration               { return time.Second }
 func (b testBackend) RPCTxFeeCap() float64                       { return 0 }
 func (b testBackend) UnprotectedAllowed(*types.Transaction) bool { return false }
+func (b testBackend) FeeTreatmentInReceipts() bool               { return false }
 func (b testBackend) SetHead(number uint64)                      {}
 func (b testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
 	if number == rpc.LatestBlockNumber {
@@ -559,6 +560,9 @@ func (b testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOr
 	panic("only implemented for number")
 }
 func (b testBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) { panic("implement me") }
+func (b testBackend) GetFeeTreatments(ctx context.Context, hash common.Hash) (types.FeeTreatments, error) {
+	panic("implement me")
+}
 func (b testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
 	header, err := b.HeaderByHash(ctx, hash)
 	if header == nil || err != nil {
@@ -728,6 +732,20 @@ func TestEstimateGas(t *testing.T) {
 			},
 			expectErr: core.ErrInsufficientFunds,
 		},
+		// call to the identity precompile moved to another account
+		{
+			blockNumber: rpc.LatestBlockNumber,
+			call: TransactionArgs{
+				From:  &accounts[0].addr,
+				To:    &randomAccounts[1].addr,
+				Input: &hexutil.Bytes{0x12, 0x34},
+			},
+			overrides: StateOverride{
+				common.BytesToAddress([]byte{0x04}): OverrideAccount{MovePrecompileTo: &randomAccounts[1].addr},
+			},
+			expectErr: nil,
+			want:      21050,
+		},
 		// Test for a bug where the gas price was set to zero but the basefee non-zero
 		//
 		// contract BasefeeChecker {
@@ -783,7 +801,7 @@ func TestEstimateGas(t *testing.T) {
 		},
 	}
 	for i, tc := range testSuite {
-		result, err := api.EstimateGas(context.Background(), tc.call, &rpc.BlockNumberOrHash{BlockNumber: &tc.blockNumber}, &tc.overrides)
+		result, err := api.EstimateGas(context.Background(), tc.call, &rpc.BlockNumberOrHash{BlockNumber: &tc.blockNumber}, &tc.overrides, nil)
 		if tc.expectErr != nil {
 			if err == nil {
 				t.Errorf("test %d: want error %v, have nothing", i, tc.expectErr)
@@ -1004,6 +1022,85 @@ func TestCall(t *testing.T) {
 	}
 }
 
+func TestSimulateV1(t *testing.T) {
+	t.Parallel()
+	var (
+		accounts = newAccounts(2)
+		genesis  = &core.Genesis{
+			Config: params.TestFlareChainConfig,
+			Alloc: types.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		identity = common.BytesToAddress([]byte{0x04})
+		moved    = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+		clock    = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	)
+	backend := newTestBackend(t, 1, genesis, dummy.NewCoinbaseFaker(), func(i int, b *core.BlockGen) {})
+	api := NewBlockChainAPI(backend)
+	head := backend.chain.CurrentBlock()
+	later := hexutil.Uint64(head.Time + 100)
+
+	results, err := api.SimulateV1(context.Background(), simOpts{
+		TraceTransfers: true,
+		BlockStateCalls: []simBlock{
+			{
+				Calls: []TransactionArgs{{
+					From:  &accounts[0].addr,
+					To:    &accounts[1].addr,
+					Value: (*hexutil.Big)(big.NewInt(1000)),
+				}},
+			},
+			{
+				// The third block leaves a gap filled with an empty block.
+				BlockOverrides: &BlockOverrides{
+					Number: (*hexutil.Big)(new(big.Int).Add(head.Number, big.NewInt(3))),
+					Time:   &later,
+				},
+				StateOverrides: &StateOverride{
+					identity: {MovePrecompileTo: &moved},
+					clock:    {Code: hex2Bytes("4260005260206000f3")},
+				},
+				Calls: []TransactionArgs{
+					{From: &accounts[1].addr, To: &moved, Input: &hexutil.Bytes{0x12, 0x34}},
+					{From: &accounts[1].addr, To: &identity, Input: &hexutil.Bytes{0x12, 0x34}},
+					{From: &accounts[1].addr, To: &clock},
+				},
+			},
+		},
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	// The transfer is reported as an ERC20 Transfer log.
+	calls := results[0]["calls"].([]simCallResult)
+	require.Len(t, calls, 1)
+	require.Equal(t, hexutil.Uint64(types.ReceiptStatusSuccessful), calls[0].Status)
+	require.Len(t, calls[0].Logs, 1)
+	require.Equal(t, transferAddress, calls[0].Logs[0].Address)
+	require.Equal(t, transferTopic, calls[0].Logs[0].Topics[0])
+	require.Equal(t, common.BigToHash(big.NewInt(1000)).Bytes(), calls[0].Logs[0].Data)
+	require.Equal(t, results[0]["hash"], calls[0].Receipt["blockHash"])
+	require.Equal(t, accounts[0].addr, calls[0].Receipt["from"])
+
+	require.Empty(t, results[1]["calls"])
+	require.Equal(t, results[1]["hash"], results[2]["parentHash"])
+	require.Equal(t, later, results[2]["timestamp"])
+
+	// The identity precompile runs at its new address only.
+	calls = results[2]["calls"].([]simCallResult)
+	require.Len(t, calls, 3)
+	require.Equal(t, hexutil.Bytes{0x12, 0x34}, calls[0].ReturnValue)
+	require.Empty(t, calls[1].ReturnValue)
+	require.Equal(t, common.BigToHash(new(big.Int).SetUint64(uint64(later))).Bytes(), []byte(calls[2].ReturnValue))
+
+	// Block numbers must increase.
+	_, err = api.SimulateV1(context.Background(), simOpts{
+		BlockStateCalls: []simBlock{{BlockOverrides: &BlockOverrides{Number: (*hexutil.Big)(head.Number)}}},
+	}, nil)
+	require.ErrorContains(t, err, "block numbers must be in order")
+}
+
 func TestSignTransaction(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
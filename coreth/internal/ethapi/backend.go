@@ -62,6 +62,7 @@ type Backend interface {
 	RPCTxFeeCap() float64         // global tx fee cap for all transaction related APIs
 
 	UnprotectedAllowed(tx *types.Transaction) bool // allows only for EIP155 transactions.
+	FeeTreatmentInReceipts() bool                  // adds the Flare fee treatment to transaction receipts
 
 	// Blockchain API
 	HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error)
@@ -75,6 +76,7 @@ type Backend interface {
 	StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error)
 	StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error)
 	GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error)
+	GetFeeTreatments(ctx context.Context, hash common.Hash) (types.FeeTreatments, error)
 	GetEVM(ctx context.Context, msg *core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config, blockCtx *vm.BlockContext) *vm.EVM
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
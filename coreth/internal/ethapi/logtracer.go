@@ -0,0 +1,184 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package ethapi
+
+import (
+	"math/big"
+
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// keccak256("Transfer(address,address,uint256)")
+	transferTopic = common.HexToHash("ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	// ERC-7528
+	transferAddress = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+)
+
+// tracer records the logs of a simulated call, dropping the ones of reverted
+// call frames. If traceTransfers is set, value transfers are recorded as ERC20
+// Transfer logs emitted by the ERC-7528 native asset address:
+//   - the value of the transaction and of every call
+//   - the balance sent by a self destruct
+//   - the inflation minted by the Flare daemon
+//
+// Logs of the Flare daemon call are recorded, the ones of the other Flare
+// system calls are not traced.
+type tracer struct {
+	// logs keeps the logs of all open call frames, so that the ones of
+	// failed calls can be dropped.
+	logs           [][]*types.Log
+	count          int
+	system         int // number of logs of the transaction before the last system call
+	traceTransfers bool
+	blockNumber    uint64
+	txHash         common.Hash
+	txIdx          uint
+}
+
+func newTracer(traceTransfers bool, blockNumber uint64) *tracer {
+	return &tracer{
+		traceTransfers: traceTransfers,
+		blockNumber:    blockNumber,
+	}
+}
+
+// reset prepares the tracer for the next call of the block.
+func (t *tracer) reset(txHash common.Hash, txIdx uint) {
+	t.logs = nil
+	t.txHash = txHash
+	t.txIdx = txIdx
+}
+
+// Logs returns the logs of the last traced call.
+func (t *tracer) Logs() []*types.Log {
+	if len(t.logs) == 0 {
+		return []*types.Log{}
+	}
+	return t.logs[0]
+}
+
+func (t *tracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *tracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *tracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.logs = append(t.logs, make([]*types.Log, 0))
+	if t.traceTransfers && value != nil && value.Sign() > 0 {
+		t.captureTransfer(from, to, value)
+	}
+}
+
+func (t *tracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	// Only the top call frame is left, so its logs are kept as the ones of
+	// the transaction unless it failed.
+	if err != nil && len(t.logs) > 0 {
+		t.logs[0] = t.logs[0][:0]
+	}
+}
+
+func (t *tracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.logs = append(t.logs, make([]*types.Log, 0))
+	if t.traceTransfers && typ != vm.DELEGATECALL && value != nil && value.Sign() > 0 {
+		t.captureTransfer(from, to, value)
+	}
+}
+
+func (t *tracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	size := len(t.logs)
+	if size <= 1 {
+		return
+	}
+	// pop call
+	call := t.logs[size-1]
+	t.logs = t.logs[:size-1]
+	size--
+
+	// Clear logs if call failed.
+	if err == nil {
+		t.logs[size-1] = append(t.logs[size-1], call...)
+	}
+}
+
+func (t *tracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || op < vm.LOG0 || op > vm.LOG4 || len(t.logs) == 0 {
+		return
+	}
+	var (
+		stack  = scope.Stack.Data()
+		topics = make([]common.Hash, int(op-vm.LOG0))
+	)
+	if len(stack) < 2+len(topics) {
+		return
+	}
+	offset, size := scope.Stack.Back(0), scope.Stack.Back(1)
+	if !offset.IsUint64() || !size.IsUint64() {
+		return
+	}
+	for i := range topics {
+		topics[i] = common.Hash(scope.Stack.Back(2 + i).Bytes32())
+	}
+	// The memory of the log data has already been expanded.
+	data := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
+	t.captureLog(scope.Contract.Address(), topics, data)
+}
+
+func (t *tracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureSystemCallStart is called when the EVM calls the Flare daemon after
+// the top call frame has ended. Its logs are traced as the ones of a nested
+// call.
+func (t *tracer) CaptureSystemCallStart(env *vm.EVM, from common.Address, to common.Address, input []byte, gas uint64) {
+	if len(t.logs) == 0 {
+		t.logs = append(t.logs, make([]*types.Log, 0))
+	}
+	t.system = len(t.logs[0])
+	t.logs = append(t.logs, make([]*types.Log, 0))
+}
+
+// CaptureSystemCallEnd is called when the Flare daemon call finishes.
+func (t *tracer) CaptureSystemCallEnd(output []byte, gasUsed uint64, err error) {
+	t.CaptureExit(output, gasUsed, err)
+}
+
+// CaptureSystemMint is called when the daemon minted [amount] on to [to],
+// which is traced as a transfer from the zero address.
+func (t *tracer) CaptureSystemMint(to common.Address, amount *big.Int) {
+	if t.traceTransfers && len(t.logs) > 0 && amount.Sign() > 0 {
+		t.captureTransfer(common.Address{}, to, amount)
+	}
+}
+
+// CaptureSystemRevert is called when the daemon call was reverted after it
+// had finished, which drops its logs.
+func (t *tracer) CaptureSystemRevert(err error) {
+	if len(t.logs) > 0 && t.system <= len(t.logs[0]) {
+		t.logs[0] = t.logs[0][:t.system]
+	}
+}
+
+func (t *tracer) captureLog(address common.Address, topics []common.Hash, data []byte) {
+	t.logs[len(t.logs)-1] = append(t.logs[len(t.logs)-1], &types.Log{
+		Address:     address,
+		Topics:      topics,
+		Data:        data,
+		BlockNumber: t.blockNumber,
+		TxHash:      t.txHash,
+		TxIndex:     t.txIdx,
+		Index:       uint(t.count),
+	})
+	t.count++
+}
+
+func (t *tracer) captureTransfer(from, to common.Address, value *big.Int) {
+	topics := []common.Hash{
+		transferTopic,
+		common.BytesToHash(from.Bytes()),
+		common.BytesToHash(to.Bytes()),
+	}
+	t.captureLog(transferAddress, topics, common.BigToHash(value).Bytes())
+}
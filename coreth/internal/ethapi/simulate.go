@@ -0,0 +1,438 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/params"
+	"github.com/ava-labs/coreth/rpc"
+	"github.com/ava-labs/coreth/trie"
+	"github.com/ava-labs/coreth/vmerrs"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks, including the empty
+	// ones filling the gaps between the requested block numbers, that can be
+	// simulated by a single call.
+	maxSimulateBlocks = 256
+
+	// timestampIncrement is the default increment between the timestamps of
+	// consecutive simulated blocks.
+	timestampIncrement = 2
+)
+
+// simBlock is a block of calls simulated by eth_simulateV1. The header fields
+// are set by the block overrides and the state overrides are applied before
+// its calls.
+type simBlock struct {
+	BlockOverrides *BlockOverrides
+	StateOverrides *StateOverride
+	Calls          []TransactionArgs
+}
+
+// simOpts are the inputs of eth_simulateV1.
+type simOpts struct {
+	BlockStateCalls        []simBlock
+	TraceTransfers         bool
+	Validation             bool
+	ReturnFullTransactions bool
+}
+
+// simCallResult is the outcome of a simulated call.
+type simCallResult struct {
+	ReturnValue hexutil.Bytes          `json:"returnData"`
+	Logs        []*types.Log           `json:"logs"`
+	GasUsed     hexutil.Uint64         `json:"gasUsed"`
+	Status      hexutil.Uint64         `json:"status"`
+	Error       *callError             `json:"error,omitempty"`
+	Receipt     map[string]interface{} `json:"receipt"`
+	Flare       *core.FlareEffects     `json:"flare,omitempty"` // nil unless selected by the block overrides
+}
+
+// simulator runs the calls of consecutive simulated blocks on top of a base
+// block, sharing a single state.
+type simulator struct {
+	b              Backend
+	state          *state.StateDB
+	base           *types.Header
+	chainConfig    *params.ChainConfig
+	budget         uint64 // gas left for the remaining calls of the simulation
+	timeout        time.Duration
+	traceTransfers bool
+	validate       bool
+	fullTx         bool
+}
+
+// SimulateV1 executes series of transactions on top of a base state. The
+// transactions are packed into blocks. For each block, block header fields can
+// be overridden. The state can also be overridden prior to execution of each
+// block.
+//
+// The coinbase of the base block is kept unless overridden. On Songbird, the
+// fees are burnt on the coinbase and calls fail if it is overridden with
+// another address than the burn address.
+//
+// Note, this function doesn't make any changes in the state/blockchain and is
+// useful to execute and retrieve values.
+func (s *BlockChainAPI) SimulateV1(ctx context.Context, opts simOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, &invalidParamsError{message: "empty input"}
+	} else if len(opts.BlockStateCalls) > maxSimulateBlocks {
+		return nil, &clientLimitExceededError{message: "too many blocks"}
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	state, base, err := s.b.StateAndHeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	budget := s.b.RPCGasCap()
+	if budget == 0 {
+		budget = math.MaxUint64
+	}
+	sim := &simulator{
+		b:              s.b,
+		state:          state,
+		base:           base,
+		chainConfig:    s.b.ChainConfig(),
+		budget:         budget,
+		timeout:        s.b.RPCEVMTimeout(),
+		traceTransfers: opts.TraceTransfers,
+		validate:       opts.Validation,
+		fullTx:         opts.ReturnFullTransactions,
+	}
+	return sim.execute(ctx, opts.BlockStateCalls)
+}
+
+// execute runs the calls of all blocks and returns the simulated blocks.
+func (sim *simulator) execute(ctx context.Context, blocks []simBlock) ([]map[string]interface{}, error) {
+	// Setup context so it may be cancelled when the simulation has completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	var cancel context.CancelFunc
+	if sim.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, sim.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	blocks, err := sim.sanitizeChain(blocks)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		results = make([]map[string]interface{}, len(blocks))
+		headers = make([]*types.Header, 0, len(blocks))
+		parent  = sim.base
+	)
+	for bi, block := range blocks {
+		result, header, err := sim.processBlock(ctx, &block, parent, headers)
+		if err != nil {
+			return nil, err
+		}
+		results[bi] = result
+		headers = append(headers, header)
+		parent = header
+	}
+	return results, nil
+}
+
+// processBlock runs the calls of [block] on top of [parent] and returns the
+// simulated block with the results of its calls.
+func (sim *simulator) processBlock(ctx context.Context, block *simBlock, parent *types.Header, headers []*types.Header) (map[string]interface{}, *types.Header, error) {
+	header, err := sim.makeHeader(block.BlockOverrides, parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	blockContext := core.NewEVMBlockContext(header, &simChainContext{ChainContext: NewChainContext(ctx, sim.b), headers: headers}, nil)
+	if block.BlockOverrides.BlobBaseFee != nil {
+		blockContext.BlobBaseFee = block.BlockOverrides.BlobBaseFee.ToInt()
+	}
+	// Apply the upgrades activated since the parent before the state overrides.
+	if err := core.ApplyUpgrades(sim.chainConfig, &parent.Time, &blockContext, sim.state); err != nil {
+		return nil, nil, err
+	}
+	precompiles := vm.ActivePrecompiledContracts(sim.chainConfig.Rules(header.Number, header.Time))
+	if err := block.StateOverrides.Apply(sim.state, precompiles); err != nil {
+		return nil, nil, err
+	}
+	var (
+		gasUsed  uint64
+		txs      = make([]*types.Transaction, len(block.Calls))
+		senders  = make([]common.Address, len(block.Calls))
+		receipts = make([]*types.Receipt, len(block.Calls))
+		results  = make([]simCallResult, len(block.Calls))
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		vmConfig = vm.Config{NoBaseFee: !sim.validate}
+		logs     *tracer
+	)
+	if sim.traceTransfers {
+		logs = newTracer(true, header.Number.Uint64())
+		vmConfig.Tracer = logs
+		vmConfig.TraceSystemCalls = true
+	}
+	for i, call := range block.Calls {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		if err := sim.sanitizeCall(&call, header, gasUsed); err != nil {
+			return nil, nil, err
+		}
+		tx := call.toTransaction()
+		txs[i], senders[i] = tx, call.from()
+		if logs != nil {
+			logs.reset(tx.Hash(), uint(i))
+		}
+		msg, err := call.ToMessage(0, header.BaseFee)
+		if err != nil {
+			return nil, nil, err
+		}
+		msg.Nonce = uint64(*call.Nonce)
+		msg.SkipAccountChecks = !sim.validate
+		block.BlockOverrides.ApplyFlare(msg)
+
+		sim.state.SetTxContext(tx.Hash(), i)
+		evm := vm.NewEVM(blockContext, core.NewEVMTxContext(msg), sim.state, sim.chainConfig, vmConfig)
+		evm.SetPrecompiles(precompiles)
+		stop := context.AfterFunc(ctx, evm.Cancel)
+		result, err := core.ApplyMessage(evm, msg, gp)
+		stop()
+		if err != nil {
+			return nil, nil, txValidationError(err)
+		}
+		if err := sim.state.Error(); err != nil {
+			return nil, nil, err
+		}
+		if evm.Cancelled() {
+			return nil, nil, fmt.Errorf("execution aborted (timeout = %v)", sim.timeout)
+		}
+		sim.state.Finalise(true)
+		gasUsed += result.UsedGas
+		sim.budget -= result.UsedGas
+
+		receipt := &types.Receipt{
+			Type:              tx.Type(),
+			CumulativeGasUsed: gasUsed,
+			TxHash:            tx.Hash(),
+			GasUsed:           result.UsedGas,
+			EffectiveGasPrice: msg.GasPrice,
+			BlockNumber:       header.Number,
+			TransactionIndex:  uint(i),
+		}
+		if msg.To == nil {
+			receipt.ContractAddress = crypto.CreateAddress(msg.From, msg.Nonce)
+		}
+		receipt.Logs = sim.state.GetLogs(tx.Hash(), header.Number.Uint64(), common.Hash{})
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		receipts[i] = receipt
+
+		callResult := simCallResult{
+			ReturnValue: result.Return(),
+			Logs:        receipt.Logs,
+			GasUsed:     hexutil.Uint64(result.UsedGas),
+			Status:      hexutil.Uint64(types.ReceiptStatusSuccessful),
+			Flare:       result.FlareEffects,
+		}
+		if logs != nil {
+			callResult.Logs = logs.Logs()
+		}
+		if result.Failed() {
+			receipt.Status = types.ReceiptStatusFailed
+			callResult.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			if errors.Is(result.Err, vmerrs.ErrExecutionReverted) {
+				// If the result contains a revert reason, try to unpack it.
+				revertErr := newRevertError(result.Revert())
+				callResult.Error = &callError{Message: revertErr.Error(), Code: errCodeReverted, Data: revertErr.reason}
+			} else {
+				callResult.Error = &callError{Message: result.Err.Error(), Code: errCodeVMError}
+			}
+		} else {
+			receipt.Status = types.ReceiptStatusSuccessful
+		}
+		results[i] = callResult
+	}
+	header.GasUsed = gasUsed
+	header.Root = sim.state.IntermediateRoot(sim.chainConfig.IsEIP158(header.Number))
+	b := types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil))
+	hash := b.Hash()
+
+	// The hash of the block is only known once all of its calls ran.
+	signer := types.MakeSigner(sim.chainConfig, header.Number, header.Time)
+	for i, receipt := range receipts {
+		receipt.BlockHash = hash
+		for _, log := range receipt.Logs {
+			log.BlockHash = hash
+		}
+		for _, log := range results[i].Logs {
+			log.BlockHash = hash
+		}
+		// The calls are not signed, so their sender is set explicitly.
+		results[i].Receipt = marshalReceipt(receipt, hash, header.Number.Uint64(), signer, txs[i], i)
+		results[i].Receipt["from"] = senders[i]
+	}
+	fields := RPCMarshalBlock(b, true, sim.fullTx, sim.chainConfig)
+	if sim.fullTx {
+		for i, tx := range fields["transactions"].([]interface{}) {
+			tx.(*RPCTransaction).From = senders[i]
+		}
+	}
+	fields["calls"] = results
+	return fields, b.Header(), nil
+}
+
+// sanitizeCall fills in the defaults of [call] for the state and gas left in
+// the block.
+func (sim *simulator) sanitizeCall(call *TransactionArgs, header *types.Header, gasUsed uint64) error {
+	if call.Nonce == nil {
+		nonce := sim.state.GetNonce(call.from())
+		call.Nonce = (*hexutil.Uint64)(&nonce)
+	}
+	// Let the call run wild unless explicitly specified.
+	if call.Gas == nil {
+		remaining := header.GasLimit - gasUsed
+		call.Gas = (*hexutil.Uint64)(&remaining)
+	}
+	if gasUsed+uint64(*call.Gas) > header.GasLimit {
+		return &blockGasLimitReachedError{fmt.Sprintf("block gas limit reached: %d >= %d", gasUsed, header.GasLimit)}
+	}
+	if sim.budget == 0 {
+		return &clientLimitExceededError{message: "gas budget of the simulation exhausted"}
+	}
+	return call.callDefaults(sim.budget, header.BaseFee, sim.chainConfig.ChainID)
+}
+
+// makeHeader returns the header of the simulated block following [parent]
+// with the given overrides. The number and time of the block must be set.
+func (sim *simulator) makeHeader(overrides *BlockOverrides, parent *types.Header) (*types.Header, error) {
+	header := &types.Header{
+		ParentHash:  parent.Hash(),
+		UncleHash:   types.EmptyUncleHash,
+		Coinbase:    sim.base.Coinbase,
+		Difficulty:  new(big.Int).Set(sim.base.Difficulty),
+		Number:      new(big.Int).Set(overrides.Number.ToInt()),
+		GasLimit:    sim.base.GasLimit,
+		Time:        uint64(*overrides.Time),
+		ExtDataHash: types.EmptyExtDataHash,
+	}
+	if overrides.Coinbase != nil {
+		header.Coinbase = *overrides.Coinbase
+	}
+	if overrides.Difficulty != nil {
+		header.Difficulty = new(big.Int).Set(overrides.Difficulty.ToInt())
+	}
+	if overrides.GasLimit != nil {
+		header.GasLimit = uint64(*overrides.GasLimit)
+	}
+	if sim.chainConfig.IsApricotPhase3(header.Time) {
+		// The fee window is always rolled, so that the base fee of the next
+		// block can be computed.
+		extra, baseFee, err := dummy.CalcBaseFee(sim.chainConfig, parent, header.Time)
+		if err != nil {
+			return nil, err
+		}
+		header.Extra = extra
+		switch {
+		case overrides.BaseFee != nil:
+			header.BaseFee = new(big.Int).Set(overrides.BaseFee.ToInt())
+		case sim.validate:
+			header.BaseFee = baseFee
+		default:
+			// Without validation the calls are not required to pay the base fee.
+			header.BaseFee = new(big.Int)
+		}
+	}
+	if sim.chainConfig.IsApricotPhase4(header.Time) {
+		header.ExtDataGasUsed = new(big.Int)
+		header.BlockGasCost = new(big.Int)
+	}
+	if sim.chainConfig.IsCancun(header.Number, header.Time) {
+		var excessBlobGas, blobGasUsed uint64
+		header.ExcessBlobGas = &excessBlobGas
+		header.BlobGasUsed = &blobGasUsed
+		header.ParentBeaconRoot = &common.Hash{}
+	}
+	return header, nil
+}
+
+// sanitizeChain sets the number and time of the blocks that do not override
+// them and checks that both increase. Gaps between block numbers are filled
+// with empty blocks.
+func (sim *simulator) sanitizeChain(blocks []simBlock) ([]simBlock, error) {
+	var (
+		res           = make([]simBlock, 0, len(blocks))
+		base          = sim.base
+		prevNumber    = base.Number
+		prevTimestamp = base.Time
+	)
+	for _, block := range blocks {
+		overrides := new(BlockOverrides)
+		if block.BlockOverrides != nil {
+			*overrides = *block.BlockOverrides
+		}
+		block.BlockOverrides = overrides
+		if overrides.Number == nil {
+			n := new(big.Int).Add(prevNumber, common.Big1)
+			overrides.Number = (*hexutil.Big)(n)
+		}
+		number := overrides.Number.ToInt()
+		if number.Cmp(prevNumber) <= 0 {
+			return nil, &invalidBlockNumberError{fmt.Sprintf("block numbers must be in order: %d <= %d", number, prevNumber)}
+		}
+		if total := new(big.Int).Sub(number, base.Number); total.Cmp(big.NewInt(maxSimulateBlocks)) > 0 {
+			return nil, &clientLimitExceededError{message: "too many blocks"}
+		}
+		// Fill the gap with empty blocks.
+		for n := new(big.Int).Add(prevNumber, common.Big1); n.Cmp(number) < 0; n = new(big.Int).Add(n, common.Big1) {
+			t := prevTimestamp + timestampIncrement
+			res = append(res, simBlock{BlockOverrides: &BlockOverrides{Number: (*hexutil.Big)(n), Time: (*hexutil.Uint64)(&t)}})
+			prevTimestamp = t
+		}
+		prevNumber = number
+		if overrides.Time == nil {
+			t := prevTimestamp + timestampIncrement
+			overrides.Time = (*hexutil.Uint64)(&t)
+		} else if t := uint64(*overrides.Time); t < prevTimestamp {
+			// Blocks can share a timestamp, as on the chain.
+			return nil, &invalidBlockTimestampError{fmt.Sprintf("block timestamps must be in order: %d < %d", t, prevTimestamp)}
+		}
+		prevTimestamp = uint64(*overrides.Time)
+		res = append(res, block)
+	}
+	return res, nil
+}
+
+// simChainContext resolves the headers of the simulated blocks preceding the
+// current one, so that BLOCKHASH returns their hash.
+type simChainContext struct {
+	*ChainContext
+	headers []*types.Header
+}
+
+func (c *simChainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	for _, header := range c.headers {
+		if header.Number.Uint64() == number {
+			if header.Hash() != hash {
+				return nil
+			}
+			return header
+		}
+	}
+	return c.ChainContext.GetHeader(hash, number)
+}
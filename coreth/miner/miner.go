@@ -49,6 +49,12 @@ type Backend interface {
 type Config struct {
 	Etherbase                    common.Address `toml:",omitempty"` // Public address for block mining rewards
 	TestOnlyAllowDuplicateBlocks bool           // Allow mining of duplicate blocks (used in tests only)
+	PrioritisedGas               uint64         // Block gas reserved for pending prioritised contract calls
+}
+
+// DefaultConfig contains the default configurations for mining.
+var DefaultConfig = Config{
+	PrioritisedGas: 3_000_000,
 }
 
 type Miner struct {
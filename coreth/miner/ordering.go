@@ -38,9 +38,10 @@ import (
 
 // txWithMinerFee wraps a transaction with its gas price or effective miner gasTipCap
 type txWithMinerFee struct {
-	tx   *txpool.LazyTransaction
-	from common.Address
-	fees *uint256.Int
+	tx          *txpool.LazyTransaction
+	from        common.Address
+	fees        *uint256.Int
+	prioritised bool // Whether the transaction belongs to the prioritised lane
 }
 
 // newTxWithMinerFee creates a wrapped transaction, calculating the effective
@@ -70,6 +71,10 @@ type txByPriceAndTime []*txWithMinerFee
 
 func (s txByPriceAndTime) Len() int { return len(s) }
 func (s txByPriceAndTime) Less(i, j int) bool {
+	// Transactions in the prioritised lane go before any others
+	if s[i].prioritised != s[j].prioritised {
+		return s[i].prioritised
+	}
 	// If the prices are equal, use the time the transaction was first seen for
 	// deterministic sorting
 	cmp := s[i].fees.Cmp(s[j].fees)
@@ -97,10 +102,11 @@ func (s *txByPriceAndTime) Pop() interface{} {
 // transactions in a profit-maximizing sorted order, while supporting removing
 // entire batches of transactions for non-executable accounts.
 type transactionsByPriceAndNonce struct {
-	txs     map[common.Address][]*txpool.LazyTransaction // Per account nonce-sorted list of transactions
-	heads   txByPriceAndTime                             // Next transaction for each unique account (price heap)
-	signer  types.Signer                                 // Signer for the set of transactions
-	baseFee *uint256.Int                                 // Current base fee
+	txs        map[common.Address][]*txpool.LazyTransaction // Per account nonce-sorted list of transactions
+	heads      txByPriceAndTime                             // Next transaction for each unique account (price heap)
+	signer     types.Signer                                 // Signer for the set of transactions
+	baseFee    *uint256.Int                                 // Current base fee
+	prioritise func(tx *types.Transaction) bool             // Classifier of the prioritised lane, nil if there is none
 }
 
 // newTransactionsByPriceAndNonce creates a transaction set that can retrieve
@@ -109,31 +115,48 @@ type transactionsByPriceAndNonce struct {
 // Note, the input map is reowned so the caller should not interact any more with
 // if after providing it to the constructor.
 func newTransactionsByPriceAndNonce(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, baseFee *big.Int) *transactionsByPriceAndNonce {
+	return newPrioritisedTransactionsByPriceAndNonce(signer, txs, baseFee, nil)
+}
+
+// newPrioritisedTransactionsByPriceAndNonce creates a transaction set like
+// newTransactionsByPriceAndNonce, which additionally retrieves the transactions
+// accepted by prioritise before all others.
+func newPrioritisedTransactionsByPriceAndNonce(signer types.Signer, txs map[common.Address][]*txpool.LazyTransaction, baseFee *big.Int, prioritise func(tx *types.Transaction) bool) *transactionsByPriceAndNonce {
 	// Convert the basefee from header format to uint256 format
 	var baseFeeUint *uint256.Int
 	if baseFee != nil {
 		baseFeeUint = uint256.MustFromBig(baseFee)
 	}
+	t := &transactionsByPriceAndNonce{
+		txs:        txs,
+		signer:     signer,
+		baseFee:    baseFeeUint,
+		prioritise: prioritise,
+	}
 	// Initialize a price and received time based heap with the head transactions
-	heads := make(txByPriceAndTime, 0, len(txs))
+	t.heads = make(txByPriceAndTime, 0, len(txs))
 	for from, accTxs := range txs {
-		wrapped, err := newTxWithMinerFee(accTxs[0], from, baseFeeUint)
+		wrapped, err := t.wrap(accTxs[0], from)
 		if err != nil {
 			delete(txs, from)
 			continue
 		}
-		heads = append(heads, wrapped)
+		t.heads = append(t.heads, wrapped)
 		txs[from] = accTxs[1:]
 	}
-	heap.Init(&heads)
-
-	// Assemble and return the transaction set
-	return &transactionsByPriceAndNonce{
-		txs:     txs,
-		heads:   heads,
-		signer:  signer,
-		baseFee: baseFeeUint,
+	heap.Init(&t.heads)
+
+	return t
+}
+
+// wrap calculates the miner fee of a transaction and places it into its lane.
+func (t *transactionsByPriceAndNonce) wrap(tx *txpool.LazyTransaction, from common.Address) (*txWithMinerFee, error) {
+	wrapped, err := newTxWithMinerFee(tx, from, t.baseFee)
+	if err != nil {
+		return nil, err
 	}
+	wrapped.prioritised = t.prioritise != nil && tx.Tx != nil && t.prioritise(tx.Tx)
+	return wrapped, nil
 }
 
 // Peek returns the next transaction by price.
@@ -144,11 +167,16 @@ func (t *transactionsByPriceAndNonce) Peek() (*txpool.LazyTransaction, *uint256.
 	return t.heads[0].tx, t.heads[0].fees
 }
 
+// PeekPrioritised returns whether the next transaction belongs to the prioritised lane.
+func (t *transactionsByPriceAndNonce) PeekPrioritised() bool {
+	return len(t.heads) > 0 && t.heads[0].prioritised
+}
+
 // Shift replaces the current best head with the next one from the same account.
 func (t *transactionsByPriceAndNonce) Shift() {
 	acc := t.heads[0].from
 	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
-		if wrapped, err := newTxWithMinerFee(txs[0], acc, t.baseFee); err == nil {
+		if wrapped, err := t.wrap(txs[0], acc); err == nil {
 			t.heads[0], t.txs[acc] = wrapped, txs[1:]
 			heap.Fix(&t.heads, 0)
 			return
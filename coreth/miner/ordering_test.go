@@ -204,3 +204,63 @@ func TestTransactionTimeSort(t *testing.T) {
 		}
 	}
 }
+
+// Tests that transactions in the prioritised lane are retrieved before all
+// others, regardless of their price, while still honouring the nonce order.
+func TestTransactionPrioritisedSort(t *testing.T) {
+	t.Parallel()
+	// Generate a batch of accounts to start with
+	keys := make([]*ecdsa.PrivateKey, 5)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	signer := types.HomesteadSigner{}
+	prioritised := common.Address{0x01}
+
+	// Generate a batch of transactions, the cheapest ones calling the prioritised address
+	groups := map[common.Address][]*txpool.LazyTransaction{}
+	for start, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		for i := 0; i < 2; i++ {
+			to, price := common.Address{}, big.NewInt(int64(100+start))
+			if start%2 == 0 {
+				to, price = prioritised, big.NewInt(int64(1+start))
+			}
+			tx, _ := types.SignTx(types.NewTransaction(uint64(i), to, big.NewInt(100), 100, price, nil), signer, key)
+			groups[addr] = append(groups[addr], &txpool.LazyTransaction{
+				Hash:      tx.Hash(),
+				Tx:        tx,
+				Time:      tx.Time(),
+				GasFeeCap: uint256.MustFromBig(tx.GasFeeCap()),
+				GasTipCap: uint256.MustFromBig(tx.GasTipCap()),
+				Gas:       tx.Gas(),
+				BlobGas:   tx.BlobGas(),
+			})
+		}
+	}
+	txset := newPrioritisedTransactionsByPriceAndNonce(signer, groups, nil, func(tx *types.Transaction) bool {
+		return *tx.To() == prioritised
+	})
+
+	txs := types.Transactions{}
+	for tx, _ := txset.Peek(); tx != nil; tx, _ = txset.Peek() {
+		if lane := txset.PeekPrioritised(); lane != (*tx.Tx.To() == prioritised) {
+			t.Errorf("lane mismatch for tx %x: have %v", tx.Hash, lane)
+		}
+		txs = append(txs, tx.Tx)
+		txset.Shift()
+	}
+	if len(txs) != 2*len(keys) {
+		t.Fatalf("expected %d transactions, found %d", 2*len(keys), len(txs))
+	}
+	// The prioritised lane goes first, each lane is sorted by price
+	lanes := (len(keys) + 1) / 2 * 2
+	for i, txi := range txs {
+		if inLane := *txi.To() == prioritised; inLane != (i < lanes) {
+			t.Errorf("tx #%d out of lane: prioritised %v", i, inLane)
+		}
+		if i+1 < len(txs) && *txs[i+1].To() == *txi.To() && txi.GasPrice().Cmp(txs[i+1].GasPrice()) < 0 {
+			t.Errorf("invalid gasprice ordering: tx #%d (P=%v) < tx #%d (P=%v)", i, txi.GasPrice(), i+1, txs[i+1].GasPrice())
+		}
+	}
+}
@@ -46,6 +46,7 @@ import (
 	"github.com/ava-labs/coreth/core/txpool"
 	"github.com/ava-labs/coreth/core/types"
 	"github.com/ava-labs/coreth/core/vm"
+	"github.com/ava-labs/coreth/metrics"
 	"github.com/ava-labs/coreth/params"
 	"github.com/ava-labs/coreth/precompile/precompileconfig"
 	"github.com/ava-labs/coreth/predicate"
@@ -61,6 +62,13 @@ const (
 	targetTxsSize = 1792 * units.KiB
 )
 
+var (
+	// prioritised lane metrics of the last built block, see core.IsPrioritisedTransaction
+	prioritisedReservedGauge = metrics.NewRegisteredGauge("miner/prioritised/reserved", nil)
+	prioritisedTxsGauge      = metrics.NewRegisteredGauge("miner/prioritised/txs", nil)
+	prioritisedGasGauge      = metrics.NewRegisteredGauge("miner/prioritised/gas", nil)
+)
+
 // environment is the worker's current environment and holds all of the current state information.
 type environment struct {
 	signer  types.Signer
@@ -84,6 +92,11 @@ type environment struct {
 	// way that the gas pool and state is reset.
 	predicateResults *predicate.Results
 
+	prioritise       func(tx *types.Transaction) bool // Classifier of the prioritised lane
+	prioritisedGas   uint64                           // Block gas still reserved for the prioritised lane
+	prioritisedTxs   int                              // Number of prioritised transactions in the block
+	prioritisedUsage uint64                           // Gas used by the prioritised transactions in the block
+
 	start time.Time // Time that block building began
 }
 
@@ -247,6 +260,11 @@ func (w *worker) commitNewWork(predicateContext *precompileconfig.PredicateConte
 	filter.OnlyPlainTxs, filter.OnlyBlobTxs = true, false
 	pendingPlainTxs := w.eth.TxPool().Pending(filter)
 
+	// Reserve block gas for the pending prioritised contract calls, so that the
+	// other transactions cannot crowd them out of the block.
+	env.prioritisedGas = w.prioritisedGas(env, pendingPlainTxs)
+	prioritisedReservedGauge.Update(int64(env.prioritisedGas))
+
 	filter.OnlyPlainTxs, filter.OnlyBlobTxs = false, true
 	pendingBlobTxs := w.eth.TxPool().Pending(filter)
 
@@ -265,27 +283,47 @@ func (w *worker) commitNewWork(predicateContext *precompileconfig.PredicateConte
 	}
 	// Fill the block with all available pending transactions.
 	if len(localPlainTxs) > 0 || len(localBlobTxs) > 0 {
-		plainTxs := newTransactionsByPriceAndNonce(env.signer, localPlainTxs, env.header.BaseFee)
+		plainTxs := newPrioritisedTransactionsByPriceAndNonce(env.signer, localPlainTxs, env.header.BaseFee, env.prioritise)
 		blobTxs := newTransactionsByPriceAndNonce(env.signer, localBlobTxs, env.header.BaseFee)
 
 		w.commitTransactions(env, plainTxs, blobTxs, env.header.Coinbase)
 	}
 	if len(remotePlainTxs) > 0 || len(remoteBlobTxs) > 0 {
-		plainTxs := newTransactionsByPriceAndNonce(env.signer, remotePlainTxs, env.header.BaseFee)
+		plainTxs := newPrioritisedTransactionsByPriceAndNonce(env.signer, remotePlainTxs, env.header.BaseFee, env.prioritise)
 		blobTxs := newTransactionsByPriceAndNonce(env.signer, remoteBlobTxs, env.header.BaseFee)
 
 		w.commitTransactions(env, plainTxs, blobTxs, env.header.Coinbase)
 	}
+	prioritisedTxsGauge.Update(int64(env.prioritisedTxs))
+	prioritisedGasGauge.Update(int64(env.prioritisedUsage))
 
 	return w.commit(env)
 }
 
+// prioritisedGas returns the block gas to reserve for the pending transactions
+// of the prioritised lane, capped at the configured maximum.
+func (w *worker) prioritisedGas(env *environment, pending map[common.Address][]*txpool.LazyTransaction) uint64 {
+	var gas uint64
+	for _, txs := range pending {
+		for _, tx := range txs {
+			if tx.Tx == nil || !env.prioritise(tx.Tx) {
+				continue
+			}
+			if gas += tx.Gas; gas >= w.config.PrioritisedGas {
+				return w.config.PrioritisedGas
+			}
+		}
+	}
+	return gas
+}
+
 func (w *worker) createCurrentEnvironment(predicateContext *precompileconfig.PredicateContext, parent *types.Header, header *types.Header, tstart time.Time) (*environment, error) {
 	state, err := w.chain.StateAt(parent.Root)
 	if err != nil {
 		return nil, err
 	}
 	state.StartPrefetcher("miner", w.eth.BlockChain().CacheConfig().TriePrefetcherParallelism)
+	contracts := w.chainConfig.SystemContracts()
 	return &environment{
 		signer:           types.MakeSigner(w.chainConfig, header.Number, header.Time),
 		state:            state,
@@ -296,7 +334,10 @@ func (w *worker) createCurrentEnvironment(predicateContext *precompileconfig.Pre
 		rules:            w.chainConfig.Rules(header.Number, header.Time),
 		predicateContext: predicateContext,
 		predicateResults: predicate.NewResults(),
-		start:            tstart,
+		prioritise: func(tx *types.Transaction) bool {
+			return core.IsPrioritisedTransaction(contracts, header.Time, tx)
+		},
+		start: tstart,
 	}, nil
 }
 
@@ -400,7 +441,7 @@ func (w *worker) commitTransactions(env *environment, plainTxs, blobTxs *transac
 		switch {
 		case pltx == nil:
 			txs, ltx = blobTxs, bltx
-		case bltx == nil:
+		case bltx == nil, plainTxs.PeekPrioritised():
 			txs, ltx = plainTxs, pltx
 		default:
 			if ptip.Lt(btip) {
@@ -412,12 +453,23 @@ func (w *worker) commitTransactions(env *environment, plainTxs, blobTxs *transac
 		if ltx == nil {
 			break
 		}
+		// The transaction leaves the set in this iteration, release its reservation.
+		prioritised := txs.PeekPrioritised()
+		if prioritised {
+			env.prioritisedGas -= min(env.prioritisedGas, ltx.Gas)
+		}
 		// If we don't have enough space for the next transaction, skip the account.
 		if env.gasPool.Gas() < ltx.Gas {
 			log.Trace("Not enough gas left for transaction", "hash", ltx.Hash, "left", env.gasPool.Gas(), "needed", ltx.Gas)
 			txs.Pop()
 			continue
 		}
+		// Other transactions may not use the gas reserved for the prioritised lane.
+		if !prioritised && env.gasPool.Gas() < ltx.Gas+env.prioritisedGas {
+			log.Trace("Not enough unreserved gas left for transaction", "hash", ltx.Hash, "left", env.gasPool.Gas(), "reserved", env.prioritisedGas, "needed", ltx.Gas)
+			txs.Pop()
+			continue
+		}
 		if left := uint64(params.MaxBlobGasPerBlock - env.blobs*params.BlobTxBlobGasPerBlob); left < ltx.BlobGas {
 			log.Trace("Not enough blob gas left for transaction", "hash", ltx.Hash, "left", left, "needed", ltx.BlobGas)
 			txs.Pop()
@@ -453,6 +505,7 @@ func (w *worker) commitTransactions(env *environment, plainTxs, blobTxs *transac
 		// Start executing the transaction
 		env.state.SetTxContext(tx.Hash(), env.tcount)
 
+		gasUsed := env.header.GasUsed
 		_, err := w.commitTransaction(env, tx, coinbase)
 		switch {
 		case errors.Is(err, core.ErrNonceTooLow):
@@ -462,6 +515,10 @@ func (w *worker) commitTransactions(env *environment, plainTxs, blobTxs *transac
 
 		case errors.Is(err, nil):
 			env.tcount++
+			if prioritised {
+				env.prioritisedTxs++
+				env.prioritisedUsage += env.header.GasUsed - gasUsed
+			}
 			txs.Shift()
 
 		default:
@@ -1004,6 +1004,8 @@ type ChainConfig struct {
 
 	NetworkUpgrades // Config for timestamps that enable network upgrades. Skip encoding/decoding directly into ChainConfig.
 
+	FlareSystemContracts *FlareSystemContracts `json:"flareSystemContracts,omitempty"` // Overrides the built-in Flare system contracts schedule of the chain (nil = built-in)
+
 	AvalancheContext `json:"-"` // Avalanche specific context set during VM initialization. Not serialized.
 
 	UpgradeConfig `json:"-"` // Config specified in upgradeBytes (avalanche network upgrades or enable/disabling precompiles). Skip encoding/decoding directly into ChainConfig.
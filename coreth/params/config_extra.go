@@ -170,6 +170,11 @@ func (c *ChainConfig) Verify() error {
 	if err := c.verifyPrecompileUpgrades(); err != nil {
 		return fmt.Errorf("invalid precompile upgrades: %w", err)
 	}
+	if c.FlareSystemContracts != nil {
+		if err := c.FlareSystemContracts.Verify(); err != nil {
+			return fmt.Errorf("invalid flare system contracts: %w", err)
+		}
+	}
 
 	return nil
 }
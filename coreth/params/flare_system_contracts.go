@@ -0,0 +1,586 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cmath "github.com/ethereum/go-ethereum/common/math"
+)
+
+const (
+	FlareContractRules    = "flare"    // Flare, Coston2 and local Flare networks
+	SongbirdContractRules = "songbird" // Songbird, Coston and local Songbird networks
+)
+
+// FunctionSelector is the 4-byte selector of a system contract function.
+type FunctionSelector [4]byte
+
+// MarshalText implements encoding.TextMarshaler.
+func (s FunctionSelector) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(s[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *FunctionSelector) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("FunctionSelector", input, s[:])
+}
+
+// Activation is a value of the system contracts schedule that takes effect at
+// block timestamp Time.
+type Activation[T any] struct {
+	Time  uint64 `json:"time"`
+	Value T      `json:"value"`
+}
+
+// Schedule is a list of activations ordered by time.
+type Schedule[T any] []Activation[T]
+
+// At returns the value in effect at [blockTime] and whether any value of the
+// schedule has been activated by then.
+func (s Schedule[T]) At(blockTime uint64) (T, bool) {
+	for i := len(s) - 1; i >= 0; i-- {
+		if blockTime >= s[i].Time {
+			return s[i].Value, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+func (s Schedule[T]) verify() error {
+	for i := 1; i < len(s); i++ {
+		if s[i].Time <= s[i-1].Time {
+			return fmt.Errorf("activation at %d does not follow activation at %d", s[i].Time, s[i-1].Time)
+		}
+	}
+	return nil
+}
+
+// DaemonContract is called after every successful transaction and may
+// request inflation to be minted on to it.
+type DaemonContract struct {
+	Address        common.Address         `json:"address"`
+	Selector       FunctionSelector       `json:"selector"`
+	GasMultiplier  uint64                 `json:"gasMultiplier"`  // Gas available to the daemon, as a multiple of the block gas limit
+	MaxMintRequest *cmath.HexOrDecimal256 `json:"maxMintRequest"` // Mint requests above this amount are reverted
+}
+
+// PrioritisedContracts lists the contracts whose calls are charged the
+// nominal fee of a simple transfer, with the rest of the fee refunded.
+type PrioritisedContracts struct {
+	FTSOAddress      common.Address     `json:"ftsoAddress"`
+	FTSODataPrefixes []FunctionSelector `json:"ftsoDataPrefixes"`
+
+	SubmitterAddress        common.Address     `json:"submitterAddress"`
+	SubmitterActivationTime uint64             `json:"submitterActivationTime"` // Submitter calls are prioritised strictly after this time
+	SubmitterDataPrefixes   []FunctionSelector `json:"submitterDataPrefixes"`
+
+	DataPrefixActivationTime uint64 `json:"dataPrefixActivationTime"` // Data prefixes are checked strictly after this time
+	MaxGasLimit              uint64 `json:"maxGasLimit"`              // Calls with a higher gas limit are never prioritised
+}
+
+// StateConnectorContract finalises attestation rounds once the default
+// attestors reached a majority.
+type StateConnectorContract struct {
+	Address        Schedule[common.Address] `json:"address"`
+	CoinbaseSignal Schedule[common.Address] `json:"coinbaseSignal"`
+
+	SubmitAttestationSelector FunctionSelector `json:"submitAttestationSelector"`
+	GetAttestationSelector    FunctionSelector `json:"getAttestationSelector"`
+	FinaliseRoundSelector     FunctionSelector `json:"finaliseRoundSelector"`
+
+	DefaultAttestors Schedule[[]common.Address] `json:"defaultAttestors"`
+	// LocalAttestors overrides the local attestors otherwise read from the
+	// environment of the node.
+	LocalAttestors []common.Address `json:"localAttestors,omitempty"`
+}
+
+// GovernanceSettingsContract holds the governance address and timelock of the
+// Flare system contracts.
+type GovernanceSettingsContract struct {
+	Address        Schedule[common.Address] `json:"address"`
+	CoinbaseSignal common.Address           `json:"coinbaseSignal"`
+
+	SetGovernanceAddressSelector FunctionSelector `json:"setGovernanceAddressSelector"`
+	SetTimelockSelector          FunctionSelector `json:"setTimelockSelector"`
+
+	PermittedGovernanceAddress Schedule[common.Address] `json:"permittedGovernanceAddress,omitempty"`
+	PermittedTimelock          Schedule[uint64]         `json:"permittedTimelock,omitempty"`
+}
+
+// FundsMigration moves the balance of a genesis contract to its replacement
+// when [Selector] is called on the contract at [Address].
+type FundsMigration struct {
+	Address        Schedule[common.Address] `json:"address"`
+	CoinbaseSignal common.Address           `json:"coinbaseSignal"`
+	Selector       FunctionSelector         `json:"selector"`
+	Source         common.Address           `json:"source"`
+	Target         common.Address           `json:"target"`
+}
+
+// FlareSystemContracts is the schedule of the system contracts the node
+// interacts with on the Flare and Songbird networks.
+type FlareSystemContracts struct {
+	Rules string `json:"rules"` // Either FlareContractRules or SongbirdContractRules, empty for other networks
+
+	Daemon             DaemonContract             `json:"daemon"`
+	Prioritised        PrioritisedContracts       `json:"prioritised"`
+	StateConnector     StateConnectorContract     `json:"stateConnector"`
+	GovernanceSettings GovernanceSettingsContract `json:"governanceSettings"`
+	InitialAirdrop     FundsMigration             `json:"initialAirdrop"`
+	Distribution       FundsMigration             `json:"distribution"`
+}
+
+// IsFlare returns whether the Flare system contract rules apply.
+func (s *FlareSystemContracts) IsFlare() bool { return s.Rules == FlareContractRules }
+
+// IsSongbird returns whether the Songbird system contract rules apply.
+func (s *FlareSystemContracts) IsSongbird() bool { return s.Rules == SongbirdContractRules }
+
+// Verify checks that the schedule is well formed.
+func (s *FlareSystemContracts) Verify() error {
+	switch s.Rules {
+	case FlareContractRules, SongbirdContractRules:
+	default:
+		return fmt.Errorf("unknown rules %q", s.Rules)
+	}
+	if s.Daemon.MaxMintRequest == nil {
+		return errors.New("daemon max mint request not set")
+	}
+	if max := (*big.Int)(s.Daemon.MaxMintRequest); max.Sign() < 0 || max.BitLen() > 256 {
+		return fmt.Errorf("daemon max mint request %v out of range", max)
+	}
+	for name, schedule := range map[string]interface{ verify() error }{
+		"stateConnector.address":                        s.StateConnector.Address,
+		"stateConnector.coinbaseSignal":                 s.StateConnector.CoinbaseSignal,
+		"stateConnector.defaultAttestors":               s.StateConnector.DefaultAttestors,
+		"governanceSettings.address":                    s.GovernanceSettings.Address,
+		"governanceSettings.permittedGovernanceAddress": s.GovernanceSettings.PermittedGovernanceAddress,
+		"governanceSettings.permittedTimelock":          s.GovernanceSettings.PermittedTimelock,
+		"initialAirdrop.address":                        s.InitialAirdrop.Address,
+		"distribution.address":                          s.Distribution.Address,
+	} {
+		if err := schedule.verify(); err != nil {
+			return fmt.Errorf("invalid %s schedule: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// GetFlareSystemContracts returns the built-in system contracts schedule of
+// the network with [chainID]. Networks without Flare system contracts get an
+// empty schedule.
+func GetFlareSystemContracts(chainID *big.Int) *FlareSystemContracts {
+	if chainID != nil {
+		if contracts, ok := flareSystemContracts[chainID.Uint64()]; ok {
+			return contracts
+		}
+	}
+	return noFlareSystemContracts
+}
+
+// SystemContracts returns the Flare system contracts schedule of the chain,
+// either the one configured in genesis or the built-in one of its chain ID.
+func (c *ChainConfig) SystemContracts() *FlareSystemContracts {
+	if c.FlareSystemContracts != nil {
+		return c.FlareSystemContracts
+	}
+	return GetFlareSystemContracts(c.ChainID)
+}
+
+func unixTime(year int, month time.Month, day, hour int) uint64 {
+	return uint64(time.Date(year, month, day, hour, 0, 0, 0, time.UTC).Unix())
+}
+
+func mintAmount(amount string) *cmath.HexOrDecimal256 {
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		panic("invalid mint amount " + amount)
+	}
+	return (*cmath.HexOrDecimal256)(value)
+}
+
+var (
+	// Activation times that some of the networks compare against strictly,
+	// their schedules activate one second later.
+	songbirdStateConnectorTime = unixTime(2022, time.March, 28, 14)
+	songbirdOct22ForkTime      = unixTime(2022, time.October, 19, 15)
+	costonStateConnectorTime   = unixTime(2022, time.February, 25, 17)
+	costonOct22ForkTime        = unixTime(2022, time.October, 6, 15)
+
+	submitterTimeFlare    = unixTime(2024, time.March, 26, 12)
+	submitterTimeCostwo   = unixTime(2024, time.March, 7, 12)
+	submitterTimeSongbird = unixTime(2024, time.March, 15, 12)
+	submitterTimeCoston   = unixTime(2024, time.February, 29, 12)
+
+	signalCoinbase               = common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	stateConnectorCoinbaseSignal = common.HexToAddress("0x00000000000000000000000000000000000DEaD1")
+	stateConnectorAddress        = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	prioritisedSubmitterAddress  = common.HexToAddress("0x2cA6571Daa15ce734Bbd0Bf27D5C9D16787fc33f") // for flare, costwo, songbird and coston
+
+	daemonFlare = DaemonContract{
+		Address:        common.HexToAddress("0x1000000000000000000000000000000000000002"),
+		Selector:       FunctionSelector{0x7f, 0xec, 0x8d, 0x38},
+		GasMultiplier:  100,
+		MaxMintRequest: mintAmount("60000000000000000000000000"),
+	}
+	daemonSongbird = DaemonContract{
+		Address:        common.HexToAddress("0x1000000000000000000000000000000000000002"),
+		Selector:       FunctionSelector{0x7f, 0xec, 0x8d, 0x38},
+		GasMultiplier:  100,
+		MaxMintRequest: mintAmount("50000000000000000000000000"),
+	}
+
+	submitterDataPrefixes = []FunctionSelector{
+		{0x6c, 0x53, 0x2f, 0xae},
+		{0x9d, 0x00, 0xc9, 0xfd},
+		{0xe1, 0xb1, 0x57, 0xe7},
+		{0x57, 0xee, 0xd5, 0x80},
+		{0x83, 0x3b, 0xf6, 0xc0},
+	}
+	ftsoDataPrefixesFlare = []FunctionSelector{
+		{0x8f, 0xc6, 0xf6, 0x67},
+		{0xe2, 0xdb, 0x5a, 0x52},
+	}
+	ftsoDataPrefixesSongbird = []FunctionSelector{
+		{0xc5, 0xad, 0xc5, 0x39},
+		{0x60, 0x84, 0x8b, 0x44},
+	}
+	prioritisedFTSOAddress = common.HexToAddress("0x1000000000000000000000000000000000000003")
+
+	// function submitAttestation(uint256 _bufferNumber, bytes32 _commitHash, bytes32 _merkleRoot, bytes32 _randomNumber) external returns (bool _isInitialBufferSlot)
+	// function getAttestation(uint256 _bufferNumber) external view returns (bytes32 _merkleRoot)
+	// function finaliseRound(uint256 _bufferNumber, bytes32 _merkleRoot) external
+	// https://gitlab.com/flarenetwork/flare-smart-contracts/-/blob/6b6e5480c3cf769b5a650b961992b4f082761d76/contracts/genesis/implementation/StateConnector.sol
+	submitAttestationSelector = FunctionSelector{0xcf, 0xd1, 0xfd, 0xad}
+	getAttestationSelector    = FunctionSelector{0x29, 0xbe, 0x4d, 0xb2}
+	finaliseRoundSelector     = FunctionSelector{0xea, 0xeb, 0xf6, 0xd3}
+
+	// Signalling block.coinbase value
+	// address public constant SIGNAL_COINBASE = address(0x00000000000000000000000000000000000DEaD0);
+	// function setGovernanceAddress(address _newGovernance) external
+	// function setTimelock(uint256 _newTimelock) external
+	// https://gitlab.com/flarenetwork/flare-smart-contracts/-/blob/4bb79bfe7266b43ea46e681f8a86ab8b9ef36446/contracts/genesis/implementation/GovernanceSettings.sol
+	governanceCoinbaseSignal     = common.HexToAddress("0x00000000000000000000000000000000000DEaD0")
+	setGovernanceAddressSelector = FunctionSelector{0xcf, 0xc1, 0x62, 0x54}
+	setTimelockSelector          = FunctionSelector{0x1e, 0x89, 0x1c, 0x0a}
+
+	// function updateInitialAirdropAddress() external
+	// https://flare-explorer.flare.network/address/0x4AeE563140E36abA778944E2Ca68c3988CAd5730/contracts#address-tabs
+	initialAirdropCoinbaseSignal = common.HexToAddress("0x00000000000000000000000000000000000dead2")
+	initialAirdropSelector       = FunctionSelector{0x7d, 0x1f, 0x99, 0x46}
+	initialAirdropAddress        = common.HexToAddress("0x1000000000000000000000000000000000000006")
+
+	// function updateDistributionAddress() external
+	// https://flare-explorer.flare.network/address/0x4d1c42F41555Ae35DfC1819bd718f7D9Fb28abdD/contracts#address-tabs
+	distributionCoinbaseSignal = common.HexToAddress("0x00000000000000000000000000000000000deAD3")
+	distributionSelector       = FunctionSelector{0x5a, 0xce, 0x4f, 0x0d}
+	distributionAddress        = common.HexToAddress("0x1000000000000000000000000000000000000004")
+
+	// The default attestation providers for the state connector are drawn from the top weighted/performing FTSOs.
+	attestorsFlare = []common.Address{
+		common.HexToAddress("0x0988Cf4828F4e4eD0cE7c07467E70e19095Ee152"),
+		common.HexToAddress("0x6BC7DCa62010D418eB72CCdc58561e00C5868Ef1"),
+		common.HexToAddress("0xE34Bb361536610a9DCcEa5292262e36AfF65c06c"),
+		common.HexToAddress("0x8A3D627D86A81F5D21683F4963565C63DB5e1309"),
+		common.HexToAddress("0x2D3e7e4b19bDc920fd9C57BD3072A31F5a59FeC8"),
+		common.HexToAddress("0x6455dC38fdF739b6fE021b30C7D9672C1c6DEb5c"),
+		common.HexToAddress("0x49893c5Dfc035F4eE4E46faC014f6D4bC80F7f92"),
+		common.HexToAddress("0x08e8b2Af4874e920de27723576A13d66008Af523"),
+		common.HexToAddress("0x5D2f75392DdDa69a2818021dd6a64937904c8352"),
+	}
+	attestorsFlareSubmitter = []common.Address{
+		common.HexToAddress("0x4E07E1F3DB3Dc9BAd56Cc829747cc0148234329F"),
+		common.HexToAddress("0xB264Fad6Fdc65767998f93501945aB8F9108809d"),
+		common.HexToAddress("0x366BeC54195bfD45DBB34b79Ad2dEC4010598947"),
+		common.HexToAddress("0x2665B179d5fCE1118f06e23B5d6E7617c5Ff733A"),
+		common.HexToAddress("0x65cBaFaDD7C914179aabcE9C35f918a4E36AfFf9"),
+		common.HexToAddress("0x7eC6a7C7c4Ef003A75DC6c06352B48B37Ac2191B"),
+		common.HexToAddress("0xEa9bC2F98eFFC6A27E2C31733c1905961826f73B"),
+		common.HexToAddress("0xA4aA75a9B49c7f2B4be62b2999d7103E78D004C7"),
+		common.HexToAddress("0x4DF8436D7578C2d3bc73d33B6644913e131B70FC"),
+	}
+	attestorsSongbird = []common.Address{
+		common.HexToAddress("0x0c19f3B4927abFc596353B0f9Ddad5D817736F70"),
+	}
+	attestorsSongbirdOct22 = []common.Address{
+		common.HexToAddress("0x2D3e7e4b19bDc920fd9C57BD3072A31F5a59FeC8"),
+		common.HexToAddress("0x442DD539Fe78D43A1a9358FF3460CfE63e2bC9CC"),
+		common.HexToAddress("0x49893c5Dfc035F4eE4E46faC014f6D4bC80F7f92"),
+		common.HexToAddress("0x5D2f75392DdDa69a2818021dd6a64937904c8352"),
+		common.HexToAddress("0x6455dC38fdF739b6fE021b30C7D9672C1c6DEb5c"),
+		common.HexToAddress("0x808441Ec3Fa1721330226E69527Bc160D8d9386a"),
+		common.HexToAddress("0x823B0f5c7758E9d3bE55bA1EA840E29ccd5D5CcB"),
+		common.HexToAddress("0x85016969b9eBDB8977975a4743c9FCEeabCEAf8A"),
+		common.HexToAddress("0x8A3D627D86A81F5D21683F4963565C63DB5e1309"),
+	}
+	attestorsSongbirdSubmitter = []common.Address{
+		common.HexToAddress("0xcE397b9a395ace2e328030699bDDf4E2F049A05B"),
+		common.HexToAddress("0xeDBb013BBC314124a9f842c1887e34cfeB03B052"),
+		common.HexToAddress("0xb9eF3951ac2D04C6bdD886bF042041E3954E86aF"),
+		common.HexToAddress("0x816Cec8f3A37Fd673Cfd4229441c59cA8DbD0641"),
+		common.HexToAddress("0x14c9c4583F0b1af8a69452Ec1b29884240f83bDC"),
+		common.HexToAddress("0x0049081C2D6def64800cC011Bd9aDe8682c6593a"),
+		common.HexToAddress("0x53Fcb50a22aFd6e5438d754CB22c4726032d2488"),
+		common.HexToAddress("0x35f4F0Bb73a6040F24927e1735B089d7769F7674"),
+		common.HexToAddress("0x3B583C919fD4C863F3A17d11929346C687FfB7c3"),
+	}
+	attestorsCostwo = []common.Address{
+		common.HexToAddress("0x30e4b4542b4aAf615838B113f14c46dE1469212e"),
+		common.HexToAddress("0x3519E14183252794aaA52aA824f34482ef44cE1d"),
+		common.HexToAddress("0xb445857476181ec378Ec453ab3d122183CfC3b78"),
+		common.HexToAddress("0x6D755cd7A61A9DCFc96FaE0f927C3a73bE986ce4"),
+		common.HexToAddress("0xdC0fD24846303D58d2D66AA8820be2685735dBd2"),
+		common.HexToAddress("0x3F52c41c0500a4f018A38c9f8273b254aD7e2FCc"),
+		common.HexToAddress("0xdA6d6aA9F1f770c279c5DA0C71f4DC1142A70d5D"),
+		common.HexToAddress("0x3d895D00d2802120D39d4D2554F7ef09d6845E99"),
+		common.HexToAddress("0xc36141CFBe5Af6eB2F8b21550Ccd457DA7FaF3C6"),
+	}
+	attestorsCoston = []common.Address{
+		common.HexToAddress("0x3a6e101103ec3d9267d08f484a6b70e1440a8255"),
+	}
+
+	// FlareSystemContractsFlare is the system contracts schedule of the Flare network.
+	FlareSystemContractsFlare = &FlareSystemContracts{
+		Rules:  FlareContractRules,
+		Daemon: daemonFlare,
+		Prioritised: PrioritisedContracts{
+			FTSOAddress:              prioritisedFTSOAddress,
+			FTSODataPrefixes:         ftsoDataPrefixesFlare,
+			SubmitterAddress:         prioritisedSubmitterAddress,
+			SubmitterActivationTime:  submitterTimeFlare,
+			SubmitterDataPrefixes:    submitterDataPrefixes,
+			DataPrefixActivationTime: unixTime(2024, time.October, 10, 15),
+			MaxGasLimit:              3000000,
+		},
+		StateConnector: StateConnectorContract{
+			Address:                   Schedule[common.Address]{{unixTime(2022, time.June, 1, 0), stateConnectorAddress}},
+			CoinbaseSignal:            Schedule[common.Address]{{0, stateConnectorCoinbaseSignal}},
+			SubmitAttestationSelector: submitAttestationSelector,
+			GetAttestationSelector:    getAttestationSelector,
+			FinaliseRoundSelector:     finaliseRoundSelector,
+			DefaultAttestors: Schedule[[]common.Address]{
+				{0, attestorsFlare},
+				{submitterTimeFlare + 1, attestorsFlareSubmitter},
+			},
+		},
+		GovernanceSettings: GovernanceSettingsContract{
+			Address:                      Schedule[common.Address]{{unixTime(2022, time.September, 9, 0), common.HexToAddress("0x1000000000000000000000000000000000000007")}},
+			CoinbaseSignal:               governanceCoinbaseSignal,
+			SetGovernanceAddressSelector: setGovernanceAddressSelector,
+			SetTimelockSelector:          setTimelockSelector,
+			PermittedTimelock:            Schedule[uint64]{{unixTime(2022, time.September, 9, 0), 3600}},
+		},
+		InitialAirdrop: FundsMigration{
+			Address:        Schedule[common.Address]{{unixTime(2022, time.November, 10, 15), common.HexToAddress("0x4AeE563140E36abA778944E2Ca68c3988CAd5730")}},
+			CoinbaseSignal: initialAirdropCoinbaseSignal,
+			Selector:       initialAirdropSelector,
+			Source:         initialAirdropAddress,
+			Target:         common.HexToAddress("0xbe653C54DF337F13Fcb726101388F4a4803049F3"),
+		},
+		Distribution: FundsMigration{
+			Address:        Schedule[common.Address]{{unixTime(2023, time.March, 1, 15), common.HexToAddress("0x4d1c42F41555Ae35DfC1819bd718f7D9Fb28abdD")}},
+			CoinbaseSignal: distributionCoinbaseSignal,
+			Selector:       distributionSelector,
+			Source:         distributionAddress,
+			Target:         common.HexToAddress("0xbe653C54DF337F13Fcb726101388F4a4803049F3"),
+		},
+	}
+
+	// FlareSystemContractsCostwo is the system contracts schedule of the Coston2 network.
+	FlareSystemContractsCostwo = &FlareSystemContracts{
+		Rules:  FlareContractRules,
+		Daemon: daemonFlare,
+		Prioritised: PrioritisedContracts{
+			FTSOAddress:              prioritisedFTSOAddress,
+			FTSODataPrefixes:         ftsoDataPrefixesFlare,
+			SubmitterAddress:         prioritisedSubmitterAddress,
+			SubmitterActivationTime:  submitterTimeCostwo,
+			SubmitterDataPrefixes:    submitterDataPrefixes,
+			DataPrefixActivationTime: unixTime(2024, time.October, 10, 10),
+			MaxGasLimit:              3000000,
+		},
+		StateConnector: StateConnectorContract{
+			Address:                   Schedule[common.Address]{{unixTime(2022, time.June, 1, 0), stateConnectorAddress}},
+			CoinbaseSignal:            Schedule[common.Address]{{0, stateConnectorCoinbaseSignal}},
+			SubmitAttestationSelector: submitAttestationSelector,
+			GetAttestationSelector:    getAttestationSelector,
+			FinaliseRoundSelector:     finaliseRoundSelector,
+			DefaultAttestors:          Schedule[[]common.Address]{{0, attestorsCostwo}},
+		},
+		GovernanceSettings: GovernanceSettingsContract{
+			Address:                      Schedule[common.Address]{{unixTime(2022, time.September, 8, 0), common.HexToAddress("0x1000000000000000000000000000000000000007")}},
+			CoinbaseSignal:               governanceCoinbaseSignal,
+			SetGovernanceAddressSelector: setGovernanceAddressSelector,
+			SetTimelockSelector:          setTimelockSelector,
+			PermittedTimelock:            Schedule[uint64]{{unixTime(2022, time.September, 8, 0), 3600}},
+		},
+		InitialAirdrop: FundsMigration{
+			Address:        Schedule[common.Address]{{unixTime(2022, time.October, 27, 20), common.HexToAddress("0x28561B938342efD0677f60Fd0912e1931367a612")}},
+			CoinbaseSignal: initialAirdropCoinbaseSignal,
+			Selector:       initialAirdropSelector,
+			Source:         initialAirdropAddress,
+			Target:         common.HexToAddress("0xC83Ec6a4aFf2099942836860A28C7e248Fabc32C"),
+		},
+		Distribution: FundsMigration{
+			Address:        Schedule[common.Address]{{unixTime(2023, time.January, 26, 15), common.HexToAddress("0xdF1deD5f1905C5012cbeE8367e3F4849afEAE545")}},
+			CoinbaseSignal: distributionCoinbaseSignal,
+			Selector:       distributionSelector,
+			Source:         distributionAddress,
+			Target:         common.HexToAddress("0xC83Ec6a4aFf2099942836860A28C7e248Fabc32C"),
+		},
+	}
+
+	// FlareSystemContractsLocalFlare is the system contracts schedule of local
+	// Flare networks. The prioritised submitter contract address is read from
+	// the SUBMITTER_CONTRACT_ADDRESS environment variable.
+	FlareSystemContractsLocalFlare = &FlareSystemContracts{
+		Rules:  FlareContractRules,
+		Daemon: daemonFlare,
+		Prioritised: PrioritisedContracts{
+			FTSOAddress:      prioritisedFTSOAddress,
+			SubmitterAddress: common.HexToAddress(os.Getenv("SUBMITTER_CONTRACT_ADDRESS")),
+			MaxGasLimit:      3000000,
+		},
+		StateConnector: StateConnectorContract{
+			Address:                   Schedule[common.Address]{{unixTime(2022, time.June, 1, 0), stateConnectorAddress}},
+			CoinbaseSignal:            Schedule[common.Address]{{0, stateConnectorCoinbaseSignal}},
+			SubmitAttestationSelector: submitAttestationSelector,
+			GetAttestationSelector:    getAttestationSelector,
+			FinaliseRoundSelector:     finaliseRoundSelector,
+			DefaultAttestors: Schedule[[]common.Address]{{0, []common.Address{
+				common.HexToAddress("0x8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC"), // Private key: 56289e99c94b6912bfc12adc093c9b51124f0dc54ac7a766b2bc5ccf558d8027
+			}}},
+		},
+		GovernanceSettings: GovernanceSettingsContract{
+			Address:                      Schedule[common.Address]{{unixTime(2022, time.January, 1, 0), common.HexToAddress("0x1000000000000000000000000000000000000007")}},
+			CoinbaseSignal:               governanceCoinbaseSignal,
+			SetGovernanceAddressSelector: setGovernanceAddressSelector,
+			SetTimelockSelector:          setTimelockSelector,
+			PermittedGovernanceAddress:   Schedule[common.Address]{{unixTime(2022, time.January, 1, 0), common.HexToAddress("0x100000000000000000000000000000000000000f")}},
+			PermittedTimelock:            Schedule[uint64]{{unixTime(2022, time.January, 1, 0), 3600}},
+		},
+		InitialAirdrop: FundsMigration{
+			Address:        Schedule[common.Address]{{unixTime(2022, time.January, 1, 0), common.HexToAddress("0x1000000000000000000000000000000000000008")}},
+			CoinbaseSignal: initialAirdropCoinbaseSignal,
+			Selector:       initialAirdropSelector,
+			Source:         initialAirdropAddress,
+			Target:         signalCoinbase,
+		},
+		Distribution: FundsMigration{
+			Address:        Schedule[common.Address]{{unixTime(2023, time.January, 1, 0), common.HexToAddress("0x1000000000000000000000000000000000000009")}},
+			CoinbaseSignal: distributionCoinbaseSignal,
+			Selector:       distributionSelector,
+			Source:         distributionAddress,
+			Target:         signalCoinbase,
+		},
+	}
+
+	// FlareSystemContractsSongbird is the system contracts schedule of the Songbird network.
+	FlareSystemContractsSongbird = &FlareSystemContracts{
+		Rules:  SongbirdContractRules,
+		Daemon: daemonSongbird,
+		Prioritised: PrioritisedContracts{
+			FTSOAddress:              prioritisedFTSOAddress,
+			FTSODataPrefixes:         ftsoDataPrefixesSongbird,
+			SubmitterAddress:         prioritisedSubmitterAddress,
+			SubmitterActivationTime:  submitterTimeSongbird,
+			SubmitterDataPrefixes:    submitterDataPrefixes,
+			DataPrefixActivationTime: unixTime(2024, time.October, 10, 13),
+			MaxGasLimit:              math.MaxUint64,
+		},
+		StateConnector: StateConnectorContract{
+			Address: Schedule[common.Address]{
+				{songbirdStateConnectorTime + 1, common.HexToAddress("0x3A1b3220527aBA427d1e13e4b4c48c31460B4d91")},
+				{songbirdOct22ForkTime + 1, common.HexToAddress("0x0c13aDA1C7143Cf0a0795FFaB93eEBb6FAD6e4e3")},
+			},
+			CoinbaseSignal: Schedule[common.Address]{
+				{0, signalCoinbase},
+				{songbirdOct22ForkTime + 1, stateConnectorCoinbaseSignal},
+			},
+			SubmitAttestationSelector: submitAttestationSelector,
+			GetAttestationSelector:    getAttestationSelector,
+			FinaliseRoundSelector:     finaliseRoundSelector,
+			DefaultAttestors: Schedule[[]common.Address]{
+				{0, attestorsSongbird},
+				{songbirdOct22ForkTime + 1, attestorsSongbirdOct22},
+				{submitterTimeSongbird + 1, attestorsSongbirdSubmitter},
+			},
+		},
+	}
+
+	// FlareSystemContractsCoston is the system contracts schedule of the Coston network.
+	FlareSystemContractsCoston = &FlareSystemContracts{
+		Rules:  SongbirdContractRules,
+		Daemon: daemonSongbird,
+		Prioritised: PrioritisedContracts{
+			FTSOAddress:              prioritisedFTSOAddress,
+			FTSODataPrefixes:         ftsoDataPrefixesSongbird,
+			SubmitterAddress:         prioritisedSubmitterAddress,
+			SubmitterActivationTime:  submitterTimeCoston,
+			SubmitterDataPrefixes:    submitterDataPrefixes,
+			DataPrefixActivationTime: unixTime(2024, time.October, 10, 8),
+			MaxGasLimit:              math.MaxUint64,
+		},
+		StateConnector: StateConnectorContract{
+			Address: Schedule[common.Address]{
+				{costonStateConnectorTime + 1, common.HexToAddress("0x947c76694491d3fD67a73688003c4d36C8780A97")},
+				{costonOct22ForkTime + 1, common.HexToAddress("0x0c13aDA1C7143Cf0a0795FFaB93eEBb6FAD6e4e3")},
+			},
+			CoinbaseSignal: Schedule[common.Address]{
+				{0, signalCoinbase},
+				{costonOct22ForkTime + 1, stateConnectorCoinbaseSignal},
+			},
+			SubmitAttestationSelector: submitAttestationSelector,
+			GetAttestationSelector:    getAttestationSelector,
+			FinaliseRoundSelector:     finaliseRoundSelector,
+			DefaultAttestors: Schedule[[]common.Address]{
+				{0, attestorsCoston},
+				{costonOct22ForkTime + 1, attestorsCostwo},
+			},
+		},
+	}
+
+	// FlareSystemContractsLocal is the system contracts schedule of local
+	// Songbird networks. The prioritised submitter contract address is read
+	// from the SUBMITTER_CONTRACT_ADDRESS environment variable.
+	FlareSystemContractsLocal = &FlareSystemContracts{
+		Rules:  SongbirdContractRules,
+		Daemon: daemonSongbird,
+		Prioritised: PrioritisedContracts{
+			FTSOAddress:      prioritisedFTSOAddress,
+			SubmitterAddress: common.HexToAddress(os.Getenv("SUBMITTER_CONTRACT_ADDRESS")),
+			MaxGasLimit:      math.MaxUint64,
+		},
+		StateConnector: StateConnectorContract{
+			Address:                   Schedule[common.Address]{{0, stateConnectorAddress}},
+			CoinbaseSignal:            Schedule[common.Address]{{0, signalCoinbase}},
+			SubmitAttestationSelector: submitAttestationSelector,
+			GetAttestationSelector:    getAttestationSelector,
+			FinaliseRoundSelector:     finaliseRoundSelector,
+			DefaultAttestors:          Schedule[[]common.Address]{{0, []common.Address{}}},
+		},
+	}
+
+	noFlareSystemContracts = &FlareSystemContracts{
+		Daemon: daemonSongbird,
+	}
+
+	flareSystemContracts = map[uint64]*FlareSystemContracts{
+		FlareChainID.Uint64():      FlareSystemContractsFlare,
+		CostwoChainID.Uint64():     FlareSystemContractsCostwo,
+		LocalFlareChainID.Uint64(): FlareSystemContractsLocalFlare,
+		SongbirdChainID.Uint64():   FlareSystemContractsSongbird,
+		CostonChainID.Uint64():     FlareSystemContractsCoston,
+		LocalChainID.Uint64():      FlareSystemContractsLocal,
+	}
+)
@@ -0,0 +1,100 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleAt(t *testing.T) {
+	schedule := Schedule[uint64]{{Time: 10, Value: 1}, {Time: 20, Value: 2}}
+
+	for blockTime, want := range map[uint64]struct {
+		value     uint64
+		activated bool
+	}{
+		0:  {0, false},
+		9:  {0, false},
+		10: {1, true},
+		19: {1, true},
+		20: {2, true},
+		99: {2, true},
+	} {
+		value, activated := schedule.At(blockTime)
+		assert.Equal(t, want.value, value, "time %d", blockTime)
+		assert.Equal(t, want.activated, activated, "time %d", blockTime)
+	}
+}
+
+func TestFlareSystemContractsVerify(t *testing.T) {
+	for chainID, contracts := range flareSystemContracts {
+		assert.NoError(t, contracts.Verify(), "chain %d", chainID)
+	}
+
+	unordered := *FlareSystemContractsFlare
+	unordered.StateConnector.DefaultAttestors = Schedule[[]common.Address]{{Time: 10}, {Time: 10}}
+	assert.ErrorContains(t, unordered.Verify(), "stateConnector.defaultAttestors")
+
+	noRules := *FlareSystemContractsFlare
+	noRules.Rules = ""
+	assert.ErrorContains(t, noRules.Verify(), "unknown rules")
+
+	tooLarge := *FlareSystemContractsFlare
+	tooLarge.Daemon.MaxMintRequest = (*math.HexOrDecimal256)(new(big.Int).Lsh(big.NewInt(1), 256))
+	assert.ErrorContains(t, tooLarge.Verify(), "out of range")
+}
+
+func TestFlareSystemContractsJSON(t *testing.T) {
+	for chainID, contracts := range flareSystemContracts {
+		data, err := json.Marshal(contracts)
+		require.NoError(t, err)
+
+		var decoded FlareSystemContracts
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, contracts, &decoded, "chain %d", chainID)
+	}
+}
+
+func TestChainConfigSystemContracts(t *testing.T) {
+	config := &ChainConfig{ChainID: FlareChainID}
+	assert.Same(t, FlareSystemContractsFlare, config.SystemContracts())
+
+	config = &ChainConfig{ChainID: big.NewInt(1)}
+	assert.False(t, config.SystemContracts().IsFlare())
+	assert.False(t, config.SystemContracts().IsSongbird())
+
+	var genesisConfig ChainConfig
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"chainId": 162,
+		"flareSystemContracts": {
+			"rules": "flare",
+			"daemon": {
+				"address": "0x1000000000000000000000000000000000000002",
+				"selector": "0x7fec8d38",
+				"gasMultiplier": 100,
+				"maxMintRequest": "60000000000000000000000000"
+			},
+			"stateConnector": {
+				"address": [{"time": 0, "value": "0x1000000000000000000000000000000000000001"}],
+				"localAttestors": ["0x8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC"]
+			}
+		}
+	}`), &genesisConfig))
+	contracts := genesisConfig.SystemContracts()
+	require.NoError(t, contracts.Verify())
+	assert.True(t, contracts.IsFlare())
+	assert.Equal(t, FunctionSelector{0x7f, 0xec, 0x8d, 0x38}, contracts.Daemon.Selector)
+	assert.Equal(t, big.NewInt(0).Mul(big.NewInt(60_000_000), big.NewInt(1e18)), (*big.Int)(contracts.Daemon.MaxMintRequest))
+	assert.Equal(t, []common.Address{common.HexToAddress("0x8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC")}, contracts.StateConnector.LocalAttestors)
+	address, activated := contracts.StateConnector.Address.At(0)
+	assert.True(t, activated)
+	assert.Equal(t, common.HexToAddress("0x1000000000000000000000000000000000000001"), address)
+}
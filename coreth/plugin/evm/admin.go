@@ -4,11 +4,13 @@
 package evm
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/ava-labs/avalanchego/api"
 	"github.com/ava-labs/avalanchego/utils/profiler"
+	"github.com/ava-labs/coreth/core/state/pruner"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -89,3 +91,19 @@ func (p *Admin) GetVMConfig(_ *http.Request, _ *struct{}, reply *ConfigReply) er
 	reply.Config = &p.vm.config
 	return nil
 }
+
+type PruningStatusReply struct {
+	Status *pruner.OnlineStatus `json:"status"`
+}
+
+// GetPruningStatus returns the progress of the online pruning session
+func (p *Admin) GetPruningStatus(_ *http.Request, _ *struct{}, reply *PruningStatusReply) error {
+	log.Info("Admin: GetPruningStatus called")
+
+	status := p.vm.blockChain.OnlinePruningStatus()
+	if status == nil {
+		return errors.New("online pruning is not enabled")
+	}
+	reply.Status = status
+	return nil
+}
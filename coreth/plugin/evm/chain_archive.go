@@ -0,0 +1,97 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/codec"
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/internal/chainarchive"
+	"github.com/ava-labs/coreth/params"
+)
+
+var _ chainarchive.AtomicTxs = (*chainArchiveAtomicTxs)(nil)
+
+// chainArchiveAtomicTxs bundles the atomic transactions of the atomic
+// repository with the blocks exported into chain archives, and accepts the
+// blocks imported from them.
+type chainArchiveAtomicTxs struct {
+	vm     *VM
+	repo   AtomicTxRepository
+	config *params.ChainConfig
+	codec  codec.Manager
+}
+
+// Read returns the atomic transactions accepted at [height] encoded as a
+// batch, nil if there are none.
+func (a *chainArchiveAtomicTxs) Read(height uint64) ([]byte, error) {
+	txs, err := a.repo.GetByHeight(height)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a.codec.Marshal(codecVersion, txs)
+}
+
+// Verify checks that the encoded atomic transactions are the ones of the extra
+// data of [block].
+func (a *chainArchiveAtomicTxs) Verify(block *types.Block, txsBytes []byte) error {
+	rules := a.config.Rules(block.Number(), block.Time())
+	if rules.IsApricotPhase1 {
+		if hash := types.CalcExtDataHash(block.ExtData()); block.Header().ExtDataHash != hash {
+			return fmt.Errorf("extra data hash mismatch: have %x, want %x", block.Header().ExtDataHash, hash)
+		}
+	}
+	want, err := ExtractAtomicTxs(block.ExtData(), rules.IsApricotPhase5, a.codec)
+	if err != nil {
+		return err
+	}
+	var have []*Tx
+	if len(txsBytes) > 0 {
+		if have, err = ExtractAtomicTxsBatch(txsBytes, a.codec); err != nil {
+			return err
+		}
+	}
+	if len(have) != len(want) {
+		return fmt.Errorf("atomic transaction count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i, tx := range want {
+		if have[i].ID() != tx.ID() {
+			return fmt.Errorf("atomic transaction %d mismatch: have %s, want %s", i, have[i].ID(), tx.ID())
+		}
+	}
+	return nil
+}
+
+// Accept accepts [ethBlock], inserted into the chain by an import, as the
+// consensus engine would. The atomic transactions of the block are written to
+// the atomic repository and applied to shared memory, and the block becomes
+// the last accepted block of the VM.
+func (a *chainArchiveAtomicTxs) Accept(ethBlock *types.Block) error {
+	vm := a.vm
+	vm.ctx.Lock.Lock()
+	defer vm.ctx.Lock.Unlock()
+
+	blk, err := vm.newBlock(ethBlock)
+	if err != nil {
+		return err
+	}
+	// The atomic state of the block is verified when it is inserted, unless it
+	// was inserted before the import was interrupted.
+	if _, err := vm.atomicBackend.GetVerifiedAtomicState(ethBlock.Hash()); err != nil {
+		if _, err := vm.atomicBackend.InsertTxs(ethBlock.Hash(), ethBlock.NumberU64(), ethBlock.ParentHash(), blk.atomicTxs); err != nil {
+			return fmt.Errorf("failed to insert atomic transactions: %w", err)
+		}
+	}
+	if err := blk.Accept(context.Background()); err != nil {
+		return err
+	}
+	return vm.State.SetLastAcceptedBlock(blk)
+}
@@ -0,0 +1,142 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package evm
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	commonEng "github.com/ava-labs/avalanchego/snow/engine/common"
+	"github.com/ava-labs/avalanchego/utils/crypto/secp256k1"
+	"github.com/ava-labs/coreth/eth"
+	"github.com/ava-labs/coreth/internal/chainarchive"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that the accepted atomic transactions are exported with their blocks
+// and verified against them.
+func TestExportChainArchiveAtomicTxs(t *testing.T) {
+	require := require.New(t)
+	issuer, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase2, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: 50000000,
+	})
+	defer func() {
+		require.NoError(vm.Shutdown(context.Background()))
+	}()
+
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], initialBaseFee, []*secp256k1.PrivateKey{testKeys[0]})
+	require.NoError(err)
+	require.NoError(vm.mempool.AddLocalTx(importTx))
+	<-issuer
+
+	blk, err := vm.BuildBlock(context.Background())
+	require.NoError(err)
+	require.NoError(blk.Verify(context.Background()))
+	require.NoError(vm.SetPreference(context.Background(), blk.ID()))
+	require.NoError(blk.Accept(context.Background()))
+	vm.blockChain.DrainAcceptorQueue()
+
+	dir := filepath.Join(t.TempDir(), "archive")
+	ok, err := eth.NewAdminAPI(vm.eth).ExportChainArchive(dir, nil, nil)
+	require.NoError(err)
+	require.True(ok)
+
+	archive, err := chainarchive.Open(dir)
+	require.NoError(err)
+	require.True(archive.Manifest().AtomicTxs)
+	require.Equal(uint64(1), archive.Manifest().Last)
+	entries, err := archive.ReadChunk(0)
+	require.NoError(err)
+	require.Len(entries, 2)
+	require.Empty(entries[0].AtomicTxs)
+
+	txs, err := ExtractAtomicTxsBatch(entries[1].AtomicTxs, vm.codec)
+	require.NoError(err)
+	require.Len(txs, 1)
+	require.Equal(importTx.ID(), txs[0].ID())
+
+	atomicTxs := vm.eth.AtomicTxs()
+	require.NoError(atomicTxs.Verify(entries[1].Block, entries[1].AtomicTxs))
+	require.ErrorContains(atomicTxs.Verify(entries[1].Block, nil), "atomic transaction count mismatch")
+	require.ErrorContains(atomicTxs.Verify(entries[0].Block, entries[1].AtomicTxs), "atomic transaction count mismatch")
+}
+
+// Tests that the blocks imported from a chain archive are accepted with their
+// atomic transactions, and that a node restarted after the import starts from
+// the imported chain.
+func TestImportChainArchive(t *testing.T) {
+	require := require.New(t)
+	utxos := map[ids.ShortID]uint64{testShortIDAddrs[0]: 50000000}
+	issuer, source, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase2, "", "", utxos)
+	defer func() {
+		require.NoError(source.Shutdown(context.Background()))
+	}()
+
+	importTx, err := source.newImportTx(source.ctx.XChainID, testEthAddrs[0], initialBaseFee, []*secp256k1.PrivateKey{testKeys[0]})
+	require.NoError(err)
+	require.NoError(source.mempool.AddLocalTx(importTx))
+	<-issuer
+
+	blk, err := source.BuildBlock(context.Background())
+	require.NoError(err)
+	require.NoError(blk.Verify(context.Background()))
+	require.NoError(source.SetPreference(context.Background(), blk.ID()))
+	require.NoError(blk.Accept(context.Background()))
+	source.blockChain.DrainAcceptorQueue()
+
+	dir := filepath.Join(t.TempDir(), "archive")
+	ok, err := eth.NewAdminAPI(source.eth).ExportChainArchive(dir, nil, nil)
+	require.NoError(err)
+	require.True(ok)
+
+	// The node importing the archive holds the same UTXO in shared memory.
+	_, vm, db, sharedMemory, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase2, "", "", utxos)
+	vm.ctx.Lock.Unlock()
+	ok, err = eth.NewAdminAPI(vm.eth).ImportChainArchive(dir)
+	require.NoError(err)
+	require.True(ok)
+
+	lastAccepted, err := vm.LastAccepted(context.Background())
+	require.NoError(err)
+	require.Equal(blk.ID(), lastAccepted)
+	txs, err := vm.atomicTxRepository.GetByHeight(1)
+	require.NoError(err)
+	require.Len(txs, 1)
+	require.Equal(importTx.ID(), txs[0].ID())
+	inputID := importTx.UnsignedAtomicTx.(*UnsignedImportTx).ImportedInputs[0].InputID()
+	_, err = vm.ctx.SharedMemory.Get(vm.ctx.XChainID, [][]byte{inputID[:]})
+	require.Error(err, "imported UTXO was not consumed")
+
+	vm.ctx.Lock.Lock()
+	require.NoError(vm.Shutdown(context.Background()))
+
+	ctx := NewContext()
+	ctx.SharedMemory = sharedMemory.NewSharedMemory(ctx.ChainID)
+	restarted := &VM{}
+	require.NoError(restarted.Initialize(
+		context.Background(),
+		ctx,
+		db,
+		BuildGenesisTest(t, genesisJSONApricotPhase2),
+		[]byte(""),
+		[]byte(""),
+		make(chan commonEng.Message, 1),
+		[]*commonEng.Fx{},
+		nil,
+	))
+	defer func() {
+		require.NoError(restarted.Shutdown(context.Background()))
+	}()
+
+	lastAccepted, err = restarted.LastAccepted(context.Background())
+	require.NoError(err)
+	require.Equal(blk.ID(), lastAccepted)
+	require.Equal(uint64(1), restarted.blockChain.LastAcceptedBlock().NumberU64())
+	txs, err = restarted.atomicTxRepository.GetByHeight(1)
+	require.NoError(err)
+	require.Len(txs, 1)
+	require.Equal(importTx.ID(), txs[0].ID())
+}
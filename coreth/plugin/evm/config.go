@@ -8,8 +8,11 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ava-labs/coreth/core/rawdb"
 	"github.com/ava-labs/coreth/core/txpool/legacypool"
 	"github.com/ava-labs/coreth/eth"
+	"github.com/ava-labs/coreth/miner"
+	"github.com/ava-labs/coreth/rpc"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/spf13/cast"
@@ -44,12 +47,16 @@ const (
 	defaultPullGossipFrequency                    = 1 * time.Second
 	defaultTxRegossipFrequency                    = 30 * time.Second
 	defaultOfflinePruningBloomFilterSize   uint64 = 512 // Default size (MB) for the offline pruner to use
+	defaultOnlinePruningBloomFilterSize    uint64 = 512 // Default size (MB) for the online pruner to use
+	defaultOnlinePruningInterval           uint64 = 100_000
 	defaultLogLevel                               = "info"
 	defaultLogJSONFormat                          = false
 	defaultMaxOutboundActiveRequests              = 16
 	defaultPopulateMissingTriesParallelism        = 1024
 	defaultStateSyncServerTrieCache               = 64 // MB
 	defaultAcceptedCacheSize                      = 32 // blocks
+	defaultFreezerDepth                    uint64 = 90_000
+	defaultStateHistory                    uint64 = 90_000
 
 	// defaultStateSyncMinBlocks is the minimum number of blocks the blockchain
 	// should be ahead of local last accepted to perform state sync.
@@ -91,6 +98,18 @@ type Config struct {
 	CorethAdminAPIDir     string `json:"coreth-admin-api-dir"`     // Deprecated: use AdminAPIDir instead
 	WarpAPIEnabled        bool   `json:"warp-api-enabled"`
 
+	// TraceAPIEnabled serves the Parity/OpenEthereum compatible trace
+	// namespace on the eth RPC endpoints.
+	TraceAPIEnabled bool `json:"trace-api-enabled"`
+	// TraceStreamEnabled serves the struct logs of transactions as JSON lines
+	// on the trace-stream endpoint, while they are re-executed. Its requests
+	// are held to the API max duration and charged as the method
+	// "trace-stream" against the API quotas.
+	TraceStreamEnabled bool `json:"trace-stream-enabled"`
+	// TraceFileDir is the directory debug_traceTransactionToFile writes the
+	// struct logs to. The system temporary directory is used if it is empty.
+	TraceFileDir string `json:"trace-file-dir"`
+
 	// EnabledEthAPIs is a list of Ethereum services that should be enabled
 	// If none is specified, then we use the default list [defaultEnabledAPIs]
 	EnabledEthAPIs []string `json:"eth-apis"`
@@ -139,6 +158,10 @@ type Config struct {
 	TxPoolGlobalQueue  uint64   `json:"tx-pool-global-queue"`
 	TxPoolLifetime     Duration `json:"tx-pool-lifetime"`
 
+	// Prioritised lane settings, for calls to the Flare prioritised contracts
+	TxPoolPrioritisedSlots uint64 `json:"tx-pool-prioritised-slots"`
+	MinerPrioritisedGas    uint64 `json:"miner-prioritised-gas"`
+
 	APIMaxDuration           Duration      `json:"api-max-duration"`
 	WSCPURefillRate          Duration      `json:"ws-cpu-refill-rate"`
 	WSCPUMaxStored           Duration      `json:"ws-cpu-max-stored"`
@@ -146,6 +169,20 @@ type Config struct {
 	AllowUnfinalizedQueries  bool          `json:"allow-unfinalized-queries"`
 	AllowUnprotectedTxs      bool          `json:"allow-unprotected-txs"`
 	AllowUnprotectedTxHashes []common.Hash `json:"allow-unprotected-tx-hashes"`
+	FeeTreatmentInReceipts   bool          `json:"fee-treatment-in-receipts"`
+
+	// RPC limits of the public endpoints. Batch limits of zero mean unlimited.
+	APIBatchRequestLimit    int `json:"api-batch-request-limit"`
+	APIBatchResponseMaxSize int `json:"api-batch-response-max-size"`
+	// Every call is charged the cost of its method (1 if not listed) against
+	// the token-bucket quota of its client. Clients presenting one of the
+	// API keys in the key header get the quota of the key, other clients
+	// get the default quota per IP address.
+	APIKeyHeader   string               `json:"api-key-header"`
+	APIKeys        map[string]rpc.Quota `json:"api-keys"`
+	APIQuotaRate   float64              `json:"api-quota-rate"`
+	APIQuotaBurst  int                  `json:"api-quota-burst"`
+	APIMethodCosts map[string]int       `json:"api-method-costs"`
 
 	// Keystore Settings
 	KeystoreDirectory             string `json:"keystore-directory"` // both absolute and relative supported
@@ -172,6 +209,12 @@ type Config struct {
 	OfflinePruningBloomFilterSize uint64 `json:"offline-pruning-bloom-filter-size"`
 	OfflinePruningDataDirectory   string `json:"offline-pruning-data-directory"`
 
+	// Online Pruning Settings
+	OnlinePruning                bool   `json:"online-pruning-enabled"`
+	OnlinePruningBloomFilterSize uint64 `json:"online-pruning-bloom-filter-size"`
+	OnlinePruningDataDirectory   string `json:"online-pruning-data-directory"` // Defaults to the online-pruning directory of the chain data directory
+	OnlinePruningInterval        uint64 `json:"online-pruning-interval"`       // Blocks accepted between the end of a session and the beginning of the next one
+
 	// VM2VM network
 	MaxOutboundActiveRequests int64 `json:"max-outbound-active-requests"`
 
@@ -187,6 +230,39 @@ type Config struct {
 	// Database Settings
 	InspectDatabase bool `json:"inspect-database"` // Inspects the database on startup if enabled.
 
+	// FreezerEnabled moves the headers, bodies and receipts of the blocks
+	// accepted more than FreezerDepth blocks ago out of the key-value store
+	// into append-only compressed files in FreezerDirectory. Reads of these
+	// blocks fall back to the files transparently.
+	FreezerEnabled bool `json:"freezer-enabled"`
+	// FreezerDirectory is the directory of the frozen blocks, which may be on
+	// cheaper storage than the key-value store. Defaults to the "ancient"
+	// directory of the chain data directory.
+	FreezerDirectory string `json:"freezer-directory"`
+	// FreezerDepth is the number of most recent accepted blocks kept in the
+	// key-value store.
+	FreezerDepth uint64 `json:"freezer-depth"`
+
+	// StateScheme selects how the state tries are stored, "hash" or "path".
+	// Defaults to the scheme of the stored state, or "hash" for a new database.
+	// The path-based scheme keeps a single persisted state and the reverse
+	// diffs of the StateHistory most recent blocks, it requires pruning.
+	StateScheme string `json:"state-scheme"`
+	// StateHistory is the number of most recent blocks whose state can be
+	// reconstructed with the path-based scheme.
+	StateHistory uint64 `json:"state-history"`
+	// StateSchemeMigrationEnabled converts a hash-based state to the path-based
+	// scheme on startup when "path" is selected.
+	StateSchemeMigrationEnabled bool `json:"state-scheme-migration-enabled"`
+	// StateArchiveEnabled keeps the state diffs of the accepted blocks, from
+	// which the historical states are served with pruning enabled. The archive
+	// starts from the last accepted block when enabled.
+	StateArchiveEnabled bool `json:"state-archive-enabled"`
+	// StateArchiveRebuild discards a state archive that does not reach the
+	// last accepted block and rebuilds it from there, instead of failing on
+	// startup. The historical states of the discarded archive are lost.
+	StateArchiveRebuild bool `json:"state-archive-rebuild"`
+
 	// SkipUpgradeCheck disables checking that upgrades must take place before the last
 	// accepted block. Skipping this check is useful when a node operator does not update
 	// their node before the network upgrade and their node accepts blocks that have
@@ -213,6 +289,30 @@ type Config struct {
 	// TxLookupLimit can be still used to control unindexing old transactions.
 	SkipTxIndexing bool `json:"skip-tx-indexing"`
 
+	// GovernanceIndexEnabled indexes the Flare governance changes of every
+	// accepted block in the background, so they can be queried without
+	// replaying the chain.
+	GovernanceIndexEnabled bool `json:"governance-index-enabled"`
+
+	// TraceIndexEnabled traces every accepted block with the flat call tracer
+	// and indexes the traces by address, so that trace_filter can be served
+	// without re-executing the blocks.
+	TraceIndexEnabled bool `json:"trace-index-enabled"`
+	// TraceHistory is the maximum number of blocks from head whose traces
+	// are reserved:
+	//  * 0:   means no limit
+	//  * N:   means N block limit [HEAD-N+1, HEAD] and delete extra traces
+	TraceHistory uint64 `json:"trace-history"`
+
+	// LogIndexEnabled indexes the logs of every accepted block by address and
+	// by topic, so that eth_getLogs can look up the matching blocks instead of
+	// scanning the bloom filters. Ranges covered by the index are not subject
+	// to api-max-blocks-per-request.
+	LogIndexEnabled bool `json:"log-index-enabled"`
+	// LogIndexRebuild drops the existing log index on startup and indexes the
+	// accepted blocks again.
+	LogIndexRebuild bool `json:"log-index-rebuild"`
+
 	// WarpOffChainMessages encodes off-chain messages (unrelated to any on-chain event ie. block or AddressedCall)
 	// that the node should be willing to sign.
 	// Note: only supports AddressedCall payloads as defined here:
@@ -245,6 +345,8 @@ func (c *Config) SetDefaults() {
 	c.TxPoolAccountQueue = legacypool.DefaultConfig.AccountQueue
 	c.TxPoolGlobalQueue = legacypool.DefaultConfig.GlobalQueue
 	c.TxPoolLifetime.Duration = legacypool.DefaultConfig.Lifetime
+	c.TxPoolPrioritisedSlots = legacypool.DefaultConfig.PrioritisedSlots
+	c.MinerPrioritisedGas = miner.DefaultConfig.PrioritisedGas
 
 	c.APIMaxDuration.Duration = defaultApiMaxDuration
 	c.WSCPURefillRate.Duration = defaultWsCpuRefillRate
@@ -270,6 +372,8 @@ func (c *Config) SetDefaults() {
 	c.PullGossipFrequency.Duration = defaultPullGossipFrequency
 	c.RegossipFrequency.Duration = defaultTxRegossipFrequency
 	c.OfflinePruningBloomFilterSize = defaultOfflinePruningBloomFilterSize
+	c.OnlinePruningBloomFilterSize = defaultOnlinePruningBloomFilterSize
+	c.OnlinePruningInterval = defaultOnlinePruningInterval
 	c.LogLevel = defaultLogLevel
 	c.LogJSONFormat = defaultLogJSONFormat
 	c.MaxOutboundActiveRequests = defaultMaxOutboundActiveRequests
@@ -280,6 +384,8 @@ func (c *Config) SetDefaults() {
 	c.StateSyncRequestSize = defaultStateSyncRequestSize
 	c.AllowUnprotectedTxHashes = defaultAllowUnprotectedTxHashes
 	c.AcceptedCacheSize = defaultAcceptedCacheSize
+	c.FreezerDepth = defaultFreezerDepth
+	c.StateHistory = defaultStateHistory
 }
 
 func (d *Duration) UnmarshalJSON(data []byte) (err error) {
@@ -313,17 +419,75 @@ func (c *Config) Validate() error {
 	if !c.Pruning && c.OfflinePruning {
 		return fmt.Errorf("cannot run offline pruning while pruning is disabled")
 	}
+	if !c.Pruning && c.OnlinePruning {
+		return fmt.Errorf("cannot run online pruning while pruning is disabled")
+	}
+	if c.OfflinePruning && c.OnlinePruning {
+		return fmt.Errorf("cannot run offline pruning while online pruning is enabled")
+	}
+	switch c.StateScheme {
+	case "", rawdb.HashScheme:
+		if c.StateSchemeMigrationEnabled {
+			return fmt.Errorf("cannot migrate the state scheme without selecting the %q scheme", rawdb.PathScheme)
+		}
+	case rawdb.PathScheme:
+		if !c.Pruning {
+			return fmt.Errorf("cannot use the %q state scheme while pruning is disabled", rawdb.PathScheme)
+		}
+		if c.OfflinePruning || c.OnlinePruning {
+			return fmt.Errorf("cannot run offline pruning (enabled: %t)/online pruning (enabled: %t) with the %q state scheme", c.OfflinePruning, c.OnlinePruning, rawdb.PathScheme)
+		}
+		if c.PopulateMissingTries != nil {
+			return fmt.Errorf("cannot enable populate missing tries with the %q state scheme", rawdb.PathScheme)
+		}
+	default:
+		return fmt.Errorf("unknown state scheme %q", c.StateScheme)
+	}
+	if !c.Pruning && c.StateArchiveEnabled {
+		return fmt.Errorf("cannot enable the state archive while pruning is disabled")
+	}
 	// If pruning is enabled, the commit interval must be non-zero so the node commits state tries every CommitInterval blocks.
 	if c.Pruning && c.CommitInterval == 0 {
 		return fmt.Errorf("cannot use commit interval of 0 with pruning enabled")
 	}
 
+	if c.APIBatchRequestLimit < 0 || c.APIBatchResponseMaxSize < 0 {
+		return fmt.Errorf("api-batch-request-limit (%d) and api-batch-response-max-size (%d) must be non-negative", c.APIBatchRequestLimit, c.APIBatchResponseMaxSize)
+	}
+	if err := c.validateQuota(rpc.Quota{Rate: c.APIQuotaRate, Burst: c.APIQuotaBurst}); err != nil {
+		return fmt.Errorf("invalid default api quota: %w", err)
+	}
+	for _, quota := range c.APIKeys {
+		if err := c.validateQuota(quota); err != nil {
+			return fmt.Errorf("invalid quota of api key %q: %w", quota.Name, err)
+		}
+	}
+
 	if c.PushGossipPercentStake < 0 || c.PushGossipPercentStake > 1 {
 		return fmt.Errorf("push-gossip-percent-stake is %f but must be in the range [0, 1]", c.PushGossipPercentStake)
 	}
 	return nil
 }
 
+// validateQuota checks that every method can be called within [quota].
+func (c *Config) validateQuota(quota rpc.Quota) error {
+	if quota.Rate < 0 {
+		return fmt.Errorf("rate %f must be non-negative", quota.Rate)
+	}
+	if quota.Rate == 0 {
+		return nil
+	}
+	if quota.Burst < 1 {
+		return fmt.Errorf("burst %d must be at least the default cost 1", quota.Burst)
+	}
+	for method, cost := range c.APIMethodCosts {
+		if cost < 0 || cost > quota.Burst {
+			return fmt.Errorf("cost %d of %s must be in the range [0, burst %d]", cost, method, quota.Burst)
+		}
+	}
+	return nil
+}
+
 func (c *Config) Deprecate() string {
 	msg := ""
 	// Deprecate the old config options and set the new ones.
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ava-labs/coreth/rpc"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 )
@@ -65,6 +66,19 @@ func TestUnmarshalConfig(t *testing.T) {
 			false,
 		},
 
+		{
+			"api quotas",
+			[]byte(`{"api-key-header": "X-Api-Key", "api-keys": {"secret": {"name": "partner", "rate": 100, "burst": 1000}}, "api-quota-rate": 10, "api-quota-burst": 100, "api-method-costs": {"eth_getLogs": 20}}`),
+			Config{
+				APIKeyHeader:   "X-Api-Key",
+				APIKeys:        map[string]rpc.Quota{"secret": {Name: "partner", Rate: 100, Burst: 1000}},
+				APIQuotaRate:   10,
+				APIQuotaBurst:  100,
+				APIMethodCosts: map[string]int{"eth_getLogs": 20},
+			},
+			false,
+		},
+
 		{
 			"state sync enabled",
 			[]byte(`{"state-sync-enabled":true}`),
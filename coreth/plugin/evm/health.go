@@ -3,12 +3,35 @@
 
 package evm
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// attestationDivergenceHealthWindow is how long the chain is reported unhealthy
+// after the local attestors diverged from the default attestors.
+const attestationDivergenceHealthWindow = time.Hour
 
 // Health returns nil if this chain is healthy.
 // Also returns details, which should be one of:
 // string, []byte, map[string]string
 func (vm *VM) HealthCheck(context.Context) (interface{}, error) {
-	// TODO perform actual health check
-	return nil, nil
+	if vm.blockChain == nil {
+		return nil, nil
+	}
+	divergence, detected := vm.blockChain.LastAttestationDivergence()
+	if divergence == nil {
+		return nil, nil
+	}
+	details := map[string]string{
+		"lastAttestationDivergenceRound": divergence.Round.String(),
+		"lastAttestationDivergenceBlock": fmt.Sprint(divergence.BlockNumber),
+		"lastAttestationDivergenceTime":  detected.UTC().Format(time.RFC3339),
+	}
+	if time.Since(detected) > attestationDivergenceHealthWindow {
+		return details, nil
+	}
+	return details, fmt.Errorf("local attestors diverged from default attestors on round %s in block %d (default decision %q, local decision %q)",
+		divergence.Round, divergence.BlockNumber, divergence.DefaultDecision, divergence.LocalDecision)
 }
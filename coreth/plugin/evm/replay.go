@@ -0,0 +1,59 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package evm
+
+import (
+	"math/big"
+
+	"github.com/ava-labs/avalanchego/snow"
+	"github.com/ava-labs/coreth/consensus/dummy"
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/state"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/params"
+	warpcontract "github.com/ava-labs/coreth/precompile/contracts/warp"
+)
+
+// ConfigureReplayGenesis configures the chain of [g] for the network of [ctx]
+// the way the VM does on initialisation, so that blocks replayed outside of
+// the VM are processed under the same rules. The network upgrades are only
+// taken from the network if [ctx] sets its ID, otherwise the genesis
+// configuration is used as is.
+func ConfigureReplayGenesis(g *core.Genesis, ctx *snow.Context) error {
+	if ctx.NetworkID != 0 {
+		flareSystemContracts := g.Config.FlareSystemContracts
+		g.Config = params.GetChainConfig(ctx.NetworkID, new(big.Int).Set(g.Config.ChainID))
+		g.Config.FlareSystemContracts = flareSystemContracts
+	}
+	if err := setFlareSystemContracts(g.Config, ctx.NetworkID, nil); err != nil {
+		return err
+	}
+	if g.Config.DurangoBlockTimestamp != nil {
+		g.Config.PrecompileUpgrades = append(g.Config.PrecompileUpgrades, params.PrecompileUpgrade{
+			Config: warpcontract.NewDefaultConfig(g.Config.DurangoBlockTimestamp),
+		})
+	}
+	g.Config.AvalancheContext = params.AvalancheContext{
+		SnowCtx: ctx,
+	}
+	g.Config.SetEthUpgrades()
+	return g.Config.Verify()
+}
+
+// NewReplayCallbacks returns the consensus callbacks to replay accepted blocks
+// outside of the VM. The atomic transactions of the blocks are applied to the
+// EVM state without being verified against the shared memory, which is not
+// available offline.
+func NewReplayCallbacks(ctx *snow.Context, config *params.ChainConfig) dummy.ConsensusCallbacks {
+	return dummy.ConsensusCallbacks{
+		OnExtraStateChange: func(block *types.Block, state *state.StateDB) (*big.Int, *big.Int, error) {
+			rules := config.Rules(block.Number(), block.Time())
+			txs, err := ExtractAtomicTxs(block.ExtData(), rules.IsApricotPhase5, Codec)
+			if err != nil {
+				return nil, nil, err
+			}
+			return applyAtomicTxs(ctx, rules, block, txs, state)
+		},
+	}
+}
@@ -290,6 +290,14 @@ func (client *stateSyncerClient) syncAtomicTrie(ctx context.Context) error {
 
 func (client *stateSyncerClient) syncStateTrie(ctx context.Context) error {
 	log.Info("state sync: sync starting", "root", client.syncSummary.BlockRoot)
+	triedb := client.chain.BlockChain().TrieDB()
+	if triedb.Scheme() == rawdb.PathScheme {
+		// The synced trie nodes overwrite the persisted state of the path-based
+		// trie database, which is enabled again once the sync completes.
+		if err := triedb.Disable(); err != nil {
+			return err
+		}
+	}
 	evmSyncer, err := statesync.NewStateSyncer(&statesync.StateSyncerConfig{
 		Client:                   client.client,
 		Root:                     client.syncSummary.BlockRoot,
@@ -298,6 +306,7 @@ func (client *stateSyncerClient) syncStateTrie(ctx context.Context) error {
 		MaxOutstandingCodeHashes: statesync.DefaultMaxOutstandingCodeHashes,
 		NumCodeFetchingWorkers:   statesync.DefaultNumCodeFetchingWorkers,
 		RequestSize:              client.stateSyncRequestSize,
+		StateScheme:              triedb.Scheme(),
 	})
 	if err != nil {
 		return err
@@ -36,6 +36,7 @@ import (
 	"github.com/ava-labs/coreth/core/types"
 	"github.com/ava-labs/coreth/eth"
 	"github.com/ava-labs/coreth/eth/ethconfig"
+	"github.com/ava-labs/coreth/eth/tracers"
 	"github.com/ava-labs/coreth/metrics"
 	corethPrometheus "github.com/ava-labs/coreth/metrics/prometheus"
 	"github.com/ava-labs/coreth/miner"
@@ -164,6 +165,8 @@ const (
 	adminEndpoint           = "/admin"
 	ethRPCEndpoint          = "/rpc"
 	ethWSEndpoint           = "/ws"
+	traceStreamEndpoint     = "/trace-stream"
+	traceStreamMethod       = "trace-stream" // method charged for the requests to the trace-stream endpoint
 	ethTxGossipNamespace    = "eth_tx_gossip"
 	atomicTxGossipNamespace = "atomic_tx_gossip"
 )
@@ -478,7 +481,15 @@ func (vm *VM) Initialize(
 	// if the chainCtx.NetworkUpgrades is not empty, set the chain config
 	// normally it should not be empty, but some tests may not set it
 	if chainCtx.NetworkUpgrades != (upgrade.Config{}) {
+		flareSystemContracts := g.Config.FlareSystemContracts
 		g.Config = params.GetChainConfig(chainCtx.NetworkID, new(big.Int).Set(chainID))
+		g.Config.FlareSystemContracts = flareSystemContracts
+	}
+
+	// Local and staging networks may configure the Flare system contracts in
+	// genesis or in the upgrade bytes, production networks use the built-in ones.
+	if err := setFlareSystemContracts(g.Config, chainCtx.NetworkID, upgradeBytes); err != nil {
+		return err
 	}
 
 	// If the Durango is activated, activate the Warp Precompile at the same time
@@ -541,10 +552,13 @@ func (vm *VM) Initialize(
 	vm.ethConfig.TxPool.AccountQueue = vm.config.TxPoolAccountQueue
 	vm.ethConfig.TxPool.GlobalQueue = vm.config.TxPoolGlobalQueue
 	vm.ethConfig.TxPool.Lifetime = vm.config.TxPoolLifetime.Duration
+	vm.ethConfig.TxPool.PrioritisedSlots = vm.config.TxPoolPrioritisedSlots
+	vm.ethConfig.Miner.PrioritisedGas = vm.config.MinerPrioritisedGas
 
 	vm.ethConfig.AllowUnfinalizedQueries = vm.config.AllowUnfinalizedQueries
 	vm.ethConfig.AllowUnprotectedTxs = vm.config.AllowUnprotectedTxs
 	vm.ethConfig.AllowUnprotectedTxHashes = vm.config.AllowUnprotectedTxHashes
+	vm.ethConfig.FeeTreatmentInReceipts = vm.config.FeeTreatmentInReceipts
 	vm.ethConfig.Preimages = vm.config.Preimages
 	vm.ethConfig.Pruning = vm.config.Pruning
 	vm.ethConfig.TrieCleanCache = vm.config.TrieCleanCache
@@ -562,11 +576,32 @@ func (vm *VM) Initialize(
 	vm.ethConfig.OfflinePruning = vm.config.OfflinePruning
 	vm.ethConfig.OfflinePruningBloomFilterSize = vm.config.OfflinePruningBloomFilterSize
 	vm.ethConfig.OfflinePruningDataDirectory = vm.config.OfflinePruningDataDirectory
+	vm.ethConfig.OnlinePruning = vm.config.OnlinePruning
+	vm.ethConfig.OnlinePruningBloomFilterSize = vm.config.OnlinePruningBloomFilterSize
+	vm.ethConfig.OnlinePruningDataDirectory = vm.config.OnlinePruningDataDirectory
+	vm.ethConfig.OnlinePruningInterval = vm.config.OnlinePruningInterval
+	if len(vm.ethConfig.OnlinePruningDataDirectory) == 0 {
+		vm.ethConfig.OnlinePruningDataDirectory = filepath.Join(vm.ctx.ChainDataDir, "online-pruning")
+	}
+	vm.ethConfig.StateScheme = vm.config.StateScheme
+	vm.ethConfig.StateHistory = vm.config.StateHistory
+	vm.ethConfig.StateSchemeMigration = vm.config.StateSchemeMigrationEnabled
+	vm.ethConfig.StateArchive = vm.config.StateArchiveEnabled
+	vm.ethConfig.StateArchiveRebuild = vm.config.StateArchiveRebuild
+	if vm.ethConfig.StateScheme == rawdb.PathScheme {
+		vm.ethConfig.StateHistoryDirectory = filepath.Join(vm.ctx.ChainDataDir, "state-history")
+	}
 	vm.ethConfig.CommitInterval = vm.config.CommitInterval
 	vm.ethConfig.SkipUpgradeCheck = vm.config.SkipUpgradeCheck
 	vm.ethConfig.AcceptedCacheSize = vm.config.AcceptedCacheSize
 	vm.ethConfig.TransactionHistory = vm.config.TransactionHistory
 	vm.ethConfig.SkipTxIndexing = vm.config.SkipTxIndexing
+	vm.ethConfig.GovernanceIndexEnabled = vm.config.GovernanceIndexEnabled
+	vm.ethConfig.TraceIndexEnabled = vm.config.TraceIndexEnabled
+	vm.ethConfig.TraceHistory = vm.config.TraceHistory
+	vm.ethConfig.TraceFileDir = vm.config.TraceFileDir
+	vm.ethConfig.LogIndexEnabled = vm.config.LogIndexEnabled
+	vm.ethConfig.LogIndexRebuild = vm.config.LogIndexRebuild
 
 	// Create directory for offline pruning
 	if len(vm.ethConfig.OfflinePruningDataDirectory) != 0 {
@@ -575,6 +610,13 @@ func (vm *VM) Initialize(
 			return err
 		}
 	}
+	// Create directory for online pruning
+	if vm.ethConfig.OnlinePruning {
+		if err := os.MkdirAll(vm.ethConfig.OnlinePruningDataDirectory, perms.ReadWriteExecute); err != nil {
+			log.Error("failed to create online pruning data directory", "error", err)
+			return err
+		}
+	}
 
 	vm.chainConfig = g.Config
 	vm.networkID = vm.ethConfig.NetworkId
@@ -659,6 +701,12 @@ func (vm *VM) Initialize(
 	if err != nil {
 		return fmt.Errorf("failed to create atomic repository: %w", err)
 	}
+	vm.eth.SetAtomicTxs(&chainArchiveAtomicTxs{
+		vm:     vm,
+		repo:   vm.atomicTxRepository,
+		config: vm.chainConfig,
+		codec:  vm.codec,
+	})
 	vm.atomicBackend, err = NewAtomicBackend(
 		vm.db, vm.ctx.SharedMemory, bonusBlockHeights,
 		vm.atomicTxRepository, lastAcceptedHeight, lastAcceptedHash,
@@ -694,6 +742,30 @@ func (vm *VM) Initialize(
 	return vm.initializeStateSyncClient(lastAcceptedHeight)
 }
 
+// flareUpgradeConfig is the part of the upgrade bytes read by this VM.
+type flareUpgradeConfig struct {
+	FlareSystemContracts *params.FlareSystemContracts `json:"flareSystemContracts,omitempty"`
+}
+
+// setFlareSystemContracts overrides the system contracts schedule of [config]
+// with the one in [upgradeBytes], if any, and rejects custom schedules on
+// production networks.
+func setFlareSystemContracts(config *params.ChainConfig, networkID uint32, upgradeBytes []byte) error {
+	if len(upgradeBytes) > 0 {
+		var upgradeConfig flareUpgradeConfig
+		if err := json.Unmarshal(upgradeBytes, &upgradeConfig); err != nil {
+			return fmt.Errorf("failed to unmarshal upgrade bytes %s: %w", string(upgradeBytes), err)
+		}
+		if upgradeConfig.FlareSystemContracts != nil {
+			config.FlareSystemContracts = upgradeConfig.FlareSystemContracts
+		}
+	}
+	if config.FlareSystemContracts != nil && constants.ProductionNetworkIDs.Contains(networkID) {
+		return fmt.Errorf("cannot configure flare system contracts on production network %d", networkID)
+	}
+	return nil
+}
+
 func (vm *VM) initializeMetrics() error {
 	vm.sdkMetrics = prometheus.NewRegistry()
 	// If metrics are enabled, register the default metrics registry
@@ -1026,10 +1098,8 @@ func (vm *VM) onFinalizeAndAssemble(header *types.Header, state *state.StateDB,
 
 func (vm *VM) onExtraStateChange(block *types.Block, state *state.StateDB) (*big.Int, *big.Int, error) {
 	var (
-		batchContribution *big.Int = big.NewInt(0)
-		batchGasUsed      *big.Int = big.NewInt(0)
-		header                     = block.Header()
-		rules                      = vm.chainConfig.Rules(header.Number, header.Time)
+		header = block.Header()
+		rules  = vm.chainConfig.Rules(header.Number, header.Time)
 	)
 
 	txs, err := ExtractAtomicTxs(block.ExtData(), rules.IsApricotPhase5, vm.codec)
@@ -1057,18 +1127,29 @@ func (vm *VM) onExtraStateChange(block *types.Block, state *state.StateDB) (*big
 		}
 	}
 
+	return applyAtomicTxs(vm.ctx, rules, block, txs, state)
+}
+
+// applyAtomicTxs applies the atomic transactions [txs] of [block] to [state]
+// and returns their contribution to the block fee and the gas they used.
+func applyAtomicTxs(ctx *snow.Context, rules params.Rules, block *types.Block, txs []*Tx, state *state.StateDB) (*big.Int, *big.Int, error) {
+	var (
+		batchContribution *big.Int = big.NewInt(0)
+		batchGasUsed      *big.Int = big.NewInt(0)
+	)
+
 	// If there are no transactions, we can return early.
 	if len(txs) == 0 {
 		return nil, nil, nil
 	}
 
 	for _, tx := range txs {
-		if err := tx.UnsignedAtomicTx.EVMStateTransfer(vm.ctx, state); err != nil {
+		if err := tx.UnsignedAtomicTx.EVMStateTransfer(ctx, state); err != nil {
 			return nil, nil, err
 		}
 		// If ApricotPhase4 is enabled, calculate the block fee contribution
 		if rules.IsApricotPhase4 {
-			contribution, gasUsed, err := tx.BlockFeeContribution(rules.IsApricotPhase5, vm.ctx.AVAXAssetID, block.BaseFee())
+			contribution, gasUsed, err := tx.BlockFeeContribution(rules.IsApricotPhase5, ctx.AVAXAssetID, block.BaseFee())
 			if err != nil {
 				return nil, nil, err
 			}
@@ -1313,14 +1394,19 @@ func (vm *VM) setAppRequestHandlers() {
 	// Create standalone EVM TrieDB (read only) for serving leafs requests.
 	// We create a standalone TrieDB here, so that it has a standalone cache from the one
 	// used by the node when processing blocks.
-	evmTrieDB := triedb.NewDatabase(
-		vm.chaindb,
-		&triedb.Config{
-			HashDB: &hashdb.Config{
-				CleanCacheSize: vm.config.StateSyncServerTrieCache * units.MiB,
+	// The states of the path-based scheme are only accessible through the trie
+	// database of the chain, which holds the recent layers.
+	evmTrieDB := vm.blockChain.TrieDB()
+	if evmTrieDB.Scheme() != rawdb.PathScheme {
+		evmTrieDB = triedb.NewDatabase(
+			vm.chaindb,
+			&triedb.Config{
+				HashDB: &hashdb.Config{
+					CleanCacheSize: vm.config.StateSyncServerTrieCache * units.MiB,
+				},
 			},
-		},
-	)
+		)
+	}
 	networkHandler := newNetworkHandler(
 		vm.blockChain,
 		vm.chaindb,
@@ -1529,6 +1615,13 @@ func (vm *VM) CreateHandlers(context.Context) (map[string]http.Handler, error) {
 	if vm.config.HttpBodyLimit > 0 {
 		handler.SetHTTPBodyLimit(int(vm.config.HttpBodyLimit))
 	}
+	handler.SetBatchLimits(vm.config.APIBatchRequestLimit, vm.config.APIBatchResponseMaxSize)
+	handler.SetQuotas(rpc.QuotaConfig{
+		KeyHeader:   vm.config.APIKeyHeader,
+		Keys:        vm.config.APIKeys,
+		Default:     rpc.Quota{Rate: vm.config.APIQuotaRate, Burst: vm.config.APIQuotaBurst},
+		MethodCosts: vm.config.APIMethodCosts,
+	})
 
 	enabledAPIs := vm.config.EthAPIs()
 	if err := attachEthService(handler, vm.eth.APIs(), enabledAPIs); err != nil {
@@ -1566,6 +1659,18 @@ func (vm *VM) CreateHandlers(context.Context) (map[string]http.Handler, error) {
 		enabledAPIs = append(enabledAPIs, "warp")
 	}
 
+	if vm.config.TraceAPIEnabled {
+		if err := handler.RegisterName("trace", tracers.NewTraceAPI(vm.eth.APIBackend)); err != nil {
+			return nil, err
+		}
+		enabledAPIs = append(enabledAPIs, "trace")
+	}
+
+	if vm.config.TraceStreamEnabled {
+		apis[traceStreamEndpoint] = handler.LimitHTTPHandler(traceStreamMethod, tracers.NewStreamHandler(vm.eth.APIBackend))
+		enabledAPIs = append(enabledAPIs, "trace-stream")
+	}
+
 	log.Info(fmt.Sprintf("Enabled APIs: %s", strings.Join(enabledAPIs, ", ")))
 	apis[ethRPCEndpoint] = handler
 	apis[ethWSEndpoint] = handler.WebsocketHandlerWithDuration(
@@ -1584,7 +1689,20 @@ func (vm *VM) CreateHandlers(context.Context) (map[string]http.Handler, error) {
 func (vm *VM) initializeDBs(db database.Database) error {
 	// Use NewNested rather than New so that the structure of the database
 	// remains the same regardless of the provided baseDB type.
-	vm.chaindb = rawdb.NewDatabase(Database{prefixdb.NewNested(ethDBPrefix, db)})
+	chaindb := Database{prefixdb.NewNested(ethDBPrefix, db)}
+	if vm.config.FreezerEnabled {
+		dir := vm.config.FreezerDirectory
+		if dir == "" {
+			dir = filepath.Join(vm.ctx.ChainDataDir, "ancient")
+		}
+		frdb, err := rawdb.NewDatabaseWithFreezer(chaindb, dir, vm.config.FreezerDepth)
+		if err != nil {
+			return fmt.Errorf("failed to open ancient store: %w", err)
+		}
+		vm.chaindb = frdb
+	} else {
+		vm.chaindb = rawdb.NewDatabase(chaindb)
+	}
 	vm.db = versiondb.New(db)
 	vm.acceptedBlockDB = prefixdb.New(acceptedPrefix, vm.db)
 	vm.metadataDB = prefixdb.New(metadataPrefix, vm.db)
@@ -97,6 +97,7 @@ type Client struct {
 	// config fields
 	batchItemLimit       int
 	batchResponseMaxSize int
+	quotas               *quotas
 
 	// writeConn is used for writing to the connection on the caller's goroutine. It should
 	// only be accessed outside of dispatch, with the write lock held. The write lock is
@@ -133,6 +134,7 @@ func (c *Client) newClientConn(conn ServerCodec, apiMaxDuration, refillRate, max
 	// When [apiMaxDuration] or [refillRate]/[maxStored] is 0 (as is the case for
 	// all client invocations of this function), it is ignored.
 	handler.deadlineContext = apiMaxDuration
+	handler.quotas = c.quotas
 	handler.addLimiter(refillRate, maxStored)
 	return &clientConn{conn, handler}
 }
@@ -261,6 +263,7 @@ func initClient(conn ServerCodec, services *serviceRegistry, cfg *clientConfig,
 		idgen:                cfg.idgen,
 		batchItemLimit:       cfg.batchItemLimit,
 		batchResponseMaxSize: cfg.batchResponseLimit,
+		quotas:               cfg.quotas,
 		writeConn:            conn,
 		close:                make(chan struct{}),
 		closing:              make(chan struct{}),
@@ -51,6 +51,7 @@ type clientConfig struct {
 	idgen              func() ID
 	batchItemLimit     int
 	batchResponseLimit int
+	quotas             *quotas
 }
 
 func (cfg *clientConfig) initHeaders() {
@@ -32,7 +32,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"reflect"
 	"strconv"
 	"strings"
@@ -44,31 +43,6 @@ import (
 	"golang.org/x/time/rate"
 )
 
-var (
-	batchRequestLimit    = 0 // limit on total number of requests in a batch
-	batchResponseMaxSize = 0 // limit on the size of a batch response
-)
-
-func init() {
-	// Read batchRequestLimit and batchResponseMaxSize from environment variables
-	// RPC_BATCH_REQUEST_LIMIT and RPC_BATCH_RESPONSE_MAX_SIZE.
-	// If their values are invalid integers, panic.
-	if batchRequestLimitStr := os.Getenv("RPC_BATCH_REQUEST_LIMIT"); batchRequestLimitStr != "" {
-		var err error
-		batchRequestLimit, err = strconv.Atoi(batchRequestLimitStr)
-		if err != nil || batchRequestLimit < 0 {
-			panic("RPC_BATCH_REQUEST_LIMIT must be a non-negative integer")
-		}
-	}
-	if batchResponseMaxSizeStr := os.Getenv("RPC_BATCH_RESPONSE_MAX_SIZE"); batchResponseMaxSizeStr != "" {
-		var err error
-		batchResponseMaxSize, err = strconv.Atoi(batchResponseMaxSizeStr)
-		if err != nil || batchResponseMaxSize < 0 {
-			panic("RPC_BATCH_RESPONSE_MAX_SIZE must be a non-negative integer")
-		}
-	}
-}
-
 // handler handles JSON-RPC messages. There is one handler per connection. Note that
 // handler is not safe for concurrent use. Message handling never blocks indefinitely
 // because RPCs are processed on background goroutines launched by handler.
@@ -109,6 +83,7 @@ type handler struct {
 
 	deadlineContext time.Duration // limits execution after some time.Duration
 	limiter         *rate.Limiter
+	quotas          *quotas // per-client request quotas, nil means unlimited
 }
 
 type callProc struct {
@@ -618,6 +593,11 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if h.quotas != nil && !msg.isUnsubscribe() {
+		if err := h.quotas.charge(PeerInfoFromContext(cp.ctx), msg.Method, h.isRegistered(msg)); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -656,6 +636,15 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	return answer
 }
 
+// isRegistered returns whether [msg] calls a registered method or subscribes
+// to a registered service.
+func (h *handler) isRegistered(msg *jsonrpcMessage) bool {
+	if msg.isSubscribe() {
+		return h.reg.hasService(msg.namespace())
+	}
+	return h.reg.callback(msg.Method) != nil
+}
+
 // handleSubscribe processes *_subscribe method calls.
 func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
 	if !h.allowSubscribe {
@@ -337,6 +337,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	connInfo.HTTP.Host = r.Host
 	connInfo.HTTP.Origin = r.Header.Get("Origin")
 	connInfo.HTTP.UserAgent = r.Header.Get("User-Agent")
+	connInfo.HTTP.APIKey = s.quotas.apiKey(r.Header)
 	ctx := r.Context()
 	ctx = context.WithValue(ctx, peerInfoContextKey{}, connInfo)
 
@@ -349,6 +350,41 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.serveSingleRequest(ctx, codec)
 }
 
+// LimitHTTPHandler returns an HTTP handler serving requests with [handler]
+// under the limits of the server: the body limit, the quota of the client,
+// charged as a call of [method], and the maximum duration, which bounds the
+// context of the request.
+func (s *Server) LimitHTTPHandler(method string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > int64(s.httpBodyLimit) {
+			err := fmt.Errorf("content length too large (%d>%d)", r.ContentLength, s.httpBodyLimit)
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, int64(s.httpBodyLimit))
+
+		connInfo := PeerInfo{Transport: "http", RemoteAddr: r.RemoteAddr}
+		connInfo.HTTP.Version = r.Proto
+		connInfo.HTTP.Host = r.Host
+		connInfo.HTTP.Origin = r.Header.Get("Origin")
+		connInfo.HTTP.UserAgent = r.Header.Get("User-Agent")
+		connInfo.HTTP.APIKey = s.quotas.apiKey(r.Header)
+		if s.quotas != nil {
+			if err := s.quotas.charge(connInfo, method, true); err != nil {
+				http.Error(w, err.Error(), http.StatusTooManyRequests)
+				return
+			}
+		}
+		ctx := context.WithValue(r.Context(), peerInfoContextKey{}, connInfo)
+		if s.maximumDuration > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.maximumDuration)
+			defer cancel()
+		}
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // validateRequest returns a non-zero response code and error message if the
 // request is invalid.
 func (s *Server) validateRequest(r *http.Request) (int, error) {
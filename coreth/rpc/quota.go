@@ -0,0 +1,153 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/coreth/metrics"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// quotaSweepInterval is the interval at which the limiters of idle clients
+	// are dropped.
+	quotaSweepInterval = time.Minute
+
+	// ipClientLabel is the metrics label of the clients identified by their IP
+	// address, which are aggregated to keep the number of metrics bounded.
+	ipClientLabel = "ip"
+
+	// unknownMethodLabel is the metrics label of the calls of methods that are
+	// not registered, whose names are chosen by the clients.
+	unknownMethodLabel = "unknown"
+)
+
+// Quota is the request budget of a client, in units of method cost. A client
+// can spend up to [Burst] at once, which is refilled at [Rate] per second. A
+// zero rate means unlimited.
+type Quota struct {
+	// Name labels the metrics of the clients using this quota, so that API
+	// keys are not exported.
+	Name  string  `json:"name"`
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// QuotaConfig configures the per-client request quotas of a Server.
+type QuotaConfig struct {
+	// KeyHeader is the HTTP header carrying the API key of a client. Clients
+	// without a known key are identified by their IP address.
+	KeyHeader string
+	// Keys are the quotas of the clients presenting an API key.
+	Keys map[string]Quota
+	// Default is the quota of each client without a known API key.
+	Default Quota
+	// MethodCosts are the costs of a call per method. Methods not listed
+	// cost 1.
+	MethodCosts map[string]int
+}
+
+// quotas charges the calls of the clients of a Server against their quotas.
+type quotas struct {
+	config QuotaConfig
+
+	lock      sync.Mutex
+	limiters  map[string]*rate.Limiter // limiters per API key or IP address
+	lastSweep time.Time
+}
+
+func newQuotas(config QuotaConfig) *quotas {
+	return &quotas{
+		config:    config,
+		limiters:  make(map[string]*rate.Limiter),
+		lastSweep: time.Now(),
+	}
+}
+
+// apiKey returns the API key presented in [header], if any.
+func (q *quotas) apiKey(header http.Header) string {
+	if q == nil || q.config.KeyHeader == "" {
+		return ""
+	}
+	return header.Get(q.config.KeyHeader)
+}
+
+// charge deducts the cost of calling [method] from the quota of the client
+// [peer]. The calls of methods that are not [registered] are counted under a
+// single metrics label. It returns a limitExceededError if the client is over
+// its quota.
+func (q *quotas) charge(peer PeerInfo, method string, registered bool) error {
+	client, label, quota := q.client(peer)
+	cost, ok := q.config.MethodCosts[method]
+	if !ok {
+		cost = 1
+	}
+	allowed := quota.Rate <= 0 || q.limiter(client, quota).AllowN(time.Now(), cost)
+
+	methodLabel := method
+	if !registered {
+		methodLabel = unknownMethodLabel
+	}
+	if allowed {
+		metrics.GetOrRegisterCounter(fmt.Sprintf("rpc/quota/method/%s/cost", methodLabel), nil).Inc(int64(cost))
+		metrics.GetOrRegisterCounter(fmt.Sprintf("rpc/quota/client/%s/cost", label), nil).Inc(int64(cost))
+		return nil
+	}
+	metrics.GetOrRegisterCounter(fmt.Sprintf("rpc/quota/method/%s/rejected", methodLabel), nil).Inc(1)
+	metrics.GetOrRegisterCounter(fmt.Sprintf("rpc/quota/client/%s/rejected", label), nil).Inc(1)
+	return &limitExceededError{method: method}
+}
+
+// client identifies [peer] by its API key if it is known, or by its IP
+// address otherwise. It returns the identity, the metrics label and the quota
+// of the client.
+func (q *quotas) client(peer PeerInfo) (string, string, Quota) {
+	if quota, ok := q.config.Keys[peer.HTTP.APIKey]; ok && peer.HTTP.APIKey != "" {
+		label := quota.Name
+		if label == "" {
+			label = "key"
+		}
+		return "key:" + peer.HTTP.APIKey, label, quota
+	}
+	host, _, err := net.SplitHostPort(peer.RemoteAddr)
+	if err != nil {
+		host = peer.RemoteAddr
+	}
+	return "ip:" + host, ipClientLabel, q.config.Default
+}
+
+// limiter returns the token bucket of [client], creating it with a full
+// [quota] if the client has none.
+func (q *quotas) limiter(client string, quota Quota) *rate.Limiter {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(q.lastSweep) > quotaSweepInterval {
+		q.sweep(now)
+	}
+	limiter, ok := q.limiters[client]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(quota.Rate), quota.Burst)
+		q.limiters[client] = limiter
+	}
+	return limiter
+}
+
+// sweep drops the limiters that are full again, which are equivalent to the
+// limiters recreated on the next call of their clients.
+// It assumes q.lock is held.
+func (q *quotas) sweep(now time.Time) {
+	for client, limiter := range q.limiters {
+		if limiter.TokensAt(now) >= float64(limiter.Burst()) {
+			delete(q.limiters, client)
+		}
+	}
+	q.lastSweep = now
+}
@@ -0,0 +1,155 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/coreth/metrics"
+)
+
+func TestServerQuotas(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetQuotas(QuotaConfig{
+		KeyHeader:   "X-Api-Key",
+		Keys:        map[string]Quota{"secret": {Name: "partner", Rate: 1e-9, Burst: 10}},
+		Default:     Quota{Rate: 1e-9, Burst: 3},
+		MethodCosts: map[string]int{"test_null": 2},
+	})
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	call := func(client *Client, method string) error {
+		var result any
+		return client.Call(&result, method)
+	}
+	checkLimited := func(err error) {
+		t.Helper()
+		var rpcErr Error
+		if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != errcodeLimitExceeded {
+			t.Fatalf("expected quota error, got %v", err)
+		}
+	}
+
+	// Clients without a key share the quota of their IP address.
+	anonymous, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer anonymous.Close()
+	if err := call(anonymous, "test_null"); err != nil {
+		t.Fatal(err)
+	}
+	checkLimited(call(anonymous, "test_null"))
+	if err := call(anonymous, "test_noArgsRets"); err != nil {
+		t.Fatal(err)
+	}
+	checkLimited(call(anonymous, "test_noArgsRets"))
+
+	// Clients with an unknown key are charged by IP address as well.
+	unknown, err := DialOptions(context.Background(), httpsrv.URL, WithHeader("X-Api-Key", "guess"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unknown.Close()
+	checkLimited(call(unknown, "test_noArgsRets"))
+
+	// Clients with a known key have a quota of their own.
+	keyed, err := DialOptions(context.Background(), httpsrv.URL, WithHeader("X-Api-Key", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keyed.Close()
+	for i := 0; i < 5; i++ {
+		if err := call(keyed, "test_null"); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	checkLimited(call(keyed, "test_null"))
+}
+
+// Tests that calls of methods that are not registered, whose names are chosen
+// by the clients, do not register metrics of their own.
+func TestServerQuotasUnknownMethods(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetQuotas(QuotaConfig{Default: Quota{Rate: 1e-9, Burst: 1}})
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	client, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	countMetrics := func() int {
+		var n int
+		metrics.DefaultRegistry.Each(func(name string, _ interface{}) {
+			if strings.HasPrefix(name, "rpc/quota/") {
+				n++
+			}
+		})
+		return n
+	}
+	call := func(method string) {
+		var result any
+		client.Call(&result, method)
+	}
+
+	// Register the metrics of the unknown methods, allowed and rejected.
+	call("test_unknown")
+	call("test_unknown")
+	want := countMetrics()
+	for i := 0; i < 100; i++ {
+		call(fmt.Sprintf("test_%x", rand.Uint64()))
+		call(fmt.Sprintf("ns%x_subscribe", rand.Uint64()))
+	}
+	if have := countMetrics(); have != want {
+		t.Fatalf("wrong number of quota metrics: have %d, want %d", have, want)
+	}
+}
+
+// Tests that the HTTP handlers served next to the server are held to its
+// quotas and maximum duration.
+func TestLimitHTTPHandler(t *testing.T) {
+	server := NewServer(time.Minute)
+	defer server.Stop()
+	server.SetQuotas(QuotaConfig{
+		Default:     Quota{Rate: 1e-9, Burst: 3},
+		MethodCosts: map[string]int{"trace_stream": 2},
+	})
+	var deadline time.Time
+	handler := server.LimitHTTPHandler("trace_stream", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ = r.Context().Deadline()
+	}))
+	httpsrv := httptest.NewServer(handler)
+	defer httpsrv.Close()
+
+	post := func() int {
+		resp, err := http.Post(httpsrv.URL, "application/json", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := post(); code != http.StatusOK {
+		t.Fatalf("wrong status code: have %d, want %d", code, http.StatusOK)
+	}
+	if until := time.Until(deadline); until <= 0 || until > time.Minute {
+		t.Fatalf("request not bounded by the maximum duration, deadline in %v", until)
+	}
+	if code := post(); code != http.StatusTooManyRequests {
+		t.Fatalf("wrong status code: have %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
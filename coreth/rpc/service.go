@@ -112,6 +112,14 @@ func (r *serviceRegistry) callback(method string) *callback {
 	return r.services[before].callbacks[after]
 }
 
+// hasService returns whether a service is registered under the given name.
+func (r *serviceRegistry) hasService(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.services[name]
+	return ok
+}
+
 // subscription returns a subscription callback in the given service.
 func (r *serviceRegistry) subscription(service, name string) *callback {
 	r.mu.Lock()
@@ -155,6 +155,35 @@ func (n *Notifier) Notify(id ID, data any) error {
 	return nil
 }
 
+// Throttle charges [cost] of processing time to the CPU limiter of the
+// connection, waiting until the limiter allows it or ctx is done. It lets
+// subscriptions whose notifications are expensive to compute apply the same
+// backpressure as method calls. It is a no-op if the connection is not
+// limited.
+func (n *Notifier) Throttle(ctx context.Context, cost time.Duration) error {
+	if n.h.limiter == nil {
+		return nil
+	}
+	// The limiter holds at least the deadline of a call, which bounds the
+	// cost of a single notification the same way.
+	reservation := n.h.limiter.ReserveN(time.Now(), int(min(cost, n.h.deadlineContext)))
+	if !reservation.OK() {
+		return nil
+	}
+	timer := time.NewTimer(reservation.Delay())
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		reservation.Cancel()
+		return ctx.Err()
+	case <-n.h.conn.closed():
+		reservation.Cancel()
+		return errDead
+	case <-timer.C:
+		return nil
+	}
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 // Deprecated: use subscription error channel
 func (n *Notifier) Closed() <-chan interface{} {
@@ -31,6 +31,7 @@ type StateSyncerConfig struct {
 	MaxOutstandingCodeHashes int    // Maximum number of code hashes in the code syncer queue
 	NumCodeFetchingWorkers   int    // Number of code syncing threads
 	RequestSize              uint16 // Number of leafs to request from a peer at a time
+	StateScheme              string // Scheme the synced trie nodes are written with, hash-based if empty
 }
 
 // stateSync keeps the state of the entire state sync operation.
@@ -38,6 +39,7 @@ type stateSync struct {
 	db        ethdb.Database    // database we are syncing
 	root      common.Hash       // root of the EVM state we are syncing to
 	trieDB    *triedb.Database  // trieDB on top of db we are syncing. used to restore any existing tries.
+	scheme    string            // scheme the synced trie nodes are written with
 	snapshot  snapshot.Snapshot // used to access the database we are syncing as a snapshot.
 	batchSize int               // write batches when they reach this size
 	client    syncclient.Client // used to contact peers over the network
@@ -69,6 +71,7 @@ func NewStateSyncer(config *StateSyncerConfig) (*stateSync, error) {
 		client:          config.Client,
 		root:            config.Root,
 		trieDB:          triedb.NewDatabase(config.DB, nil),
+		scheme:          config.StateScheme,
 		snapshot:        snapshot.NewDiskLayer(config.DB),
 		stats:           newTrieSyncStats(),
 		triesInProgress: make(map[common.Hash]*trieToSync),
@@ -100,7 +103,7 @@ func NewStateSyncer(config *StateSyncerConfig) (*stateSync, error) {
 
 	// create a trieToSync for the main trie and mark it as in progress.
 	var err error
-	ss.mainTrie, err = NewTrieToSync(ss, ss.root, common.Hash{}, NewMainTrieTask(ss))
+	ss.mainTrie, err = NewTrieToSync(ss, ss.root, []common.Hash{{}}, NewMainTrieTask(ss))
 	if err != nil {
 		return nil, err
 	}
@@ -194,13 +197,10 @@ func (t *stateSync) storageTrieProducer(ctx context.Context) error {
 			return ctx.Err()
 		}
 
-		// Arbitrarily use the first account for making requests to the server.
+		// create a trieToSync for the storage trie and mark it as in progress.
 		// Note: getNextTrie guarantees that if a non-nil storage root is returned, then the
 		// slice of account hashes is non-empty.
-		syncAccount := accounts[0]
-
-		// create a trieToSync for the storage trie and mark it as in progress.
-		storageTrie, err := NewTrieToSync(t, root, syncAccount, NewStorageTrieTask(t, root, accounts))
+		storageTrie, err := NewTrieToSync(t, root, accounts, NewStorageTrieTask(t, root, accounts))
 		if err != nil {
 			return err
 		}
@@ -62,15 +62,25 @@ type trieToSync struct {
 }
 
 // NewTrieToSync initializes a trieToSync and restores any previously started segments.
-func NewTrieToSync(sync *stateSync, root common.Hash, account common.Hash, syncTask syncTask) (*trieToSync, error) {
-	batch := sync.db.NewBatch() // TODO: migrate state sync to use database schemes.
+// [accounts] are the hashes of the accounts owning the trie, the first one is
+// used for making requests to the server (empty for the main trie).
+func NewTrieToSync(sync *stateSync, root common.Hash, accounts []common.Hash, syncTask syncTask) (*trieToSync, error) {
+	batch := sync.db.NewBatch()
 	writeFn := func(path []byte, hash common.Hash, blob []byte) {
-		rawdb.WriteTrieNode(batch, account, path, hash, blob, rawdb.HashScheme)
+		if sync.scheme != rawdb.PathScheme {
+			rawdb.WriteTrieNode(batch, accounts[0], path, hash, blob, rawdb.HashScheme)
+			return
+		}
+		// The nodes of the path-based scheme are keyed by their owner, so a
+		// storage trie shared by several accounts is written for each of them.
+		for _, account := range accounts {
+			rawdb.WriteTrieNode(batch, account, path, hash, blob, rawdb.PathScheme)
+		}
 	}
 	trieToSync := &trieToSync{
 		sync:         sync,
 		root:         root,
-		account:      account,
+		account:      accounts[0],
 		batch:        batch,
 		stackTrie:    trie.NewStackTrie(&trie.StackTrieOptions{Writer: writeFn}),
 		isMainTrie:   (root == sync.root),
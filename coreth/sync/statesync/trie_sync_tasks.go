@@ -109,6 +109,11 @@ func (s *storageTrieTask) IterateLeafs(seek common.Hash) ethdb.Iterator {
 }
 
 func (s *storageTrieTask) OnStart() (bool, error) {
+	// The nodes of the path-based scheme are keyed by their owner, a storage
+	// trie on disk cannot be reused for other accounts, so it is synced again.
+	if s.sync.scheme == rawdb.PathScheme {
+		return false, nil
+	}
 	// check if this storage root is on disk
 	var firstAccount common.Hash
 	if len(s.accounts) > 0 {
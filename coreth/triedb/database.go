@@ -256,6 +256,18 @@ func (db *Database) Dereference(root common.Hash) error {
 	return nil
 }
 
+// SetFlushHook installs the hook called for every trie node written to disk.
+// It's only supported by hash-based database and will return an error for
+// others.
+func (db *Database) SetFlushHook(hook hashdb.FlushHook) error {
+	hdb, ok := db.backend.(*hashdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	hdb.SetFlushHook(hook)
+	return nil
+}
+
 // Recover rollbacks the database to a specified historical point. The state is
 // supported as the rollback destination only if it's canonical state and the
 // corresponding trie histories are existent. It's only supported by path-based
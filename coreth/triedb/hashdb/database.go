@@ -31,6 +31,7 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ava-labs/coreth/core/rawdb"
@@ -140,8 +141,14 @@ type Database struct {
 	lock sync.RWMutex
 
 	referenceRoot bool
+	flushHook     atomic.Pointer[FlushHook] // Observer of the nodes written to disk, might be nil
 }
 
+// FlushHook is called for every trie node written to disk, before the node is
+// added to [batch]. Any data the hook writes to [batch] is persisted
+// atomically with the node.
+type FlushHook func(batch ethdb.KeyValueWriter, hash common.Hash)
+
 // cachedNode is all the information we know about a single cached trie node
 // in the memory database write layer.
 type cachedNode struct {
@@ -386,14 +393,29 @@ type flushItem struct {
 	rlp  []byte
 }
 
+// SetFlushHook installs the hook called for every trie node written to disk,
+// replacing the previous one. A nil hook removes it.
+func (db *Database) SetFlushHook(hook FlushHook) {
+	if hook == nil {
+		db.flushHook.Store(nil)
+		return
+	}
+	db.flushHook.Store(&hook)
+}
+
 // writeFlushItems writes all items in [toFlush] to disk in batches of
 // [ethdb.IdealBatchSize]. This function does not access any variables inside
-// of [Database] and does not need to be synchronized.
+// of [Database] other than the atomic flush hook and does not need to be
+// synchronized.
 func (db *Database) writeFlushItems(toFlush []*flushItem) error {
 	batch := db.diskdb.NewBatch()
+	hook := db.flushHook.Load()
 	for _, item := range toFlush {
 		rlp := item.node.node
 		item.rlp = rlp
+		if hook != nil {
+			(*hook)(batch, item.hash)
+		}
 		rawdb.WriteLegacyTrieNode(batch, item.hash, rlp)
 
 		// If we exceeded the ideal batch size, commit and reset
@@ -31,6 +31,7 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/ava-labs/coreth/core/rawdb"
 	"github.com/ava-labs/coreth/core/types"
@@ -95,10 +96,11 @@ type layer interface {
 
 // Config contains the settings for database.
 type Config struct {
-	StateHistory   uint64 // Number of recent blocks to maintain state history for
-	CleanCacheSize int    // Maximum memory allowance (in bytes) for caching clean nodes
-	DirtyCacheSize int    // Maximum memory allowance (in bytes) for caching dirty nodes
-	ReadOnly       bool   // Flag whether the database is opened in read only mode.
+	StateHistory     uint64 // Number of recent blocks to maintain state history for
+	HistoryDirectory string // Directory of the state history freezer, state history is disabled if empty
+	CleanCacheSize   int    // Maximum memory allowance (in bytes) for caching clean nodes
+	DirtyCacheSize   int    // Maximum memory allowance (in bytes) for caching dirty nodes
+	ReadOnly         bool   // Flag whether the database is opened in read only mode.
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -145,8 +147,7 @@ type Database struct {
 	tree       *layerTree     // The group for all known layers
 	lock       sync.RWMutex   // Lock to prevent mutations from happening at the same time
 
-	// NOTE(freezer): This is disabled since we do not have a freezer.
-	// freezer    *rawdb.ResettableFreezer // Freezer for storing trie histories, nil possible in tests
+	freezer ethdb.ResettableAncientStore // Freezer for storing trie histories, nil possible in tests
 }
 
 // New attempts to load an already existing layer from a persistent key-value
@@ -168,48 +169,48 @@ func New(diskdb ethdb.Database, config *Config) *Database {
 	// and in-memory layer journal.
 	db.tree = newLayerTree(db.loadLayers())
 
-	// NOTE(freezer): This is disabled since we do not have a freezer.
-	// Open the freezer for state history if the passed database contains an
-	// ancient store. Otherwise, all the relevant functionalities are disabled.
+	// Open the freezer for state history if a directory is configured for it.
+	// Otherwise, all the relevant functionalities are disabled.
 	//
 	// Because the freezer can only be opened once at the same time, this
 	// mechanism also ensures that at most one **non-readOnly** database
 	// is opened at the same time to prevent accidental mutation.
-	// if ancient, err := diskdb.AncientDatadir(); err == nil && ancient != "" && !db.readOnly {
-	// 	freezer, err := rawdb.NewStateFreezer(ancient, false)
-	// 	if err != nil {
-	// 		log.Crit("Failed to open state history freezer", "err", err)
-	// 	}
-	// 	db.freezer = freezer
-	//
-	// 	diskLayerID := db.tree.bottom().stateID()
-	// 	if diskLayerID == 0 {
-	// 		// Reset the entire state histories in case the trie database is
-	// 		// not initialized yet, as these state histories are not expected.
-	// 		frozen, err := db.freezer.Ancients()
-	// 		if err != nil {
-	// 			log.Crit("Failed to retrieve head of state history", "err", err)
-	// 		}
-	// 		if frozen != 0 {
-	// 			err := db.freezer.Reset()
-	// 			if err != nil {
-	// 				log.Crit("Failed to reset state histories", "err", err)
-	// 			}
-	// 			log.Info("Truncated extraneous state history")
-	// 		}
-	// 	} else {
-	// 		// Truncate the extra state histories above in freezer in case
-	// 		// it's not aligned with the disk layer.
-	// 		pruned, err := truncateFromHead(db.diskdb, freezer, diskLayerID)
-	// 		if err != nil {
-	// 			log.Crit("Failed to truncate extra state histories", "err", err)
-	// 		}
-	// 		if pruned != 0 {
-	// 			log.Warn("Truncated extra state histories", "number", pruned)
-	// 		}
-	// 	}
-	// }
-	// // Disable database in case node is still in the initial state sync stage.
+	if config.HistoryDirectory != "" && !db.readOnly {
+		freezer, err := rawdb.NewStateFreezer(config.HistoryDirectory)
+		if err != nil {
+			log.Crit("Failed to open state history freezer", "err", err)
+		}
+		db.freezer = freezer
+
+		diskLayerID := db.tree.bottom().stateID()
+		if diskLayerID == 0 {
+			// Reset the entire state histories in case the trie database is
+			// not initialized yet, as these state histories are not expected.
+			frozen, err := db.freezer.Ancients()
+			if err != nil {
+				log.Crit("Failed to retrieve head of state history", "err", err)
+			}
+			if frozen != 0 {
+				err := db.freezer.Reset()
+				if err != nil {
+					log.Crit("Failed to reset state histories", "err", err)
+				}
+				log.Info("Truncated extraneous state history")
+			}
+		} else {
+			// Truncate the extra state histories above in freezer in case
+			// it's not aligned with the disk layer.
+			pruned, err := truncateFromHead(db.diskdb, freezer, diskLayerID)
+			if err != nil {
+				log.Crit("Failed to truncate extra state histories", "err", err)
+			}
+			if pruned != 0 {
+				log.Warn("Truncated extra state histories", "number", pruned)
+			}
+		}
+	}
+	// NOTE: This is disabled since we do not have SnapSyncStatusFlag.
+	// Disable database in case node is still in the initial state sync stage.
 	// if rawdb.ReadSnapSyncStatusFlag(diskdb) == rawdb.StateSyncRunning && !db.readOnly {
 	// 	if err := db.Disable(); err != nil {
 	// 		log.Crit("Failed to disable database", "err", err) // impossible to happen
@@ -257,7 +258,9 @@ func (db *Database) Update(root common.Hash, parentRoot common.Hash, block uint6
 
 // Commit traverses downwards the layer tree from a specified layer with the
 // provided state root and all the layers below are flattened downwards. It
-// can be used alone and mostly for test purposes.
+// can be used alone and mostly for test purposes. If the state has no diff
+// layer, which happens once it is merged into the disk layer, the dirty nodes
+// of the disk layer are flushed.
 func (db *Database) Commit(root common.Hash, report bool) error {
 	// Hold the lock to prevent concurrent mutations.
 	db.lock.Lock()
@@ -267,6 +270,14 @@ func (db *Database) Commit(root common.Hash, report bool) error {
 	if err := db.modifyAllowed(); err != nil {
 		return err
 	}
+	// A state without a diff layer has already been merged into the disk
+	// layer, whose cached dirty nodes are flushed instead.
+	if _, ok := db.tree.get(types.TrieRootHash(root)).(*diffLayer); !ok {
+		dl := db.tree.bottom()
+		dl.lock.Lock()
+		defer dl.lock.Unlock()
+		return dl.buffer.flush(db.diskdb, dl.cleans, dl.id, true)
+	}
 	return db.tree.cap(root, 0)
 }
 
@@ -321,16 +332,15 @@ func (db *Database) Enable(root common.Hash) error {
 	if err := batch.Write(); err != nil {
 		return err
 	}
-	// NOTE(freezer): This is disabled since we do not have a freezer.
 	// Clean up all state histories in freezer. Theoretically
 	// all root->id mappings should be removed as well. Since
 	// mappings can be huge and might take a while to clear
 	// them, just leave them in disk and wait for overwriting.
-	// if db.freezer != nil {
-	// 	if err := db.freezer.Reset(); err != nil {
-	// 		return err
-	// 	}
-	// }
+	if db.freezer != nil {
+		if err := db.freezer.Reset(); err != nil {
+			return err
+		}
+	}
 	// Re-construct a new disk layer backed by persistent state
 	// with **empty clean cache and node buffer**.
 	db.tree.reset(newDiskLayer(root, 0, db, nil, newNodeBuffer(db.bufferSize, nil, 0)))
@@ -346,8 +356,47 @@ func (db *Database) Enable(root common.Hash) error {
 // The state is supported as the rollback destination only if it's
 // canonical state and the corresponding trie histories are existent.
 func (db *Database) Recover(root common.Hash, loader triestate.TrieLoader) error {
-	// NOTE(freezer): This is disabled since we do not have a freezer.
-	return errors.New("state rollback is non-supported")
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// Short circuit if rollback operation is not supported.
+	if err := db.modifyAllowed(); err != nil {
+		return err
+	}
+	if db.freezer == nil {
+		return errors.New("state rollback is non-supported")
+	}
+	// Short circuit if the target state is not recoverable.
+	root = types.TrieRootHash(root)
+	if !db.Recoverable(root) {
+		return errStateUnrecoverable
+	}
+	// Apply the state histories upon the disk layer in order.
+	var (
+		start = time.Now()
+		dl    = db.tree.bottom()
+	)
+	for dl.rootHash() != root {
+		h, err := readHistory(db.freezer, dl.stateID())
+		if err != nil {
+			return err
+		}
+		dl, err = dl.revert(h, loader)
+		if err != nil {
+			return err
+		}
+		// reset layer with newly created disk layer. It must be
+		// done after each revert operation, otherwise the new
+		// disk layer won't be accessible from outside.
+		db.tree.reset(dl)
+	}
+	rawdb.DeleteTrieJournal(db.diskdb)
+	_, err := truncateFromHead(db.diskdb, db.freezer, dl.stateID())
+	if err != nil {
+		return err
+	}
+	log.Debug("Recovered state", "root", root, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
 }
 
 // Recoverable returns the indicator if the specified state is recoverable.
@@ -365,21 +414,22 @@ func (db *Database) Recoverable(root common.Hash) bool {
 	if *id >= dl.stateID() {
 		return false
 	}
-	return false
-	// NOTE(freezer): This is disabled since we do not have a freezer.
+	if db.freezer == nil {
+		return false
+	}
 	// Ensure the requested state is a canonical state and all state
 	// histories in range [id+1, disklayer.ID] are present and complete.
-	// parent := root
-	// return checkHistories(db.freezer, *id+1, dl.stateID()-*id, func(m *meta) error {
-	// 	if m.parent != parent {
-	// 		return errors.New("unexpected state history")
-	// 	}
-	// 	if len(m.incomplete) > 0 {
-	// 		return errors.New("incomplete state history")
-	// 	}
-	// 	parent = m.root
-	// 	return nil
-	// }) == nil
+	parent := root
+	return checkHistories(db.freezer, *id+1, dl.stateID()-*id, func(m *meta) error {
+		if m.parent != parent {
+			return errors.New("unexpected state history")
+		}
+		if len(m.incomplete) > 0 {
+			return errors.New("incomplete state history")
+		}
+		parent = m.root
+		return nil
+	}) == nil
 }
 
 // Close closes the trie database and the held freezer.
@@ -394,13 +444,11 @@ func (db *Database) Close() error {
 	// Release the memory held by clean cache.
 	db.tree.bottom().resetCache()
 
-	// NOTE(freezer): This is disabled since we do not have a freezer.
 	// Close the attached state history freezer.
-	//if db.freezer == nil {
-	//	return nil
-	//}
-	//return db.freezer.Close()
-	return nil
+	if db.freezer == nil {
+		return nil
+	}
+	return db.freezer.Close()
 }
 
 // Size returns the current storage size of the memory cache in front of the
@@ -42,7 +42,6 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/holiman/uint256"
-	"github.com/stretchr/testify/require"
 )
 
 func updateTrie(addrHash common.Hash, root common.Hash, dirties, cleans map[common.Hash][]byte) (common.Hash, *trienode.NodeSet) {
@@ -109,13 +108,12 @@ type tester struct {
 
 func newTester(t *testing.T, historyLimit uint64) *tester {
 	var (
-		// NOTE(freezer): This is disabled since we do not have a freezer.
-		// disk, _ = rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
 		disk = rawdb.NewMemoryDatabase()
 		db   = New(disk, &Config{
-			StateHistory:   historyLimit,
-			CleanCacheSize: 256 * 1024,
-			DirtyCacheSize: 256 * 1024,
+			StateHistory:     historyLimit,
+			HistoryDirectory: t.TempDir(),
+			CleanCacheSize:   256 * 1024,
+			DirtyCacheSize:   256 * 1024,
 		})
 		obj = &tester{
 			db:           db,
@@ -349,6 +347,40 @@ func (t *tester) verifyState(root common.Hash) error {
 	return nil
 }
 
+// verifyHistory checks that the state histories of the states below or equal
+// to the disk layer are stored and linked, and that no history is stored for
+// the states above it.
+func (t *tester) verifyHistory() error {
+	bottom := t.bottomIndex()
+	for i, root := range t.roots {
+		// The state history related to the state above disk layer should not exist.
+		if i > bottom {
+			_, err := readHistory(t.db.freezer, uint64(i+1))
+			if err == nil {
+				return errors.New("unexpected state history")
+			}
+			continue
+		}
+		// The state history related to the state below or equal to the disk layer
+		// should exist.
+		obj, err := readHistory(t.db.freezer, uint64(i+1))
+		if err != nil {
+			return err
+		}
+		parent := types.EmptyRootHash
+		if i != 0 {
+			parent = t.roots[i-1]
+		}
+		if obj.meta.parent != parent {
+			return fmt.Errorf("unexpected parent, want: %x, got: %x", parent, obj.meta.parent)
+		}
+		if obj.meta.root != root {
+			return fmt.Errorf("unexpected root, want: %x, got: %x", root, obj.meta.root)
+		}
+	}
+	return nil
+}
+
 // bottomIndex returns the index of current disk layer.
 func (t *tester) bottomIndex() int {
 	bottom := t.db.tree.bottom()
@@ -365,10 +397,9 @@ func TestDatabaseRollback(t *testing.T) {
 	tester := newTester(t, 0)
 	defer tester.release()
 
-	// NOTE(freezer): This is disabled since we do not have a freezer.
-	// if err := tester.verifyHistory(); err != nil {
-	// 	t.Fatalf("Invalid state history, err: %v", err)
-	// }
+	if err := tester.verifyHistory(); err != nil {
+		t.Fatalf("Invalid state history, err: %v", err)
+	}
 	// Revert database from top to bottom
 	for i := tester.bottomIndex(); i >= 0; i-- {
 		root := tester.roots[i]
@@ -377,17 +408,14 @@ func TestDatabaseRollback(t *testing.T) {
 			parent = tester.roots[i-1]
 		}
 		loader := newHashLoader(tester.snapAccounts[root], tester.snapStorages[root])
-		// NOTE(freezer): This is disabled since we do not have a freezer.
-		// if err := tester.db.Recover(parent, loader); err != nil {
-		// 	t.Fatalf("Failed to revert db, err: %v", err)
-		// }
-		require.ErrorContains(t, tester.db.Recover(parent, loader), "state rollback is non-supported")
+		if err := tester.db.Recover(parent, loader); err != nil {
+			t.Fatalf("Failed to revert db, err: %v", err)
+		}
 		tester.verifyState(parent)
 	}
-	// NOTE(freezer): This is disabled since we do not have a freezer.
-	// if tester.db.tree.len() != 1 {
-	// 	t.Fatal("Only disk layer is expected")
-	// }
+	if tester.db.tree.len() != 1 {
+		t.Fatal("Only disk layer is expected")
+	}
 }
 
 func TestDatabaseRecoverable(t *testing.T) {
@@ -423,9 +451,7 @@ func TestDatabaseRecoverable(t *testing.T) {
 	}
 	for i, c := range cases {
 		result := tester.db.Recoverable(c.root)
-		// NOTE(freezer): This is disabled since we do not have a freezer.
-		// originally was `result != c.expect`
-		if result != false {
+		if result != c.expect {
 			t.Fatalf("case: %d, unexpected result, want %t, got %t", i, c.expect, result)
 		}
 	}
@@ -450,15 +476,14 @@ func TestDisable(t *testing.T) {
 	if blob := rawdb.ReadTrieJournal(tester.db.diskdb); len(blob) != 0 {
 		t.Fatal("Failed to clean journal")
 	}
-	// NOTE(freezer): This is disabled since we do not have a freezer.
 	// Ensure all trie histories are removed
-	// n, err := tester.db.freezer.Ancients()
-	// if err != nil {
-	// 	t.Fatal("Failed to clean state history")
-	// }
-	// if n != 0 {
-	// 	t.Fatal("Failed to clean state history")
-	// }
+	n, err := tester.db.freezer.Ancients()
+	if err != nil {
+		t.Fatal("Failed to clean state history")
+	}
+	if n != 0 {
+		t.Fatal("Failed to clean state history")
+	}
 	// Verify layer tree structure, single disk layer is expected
 	if tester.db.tree.len() != 1 {
 		t.Fatalf("Extra layer kept %d", tester.db.tree.len())
@@ -486,11 +511,10 @@ func TestCommit(t *testing.T) {
 	if err := tester.verifyState(tester.lastHash()); err != nil {
 		t.Fatalf("State is invalid, err: %v", err)
 	}
-	// NOTE(freezer): This is disabled since we do not have a freezer.
 	// Verify state histories
-	// if err := tester.verifyHistory(); err != nil {
-	// 	t.Fatalf("State history is invalid, err: %v", err)
-	// }
+	if err := tester.verifyHistory(); err != nil {
+		t.Fatalf("State history is invalid, err: %v", err)
+	}
 }
 
 func TestJournal(t *testing.T) {
@@ -559,22 +583,22 @@ func TestCorruptedJournal(t *testing.T) {
 // In this scenario, it is mandatory to update the persistent state before
 // truncating the tail histories. This ensures that the ID of the persistent state
 // always falls within the range of [oldest-history-id, latest-history-id].
-// func TestTailTruncateHistory(t *testing.T) {
-// 	tester := newTester(t, 10)
-// 	defer tester.release()
-//
-// 	tester.db.Close()
-// 	tester.db = New(tester.db.diskdb, &Config{StateHistory: 10})
-//
-// 	head, err := tester.db.freezer.Ancients()
-// 	if err != nil {
-// 		t.Fatalf("Failed to obtain freezer head")
-// 	}
-// 	stored := rawdb.ReadPersistentStateID(tester.db.diskdb)
-// 	if head != stored {
-// 		t.Fatalf("Failed to truncate excess history object above, stored: %d, head: %d", stored, head)
-// 	}
-// }
+func TestTailTruncateHistory(t *testing.T) {
+	tester := newTester(t, 10)
+	defer tester.release()
+
+	tester.db.Close()
+	tester.db = New(tester.db.diskdb, &Config{StateHistory: 10, HistoryDirectory: tester.db.config.HistoryDirectory})
+
+	head, err := tester.db.freezer.Ancients()
+	if err != nil {
+		t.Fatalf("Failed to obtain freezer head")
+	}
+	stored := rawdb.ReadPersistentStateID(tester.db.diskdb)
+	if head != stored {
+		t.Fatalf("Failed to truncate excess history object above, stored: %d, head: %d", stored, head)
+	}
+}
 
 // copyAccounts returns a deep-copied account set of the provided one.
 func copyAccounts(set map[common.Hash][]byte) map[common.Hash][]byte {
@@ -189,24 +189,23 @@ func (dl *diskLayer) commit(bottom *diffLayer, force bool) (*diskLayer, error) {
 		overflow bool
 		oldest   uint64
 	)
-	// NOTE(freezer): This is disabled since we do not have a freezer.
-	// if dl.db.freezer != nil {
-	// 	err := writeHistory(dl.db.freezer, bottom)
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	// 	// Determine if the persisted history object has exceeded the configured
-	// 	// limitation, set the overflow as true if so.
-	// 	tail, err := dl.db.freezer.Tail()
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	// 	limit := dl.db.config.StateHistory
-	// 	if limit != 0 && bottom.stateID()-tail > limit {
-	// 		overflow = true
-	// 		oldest = bottom.stateID() - limit + 1 // track the id of history **after truncation**
-	// 	}
-	// }
+	if dl.db.freezer != nil {
+		err := writeHistory(dl.db.freezer, bottom)
+		if err != nil {
+			return nil, err
+		}
+		// Determine if the persisted history object has exceeded the configured
+		// limitation, set the overflow as true if so.
+		tail, err := dl.db.freezer.Tail()
+		if err != nil {
+			return nil, err
+		}
+		limit := dl.db.config.StateHistory
+		if limit != 0 && bottom.stateID()-tail > limit {
+			overflow = true
+			oldest = bottom.stateID() - limit + 1 // track the id of history **after truncation**
+		}
+	}
 	// Mark the diskLayer as stale before applying any mutations on top.
 	dl.stale = true
 
@@ -236,17 +235,15 @@ func (dl *diskLayer) commit(bottom *diffLayer, force bool) (*diskLayer, error) {
 	// To remove outdated history objects from the end, we set the 'tail' parameter
 	// to 'oldest-1' due to the offset between the freezer index and the history ID.
 	if overflow {
-		// NOTE(freezer): This is disabled since we do not have a freezer.
-		// 	pruned, err := truncateFromTail(ndl.db.diskdb, ndl.db.freezer, oldest-1)
-		// 	if err != nil {
-		// 		return nil, err
-		// 	}
-		// 	log.Debug("Pruned state history", "items", pruned, "tailid", oldest)
+		pruned, err := truncateFromTail(ndl.db.diskdb, ndl.db.freezer, oldest-1)
+		if err != nil {
+			return nil, err
+		}
+		log.Debug("Pruned state history", "items", pruned, "tailid", oldest)
 	}
 	return ndl, nil
 }
 
-// nolint: unused
 // revert applies the given state history and return a reverted disk layer.
 func (dl *diskLayer) revert(h *history, loader triestate.TrieLoader) (*diskLayer, error) {
 	if h.meta.root != dl.rootHash() {
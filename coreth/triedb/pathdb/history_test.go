@@ -28,13 +28,16 @@ package pathdb
 
 import (
 	"bytes"
+	"fmt"
 	"reflect"
 	"testing"
 
+	"github.com/ava-labs/coreth/core/rawdb"
 	"github.com/ava-labs/coreth/core/types"
 	"github.com/ava-labs/coreth/trie/testutil"
 	"github.com/ava-labs/coreth/trie/triestate"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -61,7 +64,6 @@ func makeHistory() *history {
 	return newHistory(testutil.RandomHash(), types.EmptyRootHash, 0, randomStateSet(3))
 }
 
-// nolint: unused
 func makeHistories(n int) []*history {
 	var (
 		parent = types.EmptyRootHash
@@ -110,6 +112,126 @@ func TestEncodeDecodeHistory(t *testing.T) {
 	}
 }
 
+func checkHistory(t *testing.T, db ethdb.KeyValueReader, freezer ethdb.AncientReader, id uint64, root common.Hash, exist bool) {
+	blob := rawdb.ReadStateHistoryMeta(freezer, id)
+	if exist && len(blob) == 0 {
+		t.Fatalf("Failed to load trie history, %d", id)
+	}
+	if !exist && len(blob) != 0 {
+		t.Fatalf("Unexpected trie history, %d", id)
+	}
+	if exist && rawdb.ReadStateID(db, root) == nil {
+		t.Fatalf("Root->ID mapping is not found, %d", id)
+	}
+	if !exist && rawdb.ReadStateID(db, root) != nil {
+		t.Fatalf("Unexpected root->ID mapping, %d", id)
+	}
+}
+
+func checkHistoriesInRange(t *testing.T, db ethdb.KeyValueReader, freezer ethdb.AncientReader, from, to uint64, roots []common.Hash, exist bool) {
+	for i, j := from, 0; i <= to; i, j = i+1, j+1 {
+		checkHistory(t, db, freezer, i, roots[j], exist)
+	}
+}
+
+// writeHistories stores the given histories along with the root->id mappings
+// of the states they link, ids are assigned from one. It returns the roots and
+// the parent roots of the histories.
+func writeHistories(t *testing.T, db ethdb.KeyValueWriter, freezer ethdb.AncientWriter, hs []*history) ([]common.Hash, []common.Hash) {
+	var roots, parents []common.Hash
+	rawdb.WriteStateID(db, hs[0].meta.parent, 0)
+	for i, h := range hs {
+		accountData, storageData, accountIndex, storageIndex := h.encode()
+		if err := rawdb.WriteStateHistory(freezer, uint64(i+1), h.meta.encode(), accountIndex, storageIndex, accountData, storageData); err != nil {
+			t.Fatalf("Failed to write state history, %v", err)
+		}
+		rawdb.WriteStateID(db, h.meta.root, uint64(i+1))
+		roots = append(roots, h.meta.root)
+		parents = append(parents, h.meta.parent)
+	}
+	return roots, parents
+}
+
+func TestTruncateHeadHistory(t *testing.T) {
+	var (
+		db         = rawdb.NewMemoryDatabase()
+		freezer, _ = rawdb.NewStateFreezer(t.TempDir())
+		hs         = makeHistories(10)
+	)
+	defer freezer.Close()
+
+	roots, _ := writeHistories(t, db, freezer, hs)
+	for size := len(hs); size > 0; size-- {
+		pruned, err := truncateFromHead(db, freezer, uint64(size-1))
+		if err != nil {
+			t.Fatalf("Failed to truncate from head %v", err)
+		}
+		if pruned != 1 {
+			t.Error("Unexpected pruned items", "want", 1, "got", pruned)
+		}
+		checkHistoriesInRange(t, db, freezer, uint64(size), uint64(10), roots[size-1:], false)
+		checkHistoriesInRange(t, db, freezer, uint64(1), uint64(size-1), roots[:size-1], true)
+	}
+}
+
+func TestTruncateTailHistory(t *testing.T) {
+	var (
+		db         = rawdb.NewMemoryDatabase()
+		freezer, _ = rawdb.NewStateFreezer(t.TempDir())
+		hs         = makeHistories(10)
+	)
+	defer freezer.Close()
+
+	// The root->id mapping of the state a history reverts to is deleted along
+	// with the history.
+	_, parents := writeHistories(t, db, freezer, hs)
+	for newTail := 1; newTail < len(hs); newTail++ {
+		pruned, _ := truncateFromTail(db, freezer, uint64(newTail))
+		if pruned != 1 {
+			t.Error("Unexpected pruned items", "want", 1, "got", pruned)
+		}
+		checkHistoriesInRange(t, db, freezer, uint64(1), uint64(newTail), parents[:newTail], false)
+		checkHistoriesInRange(t, db, freezer, uint64(newTail+1), uint64(10), parents[newTail:], true)
+	}
+}
+
+func TestTruncateTailHistories(t *testing.T) {
+	var cases = []struct {
+		limit       uint64
+		expPruned   int
+		maxPruned   uint64
+		minUnpruned uint64
+		empty       bool
+	}{
+		{1, 9, 9, 10, false},
+		{0, 10, 10, 0 /* no meaning */, true},
+		{10, 0, 0, 1, false},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case-%d", i), func(t *testing.T) {
+			var (
+				db         = rawdb.NewMemoryDatabase()
+				freezer, _ = rawdb.NewStateFreezer(t.TempDir())
+				hs         = makeHistories(10)
+			)
+			defer freezer.Close()
+
+			_, parents := writeHistories(t, db, freezer, hs)
+			pruned, _ := truncateFromTail(db, freezer, uint64(10)-c.limit)
+			if pruned != c.expPruned {
+				t.Error("Unexpected pruned items", "want", c.expPruned, "got", pruned)
+			}
+			if c.empty {
+				checkHistoriesInRange(t, db, freezer, uint64(1), uint64(10), parents, false)
+			} else {
+				tail := 10 - int(c.limit)
+				checkHistoriesInRange(t, db, freezer, uint64(1), c.maxPruned, parents[:tail], false)
+				checkHistoriesInRange(t, db, freezer, c.minUnpruned, uint64(10), parents[tail:], true)
+			}
+		})
+	}
+}
+
 func compareSet[k comparable](a, b map[k][]byte) bool {
 	if len(a) != len(b) {
 		return false
@@ -28,7 +28,6 @@ package pathdb
 
 import "github.com/ava-labs/coreth/metrics"
 
-// nolint: unused
 var (
 	cleanHitMeter   = metrics.NewRegisteredMeter("pathdb/clean/hit", nil)
 	cleanMissMeter  = metrics.NewRegisteredMeter("pathdb/clean/miss", nil)